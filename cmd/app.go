@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/cmd/server/config"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/cmd/server/external/database"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/cmd/server/external/notifier"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/appointment"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/audit"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/dentist"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/patient"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/reminder"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/report"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/user"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/waitlist"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/webhook"
+	"gorm.io/gorm"
+)
+
+// app holds the config and the services every command shares, the background jobs of the services only run
+// with serve
+type app struct {
+	config       *config.EnvConfig
+	db           *gorm.DB
+	migrator     *database.Migrator
+	dentists     *dentist.Service
+	patients     *patient.Service
+	appointments *appointment.Service
+	waitlist     *waitlist.Service
+	reminders    *reminder.Service
+	webhooks     *webhook.Service
+	reports      *report.Service
+	users        *user.Service
+	audit        *audit.Service
+}
+
+// loadConfig merges the config of the environment once the flags of the loader are parsed
+func loadConfig(loader *config.Loader) (*config.EnvConfig, error) {
+	envConfig, err := loader.Load()
+	if err != nil {
+		return nil, fmt.Errorf("loading config: %w", err)
+	}
+	return envConfig, nil
+}
+
+// newApp connects to the database of the config, migrates it as DB_MIGRATE says and wires the services
+func newApp(envConfig *config.EnvConfig) (*app, error) {
+	db, err := connect(envConfig)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to database: %w", err)
+	}
+
+	// The migrator is kept for the readiness checks, so they don't load the migrations again on every probe
+	migrator, err := database.NewMigrator(db, envConfig.Private.Location)
+	if err != nil {
+		return nil, fmt.Errorf("loading migrations: %w", err)
+	}
+
+	err = migrateOnStart(envConfig, migrator)
+	if err != nil {
+		return nil, err
+	}
+
+	location := envConfig.Private.Location
+	a := &app{config: envConfig, db: db, migrator: migrator}
+
+	// Dentists, patients and appointments
+	a.dentists = dentist.NewService(database.NewDentistRepository(db), location)
+	a.patients = patient.NewService(database.NewPatientRepository(db))
+	a.appointments = appointment.NewService(database.NewOtherAppointmentRepository(db), location)
+
+	// Waitlist, freed slots are offered to the queue and offers that aren't answered in time move on
+	a.waitlist = waitlist.NewService(database.NewWaitlistRepository(db), a.appointments, location, envConfig.Private.WaitlistOfferTTL)
+	a.appointments.OnSlotFreed(a.waitlist.SlotFreed)
+	a.dentists.OnSlotFreed(a.waitlist.SlotFreed)
+	a.patients.OnSlotFreed(a.waitlist.SlotFreed)
+
+	// Reminders, sent before the appointments through the configured notifier
+	var notifierChannel reminder.Notifier
+	switch envConfig.Private.Notifier {
+	case "smtp":
+		notifierChannel = notifier.NewSMTPNotifier(notifier.SMTPParams{
+			Host:     envConfig.Private.SMTPHost,
+			Port:     envConfig.Private.SMTPPort,
+			Username: envConfig.Private.SMTPUsername,
+			Password: envConfig.Private.SMTPPassword,
+			From:     envConfig.Private.SMTPFrom,
+		})
+	case "file":
+		notifierChannel = notifier.NewFileNotifier(envConfig.Private.NotifierFile)
+	default:
+		notifierChannel = notifier.NewFileNotifier("")
+	}
+	a.reminders = reminder.NewService(database.NewReminderRepository(db), notifierChannel, location, envConfig.Private.ReminderOffsets)
+
+	// Webhooks, the events the services store in the outbox are POSTed to the subscriptions
+	a.webhooks = webhook.NewService(database.NewWebhookRepository(db), &http.Client{Timeout: 10 * time.Second})
+
+	// Reports, users and audit
+	a.reports = report.NewService(database.NewReportRepository(db))
+	a.users = user.NewService(database.NewUserRepository(db), user.TokenConfig{
+		SigningKey: []byte(envConfig.Private.SecretKey),
+		AccessTTL:  envConfig.Private.AccessTokenTTL,
+		RefreshTTL: envConfig.Private.RefreshTokenTTL,
+	})
+	a.audit = audit.NewService(database.NewAuditRepository(db))
+
+	return a, nil
+}
+
+// close closes the connections to the database, the services can't be used after it
+func (a *app) close() error {
+	sqlDB, err := a.db.DB()
+	if err != nil {
+		return err
+	}
+
+	err = sqlDB.Close()
+	if err != nil {
+		return fmt.Errorf("closing database: %w", err)
+	}
+	return nil
+}
+
+// connect opens the database of the config
+func connect(envConfig *config.EnvConfig) (*gorm.DB, error) {
+	return database.Connect(database.ConnectionParams{
+		Driver:   envConfig.Private.DBDriver,
+		SSLMode:  envConfig.Private.DBSSLMode,
+		User:     envConfig.Private.DBUser,
+		Password: envConfig.Private.DBPass,
+		Host:     envConfig.Private.DBHost,
+		Port:     envConfig.Private.DBPort,
+		Database: envConfig.Private.DBName,
+
+		MaxOpenConns:    envConfig.Private.DBMaxOpenConns,
+		MaxIdleConns:    envConfig.Private.DBMaxIdleConns,
+		ConnMaxLifetime: envConfig.Private.DBConnMaxLifetime,
+		LogLevel:        envConfig.Private.LogLevel,
+	})
+}
+
+// migrateOnStart applies the pending migrations with DB_MIGRATE, without it there must be none
+func migrateOnStart(envConfig *config.EnvConfig, migrator *database.Migrator) error {
+	if !envConfig.Private.DBMigrate {
+		pending, err := migrator.Pending()
+		if err != nil {
+			return fmt.Errorf("checking migrations: %w", err)
+		}
+		if len(pending) > 0 {
+			return internal.ErMigrationsPending
+		}
+		return nil
+	}
+
+	applied, err := migrator.Up()
+	if err != nil {
+		return fmt.Errorf("migrating database: %w", err)
+	}
+	for _, migration := range applied {
+		log.Printf("database: applied migration %04d_%s", migration.Version, migration.Name)
+	}
+	return nil
+}
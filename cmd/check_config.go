@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/cmd/server/external/database"
+)
+
+// checkConfig loads the config and reports its settings without the secrets, then connects to the database and
+// counts the pending migrations unless it is offline. It fails like serve would on start
+func checkConfig(args []string) error {
+	set, loader := configFlags("check-config")
+	offline := set.Bool("offline", false, "only check the config, without connecting to the database")
+	err := set.Parse(args)
+	if err != nil {
+		return err
+	}
+	if set.NArg() > 0 {
+		return errors.New("check-config takes no arguments besides the flags")
+	}
+
+	envConfig, err := loadConfig(loader)
+	if err != nil {
+		return err
+	}
+
+	private := envConfig.Private
+	target := private.DBName
+	if private.DBDriver != database.DriverSQLite {
+		target = fmt.Sprintf("%s@%s:%s/%s", private.DBUser, private.DBHost, private.DBPort, private.DBName)
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(writer, "Environment\t%s\n", envConfig.Public.Env)
+	fmt.Fprintf(writer, "Address\t%s%s\n", private.Host, private.BasePath)
+	fmt.Fprintf(writer, "Log level\t%s\n", private.LogLevel)
+	fmt.Fprintf(writer, "HTTP timeouts\tread header %s, write %s, imports and exports %s, idle %s, shutdown %s\n", private.HTTPReadHeaderTimeout, private.HTTPWriteTimeout, private.HTTPTransferTimeout, private.HTTPIdleTimeout, private.ShutdownTimeout)
+	fmt.Fprintf(writer, "HTTP max header\t%d bytes\n", private.HTTPMaxHeaderBytes)
+	fmt.Fprintf(writer, "Readiness\tchecks within %s, draining for %s on shutdown\n", private.ReadyCheckTimeout, private.ShutdownDrainDelay)
+	fmt.Fprintf(writer, "Time zone\t%s\n", private.Location)
+	fmt.Fprintf(writer, "Token TTLs\taccess %s, refresh %s\n", private.AccessTokenTTL, private.RefreshTokenTTL)
+	fmt.Fprintf(writer, "Reminders\t%v through %s\n", private.ReminderOffsets, private.Notifier)
+	fmt.Fprintf(writer, "Database\t%s %s\n", private.DBDriver, target)
+	fmt.Fprintf(writer, "Migrate on start\t%t\n", private.DBMigrate)
+	fmt.Fprintf(writer, "Connection pool\t%d open, %d idle, %s lifetime\n", private.DBMaxOpenConns, private.DBMaxIdleConns, private.DBConnMaxLifetime)
+	err = writer.Flush()
+	if err != nil || *offline {
+		return err
+	}
+
+	db, err := connect(envConfig)
+	if err != nil {
+		return fmt.Errorf("connecting to database: %w", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	err = sqlDB.Ping()
+	if err != nil {
+		return fmt.Errorf("connecting to database: %w", err)
+	}
+
+	migrator, err := database.NewMigrator(db, private.Location)
+	if err != nil {
+		return fmt.Errorf("loading migrations: %w", err)
+	}
+
+	pending, err := migrator.Pending()
+	if err != nil {
+		return fmt.Errorf("checking migrations: %w", err)
+	}
+
+	fmt.Printf("Database reachable, %d pending migrations\n", len(pending))
+	if len(pending) > 0 && !private.DBMigrate {
+		return errors.New("the server won't start until the pending migrations are applied with migrate up")
+	}
+	return nil
+}
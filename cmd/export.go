@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/cmd/server/handler"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/export"
+)
+
+// exportCommand writes every dentist, patient or appointment to a file or the standard output, with the columns
+// of the export endpoints and the dates in the clinic time zone
+func exportCommand(args []string) error {
+	set, loader := configFlags("export")
+	format := set.String("format", export.CSV, "csv, jsonl or xlsx")
+	output := set.String("o", "", "file to write, the standard output when empty")
+	includeDeleted := set.Bool("include-deleted", false, "also export deleted dentists or patients")
+	err := set.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if set.NArg() != 1 || !slices.Contains([]string{"dentists", "patients", "appointments"}, set.Arg(0)) {
+		return errors.New("usage: export [-format csv|jsonl|xlsx] [-o file] [-include-deleted] dentists|patients|appointments")
+	}
+	if !slices.Contains(export.Formats, *format) {
+		return fmt.Errorf("format must be one of %v", export.Formats)
+	}
+
+	envConfig, err := loadConfig(loader)
+	if err != nil {
+		return err
+	}
+
+	a, err := newApp(envConfig)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	var file *os.File
+	if *output != "" {
+		file, err = os.Create(*output)
+		if err != nil {
+			return err
+		}
+		w = file
+	}
+
+	location := envConfig.Private.Location
+	switch set.Arg(0) {
+	case "dentists":
+		err = handler.NewDentistHandler(a.dentists).ExportTo(w, *format, location, *includeDeleted)
+	case "patients":
+		err = handler.NewPatientHandler(a.patients).ExportTo(w, *format, location, *includeDeleted)
+	default:
+		err = handler.NewAppointmentHandler(a.appointments, a.patients, a.dentists).ExportTo(w, *format, location)
+	}
+	if file != nil {
+		err = errors.Join(err, file.Close())
+	}
+	if err != nil {
+		return fmt.Errorf("exporting %s: %w", set.Arg(0), err)
+	}
+	return nil
+}
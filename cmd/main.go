@@ -1,22 +1,18 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
-	"net/http"
-
-	"github.com/10Daniel10/web-server-go-ExamenFinal/docs"
-	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/appointment"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	// The time zones are embedded so TIME_ZONE works on hosts without a zoneinfo database
+	_ "time/tzdata"
 
 	"github.com/10Daniel10/web-server-go-ExamenFinal/cmd/server/config"
-	"github.com/10Daniel10/web-server-go-ExamenFinal/cmd/server/external/database"
-	"github.com/10Daniel10/web-server-go-ExamenFinal/cmd/server/handler"
-	"github.com/10Daniel10/web-server-go-ExamenFinal/cmd/server/middleware"
-	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/dentist"
-	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/patient"
-	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
-	swaggerFiles "github.com/swaggo/files"
-	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
 //	@title			Dental Clinic API
@@ -51,127 +47,109 @@ import (
 //	@license.name	Apache 2.0
 //	@license.url	http://www.apache.org/licenses/LICENSE-2.0.html
 
-//	@securityDefinitions.apikey	APIKey
+//	@tag.name			Auth
+//	@tag.description	Login and token refresh
+
+//	@tag.name			User
+//	@tag.description	User operations for managing User
+
+//	@tag.name			Waitlist
+//	@tag.description	Queue of Patients waiting for a freed slot of a Dentist
+
+//	@tag.name			Webhook
+//	@tag.description	Subscriptions of URLs to the events of Patients, Dentists and Appointments
+
+//	@tag.name			Report
+//	@tag.description	Utilization, attendance and admissions computed from the Appointments and Patients
+
+//	@tag.name			Audit
+//	@tag.description	Log of the changes made to Patients, Dentists and Appointments
+
+//	@securityDefinitions.apikey	BearerAuth
 //	@in							header
-//	@name						SECRET_KEY
-//	@description				Add secret key here
+//	@name						Authorization
+//	@description				Add "Bearer " followed by the access token given by /auth/login
+
+//	@securityDefinitions.apikey	FeedToken
+//	@in							query
+//	@name						token
+//	@description				Feed token given by /auth/feed-token, only for the calendar feeds
 
 //	@externalDocs.description	OpenAPI
 //	@externalDocs.url			https://swagger.io/resources/open-api/
 
 func main() {
-	var err error
-
-	err = godotenv.Load(".env")
-	if err != nil {
-		panic(fmt.Sprintf("Error loading .env file: %v", err))
-		return
+	// The .env file is optional, its variables don't override the ones already set
+	err := godotenv.Load(".env")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		fmt.Fprintf(os.Stderr, "Error loading .env file: %v\n", err)
+		os.Exit(1)
 	}
 
-	envConfig, err := config.NewEnvConfig("local")
-	if err != nil {
-		panic(fmt.Sprintf("Error loading config: %v", err))
-		return
+	name, args := "serve", []string{}
+	if len(os.Args) > 1 {
+		name, args = os.Args[1], os.Args[2:]
 	}
 
-	db, err := database.Connect(database.ConnectionParams{
-		User:     envConfig.Private.DBUser,
-		Password: envConfig.Private.DBPass,
-		Host:     envConfig.Private.DBHost,
-		Port:     envConfig.Private.DBPort,
-		Database: envConfig.Private.DBName,
-	})
-	if err != nil {
-		panic(fmt.Sprintf("Error connecting to database: %v", err))
+	if name == "help" || name == "-h" || name == "--help" {
+		fmt.Print(usage())
 		return
 	}
 
-	// Initialize and inject dependencies
-	// Dentists
-	dentistRepository := database.NewDentistRepository(db)
-	dentistService := dentist.NewService(dentistRepository)
-	dentistController := handler.NewDentistHandler(dentistService)
-
-	// Patients
-	patientRepository := database.NewPatientRepository(db)
-	patientService := patient.NewService(patientRepository)
-	patientController := handler.NewPatientHandler(patientService)
-
-	// Appointments
-	appointmentRepository := database.NewOtherAppointmentRepository(db)
-	appointmentService := appointment.NewService(appointmentRepository)
-	appointmentController := handler.NewAppointmentHandler(appointmentService, patientService, dentistService)
-
-	router := config.SetupRouter()
-	{
-		// Define global behavior
-		router.NoRoute(func(c *gin.Context) {
-			c.JSON(http.StatusNotFound, gin.H{"message": "Not found"})
-		})
-
-		router.NoMethod(func(c *gin.Context) {
-			c.JSON(http.StatusMethodNotAllowed, gin.H{"message": "Method not allowed"})
-		})
-	}
-	baseGroup := router.Group(envConfig.Private.BasePath)
-	{
-		baseGroup.GET("/ping", func(c *gin.Context) {
-			c.JSON(200, gin.H{
-				"message": "pong",
-			})
-		})
+	current, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", name, usage())
+		os.Exit(2)
 	}
 
-	//Auth middleware
-	authKeys := middleware.NewAuthKeys(envConfig.Private.SecretKey, envConfig.Public.PubKey)
-
-	docsGroup := baseGroup.Group("/docs")
-	{
-		docs.SwaggerInfo.Host = envConfig.Private.Host
-		docs.SwaggerInfo.BasePath = envConfig.Private.BasePath
-		docsGroup.GET("/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	err = current.run(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return
 	}
-
-	dentistGroup := baseGroup.Group("/dentists")
-	{
-		// Configure routes
-		dentistGroup.GET("", dentistController.GetAll)
-		dentistGroup.GET("/q", dentistController.GetByLicense)
-		dentistGroup.GET("/:id", dentistController.GetById)
-		dentistGroup.POST("", authKeys.Validate, dentistController.Create)
-		dentistGroup.PUT("/:id", authKeys.Validate, dentistController.Update)
-		dentistGroup.PATCH("/:id", authKeys.Validate, dentistController.Patch)
-		dentistGroup.DELETE("/:id", authKeys.Validate, dentistController.Delete)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
 	}
+}
 
-	patientGroup := baseGroup.Group("/patients")
-	{
-		// Configure routes
-		patientGroup.GET("", patientController.GetAll)
-		patientGroup.GET("/q", patientController.GetByDNI)
-		patientGroup.GET("/:id", patientController.GetById)
-		patientGroup.POST("", authKeys.Validate, patientController.Create)
-		patientGroup.PUT("/:id", authKeys.Validate, patientController.Update)
-		patientGroup.PATCH("/:id", authKeys.Validate, patientController.Patch)
-		patientGroup.DELETE("/:id", authKeys.Validate, patientController.Delete)
-	}
+// command is a subcommand of the binary, run gets the arguments that follow its name
+type command struct {
+	usage string
+	run   func(args []string) error
+}
 
-	appointmentGroup := baseGroup.Group("/appointments")
-	{
-		// Configure routes
-		appointmentGroup.GET("", appointmentController.GetAll)
-		appointmentGroup.GET("/:id", appointmentController.GetById)
-		appointmentGroup.GET("/q", appointmentController.GetByDNI)
-		appointmentGroup.POST("", authKeys.Validate, appointmentController.Create)
-		appointmentGroup.PUT("/:id", authKeys.Validate, appointmentController.Update)
-		appointmentGroup.PATCH("/:id", authKeys.Validate, appointmentController.Patch)
-		appointmentGroup.DELETE("/:id", authKeys.Validate, appointmentController.Delete)
+// commands are the subcommands of the binary, serve runs when none is given
+var commands = map[string]command{
+	"serve":        {usage: "serve [flags]                          run the web server", run: serve},
+	"migrate":      {usage: "migrate [flags] up|down|status|create  change the schema of the database", run: migrate},
+	"seed":         {usage: "seed [flags]                           fill the database with fake dentists, patients and appointments", run: seed},
+	"user":         {usage: "user create [flags]                    create a user, e.g. the first admin", run: userCommand},
+	"export":       {usage: "export [flags] <entity>                write the dentists, patients or appointments to a file", run: exportCommand},
+	"check-config": {usage: "check-config [flags]                   check the config and the connection to the database", run: checkConfig},
+}
 
+// usage lists the commands
+func usage() string {
+	lines := make([]string, 0, len(commands))
+	for _, current := range commands {
+		lines = append(lines, "  "+current.usage)
 	}
+	sort.Strings(lines)
+	return "Usage: <binary> <command> [arguments]\n\nCommands:\n" + strings.Join(lines, "\n") + "\n\n" +
+		"The flags of every command include -env, -config and one per setting, see <command> -h\n"
+}
 
-	err = router.Run(envConfig.Private.Host)
-	if err != nil {
-		panic(fmt.Sprintf("Error running server: %v", err))
-		return
-	}
+// configFlags returns the flag set of a command that loads the config, with the flags of the settings
+func configFlags(name string) (*flag.FlagSet, *config.Loader) {
+	set := flags(name)
+	loader := config.NewLoader()
+	loader.RegisterFlags(set)
+	return set, loader
+}
+
+// flags returns the flag set of a command, its errors are returned by Parse instead of exiting
+func flags(name string) *flag.FlagSet {
+	set := flag.NewFlagSet(name, flag.ContinueOnError)
+	set.SetOutput(os.Stderr)
+	return set
 }
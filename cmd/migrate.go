@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/cmd/server/external/database"
+)
+
+const migrateUsage = "usage: migrate [flags] up | down [steps] | status | create <name>"
+
+// migrate runs the migrate subcommand, up applies the pending migrations, down reverts the last ones (one by
+// default), status lists them and create writes the files of a new one in the source tree
+func migrate(args []string) error {
+	set, loader := configFlags("migrate")
+	err := set.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	args = set.Args()
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	if args[0] == "create" {
+		if len(args) != 2 {
+			return errors.New(migrateUsage)
+		}
+
+		paths, err := database.CreateMigration(database.MigrationsDir, args[1])
+		if err != nil {
+			return err
+		}
+		for _, path := range paths {
+			fmt.Println("Created", path)
+		}
+		return nil
+	}
+
+	envConfig, err := loadConfig(loader)
+	if err != nil {
+		return err
+	}
+
+	db, err := connect(envConfig)
+	if err != nil {
+		return fmt.Errorf("connecting to database: %w", err)
+	}
+
+	migrator, err := database.NewMigrator(db, envConfig.Private.Location)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case args[0] == "up" && len(args) == 1:
+		applied, err := migrator.Up()
+		for _, migration := range applied {
+			fmt.Printf("Applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Println("No pending migrations")
+		}
+		return err
+
+	case args[0] == "down" && len(args) <= 2:
+		steps := 1
+		if len(args) == 2 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("steps must be a positive number")
+			}
+		}
+
+		reverted, err := migrator.Down(steps)
+		for _, migration := range reverted {
+			fmt.Printf("Reverted %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(reverted) == 0 {
+			fmt.Println("No applied migrations")
+		}
+		return err
+
+	case args[0] == "status" && len(args) == 1:
+		status, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "VERSION\tNAME\tAPPLIED AT")
+		for _, current := range status {
+			appliedAt := "pending"
+			if current.AppliedAt != nil {
+				appliedAt = current.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(writer, "%04d\t%s\t%s\n", current.Version, current.Name, appliedAt)
+		}
+		return writer.Flush()
+
+	default:
+		return errors.New(migrateUsage)
+	}
+}
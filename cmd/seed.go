@@ -0,0 +1,184 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/appointment"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/auth"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/dentist"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/patient"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/schedule"
+)
+
+var (
+	seedNames     = []string{"Ana", "Bruno", "Carla", "Diego", "Elena", "Facundo", "Gabriela", "Hernán", "Inés", "Julián", "Lucía", "Martín", "Natalia", "Pablo", "Romina", "Santiago", "Valentina", "Tomás"}
+	seedLastnames = []string{"Fernández", "González", "Rodríguez", "López", "Martínez", "García", "Pérez", "Sánchez", "Romero", "Sosa", "Álvarez", "Torres", "Ruiz", "Ramírez", "Flores", "Benítez", "Acosta", "Medina"}
+	seedStreets   = []string{"Av. Corrientes", "Av. Santa Fe", "Av. Rivadavia", "Calle Florida", "Av. Cabildo", "Calle Defensa", "Av. Belgrano", "Calle Lavalle"}
+	seedReasons   = []string{"Checkup", "Cleaning", "Filling", "Root canal", "Extraction", "Whitening", "Braces adjustment", "Tooth pain"}
+)
+
+// seedShifts are the working hours of the seeded dentists, from Monday to Friday
+var seedShifts = [][2]string{{"09:00", "13:00"}, {"14:00", "18:00"}}
+
+// seed fills the database with fake dentists, patients and appointments for demos, created through the services
+// so they are checked, audited and published like the ones of the API. Appointments are spread four weeks around
+// today, the past ones are completed, missed or cancelled and some upcoming ones are confirmed
+func seed(args []string) error {
+	set, loader := configFlags("seed")
+	dentists := set.Int("dentists", 5, "number of dentists")
+	patients := set.Int("patients", 40, "number of patients")
+	appointments := set.Int("appointments", 150, "number of appointments")
+	randomSeed := set.Int64("seed", 0, "seed of the fake data, a random one when 0")
+	err := set.Parse(args)
+	if err != nil {
+		return err
+	}
+	if set.NArg() > 0 || *dentists < 1 || *patients < 1 || *appointments < 0 {
+		return errors.New("seed needs at least one dentist and one patient, and no arguments besides the flags")
+	}
+
+	if *randomSeed == 0 {
+		*randomSeed = time.Now().UnixNano()
+	}
+	random := rand.New(rand.NewSource(*randomSeed))
+
+	envConfig, err := loadConfig(loader)
+	if err != nil {
+		return err
+	}
+
+	a, err := newApp(envConfig)
+	if err != nil {
+		return err
+	}
+	location := envConfig.Private.Location
+
+	var hours []schedule.WorkingHours
+	for weekday := time.Monday; weekday <= time.Friday; weekday++ {
+		for _, shift := range seedShifts {
+			hours = append(hours, schedule.WorkingHours{Weekday: weekday, Start: shift[0], End: shift[1]})
+		}
+	}
+
+	dentistIDs := make([]uint, 0, *dentists)
+	for len(dentistIDs) < *dentists {
+		created, err := a.dentists.Create(auth.System, dentist.Dentist{
+			Name:     pick(random, seedNames),
+			Lastname: pick(random, seedLastnames),
+			License:  fmt.Sprintf("MP-%06d", random.Intn(1000000)),
+		})
+		if errors.Is(err, internal.ErLicenseAlreadyExists) || errors.Is(err, internal.ErLicenseBelongsToDeleted) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("creating dentist: %w", err)
+		}
+
+		_, err = a.dentists.UpdateSchedule(auth.System, created.ID, hours)
+		if err != nil {
+			return fmt.Errorf("creating schedule of dentist %d: %w", created.ID, err)
+		}
+		dentistIDs = append(dentistIDs, created.ID)
+	}
+
+	now := time.Now()
+	patientIDs := make([]uint, 0, *patients)
+	for len(patientIDs) < *patients {
+		name, lastname := pick(random, seedNames), pick(random, seedLastnames)
+		dni := fmt.Sprintf("%08d", 20000000+random.Intn(30000000))
+		created, err := a.patients.Create(auth.System, patient.Patient{
+			Name:          name,
+			Lastname:      lastname,
+			Address:       fmt.Sprintf("%s %d", pick(random, seedStreets), 100+random.Intn(4900)),
+			DNI:           dni,
+			Email:         fmt.Sprintf("%s.%s.%s@example.com", plain(name), plain(lastname), dni[len(dni)-3:]),
+			AdmissionDate: now.Add(-time.Duration(random.Int63n(int64(2 * 365 * 24 * time.Hour)))),
+		})
+		if errors.Is(err, internal.ErDniAlreadyExists) || errors.Is(err, internal.ErDniBelongsToDeleted) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("creating patient: %w", err)
+		}
+		patientIDs = append(patientIDs, created.ID)
+	}
+
+	// Slots that are taken or fall on weekends are tried again, up to a few times the appointments asked for
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+	booked := 0
+	for attempt := 0; booked < *appointments && attempt < *appointments*5; attempt++ {
+		day := today.AddDate(0, 0, random.Intn(57)-28)
+		if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+			continue
+		}
+
+		// Half hour slots of a shift, an hour long appointment doesn't start in the last slot
+		duration := uint(30 * (1 + random.Intn(2)))
+		shift := seedShifts[random.Intn(len(seedShifts))]
+		start, _ := time.ParseInLocation("2006-01-02 15:04", day.Format("2006-01-02 ")+shift[0], location)
+		slots := 8 - int(duration/30) + 1
+		date := start.Add(time.Duration(random.Intn(slots)*30) * time.Minute)
+
+		created, err := a.appointments.Create(auth.System, appointment.Appointment{
+			PatientID:   pick(random, patientIDs),
+			DentistID:   pick(random, dentistIDs),
+			Date:        date,
+			Duration:    duration,
+			Description: pick(random, seedReasons),
+		})
+		if errors.Is(err, internal.ErAppointmentConflict) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("creating appointment: %w", err)
+		}
+		booked++
+
+		for _, status := range seedLifecycle(random, created.EndDate.Before(now)) {
+			reason := ""
+			if status == appointment.StatusCancelled {
+				reason = "Patient asked to cancel"
+			}
+			_, err = a.appointments.Transition(auth.System, created.ID, status, reason)
+			if err != nil {
+				return fmt.Errorf("moving appointment %d to %s: %w", created.ID, status, err)
+			}
+		}
+	}
+
+	fmt.Printf("Created %d dentists, %d patients and %d appointments with seed %d\n", len(dentistIDs), len(patientIDs), booked, *randomSeed)
+	return nil
+}
+
+// seedLifecycle returns the statuses a new appointment moves through, past appointments are closed
+func seedLifecycle(random *rand.Rand, past bool) []appointment.Status {
+	chance := random.Intn(100)
+	switch {
+	case past && chance < 75:
+		return []appointment.Status{appointment.StatusCheckedIn, appointment.StatusCompleted}
+	case past && chance < 85:
+		return []appointment.Status{appointment.StatusNoShow}
+	case past:
+		return []appointment.Status{appointment.StatusCancelled}
+	case chance < 30:
+		return []appointment.Status{appointment.StatusConfirmed}
+	case chance < 35:
+		return []appointment.Status{appointment.StatusCancelled}
+	default:
+		return nil
+	}
+}
+
+func pick[T any](random *rand.Rand, items []T) T {
+	return items[random.Intn(len(items))]
+}
+
+// plain lowercases a name and drops its accents, for the emails
+func plain(name string) string {
+	return strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u").Replace(strings.ToLower(name))
+}
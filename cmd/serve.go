@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/cmd/server/config"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/cmd/server/external/database"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/cmd/server/handler"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/cmd/server/middleware"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/docs"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/auth"
+	"github.com/gin-gonic/gin"
+	swaggerFiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
+	"gorm.io/gorm"
+)
+
+// serve runs the web server and the background jobs of the services, it is the command when none is given. On
+// SIGINT or SIGTERM it stops taking requests, lets the ones in flight finish within SHUTDOWN_TIMEOUT, stops the
+// jobs and closes the database; a second signal stops it at once
+func serve(args []string) error {
+	set, loader := configFlags("serve")
+	err := set.Parse(args)
+	if err != nil {
+		return err
+	}
+	if set.NArg() > 0 {
+		return errors.New("serve takes no arguments besides the flags")
+	}
+
+	envConfig, err := loadConfig(loader)
+	if err != nil {
+		return err
+	}
+
+	a, err := newApp(envConfig)
+	if err != nil {
+		return err
+	}
+
+	err = a.users.Bootstrap(envConfig.Private.AdminUsername, envConfig.Private.AdminPassword)
+	if err != nil {
+		return fmt.Errorf("creating admin user: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Background jobs, offers that aren't answered in time, due reminders and pending webhook deliveries
+	var jobs sync.WaitGroup
+	jobs.Add(3)
+	go func() {
+		defer jobs.Done()
+		a.waitlist.Run(ctx, time.Minute)
+	}()
+	go func() {
+		defer jobs.Done()
+		a.reminders.Run(ctx, time.Minute)
+	}()
+	go func() {
+		defer jobs.Done()
+		a.webhooks.Run(ctx, 10*time.Second)
+	}()
+
+	// Handlers
+	dentistController := handler.NewDentistHandler(a.dentists)
+	patientController := handler.NewPatientHandler(a.patients)
+	appointmentController := handler.NewAppointmentHandler(a.appointments, a.patients, a.dentists)
+	waitlistController := handler.NewWaitlistHandler(a.waitlist, a.patients, a.dentists)
+	reminderController := handler.NewReminderHandler(a.reminders, a.appointments)
+	webhookController := handler.NewWebhookHandler(a.webhooks)
+	reportController := handler.NewReportHandler(a.reports)
+	userController := handler.NewUserHandler(a.users, a.dentists)
+	auditController := handler.NewAuditHandler(a.audit)
+	healthController := handler.NewHealthHandler(a.config.Private.ReadyCheckTimeout, healthChecks(a.db, a.migrator)...)
+
+	router := config.SetupRouter(a.config.Private.LogLevel)
+	{
+		// Define global behavior
+		router.NoRoute(func(c *gin.Context) {
+			c.JSON(http.StatusNotFound, gin.H{"message": "Not found"})
+		})
+
+		router.NoMethod(func(c *gin.Context) {
+			c.JSON(http.StatusMethodNotAllowed, gin.H{"message": "Method not allowed"})
+		})
+
+		// Probes of the orchestrator, outside the base path and without auth
+		router.GET("/healthz", healthController.Live)
+		router.GET("/readyz", healthController.Ready)
+	}
+	// Dates are rendered in the clinic time zone, or in the one asked with ?tz=
+	baseGroup := router.Group(a.config.Private.BasePath, middleware.TimeZone(a.config.Private.Location))
+	{
+		baseGroup.GET("/ping", func(c *gin.Context) {
+			c.JSON(200, gin.H{
+				"message": "pong",
+			})
+		})
+	}
+
+	//Auth middleware
+	authMiddleware := middleware.NewAuth(a.users)
+	staff := authMiddleware.Require(auth.RoleAdmin, auth.RoleReceptionist)
+	admin := authMiddleware.Require(auth.RoleAdmin)
+	// Imports and exports stream large files, past the time the server gives any other request
+	transfer := middleware.Deadline(a.config.Private.HTTPTransferTimeout)
+
+	docsGroup := baseGroup.Group("/docs")
+	{
+		docs.SwaggerInfo.Host = a.config.Private.Host
+		docs.SwaggerInfo.BasePath = a.config.Private.BasePath
+		docsGroup.GET("/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	}
+
+	authGroup := baseGroup.Group("/auth")
+	{
+		authGroup.POST("/login", userController.Login)
+		authGroup.POST("/refresh", userController.Refresh)
+		authGroup.POST("/feed-token", authMiddleware.Validate, userController.RotateFeedToken)
+	}
+
+	userGroup := baseGroup.Group("/users", authMiddleware.Validate, admin)
+	{
+		userGroup.GET("", userController.GetAll)
+		userGroup.POST("", userController.Create)
+	}
+
+	waitlistGroup := baseGroup.Group("/waitlist", authMiddleware.Validate)
+	{
+		waitlistGroup.GET("", waitlistController.GetAll)
+		waitlistGroup.GET("/:id", waitlistController.GetById)
+		waitlistGroup.POST("", staff, waitlistController.Create)
+		waitlistGroup.DELETE("/:id", staff, waitlistController.Cancel)
+		waitlistGroup.POST("/offers/:id/accept", staff, waitlistController.Accept)
+		waitlistGroup.POST("/offers/:id/decline", staff, waitlistController.Decline)
+	}
+
+	webhookGroup := baseGroup.Group("/webhooks", authMiddleware.Validate, admin)
+	{
+		webhookGroup.GET("", webhookController.GetAll)
+		webhookGroup.GET("/:id", webhookController.GetById)
+		webhookGroup.POST("", webhookController.Create)
+		webhookGroup.PATCH("/:id", webhookController.Patch)
+		webhookGroup.DELETE("/:id", webhookController.Delete)
+		webhookGroup.GET("/deliveries", webhookController.GetDeliveries)
+		webhookGroup.POST("/deliveries/:id/retry", webhookController.RetryDelivery)
+	}
+
+	reportGroup := baseGroup.Group("/reports", authMiddleware.Validate)
+	{
+		reportGroup.GET("/utilization", reportController.Utilization)
+		reportGroup.GET("/appointments", reportController.Appointments)
+		reportGroup.GET("/attendance", reportController.Attendance)
+		reportGroup.GET("/admissions", staff, reportController.Admissions)
+	}
+
+	auditGroup := baseGroup.Group("/audit", authMiddleware.Validate, admin)
+	{
+		auditGroup.GET("", auditController.GetAll)
+	}
+
+	// Calendar feeds also accept a feed token in the query, for calendar apps that can't send headers
+	baseGroup.GET("/dentists/:id/calendar.ics", authMiddleware.ValidateFeed, appointmentController.DentistCalendar)
+	baseGroup.GET("/patients/:id/calendar.ics", authMiddleware.ValidateFeed, appointmentController.PatientCalendar)
+
+	dentistGroup := baseGroup.Group("/dentists", authMiddleware.Validate)
+	{
+		// Configure routes
+		dentistGroup.GET("", dentistController.GetAll)
+		dentistGroup.GET("/export", transfer, dentistController.Export)
+		dentistGroup.GET("/q", dentistController.GetByLicense)
+		dentistGroup.GET("/:id", dentistController.GetById)
+		dentistGroup.GET("/:id/schedule", dentistController.GetSchedule)
+		dentistGroup.GET("/:id/availability", dentistController.GetAvailability)
+		dentistGroup.POST("", admin, dentistController.Create)
+		dentistGroup.POST("/import", admin, transfer, dentistController.Import)
+		dentistGroup.PUT("/:id", admin, dentistController.Update)
+		dentistGroup.PATCH("/:id", admin, dentistController.Patch)
+		dentistGroup.DELETE("/:id", admin, dentistController.Delete)
+		dentistGroup.POST("/:id/restore", admin, dentistController.Restore)
+		dentistGroup.PUT("/:id/schedule", staff, dentistController.UpdateSchedule)
+	}
+
+	patientGroup := baseGroup.Group("/patients", authMiddleware.Validate)
+	{
+		// Configure routes
+		patientGroup.GET("", patientController.GetAll)
+		patientGroup.GET("/export", transfer, patientController.Export)
+		patientGroup.GET("/q", patientController.GetByDNI)
+		patientGroup.GET("/:id", patientController.GetById)
+		patientGroup.POST("", staff, patientController.Create)
+		patientGroup.POST("/import", staff, transfer, patientController.Import)
+		patientGroup.PUT("/:id", staff, patientController.Update)
+		patientGroup.PATCH("/:id", staff, patientController.Patch)
+		patientGroup.DELETE("/:id", staff, patientController.Delete)
+		patientGroup.POST("/:id/restore", admin, patientController.Restore)
+	}
+
+	appointmentGroup := baseGroup.Group("/appointments", authMiddleware.Validate)
+	{
+		// Configure routes
+		appointmentGroup.GET("", appointmentController.GetAll)
+		appointmentGroup.GET("/export", transfer, appointmentController.Export)
+		appointmentGroup.GET("/:id", appointmentController.GetById)
+		appointmentGroup.GET("/q", appointmentController.GetByDNI)
+		appointmentGroup.POST("", staff, appointmentController.Create)
+		appointmentGroup.PUT("/:id", appointmentController.Update)
+		appointmentGroup.PATCH("/:id", appointmentController.Patch)
+		appointmentGroup.DELETE("/:id", staff, appointmentController.Delete)
+		appointmentGroup.POST("/:id/confirm", appointmentController.Confirm)
+		appointmentGroup.POST("/:id/check-in", appointmentController.CheckIn)
+		appointmentGroup.POST("/:id/complete", appointmentController.Complete)
+		appointmentGroup.POST("/:id/no-show", appointmentController.NoShow)
+		appointmentGroup.POST("/:id/cancel", appointmentController.Cancel)
+		appointmentGroup.POST("/series", staff, appointmentController.CreateSeries)
+		appointmentGroup.GET("/series/:id", appointmentController.GetSeries)
+		appointmentGroup.PATCH("/:id/series", appointmentController.UpdateSeries)
+		appointmentGroup.POST("/:id/series/cancel", appointmentController.CancelSeries)
+		appointmentGroup.GET("/:id/reminders", reminderController.GetByAppointment)
+	}
+
+	server := &http.Server{
+		Addr:    a.config.Private.Host,
+		Handler: router,
+		// No ReadTimeout, it would cut off the uploads of the imports whatever their routes allow
+		ReadHeaderTimeout: a.config.Private.HTTPReadHeaderTimeout,
+		WriteTimeout:      a.config.Private.HTTPWriteTimeout,
+		IdleTimeout:       a.config.Private.HTTPIdleTimeout,
+		MaxHeaderBytes:    a.config.Private.HTTPMaxHeaderBytes,
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Printf("server: listening on %s", server.Addr)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err = <-serverErr:
+		err = fmt.Errorf("running server: %w", err)
+	case <-ctx.Done():
+		// Later signals get their default behavior back, so a second one stops the server at once
+		stop()
+
+		// Requests are still taken while the load balancer sees /readyz fail and stops routing to the server
+		healthController.Drain()
+		log.Printf("server: draining for %s", a.config.Private.ShutdownDrainDelay)
+		time.Sleep(a.config.Private.ShutdownDrainDelay)
+
+		log.Printf("server: shutting down, waiting up to %s for the requests in flight", a.config.Private.ShutdownTimeout)
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), a.config.Private.ShutdownTimeout)
+		defer cancel()
+		err = server.Shutdown(shutdownCtx)
+		if err != nil {
+			err = fmt.Errorf("shutting down server: %w", err)
+		}
+	}
+
+	// The jobs stop at the end of their current round, before the database they use is closed
+	stop()
+	jobs.Wait()
+	err = errors.Join(err, a.close())
+	if err == nil {
+		log.Printf("server: stopped")
+	}
+	return err
+}
+
+// healthChecks are the dependencies /readyz checks, the database answers and its schema has every migration of
+// this version
+func healthChecks(db *gorm.DB, migrator *database.Migrator) []handler.HealthCheck {
+	return []handler.HealthCheck{
+		{Name: "database", Check: func(ctx context.Context) error {
+			sqlDB, err := db.DB()
+			if err != nil {
+				return err
+			}
+			return sqlDB.PingContext(ctx)
+		}},
+		{Name: "migrations", Check: func(ctx context.Context) error {
+			pending, err := migrator.WithContext(ctx).Pending()
+			if err != nil {
+				return err
+			}
+			if len(pending) > 0 {
+				return fmt.Errorf("%d pending migrations", len(pending))
+			}
+			return nil
+		}},
+	}
+}
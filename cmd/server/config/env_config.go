@@ -2,20 +2,15 @@ package config
 
 import (
 	"fmt"
-	"os"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 )
 
-var envs = map[string]PublicConfig{
-	"local": {
-		PubKey: "local_key",
-	},
-	"dev": {
-		PubKey: "dev_key",
-	},
-	"prod": {
-		PubKey: "prod_key",
-	},
-}
+// minSecretKeySize is the fewest bytes of the SECRET_KEY, the HS256 key the tokens are signed with
+const minSecretKeySize = 32
 
 type EnvConfig struct {
 	Public  PublicConfig
@@ -23,84 +18,148 @@ type EnvConfig struct {
 }
 
 type PublicConfig struct {
-	PubKey string
+	// Env is the environment the config was loaded for, one of Environments
+	Env string
 }
 
 type PrivateConfig struct {
-	// Web server config
+	// Web server config, LogLevel is debug, info, warn or error
 	SecretKey string
 	Address   string
 	Port      string
 	Host      string
 	BasePath  string
-	// DB config
-	DBUser string
-	DBPass string
-	DBHost string
-	DBPort string
-	DBName string
-}
-
-func NewEnvConfig(env string) (*EnvConfig, error) {
-	publicConfig, ok := envs[env]
-	if ok == false {
-		return nil, fmt.Errorf("env not found")
-	}
+	LogLevel  string
+	// Location is the IANA time zone of the clinic, working hours follow its wall clock
+	Location *time.Location
+	// HTTP config, the longest the server waits to read the headers of a request, to write a response and for
+	// the next request of a keep-alive connection. Imports and exports have HTTPTransferTimeout to read the
+	// request and to write the response instead. On SIGINT or SIGTERM /readyz fails for ShutdownDrainDelay while
+	// requests are still taken, so the load balancer stops routing to the server, then the requests in flight
+	// have ShutdownTimeout to finish
+	HTTPReadHeaderTimeout time.Duration
+	HTTPWriteTimeout      time.Duration
+	HTTPTransferTimeout   time.Duration
+	HTTPIdleTimeout       time.Duration
+	HTTPMaxHeaderBytes    int
+	ShutdownTimeout       time.Duration
+	ShutdownDrainDelay    time.Duration
+	ReadyCheckTimeout     time.Duration
+	// Auth config, SecretKey signs the tokens
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	AdminUsername   string
+	AdminPassword   string
+	// Waitlist config, how long a patient has to answer an offer
+	WaitlistOfferTTL time.Duration
+	// Reminder config, reminders are sent ReminderOffsets before the appointments through the Notifier,
+	// log or file for local testing and smtp to email the patients
+	ReminderOffsets []time.Duration
+	Notifier        string
+	NotifierFile    string
+	SMTPHost        string
+	SMTPPort        string
+	SMTPUsername    string
+	SMTPPassword    string
+	SMTPFrom        string
+	// DB config, DBDriver is mysql, postgres or sqlite, for sqlite DBName is the path of the file or :memory:
+	// and the rest isn't used. DBSSLMode is the sslmode of postgres. With DBMigrate the server applies the
+	// pending migrations on start, without it the server doesn't start until they are applied with migrate up
+	DBMigrate bool
+	DBDriver  string
+	DBSSLMode string
+	DBUser    string
+	DBPass    string
+	DBHost    string
+	DBPort    string
+	DBName    string
+	// DB pool config, DBMaxOpenConns 0 doesn't limit the connections
+	DBMaxOpenConns    int
+	DBMaxIdleConns    int
+	DBConnMaxLifetime time.Duration
+}
+
+// newEnvConfig validates the merged values of the settings, the problems found while merging them are reported
+// along with the invalid values
+func newEnvConfig(env string, values map[string]string, problems []string) (*EnvConfig, error) {
+	v := &validator{values: values, problems: problems}
 
 	// Private config, web server
-	secretKey := os.Getenv("SECRET_KEY")
-	if secretKey == "" {
-		return nil, fmt.Errorf("SECRET_KEY not found")
+	secretKey := v.secret("SECRET_KEY", minSecretKeySize)
+	address := v.required("ADDRESS")
+	port := v.required("PORT")
+	host := v.string("HOST")
+	if host == "" {
+		host = net.JoinHostPort(address, port)
 	}
+	basePath := v.required("BASE_PATH")
+	logLevel := v.oneOf("LOG_LEVEL", "debug", "info", "warn", "error")
+	location := v.location("TIME_ZONE")
+	httpReadHeaderTimeout := v.duration("HTTP_READ_HEADER_TIMEOUT")
+	httpWriteTimeout := v.duration("HTTP_WRITE_TIMEOUT")
+	httpTransferTimeout := v.duration("HTTP_TRANSFER_TIMEOUT")
+	httpIdleTimeout := v.duration("HTTP_IDLE_TIMEOUT")
+	httpMaxHeaderBytes := v.count("HTTP_MAX_HEADER_BYTES")
+	shutdownTimeout := v.duration("SHUTDOWN_TIMEOUT")
+	shutdownDrainDelay := v.delay("SHUTDOWN_DRAIN_DELAY")
+	readyCheckTimeout := v.duration("READY_CHECK_TIMEOUT")
 
-	address := os.Getenv("ADDRESS")
-	if address == "" {
-		return nil, fmt.Errorf("ADDRESS not found")
-	}
+	// Private config, auth. The admin is only created when there are no users yet
+	accessTokenTTL := v.duration("ACCESS_TOKEN_TTL")
+	refreshTokenTTL := v.duration("REFRESH_TOKEN_TTL")
+	adminUsername := v.string("ADMIN_USERNAME")
+	adminPassword := v.string("ADMIN_PASSWORD")
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		return nil, fmt.Errorf("PORT not found")
-	}
+	// Private config, waitlist
+	waitlistOfferTTL := v.duration("WAITLIST_OFFER_TTL")
 
-	host := os.Getenv("HOST")
-	if host == "" {
-		return nil, fmt.Errorf("HOST not found")
-	}
+	// Private config, reminders
+	reminderOffsets := v.durations("REMINDER_OFFSETS")
+	notifier := v.oneOf("NOTIFIER", "log", "file", "smtp")
+	notifierFile := v.string("NOTIFIER_FILE")
+	smtpHost := v.string("SMTP_HOST")
+	smtpPort := v.string("SMTP_PORT")
+	smtpUsername := v.string("SMTP_USERNAME")
+	smtpPassword := v.string("SMTP_PASSWORD")
+	smtpFrom := v.string("SMTP_FROM")
 
-	basePath := os.Getenv("BASE_PATH")
-	if basePath == "" {
-		return nil, fmt.Errorf("BASE_PATH not found")
+	switch notifier {
+	case "file":
+		v.required("NOTIFIER_FILE")
+	case "smtp":
+		v.required("SMTP_HOST")
+		v.required("SMTP_PORT")
+		v.required("SMTP_FROM")
 	}
 
 	// Private config, database
-	dbUser := os.Getenv("DB_USER")
-	if dbUser == "" {
-		return nil, fmt.Errorf("DB_USER not found")
-	}
-
-	dbPass := os.Getenv("DB_PASS")
-	if dbPass == "" {
-		return nil, fmt.Errorf("DB_PASS not found")
-	}
-
-	dbHost := os.Getenv("DB_HOST")
-	if dbHost == "" {
-		return nil, fmt.Errorf("DB_HOST not found")
-	}
+	dbDriver := v.oneOf("DB_DRIVER", "mysql", "postgres", "sqlite")
+	dbMigrate := v.bool("DB_MIGRATE")
+	dbSSLMode := v.string("DB_SSL_MODE")
+	dbUser := v.string("DB_USER")
+	dbPass := v.string("DB_PASS")
+	dbHost := v.string("DB_HOST")
+	dbPort := v.string("DB_PORT")
+	dbName := v.required("DB_NAME")
+	dbMaxOpenConns := v.count("DB_MAX_OPEN_CONNS")
+	dbMaxIdleConns := v.count("DB_MAX_IDLE_CONNS")
+	dbConnMaxLifetime := v.duration("DB_CONN_MAX_LIFETIME")
 
-	dbPort := os.Getenv("DB_PORT")
-	if dbPort == "" {
-		return nil, fmt.Errorf("DB_PORT not found")
+	if dbDriver == "mysql" || dbDriver == "postgres" {
+		v.required("DB_USER")
+		v.required("DB_PASS")
+		v.required("DB_HOST")
+		v.required("DB_PORT")
 	}
 
-	dbName := os.Getenv("DB_NAME")
-	if dbName == "" {
-		return nil, fmt.Errorf("DB_NAME not found")
+	if len(v.problems) > 0 {
+		return nil, &ValidationError{Problems: v.problems}
 	}
 
 	return &EnvConfig{
-		Public: publicConfig,
+		Public: PublicConfig{
+			Env: env,
+		},
 		Private: PrivateConfig{
 			// Web server config
 			SecretKey: secretKey,
@@ -108,13 +167,150 @@ func NewEnvConfig(env string) (*EnvConfig, error) {
 			Port:      port,
 			Host:      host,
 			BasePath:  basePath,
+			LogLevel:  logLevel,
+			Location:  location,
+
+			// HTTP config
+			HTTPReadHeaderTimeout: httpReadHeaderTimeout,
+			HTTPWriteTimeout:      httpWriteTimeout,
+			HTTPTransferTimeout:   httpTransferTimeout,
+			HTTPIdleTimeout:       httpIdleTimeout,
+			HTTPMaxHeaderBytes:    httpMaxHeaderBytes,
+			ShutdownTimeout:       shutdownTimeout,
+			ShutdownDrainDelay:    shutdownDrainDelay,
+			ReadyCheckTimeout:     readyCheckTimeout,
+
+			// Auth config
+			AccessTokenTTL:  accessTokenTTL,
+			RefreshTokenTTL: refreshTokenTTL,
+			AdminUsername:   adminUsername,
+			AdminPassword:   adminPassword,
+
+			// Waitlist config
+			WaitlistOfferTTL: waitlistOfferTTL,
+
+			// Reminder config
+			ReminderOffsets: reminderOffsets,
+			Notifier:        notifier,
+			NotifierFile:    notifierFile,
+			SMTPHost:        smtpHost,
+			SMTPPort:        smtpPort,
+			SMTPUsername:    smtpUsername,
+			SMTPPassword:    smtpPassword,
+			SMTPFrom:        smtpFrom,
 
 			// DB config
-			DBUser: dbUser,
-			DBPass: dbPass,
-			DBHost: dbHost,
-			DBPort: dbPort,
-			DBName: dbName,
+			DBMigrate: dbMigrate,
+			DBDriver:  dbDriver,
+			DBSSLMode: dbSSLMode,
+			DBUser:    dbUser,
+			DBPass:    dbPass,
+			DBHost:    dbHost,
+			DBPort:    dbPort,
+			DBName:    dbName,
+
+			// DB pool config
+			DBMaxOpenConns:    dbMaxOpenConns,
+			DBMaxIdleConns:    dbMaxIdleConns,
+			DBConnMaxLifetime: dbConnMaxLifetime,
 		},
 	}, nil
 }
+
+// validator reads the typed values of the settings, collecting the problems of every invalid one instead of
+// stopping at the first
+type validator struct {
+	values   map[string]string
+	problems []string
+}
+
+func (v *validator) fail(format string, args ...interface{}) {
+	v.problems = append(v.problems, fmt.Sprintf(format, args...))
+}
+
+func (v *validator) string(key string) string {
+	return strings.TrimSpace(v.values[key])
+}
+
+func (v *validator) required(key string) string {
+	value := v.string(key)
+	if value == "" {
+		v.fail("%s is required", key)
+	}
+	return value
+}
+
+// secret reads a key that must have at least size bytes, so it can't be guessed
+func (v *validator) secret(key string, size int) string {
+	value := v.required(key)
+	if value != "" && len(value) < size {
+		v.fail("%s must have at least %d bytes", key, size)
+	}
+	return value
+}
+
+func (v *validator) oneOf(key string, options ...string) string {
+	value := strings.ToLower(v.string(key))
+	if !slices.Contains(options, value) {
+		v.fail("%s must be one of %s", key, strings.Join(options, ", "))
+	}
+	return value
+}
+
+func (v *validator) bool(key string) bool {
+	value, err := strconv.ParseBool(v.string(key))
+	if err != nil {
+		v.fail("%s must be true or false", key)
+	}
+	return value
+}
+
+// count reads a number that can't be negative
+func (v *validator) count(key string) int {
+	value, err := strconv.Atoi(v.string(key))
+	if err != nil || value < 0 {
+		v.fail("%s must be a number of at least 0", key)
+	}
+	return value
+}
+
+// duration reads a positive duration like 15m or 24h
+func (v *validator) duration(key string) time.Duration {
+	value, err := time.ParseDuration(v.string(key))
+	if err != nil || value <= 0 {
+		v.fail("%s must be a positive duration like 15m or 24h", key)
+	}
+	return value
+}
+
+// delay reads a duration like 5s that can be 0
+func (v *validator) delay(key string) time.Duration {
+	value, err := time.ParseDuration(v.string(key))
+	if err != nil || value < 0 {
+		v.fail("%s must be a duration like 5s, or 0", key)
+	}
+	return value
+}
+
+// durations reads a comma separated list of positive durations like 48h,2h
+func (v *validator) durations(key string) []time.Duration {
+	var durations []time.Duration
+	for _, raw := range strings.Split(v.string(key), ",") {
+		duration, err := time.ParseDuration(strings.TrimSpace(raw))
+		if err != nil || duration <= 0 {
+			v.fail("%s must be a list of positive durations like 48h,2h", key)
+			return nil
+		}
+		durations = append(durations, duration)
+	}
+	return durations
+}
+
+// location reads an IANA time zone like America/Argentina/Buenos_Aires
+func (v *validator) location(key string) *time.Location {
+	location, err := time.LoadLocation(v.string(key))
+	if err != nil {
+		v.fail("%s must be an IANA time zone like America/Argentina/Buenos_Aires", key)
+	}
+	return location
+}
@@ -2,9 +2,19 @@ package config
 
 import "github.com/gin-gonic/gin"
 
-func SetupRouter() *gin.Engine {
+// SetupRouter returns the router with the request log and the recovery of panics, gin only runs in debug mode
+// with the debug log level. The probes of the orchestrator are only logged in debug
+func SetupRouter(logLevel string) *gin.Engine {
+	var skipPaths []string
+	if logLevel == "debug" {
+		gin.SetMode(gin.DebugMode)
+	} else {
+		gin.SetMode(gin.ReleaseMode)
+		skipPaths = []string{"/healthz", "/readyz"}
+	}
+
 	router := gin.New()
-	router.Use(gin.Logger())
+	router.Use(gin.LoggerWithConfig(gin.LoggerConfig{SkipPaths: skipPaths}))
 	router.Use(gin.Recovery())
 	return router
 }
@@ -0,0 +1,304 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Environments the server can run in, each one reads its own config file
+var Environments = []string{"local", "dev", "prod"}
+
+// setting is a value of the config, known by the same key in every source: KEY in the environment and in the
+// config file, where nested sections are joined with underscores, and -key in the flags
+type setting struct {
+	key          string
+	defaultValue string
+	usage        string
+	// secret settings can also be read from the file named by KEY_FILE
+	secret bool
+}
+
+// settings lists every value of the config with its default
+var settings = []setting{
+	// Web server
+	{key: "SECRET_KEY", usage: "key that signs the tokens, at least 32 bytes", secret: true},
+	{key: "ADDRESS", defaultValue: "localhost", usage: "address of the server"},
+	{key: "PORT", defaultValue: "8080", usage: "port of the server"},
+	{key: "HOST", usage: "host and port the server listens on, ADDRESS:PORT when empty"},
+	{key: "BASE_PATH", defaultValue: "/api/v1", usage: "path all the routes are under"},
+	{key: "TIME_ZONE", defaultValue: "UTC", usage: "IANA time zone of the clinic"},
+	{key: "LOG_LEVEL", defaultValue: "info", usage: "debug, info, warn or error"},
+	{key: "HTTP_READ_HEADER_TIMEOUT", defaultValue: "15s", usage: "time to read the headers of a request"},
+	{key: "HTTP_WRITE_TIMEOUT", defaultValue: "1m", usage: "time to write a response"},
+	{key: "HTTP_TRANSFER_TIMEOUT", defaultValue: "1h", usage: "time to read an import and to write an export"},
+	{key: "HTTP_IDLE_TIMEOUT", defaultValue: "2m", usage: "time a keep-alive connection waits for the next request"},
+	{key: "HTTP_MAX_HEADER_BYTES", defaultValue: "1048576", usage: "largest size of the headers of a request"},
+	{key: "SHUTDOWN_TIMEOUT", defaultValue: "30s", usage: "time the requests in flight have to finish on shutdown"},
+	{key: "SHUTDOWN_DRAIN_DELAY", defaultValue: "5s", usage: "time /readyz fails before the server stops taking requests, 0 to skip"},
+	{key: "READY_CHECK_TIMEOUT", defaultValue: "2s", usage: "time the checks of /readyz have to answer"},
+	// Auth
+	{key: "ACCESS_TOKEN_TTL", defaultValue: "15m", usage: "lifetime of the access tokens"},
+	{key: "REFRESH_TOKEN_TTL", defaultValue: "168h", usage: "lifetime of the refresh tokens"},
+	{key: "ADMIN_USERNAME", usage: "admin created on start when there are no users"},
+	{key: "ADMIN_PASSWORD", usage: "password of the admin created on start", secret: true},
+	// Waitlist
+	{key: "WAITLIST_OFFER_TTL", defaultValue: "2h", usage: "time a patient has to answer the offer of a freed slot"},
+	// Reminders
+	{key: "REMINDER_OFFSETS", defaultValue: "48h,2h", usage: "times before the appointments the reminders are sent"},
+	{key: "NOTIFIER", defaultValue: "log", usage: "log, file or smtp"},
+	{key: "NOTIFIER_FILE", usage: "file the file notifier writes to"},
+	{key: "SMTP_HOST", usage: "host of the smtp notifier"},
+	{key: "SMTP_PORT", usage: "port of the smtp notifier"},
+	{key: "SMTP_USERNAME", usage: "username of the smtp notifier"},
+	{key: "SMTP_PASSWORD", usage: "password of the smtp notifier", secret: true},
+	{key: "SMTP_FROM", usage: "sender of the emails"},
+	// Database
+	{key: "DB_DRIVER", defaultValue: "mysql", usage: "mysql, postgres or sqlite"},
+	{key: "DB_MIGRATE", defaultValue: "true", usage: "apply the pending migrations on start"},
+	{key: "DB_SSL_MODE", defaultValue: "prefer", usage: "sslmode of postgres"},
+	{key: "DB_USER", usage: "user of the database"},
+	{key: "DB_PASS", usage: "password of the database", secret: true},
+	{key: "DB_HOST", usage: "host of the database"},
+	{key: "DB_PORT", usage: "port of the database"},
+	{key: "DB_NAME", usage: "name of the database, the file or :memory: with sqlite"},
+	{key: "DB_MAX_OPEN_CONNS", defaultValue: "25", usage: "most connections open at once, 0 for no limit"},
+	{key: "DB_MAX_IDLE_CONNS", defaultValue: "10", usage: "most idle connections kept open"},
+	{key: "DB_CONN_MAX_LIFETIME", defaultValue: "30m", usage: "time a connection is reused before it is closed"},
+}
+
+// Loader merges the sources of the config, each one overrides the previous: the defaults, the config file of
+// the environment, the environment variables and the flags. The environment is picked with -env or APP_ENV,
+// local by default, and its file is config.<env>.yaml, .yml or .toml unless -config or CONFIG_FILE name another
+type Loader struct {
+	env   flagValue
+	file  flagValue
+	flags map[string]*flagValue
+}
+
+func NewLoader() *Loader {
+	return &Loader{flags: map[string]*flagValue{}}
+}
+
+// RegisterFlags adds -env, -config and a flag per setting like -db-host to the flag set of a command, Load reads
+// the ones given once it is parsed
+func (l *Loader) RegisterFlags(set *flag.FlagSet) {
+	set.Var(&l.env, "env", fmt.Sprintf("environment, one of %v (APP_ENV)", Environments))
+	set.Var(&l.file, "config", "YAML or TOML config file (CONFIG_FILE)")
+	for _, current := range settings {
+		keys := []string{current.key}
+		if current.secret {
+			keys = append(keys, current.key+"_FILE")
+		}
+
+		for _, key := range keys {
+			value := &flagValue{}
+			l.flags[key] = value
+			usage := current.usage
+			if key != current.key {
+				usage = "file with the " + strings.ToLower(strings.ReplaceAll(current.key, "_", " "))
+			}
+			set.Var(value, strings.ToLower(strings.ReplaceAll(key, "_", "-")), fmt.Sprintf("%s (%s)", usage, key))
+		}
+	}
+}
+
+// Load merges the sources and validates the result, every missing or invalid value is reported at once in a
+// *ValidationError
+func (l *Loader) Load() (*EnvConfig, error) {
+	env := pick(l.env, "APP_ENV", "local")
+	if !slices.Contains(Environments, env) {
+		return nil, &ValidationError{Problems: []string{fmt.Sprintf("APP_ENV must be one of %v", Environments)}}
+	}
+
+	values := map[string]string{}
+	for _, current := range settings {
+		values[current.key] = current.defaultValue
+	}
+
+	path := pick(l.file, "CONFIG_FILE", "")
+	fileValues, err := readConfigFile(path, env)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	for key := range fileValues {
+		if !known(key) {
+			problems = append(problems, fmt.Sprintf("%s in the config file isn't a setting", key))
+			delete(fileValues, key)
+		}
+	}
+
+	// Set environment variables override even when empty, like the flags
+	envValues := map[string]string{}
+	flagValues := map[string]string{}
+	for key, value := range l.flags {
+		if envValue, ok := os.LookupEnv(key); ok {
+			envValues[key] = envValue
+		}
+		if value.set {
+			flagValues[key] = value.value
+		}
+	}
+
+	sources := []struct {
+		name   string
+		values map[string]string
+	}{{"config file", fileValues}, {"environment", envValues}, {"flags", flagValues}}
+	for _, source := range sources {
+		for _, current := range settings {
+			value, given := source.values[current.key]
+			if !current.secret {
+				if given {
+					values[current.key] = value
+				}
+				continue
+			}
+
+			// A secret or its file replaces both of the previous sources, so the file of the config file
+			// doesn't clash with the secret of the environment
+			file, fileGiven := source.values[current.key+"_FILE"]
+			if !given && !fileGiven {
+				continue
+			}
+			if value != "" && file != "" {
+				problems = append(problems, fmt.Sprintf("%s and %s_FILE can't both be set in the %s", current.key, current.key, source.name))
+			}
+			values[current.key] = value
+			values[current.key+"_FILE"] = file
+		}
+	}
+
+	// Secrets are read from their files when they aren't given directly
+	for _, current := range settings {
+		file := values[current.key+"_FILE"]
+		if !current.secret || file == "" || values[current.key] != "" {
+			continue
+		}
+
+		content, err := os.ReadFile(file)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s_FILE can't be read: %v", current.key, err))
+			continue
+		}
+		values[current.key] = strings.TrimRight(string(content), "\r\n")
+	}
+
+	return newEnvConfig(env, values, problems)
+}
+
+// pick returns the value of the flag when it was given, or else the environment variable or the default
+func pick(flag flagValue, key string, defaultValue string) string {
+	if flag.set {
+		return flag.value
+	}
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return defaultValue
+}
+
+// known reports whether the key is a setting or the file of a secret one
+func known(key string) bool {
+	return slices.ContainsFunc(settings, func(current setting) bool {
+		return current.key == key || current.secret && current.key+"_FILE" == key
+	})
+}
+
+// readConfigFile reads the values of the config file, the given one or else the first config.<env> file found.
+// There is no file by default, so only a given file must exist
+func readConfigFile(path string, env string) (map[string]string, error) {
+	if path == "" {
+		for _, extension := range []string{".yaml", ".yml", ".toml"} {
+			candidate := "config." + env + extension
+			if _, err := os.Stat(candidate); err == nil {
+				path = candidate
+				break
+			}
+		}
+		if path == "" {
+			return nil, nil
+		}
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("config file %s not found", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &raw)
+	case ".toml":
+		err = toml.Unmarshal(content, &raw)
+	default:
+		return nil, fmt.Errorf("config file %s must be .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	values := map[string]string{}
+	flatten("", raw, values)
+	return values, nil
+}
+
+// flatten stores the values of nested sections under their keys joined with underscores, like db.host in
+// DB_HOST, lists are joined with commas
+func flatten(prefix string, raw map[string]any, values map[string]string) {
+	for key, value := range raw {
+		key = strings.ToUpper(prefix + key)
+		switch typed := value.(type) {
+		case map[string]any:
+			flatten(key+"_", typed, values)
+		case []any:
+			items := make([]string, 0, len(typed))
+			for _, item := range typed {
+				items = append(items, fmt.Sprint(item))
+			}
+			values[key] = strings.Join(items, ",")
+		case nil:
+			values[key] = ""
+		default:
+			values[key] = fmt.Sprint(typed)
+		}
+	}
+}
+
+// flagValue is a string flag that tells whether it was given, so an empty flag still overrides the rest
+type flagValue struct {
+	value string
+	set   bool
+}
+
+func (f *flagValue) String() string {
+	return f.value
+}
+
+func (f *flagValue) Set(value string) error {
+	f.value = value
+	f.set = true
+	return nil
+}
+
+// ValidationError lists every missing or invalid value of the config
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid config:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
@@ -2,9 +2,16 @@ package database
 
 import (
 	"errors"
+	"time"
+
 	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
 	model "github.com/10Daniel10/web-server-go-ExamenFinal/internal/appointment"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/audit"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/event"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/pagination"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/schedule"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type AppointmentRepository struct {
@@ -15,13 +22,31 @@ func NewOtherAppointmentRepository(db *gorm.DB) *AppointmentRepository {
 	return &AppointmentRepository{db: db}
 }
 
-func (a *AppointmentRepository) GetAll() ([]model.Appointment, error) {
-	var data []model.Appointment
-	query := a.db.Find(&data)
-	if query.Error != nil {
-		return nil, internal.ErServiceUnavailable
+func (a *AppointmentRepository) GetAll(filter model.Filter, page pagination.Request) (pagination.Page[model.Appointment], error) {
+	query := a.db.Model(&model.Appointment{})
+	if filter.PatientID != 0 {
+		query = query.Where("patient_id = ?", filter.PatientID)
 	}
-	return data, nil
+	if filter.DentistID != 0 {
+		query = query.Where("dentist_id = ?", filter.DentistID)
+	}
+	if filter.PatientDNI != "" {
+		query = query.Where("patient_id IN (?)", a.db.Table("patients").Select("id").Where("dni = ?", filter.PatientDNI))
+	}
+	if filter.DentistLicense != "" {
+		query = query.Where("dentist_id IN (?)", a.db.Table("dentists").Select("id").Where("license = ?", filter.DentistLicense))
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("end_date > ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("date < ?", filter.To)
+	}
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
+	}
+
+	return findPage[model.Appointment](query, page)
 }
 
 func (a *AppointmentRepository) GetByID(id uint) (model.Appointment, error) {
@@ -37,24 +62,27 @@ func (a *AppointmentRepository) GetByID(id uint) (model.Appointment, error) {
 	return data, nil
 }
 
-func (a *AppointmentRepository) GetByDNI(dni string) (model.Appointment, error) {
-	var data model.Appointment
+func (a *AppointmentRepository) GetOverlapping(appointment model.Appointment) ([]model.Appointment, error) {
+	var data []model.Appointment
 	query := a.db.
-		Model(&model.Appointment{}).
-		Select("appointments.*").
-		Joins("JOIN patients ON appointments.patient_id = patients.id").
-		Where("patients.dni = ?", dni).Scan(&data)
-
+		Where("(dentist_id = ? OR patient_id = ?)", appointment.DentistID, appointment.PatientID).
+		Where("date < ? AND end_date > ?", appointment.EndDate, appointment.Date).
+		Where("id <> ?", appointment.ID).
+		Where("status <> ?", model.StatusCancelled).
+		Order("date").
+		Find(&data)
 	if query.Error != nil {
-		switch {
-		default:
-			return model.Appointment{}, internal.ErServiceUnavailable
-		}
-	}
-	if query.RowsAffected == 0 {
-		return model.Appointment{}, internal.ErNotFound
+		return nil, internal.ErServiceUnavailable
 	}
+	return data, nil
+}
 
+func (a *AppointmentRepository) GetSchedule(dentistID uint) ([]schedule.WorkingHours, error) {
+	var data []schedule.WorkingHours
+	query := a.db.Where("dentist_id = ?", dentistID).Find(&data)
+	if query.Error != nil {
+		return nil, internal.ErServiceUnavailable
+	}
 	return data, nil
 }
 
@@ -69,7 +97,9 @@ func (a *AppointmentRepository) Create(appointment model.Appointment) (model.App
 	return appointment, nil
 }
 
+// Update stores every field of the appointment and counts the change in its sequence
 func (a *AppointmentRepository) Update(appointment model.Appointment) (model.Appointment, error) {
+	appointment.Sequence++
 	query := a.db.Save(&appointment)
 	if query.Error != nil {
 		return model.Appointment{}, query.Error
@@ -85,3 +115,78 @@ func (a *AppointmentRepository) Delete(id uint) error {
 	}
 	return nil
 }
+
+func (a *AppointmentRepository) LockSchedule(dentistID uint, patientID uint) error {
+	// Rows are always locked in the same order, dentist first, to avoid deadlocks between bookings
+	var lockedID uint
+	query := a.db.Table("dentists").Select("id").Where("id = ? AND deleted_at IS NULL", dentistID).
+		Clauses(clause.Locking{Strength: "UPDATE"}).Scan(&lockedID)
+	if query.Error != nil {
+		return internal.ErServiceUnavailable
+	}
+	if query.RowsAffected == 0 {
+		return internal.ErNotFound
+	}
+
+	query = a.db.Table("patients").Select("id").Where("id = ? AND deleted_at IS NULL", patientID).
+		Clauses(clause.Locking{Strength: "UPDATE"}).Scan(&lockedID)
+	if query.Error != nil {
+		return internal.ErServiceUnavailable
+	}
+	if query.RowsAffected == 0 {
+		return internal.ErNotFound
+	}
+
+	return nil
+}
+
+func (a *AppointmentRepository) Transaction(fn func(repository model.Repository) error) error {
+	return a.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&AppointmentRepository{db: tx})
+	})
+}
+
+func (a *AppointmentRepository) CreateSeries(series model.Series) (model.Series, error) {
+	query := a.db.Omit("Appointments").Create(&series)
+	if query.Error != nil {
+		return model.Series{}, internal.ErServiceUnavailable
+	}
+	return series, nil
+}
+
+func (a *AppointmentRepository) GetSeries(id uint) (model.Series, error) {
+	var data model.Series
+	query := a.db.Preload("Appointments", func(db *gorm.DB) *gorm.DB {
+		return db.Order("date")
+	}).First(&data, id)
+	if query.Error != nil {
+		switch {
+		case errors.Is(query.Error, gorm.ErrRecordNotFound):
+			return model.Series{}, internal.ErNotFound
+		}
+		return model.Series{}, internal.ErServiceUnavailable
+	}
+	return data, nil
+}
+
+func (a *AppointmentRepository) GetSeriesAppointments(seriesID uint, from time.Time) ([]model.Appointment, error) {
+	var data []model.Appointment
+	query := a.db.Where("series_id = ?", seriesID)
+	if !from.IsZero() {
+		query = query.Where("date >= ?", from)
+	}
+
+	query = query.Order("date").Find(&data)
+	if query.Error != nil {
+		return nil, internal.ErServiceUnavailable
+	}
+	return data, nil
+}
+
+func (a *AppointmentRepository) Record(entry audit.Entry) error {
+	return record(a.db, entry)
+}
+
+func (a *AppointmentRepository) Publish(event event.Event) error {
+	return publish(a.db, event)
+}
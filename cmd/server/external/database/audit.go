@@ -0,0 +1,47 @@
+package database
+
+import (
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	model "github.com/10Daniel10/web-server-go-ExamenFinal/internal/audit"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/pagination"
+	"gorm.io/gorm"
+)
+
+type AuditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+func (a *AuditRepository) GetAll(filter model.Filter, page pagination.Request) (pagination.Page[model.Entry], error) {
+	query := a.db.Model(&model.Entry{})
+	if filter.Entity != "" {
+		query = query.Where("entity = ?", filter.Entity)
+	}
+	if filter.EntityID != 0 {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.ActorID != 0 {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at < ?", filter.To)
+	}
+
+	return findPage[model.Entry](query, page)
+}
+
+// record stores an audit entry with the given connection, repositories call it with their transaction
+// so the entry is only kept when the change it describes is committed
+func record(db *gorm.DB, entry model.Entry) error {
+	query := db.Create(&entry)
+	if query.Error != nil {
+		return internal.ErServiceUnavailable
+	}
+	return nil
+}
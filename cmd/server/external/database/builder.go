@@ -2,34 +2,129 @@ package database
 
 import (
 	"fmt"
-	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/appointment"
-	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/dentist"
-	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/patient"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"time"
+
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Drivers of the databases the server can run on
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
 )
 
+// ConnectionParams selects the database, with DriverSQLite Database is the path of the file or :memory: and
+// the server fields aren't used, SSLMode is only used by DriverPostgres. The pool isn't limited when its fields
+// are zero, and LogLevel is debug, info, warn or error like the config, debug logs every query
 type ConnectionParams struct {
-	User     string
-	Password string
-	Host     string
-	Port     string
-	Database string
+	Driver          string
+	User            string
+	Password        string
+	Host            string
+	Port            string
+	Database        string
+	SSLMode         string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	LogLevel        string
 }
 
+// logLevels maps the levels of the config to those of gorm, queries are only logged in debug and slow ones
+// from warn
+var logLevels = map[string]logger.LogLevel{
+	"debug": logger.Info,
+	"info":  logger.Warn,
+	"warn":  logger.Warn,
+	"error": logger.Error,
+}
+
+// Connect opens the database, the schema is changed by the Migrator. Dates are written and read in UTC whatever the zone of
+// the server or the database is, the session zone is UTC too so SQL date functions agree with them
 func Connect(params ConnectionParams) (*gorm.DB, error) {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		params.User, params.Password, params.Host, params.Port, params.Database)
+	dialector, err := open(params)
+	if err != nil {
+		return nil, err
+	}
 
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	level, ok := logLevels[params.LogLevel]
+	if !ok {
+		level = logger.Warn
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{
+		NowFunc: func() time.Time {
+			return time.Now().UTC()
+		},
+		// A record that isn't found is an answer of the repositories, not an error to log
+		Logger: logger.New(log.New(os.Stdout, "\r\n", log.LstdFlags), logger.Config{
+			SlowThreshold:             200 * time.Millisecond,
+			LogLevel:                  level,
+			IgnoreRecordNotFoundError: true,
+			Colorful:                  true,
+		}),
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	err = db.AutoMigrate(&dentist.Dentist{}, &patient.Patient{}, &appointment.Appointment{})
+	sqlDB, err := db.DB()
 	if err != nil {
 		return nil, err
 	}
+	sqlDB.SetMaxOpenConns(params.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(params.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(params.ConnMaxLifetime)
+
+	if params.Driver == DriverSQLite && params.Database == sqliteMemory {
+		// The in-memory database lives while a connection to it is open
+		sqlDB.SetMaxIdleConns(max(params.MaxIdleConns, 1))
+		sqlDB.SetConnMaxIdleTime(0)
+		sqlDB.SetConnMaxLifetime(0)
+	}
 
 	return db, nil
 }
+
+// open returns the dialector of the driver, MySQL is the default
+func open(params ConnectionParams) (gorm.Dialector, error) {
+	switch params.Driver {
+	case DriverMySQL, "":
+		dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=UTC&time_zone=%%27%%2B00%%3A00%%27",
+			params.User, params.Password, params.Host, params.Port, params.Database)
+		return mysql.Open(dsn), nil
+
+	case DriverPostgres:
+		dsn := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(params.User, params.Password),
+			Host:     net.JoinHostPort(params.Host, params.Port),
+			Path:     params.Database,
+			RawQuery: url.Values{"sslmode": {params.SSLMode}, "timezone": {"UTC"}}.Encode(),
+		}
+		return postgres.Open(dsn.String()), nil
+
+	case DriverSQLite:
+		return &sqlite.Dialector{DriverName: sqliteDriver, DSN: sqliteDSN(params.Database)}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", params.Driver)
+	}
+}
+
+// like is the operator of the case-insensitive filters, LIKE already ignores the case in MySQL and SQLite
+func like(db *gorm.DB) string {
+	if db.Dialector.Name() == DriverPostgres {
+		return "ILIKE"
+	}
+	return "LIKE"
+}
@@ -2,8 +2,15 @@ package database
 
 import (
 	"errors"
+	"time"
+
 	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/appointment"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/audit"
 	model "github.com/10Daniel10/web-server-go-ExamenFinal/internal/dentist"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/event"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/pagination"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/schedule"
 	"gorm.io/gorm"
 )
 
@@ -23,13 +30,22 @@ func (d *DentistRepository) Create(dentist model.Dentist) (model.Dentist, error)
 	return dentist, nil
 }
 
-func (d *DentistRepository) GetAll() ([]model.Dentist, error) {
-	var data []model.Dentist
-	query := d.db.Find(&data)
-	if query.Error != nil {
-		return nil, internal.ErServiceUnavailable
+func (d *DentistRepository) GetAll(filter model.Filter, page pagination.Request) (pagination.Page[model.Dentist], error) {
+	query := d.db.Model(&model.Dentist{})
+	if filter.IncludeDeleted {
+		query = query.Unscoped()
 	}
-	return data, nil
+	if filter.Name != "" {
+		query = query.Where("name "+like(query)+" ?", filter.Name+"%")
+	}
+	if filter.Lastname != "" {
+		query = query.Where("lastname "+like(query)+" ?", filter.Lastname+"%")
+	}
+	if filter.License != "" {
+		query = query.Where("license = ?", filter.License)
+	}
+
+	return findPage[model.Dentist](query, page)
 }
 
 func (d *DentistRepository) GetByID(id uint) (model.Dentist, error) {
@@ -69,9 +85,121 @@ func (d *DentistRepository) Update(dentist model.Dentist) (model.Dentist, error)
 }
 
 func (d *DentistRepository) Delete(id uint) error {
+	// Working hours are kept so a restored dentist gets them back
 	query := d.db.Delete(&model.Dentist{}, id)
 	if query.Error != nil {
 		return query.Error
 	}
 	return nil
 }
+
+func (d *DentistRepository) Restore(id uint) (model.Dentist, error) {
+	// The row is looked up first, a restore of a row that isn't deleted changes nothing and MySQL reports no
+	// affected rows for it
+	var data model.Dentist
+	query := d.db.Unscoped().First(&data, id)
+	if query.Error != nil {
+		if errors.Is(query.Error, gorm.ErrRecordNotFound) {
+			return model.Dentist{}, internal.ErNotFound
+		}
+		return model.Dentist{}, internal.ErServiceUnavailable
+	}
+
+	query = d.db.Unscoped().Model(&data).Update("deleted_at", nil)
+	if query.Error != nil {
+		return model.Dentist{}, internal.ErServiceUnavailable
+	}
+	return d.GetByID(id)
+}
+
+func (d *DentistRepository) Unscoped() model.Repository {
+	return &DentistRepository{db: d.db.Unscoped().Session(&gorm.Session{})}
+}
+
+func (d *DentistRepository) GetSchedule(dentistID uint) ([]schedule.WorkingHours, error) {
+	data := []schedule.WorkingHours{}
+	query := d.db.Where("dentist_id = ?", dentistID).Order("weekday, start_time").Find(&data)
+	if query.Error != nil {
+		return nil, internal.ErServiceUnavailable
+	}
+	return data, nil
+}
+
+func (d *DentistRepository) UpdateSchedule(dentistID uint, hours []schedule.WorkingHours) ([]schedule.WorkingHours, error) {
+	err := d.db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Where("dentist_id = ?", dentistID).Delete(&schedule.WorkingHours{})
+		if query.Error != nil {
+			return query.Error
+		}
+
+		if len(hours) == 0 {
+			return nil
+		}
+
+		query = tx.Create(&hours)
+		if query.Error != nil {
+			return query.Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, internal.ErServiceUnavailable
+	}
+
+	return d.GetSchedule(dentistID)
+}
+
+func (d *DentistRepository) GetAppointments(dentistID uint, from time.Time, to time.Time) ([]appointment.Appointment, error) {
+	var data []appointment.Appointment
+	query := d.db.
+		Where("dentist_id = ?", dentistID).
+		Where("date < ? AND end_date > ?", to, from).
+		Where("status <> ?", appointment.StatusCancelled).
+		Order("date").
+		Find(&data)
+	if query.Error != nil {
+		return nil, internal.ErServiceUnavailable
+	}
+	return data, nil
+}
+
+func (d *DentistRepository) Lock(ids ...uint) error {
+	return lockRows(d.db, "dentists", ids...)
+}
+
+func (d *DentistRepository) GetUpcoming(dentistID uint, from time.Time) ([]appointment.Appointment, error) {
+	return getUpcoming(d.db, "dentist_id", dentistID, from)
+}
+
+func (d *DentistRepository) CancelAppointments(ids []uint, reason string, at time.Time) error {
+	return cancelAppointments(d.db, ids, reason, at)
+}
+
+func (d *DentistRepository) ReassignAppointments(ids []uint, dentistID uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query := d.db.Model(&appointment.Appointment{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+		"dentist_id": dentistID,
+		"sequence":   gorm.Expr("sequence + 1"),
+	})
+	if query.Error != nil {
+		return internal.ErServiceUnavailable
+	}
+	return nil
+}
+
+func (d *DentistRepository) Record(entry audit.Entry) error {
+	return record(d.db, entry)
+}
+
+func (d *DentistRepository) Publish(event event.Event) error {
+	return publish(d.db, event)
+}
+
+func (d *DentistRepository) Transaction(fn func(repository model.Repository) error) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&DentistRepository{db: tx})
+	})
+}
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/appointment"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// lockRows locks the rows of the table with the ids until the transaction ends, rows are locked
+// in id order so transactions locking the same rows don't deadlock
+func lockRows(db *gorm.DB, table string, ids ...uint) error {
+	var locked []uint
+	query := db.Table(table).Select("id").Where("id IN ?", ids).Order("id").
+		Clauses(clause.Locking{Strength: "UPDATE"}).Scan(&locked)
+	if query.Error != nil {
+		return internal.ErServiceUnavailable
+	}
+
+	for _, id := range ids {
+		found := false
+		for _, lockedID := range locked {
+			found = found || lockedID == id
+		}
+		if !found {
+			return internal.ErNotFound
+		}
+	}
+	return nil
+}
+
+// getUpcoming returns the pending appointments starting from the given time whose column matches the id
+func getUpcoming(db *gorm.DB, column string, id uint, from time.Time) ([]appointment.Appointment, error) {
+	var data []appointment.Appointment
+	query := db.Where(column+" = ?", id).
+		Where("date >= ?", from).
+		Where("status IN ?", appointment.PendingStatuses).
+		Order("date").
+		Find(&data)
+	if query.Error != nil {
+		return nil, internal.ErServiceUnavailable
+	}
+	return data, nil
+}
+
+func cancelAppointments(db *gorm.DB, ids []uint, reason string, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query := db.Model(&appointment.Appointment{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+		"status":        appointment.StatusCancelled,
+		"cancelled_at":  at,
+		"cancel_reason": reason,
+		"sequence":      gorm.Expr("sequence + 1"),
+	})
+	if query.Error != nil {
+		return internal.ErServiceUnavailable
+	}
+	return nil
+}
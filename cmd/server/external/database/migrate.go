@@ -0,0 +1,511 @@
+package database
+
+import (
+	"cmp"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MigrationsDir is where migrate create writes the new migrations, relative to the root of the repository
+const MigrationsDir = "cmd/server/external/database/migrations"
+
+const (
+	// migrationLockTTL is how long the lock lasts since it was taken or refreshed, a migrator that crashed
+	// doesn't hold it forever
+	migrationLockTTL = 10 * time.Minute
+	// migrationLockRenew is how often a running migrator refreshes the lock, so a long migration keeps it
+	migrationLockRenew = migrationLockTTL / 5
+	// migrationLockWait is how long a migrator waits for another one to finish before giving up
+	migrationLockWait = 2 * time.Minute
+)
+
+// migrationsFS holds the SQL of the migrations of every driver, in migrations/<driver>/<version>_<name>.<up|down>.sql
+//
+//go:embed migrations
+var migrationsFS embed.FS
+
+var (
+	migrationFile = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+	// migrationName matches what isn't allowed in the name of a migration file
+	migrationName = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// migrationTables are the tables of the migrator itself, the applied migrations and the single row lock
+var migrationTables = map[string][]string{
+	DriverMySQL: {
+		"CREATE TABLE IF NOT EXISTS `schema_migrations` (`version` bigint unsigned NOT NULL,`name` varchar(255) NOT NULL,`applied_at` datetime(3) NOT NULL,PRIMARY KEY (`version`))",
+		"CREATE TABLE IF NOT EXISTS `schema_migrations_lock` (`id` bigint unsigned NOT NULL,`owner` varchar(100),`locked_at` datetime(3) NULL,PRIMARY KEY (`id`))",
+	},
+	DriverPostgres: {
+		`CREATE TABLE IF NOT EXISTS "schema_migrations" ("version" bigint NOT NULL,"name" varchar(255) NOT NULL,"applied_at" timestamptz(3) NOT NULL,PRIMARY KEY ("version"))`,
+		`CREATE TABLE IF NOT EXISTS "schema_migrations_lock" ("id" bigint NOT NULL,"owner" varchar(100),"locked_at" timestamptz(3),PRIMARY KEY ("id"))`,
+	},
+	DriverSQLite: {
+		"CREATE TABLE IF NOT EXISTS `schema_migrations` (`version` integer NOT NULL,`name` varchar(255) NOT NULL,`applied_at` datetime NOT NULL,PRIMARY KEY (`version`))",
+		"CREATE TABLE IF NOT EXISTS `schema_migrations_lock` (`id` integer NOT NULL,`owner` varchar(100),`locked_at` datetime,PRIMARY KEY (`id`))",
+	},
+}
+
+// Migration is a numbered change of the schema, Up applies it and Down reverts it
+type Migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus tells when a migration was applied, AppliedAt is nil while it is pending
+type MigrationStatus struct {
+	Version   uint
+	Name      string
+	AppliedAt *time.Time
+}
+
+type schemaMigration struct {
+	Version   uint `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+type schemaMigrationLock struct {
+	ID       uint `gorm:"primaryKey;autoIncrement:false"`
+	Owner    *string
+	LockedAt *time.Time
+}
+
+func (schemaMigrationLock) TableName() string {
+	return "schema_migrations_lock"
+}
+
+// Migrator applies the migrations embedded for the driver of the database. Up and Down take a lock in the
+// database first, so only one server or migrate command changes the schema at a time
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+	steps      map[string]migrationStep
+	location   *time.Location
+	owner      string
+}
+
+// NewMigrator loads the migrations of the driver of db, location is the clinic time zone the Go steps of the
+// migrations convert dates with
+func NewMigrator(db *gorm.DB, location *time.Location) (*Migrator, error) {
+	migrations, err := loadMigrations(db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	host, _ := os.Hostname()
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+		steps:      migrationSteps[db.Dialector.Name()],
+		location:   location,
+		owner:      fmt.Sprintf("%.60s:%d:%d", host, os.Getpid(), time.Now().UnixNano()),
+	}, nil
+}
+
+// WithContext returns a copy of the migrator whose queries are cancelled with ctx
+func (m *Migrator) WithContext(ctx context.Context) *Migrator {
+	copied := *m
+	copied.db = m.db.WithContext(ctx)
+	return &copied
+}
+
+// Up applies the pending migrations in order and returns them, each one runs in its own transaction. MySQL
+// commits every CREATE, ALTER and DROP on its own, a migration that fails there halfway must be fixed by hand
+func (m *Migrator) Up() ([]Migration, error) {
+	var applied []Migration
+	err := m.locked(func() error {
+		pending, err := m.Pending()
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range pending {
+			err = m.db.Transaction(func(tx *gorm.DB) error {
+				err := run(tx, migration.Up)
+				if err != nil {
+					return err
+				}
+
+				step, ok := m.steps[migration.Name]
+				if ok {
+					err = step.up(tx, m.location)
+					if err != nil {
+						return err
+					}
+				}
+				return tx.Create(&schemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now().UTC()}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+			}
+
+			applied = append(applied, migration)
+			err = m.refresh()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations, newest first, and returns them
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.locked(func() error {
+		var versions []uint
+		query := m.db.Model(&schemaMigration{}).Order("version DESC").Limit(steps).Pluck("version", &versions)
+		if query.Error != nil {
+			return query.Error
+		}
+
+		for _, version := range versions {
+			index := slices.IndexFunc(m.migrations, func(migration Migration) bool {
+				return migration.Version == version
+			})
+			if index == -1 {
+				return fmt.Errorf("migration %04d isn't known by this binary and can't be reverted", version)
+			}
+
+			migration := m.migrations[index]
+			err := m.db.Transaction(func(tx *gorm.DB) error {
+				step, ok := m.steps[migration.Name]
+				if ok {
+					err := step.down(tx, m.location)
+					if err != nil {
+						return err
+					}
+				}
+
+				err := run(tx, migration.Down)
+				if err != nil {
+					return err
+				}
+				return tx.Delete(&schemaMigration{Version: version}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+			}
+
+			reverted = append(reverted, migration)
+			err = m.refresh()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status returns every migration known by the binary in order with when it was applied
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if current, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &current.AppliedAt
+		}
+		data = append(data, status)
+	}
+	return data, nil
+}
+
+// Pending returns the migrations that aren't applied yet in order, it doesn't take the lock
+func (m *Migrator) Pending() ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var data []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			data = append(data, migration)
+		}
+	}
+	return data, nil
+}
+
+// applied returns the applied migrations by version, none when the migrator never ran
+func (m *Migrator) applied() (map[uint]schemaMigration, error) {
+	applied := map[uint]schemaMigration{}
+	if !m.db.Migrator().HasTable(&schemaMigration{}) {
+		return applied, nil
+	}
+
+	var data []schemaMigration
+	query := m.db.Find(&data)
+	if query.Error != nil {
+		return nil, query.Error
+	}
+
+	for _, current := range data {
+		applied[current.Version] = current
+	}
+	return applied, nil
+}
+
+// locked runs fn holding the lock, it creates the tables of the migrator the first time
+func (m *Migrator) locked(fn func() error) error {
+	for _, statement := range migrationTables[m.db.Dialector.Name()] {
+		query := m.db.Exec(statement)
+		if query.Error != nil {
+			return query.Error
+		}
+	}
+
+	query := m.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&schemaMigrationLock{ID: 1})
+	if query.Error != nil {
+		return query.Error
+	}
+
+	err := m.lock()
+	if err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		m.renew(done)
+	}()
+
+	err = fn()
+	close(done)
+	<-renewed
+	return errors.Join(err, m.unlock())
+}
+
+// lock takes the lock when it is free or expired, waiting for the migrator that holds it up to migrationLockWait
+func (m *Migrator) lock() error {
+	deadline := time.Now().Add(migrationLockWait)
+	for {
+		now := time.Now().UTC()
+		query := m.db.Model(&schemaMigrationLock{}).
+			Where("id = ? AND (locked_at IS NULL OR locked_at < ?)", 1, now.Add(-migrationLockTTL)).
+			Updates(map[string]any{"owner": m.owner, "locked_at": now})
+		if query.Error != nil {
+			return query.Error
+		}
+		if query.RowsAffected == 1 {
+			return nil
+		}
+
+		if now.After(deadline) {
+			var current schemaMigrationLock
+			m.db.First(&current, 1)
+			if current.Owner != nil {
+				return fmt.Errorf("%w, held by %s", internal.ErMigrationLocked, *current.Owner)
+			}
+			return internal.ErMigrationLocked
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// refresh extends the lock after each migration, so it doesn't expire while there are more to run
+func (m *Migrator) refresh() error {
+	query := m.db.Model(&schemaMigrationLock{}).
+		Where("id = ? AND owner = ?", 1, m.owner).
+		Update("locked_at", time.Now().UTC())
+	if query.Error != nil {
+		return query.Error
+	}
+	if query.RowsAffected == 0 {
+		return internal.ErMigrationLocked
+	}
+	return nil
+}
+
+// renew refreshes the lock every migrationLockRenew until done is closed, a migration running longer than
+// migrationLockTTL keeps the lock while the process is alive
+func (m *Migrator) renew(done <-chan struct{}) {
+	ticker := time.NewTicker(migrationLockRenew)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+
+		case <-ticker.C:
+			err := m.refresh()
+			if err != nil {
+				log.Printf("database: renewing the migration lock: %v", err)
+			}
+		}
+	}
+}
+
+func (m *Migrator) unlock() error {
+	return m.db.Model(&schemaMigrationLock{}).
+		Where("id = ? AND owner = ?", 1, m.owner).
+		Updates(map[string]any{"owner": nil, "locked_at": nil}).Error
+}
+
+// run executes the statements of a migration one by one, the drivers don't run several at once
+func run(tx *gorm.DB, sql string) error {
+	for _, statement := range statements(sql) {
+		query := tx.Exec(statement)
+		if query.Error != nil {
+			return query.Error
+		}
+	}
+	return nil
+}
+
+// statements splits the SQL of a migration in its statements, each one ends with a semicolon at the end of a
+// line. Blank lines and lines starting with -- are skipped
+func statements(sql string) []string {
+	var data []string
+	var current strings.Builder
+	for _, line := range strings.Split(sql, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			data = append(data, strings.TrimSuffix(strings.TrimSpace(current.String()), ";"))
+			current.Reset()
+		}
+	}
+
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		data = append(data, rest)
+	}
+	return data
+}
+
+// loadMigrations reads the embedded migrations of the driver ordered by version, every one must have both files
+func loadMigrations(driver string) ([]Migration, error) {
+	dir := path.Join("migrations", driver)
+	entries, err := fs.ReadDir(migrationsFS, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for driver %q: %w", driver, err)
+	}
+
+	byVersion := map[uint]*Migration{}
+	for _, entry := range entries {
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migration file %s must be named like 0001_name.up.sql", entry.Name())
+		}
+
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil || version == 0 {
+			return nil, fmt.Errorf("migration file %s must have a positive version", entry.Name())
+		}
+
+		content, err := fs.ReadFile(migrationsFS, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[uint(version)]
+		if !ok {
+			migration = &Migration{Version: uint(version), Name: match[2]}
+			byVersion[uint(version)] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %04d has files named %s and %s", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	data := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s must have an up and a down file", migration.Version, migration.Name)
+		}
+		data = append(data, *migration)
+	}
+	slices.SortFunc(data, func(a, b Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	return data, nil
+}
+
+// CreateMigration writes the up and down files of a new migration for every driver in dir, numbered after the
+// last migration of any of them, and returns their paths
+func CreateMigration(dir string, name string) ([]string, error) {
+	name = strings.Trim(migrationName.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return nil, fmt.Errorf("migration name must have letters or digits")
+	}
+
+	drivers := []string{DriverMySQL, DriverPostgres, DriverSQLite}
+	var last uint64
+	for _, driver := range drivers {
+		entries, err := os.ReadDir(filepath.Join(dir, driver))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			match := migrationFile.FindStringSubmatch(entry.Name())
+			if match == nil {
+				continue
+			}
+			version, _ := strconv.ParseUint(match[1], 10, 64)
+			last = max(last, version)
+		}
+	}
+
+	var paths []string
+	for _, driver := range drivers {
+		err := os.MkdirAll(filepath.Join(dir, driver), 0o755)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, direction := range []string{"up", "down"} {
+			file := filepath.Join(dir, driver, fmt.Sprintf("%04d_%s.%s.sql", last+1, name, direction))
+			content := fmt.Sprintf("-- %s %s, each statement ends with a semicolon at the end of a line\n", name, direction)
+			err = os.WriteFile(file, []byte(content), 0o644)
+			if err != nil {
+				return nil, err
+			}
+			paths = append(paths, file)
+		}
+	}
+	return paths, nil
+}
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// convertBatch is how many rows a date conversion reads at a time
+const convertBatch = 500
+
+// migrationStep is the part of a migration SQL can't express, up runs after the SQL of the migration and down
+// before it, in the same transaction and with the clinic time zone
+type migrationStep struct {
+	up   func(tx *gorm.DB, location *time.Location) error
+	down func(tx *gorm.DB, location *time.Location) error
+}
+
+// migrationSteps are the Go steps of the migrations by driver and migration name
+var migrationSteps = map[string]map[string]migrationStep{
+	DriverMySQL: {
+		"local_dates_to_utc": {up: localDatesToUTC, down: utcDatesToLocal},
+	},
+}
+
+// localDateColumns are the date columns of the baseline schema, the servers before UTC stored the wall clock of
+// the clinic time zone in them
+var localDateColumns = []struct {
+	table  string
+	column string
+}{
+	{table: "appointments", column: "date"},
+	{table: "patients", column: "admission_date"},
+}
+
+// localDatesToUTC moves the dates from the wall clock of the location to UTC, time.Date picks the offset in force
+// on each date, so the ones on both sides of a daylight saving change are moved by their own offset
+func localDatesToUTC(tx *gorm.DB, location *time.Location) error {
+	return convertDates(tx, func(date time.Time) time.Time {
+		return time.Date(date.Year(), date.Month(), date.Day(), date.Hour(), date.Minute(), date.Second(), date.Nanosecond(), location).UTC()
+	})
+}
+
+// utcDatesToLocal moves the dates back from UTC to the wall clock of the location
+func utcDatesToLocal(tx *gorm.DB, location *time.Location) error {
+	return convertDates(tx, func(date time.Time) time.Time {
+		local := date.In(location)
+		return time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), local.Minute(), local.Second(), local.Nanosecond(), time.UTC)
+	})
+}
+
+// convertDates rewrites every column of localDateColumns with convert, the rows are read in batches by id so the
+// tables aren't loaded at once
+func convertDates(tx *gorm.DB, convert func(time.Time) time.Time) error {
+	for _, current := range localDateColumns {
+		var lastID uint
+		for {
+			ids, dates, err := readDates(tx, current.table, current.column, lastID)
+			if err != nil {
+				return err
+			}
+
+			for i, id := range ids {
+				query := tx.Table(current.table).Where("id = ?", id).Update(current.column, convert(dates[i]))
+				if query.Error != nil {
+					return query.Error
+				}
+			}
+
+			if len(ids) < convertBatch {
+				break
+			}
+			lastID = ids[len(ids)-1]
+		}
+	}
+	return nil
+}
+
+// readDates returns the next batch of ids and dates of the column after lastID
+func readDates(tx *gorm.DB, table string, column string, lastID uint) ([]uint, []time.Time, error) {
+	rows, err := tx.Table(table).
+		Select([]string{"id", column}).
+		Where("id > ?", lastID).
+		Order("id").
+		Limit(convertBatch).
+		Rows()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var ids []uint
+	var dates []time.Time
+	for rows.Next() {
+		var id uint
+		var date time.Time
+		err = rows.Scan(&id, &date)
+		if err != nil {
+			return nil, nil, err
+		}
+		ids = append(ids, id)
+		dates = append(dates, date)
+	}
+	return ids, dates, rows.Err()
+}
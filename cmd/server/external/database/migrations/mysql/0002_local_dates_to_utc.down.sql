@@ -0,0 +1 @@
+-- The dates are moved back to the wall clock of the clinic time zone by the Go step of this migration
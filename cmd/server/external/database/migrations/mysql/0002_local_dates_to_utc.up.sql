@@ -0,0 +1,2 @@
+-- The dates stored before they were kept in UTC hold the wall clock of the clinic time zone, they are moved to UTC
+-- by the Go step of this migration, which follows the daylight saving changes of TIME_ZONE
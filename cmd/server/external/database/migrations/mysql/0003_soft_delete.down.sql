@@ -0,0 +1,7 @@
+DROP INDEX `idx_patients_deleted_at` ON `patients`;
+ALTER TABLE `patients`
+    DROP COLUMN `deleted_at`;
+
+DROP INDEX `idx_dentists_deleted_at` ON `dentists`;
+ALTER TABLE `dentists`
+    DROP COLUMN `deleted_at`;
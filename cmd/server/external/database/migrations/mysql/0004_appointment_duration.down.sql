@@ -0,0 +1,11 @@
+-- The foreign keys of dentist_id and patient_id use these indexes once they exist, MySQL only drops them in the
+-- same statement that adds others for the foreign keys
+
+ALTER TABLE `appointments`
+    DROP INDEX `idx_appointments_dentist_date`,
+    DROP INDEX `idx_appointments_patient_date`,
+    ADD INDEX `idx_appointments_dentist_id` (`dentist_id`),
+    ADD INDEX `idx_appointments_patient_id` (`patient_id`);
+ALTER TABLE `appointments`
+    DROP COLUMN `end_date`,
+    DROP COLUMN `duration`;
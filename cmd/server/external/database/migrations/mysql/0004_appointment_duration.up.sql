@@ -0,0 +1,7 @@
+-- Appointments last duration minutes and end at end_date, which the overlap checks compare
+
+ALTER TABLE `appointments`
+    ADD COLUMN `duration` bigint unsigned NOT NULL DEFAULT 30,
+    ADD COLUMN `end_date` datetime(3) NULL;
+CREATE INDEX `idx_appointments_patient_date` ON `appointments` (`patient_id`,`date`);
+CREATE INDEX `idx_appointments_dentist_date` ON `appointments` (`dentist_id`,`date`);
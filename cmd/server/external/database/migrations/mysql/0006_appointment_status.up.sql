@@ -0,0 +1,12 @@
+-- The status of the appointments and when each one was reached
+
+ALTER TABLE `appointments`
+    ADD COLUMN `status` varchar(20) NOT NULL DEFAULT 'scheduled',
+    ADD COLUMN `confirmed_at` datetime(3) NULL,
+    ADD COLUMN `checked_in_at` datetime(3) NULL,
+    ADD COLUMN `completed_at` datetime(3) NULL,
+    ADD COLUMN `cancelled_at` datetime(3) NULL,
+    ADD COLUMN `no_show_at` datetime(3) NULL,
+    ADD COLUMN `cancel_reason` varchar(255),
+    ADD COLUMN `updated_at` datetime(3) NULL;
+CREATE INDEX `idx_appointments_status` ON `appointments` (`status`);
@@ -0,0 +1,7 @@
+ALTER TABLE `appointments`
+    DROP FOREIGN KEY `fk_appointment_series_appointments`;
+DROP INDEX `idx_appointments_series_id` ON `appointments`;
+ALTER TABLE `appointments`
+    DROP COLUMN `sequence`,
+    DROP COLUMN `series_id`;
+DROP TABLE IF EXISTS `appointment_series`;
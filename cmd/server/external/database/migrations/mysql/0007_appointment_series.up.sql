@@ -0,0 +1,24 @@
+-- Recurring appointments, each one of a series has its sequence in it
+
+CREATE TABLE `appointment_series` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `patient_id` bigint unsigned NOT NULL,
+    `dentist_id` bigint unsigned NOT NULL,
+    `start` datetime(3) NOT NULL,
+    `duration` bigint unsigned NOT NULL DEFAULT 30,
+    `description` longtext,
+    `frequency` varchar(10) NOT NULL,
+    `interval` bigint unsigned NOT NULL DEFAULT 1,
+    `count` bigint unsigned NOT NULL DEFAULT 0,
+    `until` datetime(3) NULL,
+    `created_at` datetime(3) NOT NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_appointment_series_patient_id` (`patient_id`),
+    INDEX `idx_appointment_series_dentist_id` (`dentist_id`)
+);
+
+ALTER TABLE `appointments`
+    ADD COLUMN `series_id` bigint unsigned,
+    ADD COLUMN `sequence` bigint unsigned NOT NULL DEFAULT 0,
+    ADD INDEX `idx_appointments_series_id` (`series_id`),
+    ADD CONSTRAINT `fk_appointment_series_appointments` FOREIGN KEY (`series_id`) REFERENCES `appointment_series`(`id`);
@@ -0,0 +1,12 @@
+-- The weekly working hours of the dentists, appointments are only booked within them
+
+CREATE TABLE `working_hours` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `dentist_id` bigint unsigned NOT NULL,
+    `weekday` bigint NOT NULL,
+    `start_time` varchar(5) NOT NULL,
+    `end_time` varchar(5) NOT NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_working_hours_dentist_id` (`dentist_id`),
+    CONSTRAINT `fk_dentists_working_hours` FOREIGN KEY (`dentist_id`) REFERENCES `dentists`(`id`)
+);
@@ -0,0 +1,12 @@
+-- The users that log in, each one with a role, dentists see their own appointments
+
+CREATE TABLE `users` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `username` varchar(60) NOT NULL UNIQUE,
+    `password_hash` varchar(100) NOT NULL,
+    `role` varchar(20) NOT NULL,
+    `dentist_id` bigint unsigned,
+    `created_at` datetime(3) NOT NULL,
+    `feed_token_hash` varchar(64) UNIQUE,
+    PRIMARY KEY (`id`)
+);
@@ -0,0 +1,16 @@
+-- Who changed what and when
+
+CREATE TABLE `audit_entries` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `entity` varchar(40) NOT NULL,
+    `entity_id` bigint unsigned NOT NULL,
+    `action` varchar(40) NOT NULL,
+    `actor_id` bigint unsigned NOT NULL,
+    `actor` varchar(60) NOT NULL,
+    `created_at` datetime(3) NOT NULL,
+    `changes` longtext,
+    PRIMARY KEY (`id`),
+    INDEX `idx_audit_entries_entity` (`entity`,`entity_id`),
+    INDEX `idx_audit_entries_actor_id` (`actor_id`),
+    INDEX `idx_audit_entries_created_at` (`created_at`)
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS `waitlist_offers`;
+DROP TABLE IF EXISTS `waitlist_entries`;
@@ -0,0 +1,39 @@
+-- Patients waiting for a slot and the offers of the freed ones
+
+CREATE TABLE `waitlist_entries` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `patient_id` bigint unsigned NOT NULL,
+    `dentist_id` bigint unsigned NOT NULL,
+    `from_date` datetime(3) NOT NULL,
+    `to_date` datetime(3) NOT NULL,
+    `window_start` varchar(5) NOT NULL,
+    `window_end` varchar(5) NOT NULL,
+    `duration` bigint unsigned NOT NULL DEFAULT 30,
+    `description` longtext,
+    `status` varchar(20) NOT NULL,
+    `created_at` datetime(3) NOT NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_waitlist_entries_patient_id` (`patient_id`),
+    INDEX `idx_waitlist_entries_dentist_status` (`dentist_id`,`status`)
+);
+
+CREATE TABLE `waitlist_offers` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `entry_id` bigint unsigned NOT NULL,
+    `patient_id` bigint unsigned NOT NULL,
+    `dentist_id` bigint unsigned NOT NULL,
+    `start_date` datetime(3) NOT NULL,
+    `end_date` datetime(3) NOT NULL,
+    `status` varchar(20) NOT NULL,
+    `expires_at` datetime(3) NOT NULL,
+    `appointment_id` bigint unsigned,
+    `created_at` datetime(3) NOT NULL,
+    `responded_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_waitlist_offers_entry_id` (`entry_id`),
+    INDEX `idx_waitlist_offers_patient_id` (`patient_id`),
+    INDEX `idx_waitlist_offers_dentist_start` (`dentist_id`,`start_date`),
+    INDEX `idx_waitlist_offers_status` (`status`),
+    INDEX `idx_waitlist_offers_expires_at` (`expires_at`),
+    CONSTRAINT `fk_waitlist_entries_offers` FOREIGN KEY (`entry_id`) REFERENCES `waitlist_entries`(`id`)
+);
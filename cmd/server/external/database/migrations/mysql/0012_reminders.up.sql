@@ -0,0 +1,18 @@
+-- The reminders of the appointments, one per appointment date and offset
+
+CREATE TABLE `reminders` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `appointment_id` bigint unsigned NOT NULL,
+    `date` datetime(3) NOT NULL,
+    `offset_minutes` bigint unsigned NOT NULL,
+    `channel` varchar(20) NOT NULL,
+    `recipient` varchar(80) NOT NULL,
+    `status` varchar(20) NOT NULL,
+    `attempts` bigint unsigned NOT NULL DEFAULT 0,
+    `error` varchar(255),
+    `created_at` datetime(3) NULL,
+    `sent_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `idx_reminders_appointment_date_offset` (`appointment_id`,`date`,`offset_minutes`),
+    INDEX `idx_reminders_status` (`status`)
+);
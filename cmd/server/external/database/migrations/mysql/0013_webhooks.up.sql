@@ -0,0 +1,43 @@
+-- The outbox of the events and their deliveries to the webhook subscriptions
+
+CREATE TABLE `outbox_events` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `type` varchar(40) NOT NULL,
+    `entity` varchar(40) NOT NULL,
+    `entity_id` bigint unsigned NOT NULL,
+    `payload` longtext,
+    `created_at` datetime(3) NOT NULL,
+    `dispatched_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_outbox_events_type` (`type`),
+    INDEX `idx_outbox_events_dispatched_at` (`dispatched_at`)
+);
+
+CREATE TABLE `webhook_subscriptions` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `url` varchar(2048) NOT NULL,
+    `secret` varchar(64) NOT NULL,
+    `events` varchar(512),
+    `active` boolean NOT NULL DEFAULT true,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`id`)
+);
+
+CREATE TABLE `webhook_deliveries` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `event_id` bigint unsigned NOT NULL,
+    `subscription_id` bigint unsigned NOT NULL,
+    `event_type` varchar(40) NOT NULL,
+    `status` varchar(20) NOT NULL,
+    `attempts` bigint unsigned NOT NULL DEFAULT 0,
+    `next_attempt_at` datetime(3) NOT NULL,
+    `last_status_code` bigint,
+    `last_error` varchar(255),
+    `delivered_at` datetime(3) NULL,
+    `created_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `idx_webhook_deliveries_event_subscription` (`event_id`,`subscription_id`),
+    INDEX `idx_webhook_deliveries_subscription_id` (`subscription_id`),
+    INDEX `idx_webhook_deliveries_due` (`status`,`next_attempt_at`)
+);
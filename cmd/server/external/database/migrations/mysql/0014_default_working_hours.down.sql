@@ -0,0 +1 @@
+-- The working hours are kept, the default ones can't be told apart from the ones set afterwards
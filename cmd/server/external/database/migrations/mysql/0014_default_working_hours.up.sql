@@ -0,0 +1,8 @@
+-- The dentists stored before working hours existed get the default ones, from Monday to Friday between 09:00 and
+-- 18:00, without working hours they take no appointments
+
+INSERT INTO `working_hours` (`dentist_id`, `weekday`, `start_time`, `end_time`)
+SELECT `dentists`.`id`, `weekdays`.`weekday`, '09:00', '18:00'
+FROM `dentists`
+CROSS JOIN (SELECT 1 AS `weekday` UNION ALL SELECT 2 UNION ALL SELECT 3 UNION ALL SELECT 4 UNION ALL SELECT 5) `weekdays`
+WHERE NOT EXISTS (SELECT 1 FROM `working_hours` WHERE `working_hours`.`dentist_id` = `dentists`.`id`);
@@ -0,0 +1,5 @@
+-- Reminders record when they were claimed, the ones left sending for longer than a lease are sent again
+
+ALTER TABLE `reminders`
+    ADD COLUMN `claimed_at` datetime(3) NULL;
+UPDATE `reminders` SET `claimed_at` = `created_at`;
@@ -0,0 +1,9 @@
+-- Dentists and patients are soft deleted, deleted_at is set instead of removing the row
+
+ALTER TABLE "dentists"
+    ADD COLUMN "deleted_at" timestamptz;
+CREATE INDEX "idx_dentists_deleted_at" ON "dentists" ("deleted_at");
+
+ALTER TABLE "patients"
+    ADD COLUMN "deleted_at" timestamptz;
+CREATE INDEX "idx_patients_deleted_at" ON "patients" ("deleted_at");
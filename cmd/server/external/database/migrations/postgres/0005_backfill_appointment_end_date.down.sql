@@ -0,0 +1 @@
+-- The end dates are kept, they match the durations of the appointments
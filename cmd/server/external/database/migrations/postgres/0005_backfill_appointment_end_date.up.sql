@@ -0,0 +1,4 @@
+-- The appointments stored before durations existed get the default one, without an end date they never
+-- overlap the others
+
+UPDATE "appointments" SET "end_date" = "date" + "duration" * interval '1 minute' WHERE "end_date" IS NULL;
@@ -0,0 +1,10 @@
+DROP INDEX "idx_appointments_status";
+ALTER TABLE "appointments"
+    DROP COLUMN "updated_at",
+    DROP COLUMN "cancel_reason",
+    DROP COLUMN "no_show_at",
+    DROP COLUMN "cancelled_at",
+    DROP COLUMN "completed_at",
+    DROP COLUMN "checked_in_at",
+    DROP COLUMN "confirmed_at",
+    DROP COLUMN "status";
@@ -0,0 +1,12 @@
+-- The status of the appointments and when each one was reached
+
+ALTER TABLE "appointments"
+    ADD COLUMN "status" varchar(20) NOT NULL DEFAULT 'scheduled',
+    ADD COLUMN "confirmed_at" timestamptz(3),
+    ADD COLUMN "checked_in_at" timestamptz(3),
+    ADD COLUMN "completed_at" timestamptz(3),
+    ADD COLUMN "cancelled_at" timestamptz(3),
+    ADD COLUMN "no_show_at" timestamptz(3),
+    ADD COLUMN "cancel_reason" varchar(255),
+    ADD COLUMN "updated_at" timestamptz(3);
+CREATE INDEX "idx_appointments_status" ON "appointments" ("status");
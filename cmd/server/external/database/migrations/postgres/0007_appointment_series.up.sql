@@ -0,0 +1,24 @@
+-- Recurring appointments, each one of a series has its sequence in it
+
+CREATE TABLE "appointment_series" (
+    "id" bigserial,
+    "patient_id" bigint NOT NULL,
+    "dentist_id" bigint NOT NULL,
+    "start" timestamptz(3) NOT NULL,
+    "duration" bigint NOT NULL DEFAULT 30,
+    "description" text,
+    "frequency" varchar(10) NOT NULL,
+    "interval" bigint NOT NULL DEFAULT 1,
+    "count" bigint NOT NULL DEFAULT 0,
+    "until" timestamptz(3),
+    "created_at" timestamptz(3) NOT NULL,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_appointment_series_patient_id" ON "appointment_series" ("patient_id");
+CREATE INDEX "idx_appointment_series_dentist_id" ON "appointment_series" ("dentist_id");
+
+ALTER TABLE "appointments"
+    ADD COLUMN "series_id" bigint,
+    ADD COLUMN "sequence" bigint NOT NULL DEFAULT 0,
+    ADD CONSTRAINT "fk_appointment_series_appointments" FOREIGN KEY ("series_id") REFERENCES "appointment_series"("id");
+CREATE INDEX "idx_appointments_series_id" ON "appointments" ("series_id");
@@ -0,0 +1,16 @@
+-- Who changed what and when
+
+CREATE TABLE "audit_entries" (
+    "id" bigserial,
+    "entity" varchar(40) NOT NULL,
+    "entity_id" bigint NOT NULL,
+    "action" varchar(40) NOT NULL,
+    "actor_id" bigint NOT NULL,
+    "actor" varchar(60) NOT NULL,
+    "created_at" timestamptz(3) NOT NULL,
+    "changes" text,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_audit_entries_created_at" ON "audit_entries" ("created_at");
+CREATE INDEX "idx_audit_entries_actor_id" ON "audit_entries" ("actor_id");
+CREATE INDEX "idx_audit_entries_entity" ON "audit_entries" ("entity","entity_id");
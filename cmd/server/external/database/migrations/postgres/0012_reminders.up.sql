@@ -0,0 +1,18 @@
+-- The reminders of the appointments, one per appointment date and offset
+
+CREATE TABLE "reminders" (
+    "id" bigserial,
+    "appointment_id" bigint NOT NULL,
+    "date" timestamptz(3) NOT NULL,
+    "offset_minutes" bigint NOT NULL,
+    "channel" varchar(20) NOT NULL,
+    "recipient" varchar(80) NOT NULL,
+    "status" varchar(20) NOT NULL,
+    "attempts" bigint NOT NULL DEFAULT 0,
+    "error" varchar(255),
+    "created_at" timestamptz(3),
+    "sent_at" timestamptz(3),
+    PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_reminders_status" ON "reminders" ("status");
+CREATE UNIQUE INDEX "idx_reminders_appointment_date_offset" ON "reminders" ("appointment_id","date","offset_minutes");
@@ -0,0 +1,43 @@
+-- The outbox of the events and their deliveries to the webhook subscriptions
+
+CREATE TABLE "outbox_events" (
+    "id" bigserial,
+    "type" varchar(40) NOT NULL,
+    "entity" varchar(40) NOT NULL,
+    "entity_id" bigint NOT NULL,
+    "payload" text,
+    "created_at" timestamptz(3) NOT NULL,
+    "dispatched_at" timestamptz(3),
+    PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_outbox_events_dispatched_at" ON "outbox_events" ("dispatched_at");
+CREATE INDEX "idx_outbox_events_type" ON "outbox_events" ("type");
+
+CREATE TABLE "webhook_subscriptions" (
+    "id" bigserial,
+    "url" varchar(2048) NOT NULL,
+    "secret" varchar(64) NOT NULL,
+    "events" varchar(512),
+    "active" boolean NOT NULL DEFAULT true,
+    "created_at" timestamptz(3),
+    "updated_at" timestamptz(3),
+    PRIMARY KEY ("id")
+);
+
+CREATE TABLE "webhook_deliveries" (
+    "id" bigserial,
+    "event_id" bigint NOT NULL,
+    "subscription_id" bigint NOT NULL,
+    "event_type" varchar(40) NOT NULL,
+    "status" varchar(20) NOT NULL,
+    "attempts" bigint NOT NULL DEFAULT 0,
+    "next_attempt_at" timestamptz(3) NOT NULL,
+    "last_status_code" bigint,
+    "last_error" varchar(255),
+    "delivered_at" timestamptz(3),
+    "created_at" timestamptz(3),
+    PRIMARY KEY ("id")
+);
+CREATE INDEX "idx_webhook_deliveries_due" ON "webhook_deliveries" ("status","next_attempt_at");
+CREATE INDEX "idx_webhook_deliveries_subscription_id" ON "webhook_deliveries" ("subscription_id");
+CREATE UNIQUE INDEX "idx_webhook_deliveries_event_subscription" ON "webhook_deliveries" ("event_id","subscription_id");
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS `appointments`;
+DROP TABLE IF EXISTS `patients`;
+DROP TABLE IF EXISTS `dentists`;
@@ -0,0 +1,32 @@
+-- The schema the server created with AutoMigrate before the migrations, databases created by it are adopted
+-- as they are and get the rest of the schema from the next migrations
+
+CREATE TABLE IF NOT EXISTS `dentists` (
+    `id` integer,
+    `lastname` varchar(60) NOT NULL,
+    `name` varchar(60) NOT NULL,
+    `license` varchar(40) NOT NULL UNIQUE,
+    PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `patients` (
+    `id` integer,
+    `name` varchar(60) NOT NULL,
+    `lastname` varchar(60) NOT NULL,
+    `address` varchar(120) NOT NULL,
+    `dni` varchar(20) NOT NULL UNIQUE,
+    `email` varchar(80) NOT NULL,
+    `admission_date` datetime NOT NULL,
+    PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `appointments` (
+    `id` integer,
+    `patient_id` integer NOT NULL,
+    `dentist_id` integer NOT NULL,
+    `date` datetime NOT NULL,
+    `description` text,
+    PRIMARY KEY (`id`),
+    CONSTRAINT `fk_dentists_appointments` FOREIGN KEY (`dentist_id`) REFERENCES `dentists`(`id`),
+    CONSTRAINT `fk_patients_appointments` FOREIGN KEY (`patient_id`) REFERENCES `patients`(`id`)
+);
@@ -0,0 +1 @@
+-- Only MySQL databases were created before dates were kept in UTC, there are no dates to move in this one
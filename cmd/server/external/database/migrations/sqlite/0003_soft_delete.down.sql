@@ -0,0 +1,5 @@
+DROP INDEX `idx_patients_deleted_at`;
+ALTER TABLE `patients` DROP COLUMN `deleted_at`;
+
+DROP INDEX `idx_dentists_deleted_at`;
+ALTER TABLE `dentists` DROP COLUMN `deleted_at`;
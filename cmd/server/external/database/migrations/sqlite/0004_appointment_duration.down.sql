@@ -0,0 +1,4 @@
+DROP INDEX `idx_appointments_dentist_date`;
+DROP INDEX `idx_appointments_patient_date`;
+ALTER TABLE `appointments` DROP COLUMN `end_date`;
+ALTER TABLE `appointments` DROP COLUMN `duration`;
@@ -0,0 +1,4 @@
+-- The appointments stored before durations existed get the default one, without an end date they never
+-- overlap the others
+
+UPDATE `appointments` SET `end_date` = strftime('%Y-%m-%d %H:%M:%S+00:00', `date`, '+' || `duration` || ' minutes') WHERE `end_date` IS NULL;
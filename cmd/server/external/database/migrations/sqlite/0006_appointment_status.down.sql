@@ -0,0 +1,9 @@
+DROP INDEX `idx_appointments_status`;
+ALTER TABLE `appointments` DROP COLUMN `updated_at`;
+ALTER TABLE `appointments` DROP COLUMN `cancel_reason`;
+ALTER TABLE `appointments` DROP COLUMN `no_show_at`;
+ALTER TABLE `appointments` DROP COLUMN `cancelled_at`;
+ALTER TABLE `appointments` DROP COLUMN `completed_at`;
+ALTER TABLE `appointments` DROP COLUMN `checked_in_at`;
+ALTER TABLE `appointments` DROP COLUMN `confirmed_at`;
+ALTER TABLE `appointments` DROP COLUMN `status`;
@@ -0,0 +1,11 @@
+-- The status of the appointments and when each one was reached
+
+ALTER TABLE `appointments` ADD COLUMN `status` varchar(20) NOT NULL DEFAULT 'scheduled';
+ALTER TABLE `appointments` ADD COLUMN `confirmed_at` datetime;
+ALTER TABLE `appointments` ADD COLUMN `checked_in_at` datetime;
+ALTER TABLE `appointments` ADD COLUMN `completed_at` datetime;
+ALTER TABLE `appointments` ADD COLUMN `cancelled_at` datetime;
+ALTER TABLE `appointments` ADD COLUMN `no_show_at` datetime;
+ALTER TABLE `appointments` ADD COLUMN `cancel_reason` varchar(255);
+ALTER TABLE `appointments` ADD COLUMN `updated_at` datetime;
+CREATE INDEX `idx_appointments_status` ON `appointments` (`status`);
@@ -0,0 +1,4 @@
+DROP INDEX `idx_appointments_series_id`;
+ALTER TABLE `appointments` DROP COLUMN `sequence`;
+ALTER TABLE `appointments` DROP COLUMN `series_id`;
+DROP TABLE IF EXISTS `appointment_series`;
@@ -0,0 +1,23 @@
+-- Recurring appointments, each one of a series has its sequence in it
+
+CREATE TABLE `appointment_series` (
+    `id` integer,
+    `patient_id` integer NOT NULL,
+    `dentist_id` integer NOT NULL,
+    `start` datetime NOT NULL,
+    `duration` integer NOT NULL DEFAULT 30,
+    `description` text,
+    `frequency` varchar(10) NOT NULL,
+    `interval` integer NOT NULL DEFAULT 1,
+    `count` integer NOT NULL DEFAULT 0,
+    `until` datetime,
+    `created_at` datetime NOT NULL,
+    PRIMARY KEY (`id`)
+);
+CREATE INDEX `idx_appointment_series_dentist_id` ON `appointment_series` (`dentist_id`);
+CREATE INDEX `idx_appointment_series_patient_id` ON `appointment_series` (`patient_id`);
+
+-- SQLite can't add a foreign key to a table, nor drop a column that has one, it doesn't enforce them here anyway
+ALTER TABLE `appointments` ADD COLUMN `series_id` integer;
+ALTER TABLE `appointments` ADD COLUMN `sequence` integer NOT NULL DEFAULT 0;
+CREATE INDEX `idx_appointments_series_id` ON `appointments` (`series_id`);
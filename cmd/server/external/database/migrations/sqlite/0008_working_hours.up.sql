@@ -0,0 +1,12 @@
+-- The weekly working hours of the dentists, appointments are only booked within them
+
+CREATE TABLE `working_hours` (
+    `id` integer,
+    `dentist_id` integer NOT NULL,
+    `weekday` integer NOT NULL,
+    `start_time` varchar(5) NOT NULL,
+    `end_time` varchar(5) NOT NULL,
+    PRIMARY KEY (`id`),
+    CONSTRAINT `fk_dentists_working_hours` FOREIGN KEY (`dentist_id`) REFERENCES `dentists`(`id`)
+);
+CREATE INDEX `idx_working_hours_dentist_id` ON `working_hours` (`dentist_id`);
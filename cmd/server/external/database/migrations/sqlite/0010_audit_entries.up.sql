@@ -0,0 +1,16 @@
+-- Who changed what and when
+
+CREATE TABLE `audit_entries` (
+    `id` integer,
+    `entity` varchar(40) NOT NULL,
+    `entity_id` integer NOT NULL,
+    `action` varchar(40) NOT NULL,
+    `actor_id` integer NOT NULL,
+    `actor` varchar(60) NOT NULL,
+    `created_at` datetime NOT NULL,
+    `changes` text,
+    PRIMARY KEY (`id`)
+);
+CREATE INDEX `idx_audit_entries_created_at` ON `audit_entries` (`created_at`);
+CREATE INDEX `idx_audit_entries_actor_id` ON `audit_entries` (`actor_id`);
+CREATE INDEX `idx_audit_entries_entity` ON `audit_entries` (`entity`,`entity_id`);
@@ -0,0 +1,39 @@
+-- Patients waiting for a slot and the offers of the freed ones
+
+CREATE TABLE `waitlist_entries` (
+    `id` integer,
+    `patient_id` integer NOT NULL,
+    `dentist_id` integer NOT NULL,
+    `from_date` datetime NOT NULL,
+    `to_date` datetime NOT NULL,
+    `window_start` varchar(5) NOT NULL,
+    `window_end` varchar(5) NOT NULL,
+    `duration` integer NOT NULL DEFAULT 30,
+    `description` text,
+    `status` varchar(20) NOT NULL,
+    `created_at` datetime NOT NULL,
+    PRIMARY KEY (`id`)
+);
+CREATE INDEX `idx_waitlist_entries_dentist_status` ON `waitlist_entries` (`dentist_id`,`status`);
+CREATE INDEX `idx_waitlist_entries_patient_id` ON `waitlist_entries` (`patient_id`);
+
+CREATE TABLE `waitlist_offers` (
+    `id` integer,
+    `entry_id` integer NOT NULL,
+    `patient_id` integer NOT NULL,
+    `dentist_id` integer NOT NULL,
+    `start_date` datetime NOT NULL,
+    `end_date` datetime NOT NULL,
+    `status` varchar(20) NOT NULL,
+    `expires_at` datetime NOT NULL,
+    `appointment_id` integer,
+    `created_at` datetime NOT NULL,
+    `responded_at` datetime,
+    PRIMARY KEY (`id`),
+    CONSTRAINT `fk_waitlist_entries_offers` FOREIGN KEY (`entry_id`) REFERENCES `waitlist_entries`(`id`)
+);
+CREATE INDEX `idx_waitlist_offers_expires_at` ON `waitlist_offers` (`expires_at`);
+CREATE INDEX `idx_waitlist_offers_status` ON `waitlist_offers` (`status`);
+CREATE INDEX `idx_waitlist_offers_dentist_start` ON `waitlist_offers` (`dentist_id`,`start_date`);
+CREATE INDEX `idx_waitlist_offers_patient_id` ON `waitlist_offers` (`patient_id`);
+CREATE INDEX `idx_waitlist_offers_entry_id` ON `waitlist_offers` (`entry_id`);
@@ -0,0 +1,18 @@
+-- The reminders of the appointments, one per appointment date and offset
+
+CREATE TABLE `reminders` (
+    `id` integer,
+    `appointment_id` integer NOT NULL,
+    `date` datetime NOT NULL,
+    `offset_minutes` integer NOT NULL,
+    `channel` varchar(20) NOT NULL,
+    `recipient` varchar(80) NOT NULL,
+    `status` varchar(20) NOT NULL,
+    `attempts` integer NOT NULL DEFAULT 0,
+    `error` varchar(255),
+    `created_at` datetime,
+    `sent_at` datetime,
+    PRIMARY KEY (`id`)
+);
+CREATE INDEX `idx_reminders_status` ON `reminders` (`status`);
+CREATE UNIQUE INDEX `idx_reminders_appointment_date_offset` ON `reminders` (`appointment_id`,`date`,`offset_minutes`);
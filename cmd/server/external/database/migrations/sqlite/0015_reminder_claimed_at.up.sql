@@ -0,0 +1,4 @@
+-- Reminders record when they were claimed, the ones left sending for longer than a lease are sent again
+
+ALTER TABLE `reminders` ADD COLUMN `claimed_at` datetime;
+UPDATE `reminders` SET `claimed_at` = `created_at`;
@@ -0,0 +1,79 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/pagination"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// findPage counts the rows matched by the query and loads the requested page,
+// rows are always ordered by id last so pages are stable
+func findPage[T any](query *gorm.DB, page pagination.Request) (pagination.Page[T], error) {
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	offset := page.Offset()
+	if page.After == nil {
+		result := query.Count(&total)
+		if result.Error != nil {
+			return pagination.Page[T]{}, internal.ErServiceUnavailable
+		}
+	} else {
+		after, err := keyset(query, page.Sort, page.After)
+		if err != nil {
+			return pagination.Page[T]{}, internal.ErServiceUnavailable
+		}
+		query = query.Where(after)
+		offset = 0
+	}
+
+	ordered := query
+	for _, sort := range page.Sort {
+		ordered = ordered.Order(clause.OrderByColumn{Column: clause.Column{Name: sort.Column}, Desc: sort.Desc})
+	}
+
+	var data []T
+	result := ordered.Order("id").Limit(page.Size).Offset(offset).Find(&data)
+	if result.Error != nil {
+		return pagination.Page[T]{}, internal.ErServiceUnavailable
+	}
+
+	return pagination.NewPage(data, total, page), nil
+}
+
+// keyset returns the condition of the rows sorted after the item, by the sort columns and by id last like the
+// pages are: (a > ?) OR (a = ? AND b > ?) OR ..., with < for the descending columns. The sort columns can't be
+// null, null isn't greater, lower or equal to anything
+func keyset(query *gorm.DB, sorts []pagination.Sort, after interface{}) (clause.Expression, error) {
+	statement := &gorm.Statement{DB: query}
+	err := statement.Parse(after)
+	if err != nil {
+		return nil, err
+	}
+	item := reflect.ValueOf(after)
+
+	var next []clause.Expression
+	var equal []clause.Expression
+	for _, sort := range append(slices.Clone(sorts), pagination.Sort{Column: "id"}) {
+		field := statement.Schema.LookUpField(sort.Column)
+		if field == nil {
+			return nil, fmt.Errorf("sort column %s isn't a field of %s", sort.Column, statement.Schema.Name)
+		}
+		value, _ := field.ValueOf(context.Background(), item)
+
+		column := clause.Column{Name: sort.Column}
+		var after clause.Expression = clause.Gt{Column: column, Value: value}
+		if sort.Desc {
+			after = clause.Lt{Column: column, Value: value}
+		}
+		next = append(next, clause.And(append(slices.Clone(equal), after)...))
+		equal = append(equal, clause.Eq{Column: column, Value: value})
+	}
+	return clause.Or(next...), nil
+}
@@ -2,7 +2,13 @@ package database
 
 import (
 	"errors"
+	"time"
+
 	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/appointment"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/audit"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/event"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/pagination"
 	model "github.com/10Daniel10/web-server-go-ExamenFinal/internal/patient"
 	"gorm.io/gorm"
 )
@@ -23,13 +29,34 @@ func (dr *PatientRepository) Create(patient model.Patient) (model.Patient, error
 	return patient, nil
 }
 
-func (dr *PatientRepository) GetAll() ([]model.Patient, error) {
-	var data []model.Patient
-	query := dr.db.Find(&data)
-	if query.Error != nil {
-		return nil, internal.ErServiceUnavailable
+func (dr *PatientRepository) GetAll(filter model.Filter, page pagination.Request) (pagination.Page[model.Patient], error) {
+	query := dr.db.Model(&model.Patient{})
+	if filter.IncludeDeleted {
+		query = query.Unscoped()
 	}
-	return data, nil
+	if filter.Name != "" {
+		query = query.Where("name "+like(query)+" ?", filter.Name+"%")
+	}
+	if filter.Lastname != "" {
+		query = query.Where("lastname "+like(query)+" ?", filter.Lastname+"%")
+	}
+	if filter.DNI != "" {
+		query = query.Where("dni = ?", filter.DNI)
+	}
+	if filter.Email != "" {
+		query = query.Where("email = ?", filter.Email)
+	}
+	if !filter.AdmittedAfter.IsZero() {
+		query = query.Where("admission_date >= ?", filter.AdmittedAfter)
+	}
+	if !filter.AdmittedBefore.IsZero() {
+		query = query.Where("admission_date < ?", filter.AdmittedBefore)
+	}
+	if filter.DentistID != 0 {
+		query = query.Where("id IN (?)", dr.db.Table("appointments").Select("patient_id").Where("dentist_id = ?", filter.DentistID))
+	}
+
+	return findPage[model.Patient](query, page)
 }
 
 func (dr *PatientRepository) GetByID(id uint) (model.Patient, error) {
@@ -60,6 +87,15 @@ func (dr *PatientRepository) GetByDNI(dni string) (model.Patient, error) {
 	return data, nil
 }
 
+func (dr *PatientRepository) HasDentist(patientID uint, dentistID uint) (bool, error) {
+	var count int64
+	query := dr.db.Table("appointments").Where("patient_id = ? AND dentist_id = ?", patientID, dentistID).Count(&count)
+	if query.Error != nil {
+		return false, internal.ErServiceUnavailable
+	}
+	return count > 0, nil
+}
+
 func (dr *PatientRepository) Update(patient model.Patient) (model.Patient, error) {
 	query := dr.db.Save(&patient)
 	if query.Error != nil {
@@ -76,3 +112,52 @@ func (dr *PatientRepository) Delete(id uint) error {
 	}
 	return nil
 }
+
+func (dr *PatientRepository) Restore(id uint) (model.Patient, error) {
+	// The row is looked up first, a restore of a row that isn't deleted changes nothing and MySQL reports no
+	// affected rows for it
+	var data model.Patient
+	query := dr.db.Unscoped().First(&data, id)
+	if query.Error != nil {
+		if errors.Is(query.Error, gorm.ErrRecordNotFound) {
+			return model.Patient{}, internal.ErNotFound
+		}
+		return model.Patient{}, internal.ErServiceUnavailable
+	}
+
+	query = dr.db.Unscoped().Model(&data).Update("deleted_at", nil)
+	if query.Error != nil {
+		return model.Patient{}, internal.ErServiceUnavailable
+	}
+	return dr.GetByID(id)
+}
+
+func (dr *PatientRepository) Unscoped() model.Repository {
+	return &PatientRepository{db: dr.db.Unscoped().Session(&gorm.Session{})}
+}
+
+func (dr *PatientRepository) Lock(id uint) error {
+	return lockRows(dr.db, "patients", id)
+}
+
+func (dr *PatientRepository) GetUpcoming(patientID uint, from time.Time) ([]appointment.Appointment, error) {
+	return getUpcoming(dr.db, "patient_id", patientID, from)
+}
+
+func (dr *PatientRepository) CancelAppointments(ids []uint, reason string, at time.Time) error {
+	return cancelAppointments(dr.db, ids, reason, at)
+}
+
+func (dr *PatientRepository) Record(entry audit.Entry) error {
+	return record(dr.db, entry)
+}
+
+func (dr *PatientRepository) Publish(event event.Event) error {
+	return publish(dr.db, event)
+}
+
+func (dr *PatientRepository) Transaction(fn func(repository model.Repository) error) error {
+	return dr.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&PatientRepository{db: tx})
+	})
+}
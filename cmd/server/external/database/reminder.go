@@ -0,0 +1,79 @@
+package database
+
+import (
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/appointment"
+	model "github.com/10Daniel10/web-server-go-ExamenFinal/internal/reminder"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ReminderRepository struct {
+	db *gorm.DB
+}
+
+func NewReminderRepository(db *gorm.DB) *ReminderRepository {
+	return &ReminderRepository{db: db}
+}
+
+func (r *ReminderRepository) GetUpcoming(from time.Time, to time.Time) ([]model.Upcoming, error) {
+	var data []model.Upcoming
+	query := r.db.Table("appointments").
+		Select("appointments.id AS appointment_id, appointments.date, appointments.duration, appointments.description, "+
+			"patients.name AS patient_name, patients.lastname AS patient_lastname, patients.email AS patient_email, "+
+			"dentists.name AS dentist_name, dentists.lastname AS dentist_lastname").
+		Joins("JOIN patients ON patients.id = appointments.patient_id AND patients.deleted_at IS NULL").
+		Joins("JOIN dentists ON dentists.id = appointments.dentist_id AND dentists.deleted_at IS NULL").
+		Where("appointments.status IN ?", []appointment.Status{appointment.StatusScheduled, appointment.StatusConfirmed}).
+		Where("appointments.date > ? AND appointments.date <= ?", from, to).
+		Order("appointments.date").
+		Scan(&data)
+	if query.Error != nil {
+		return nil, internal.ErServiceUnavailable
+	}
+	return data, nil
+}
+
+func (r *ReminderRepository) GetByAppointments(ids []uint) ([]model.Reminder, error) {
+	var data []model.Reminder
+	query := r.db.Where("appointment_id IN ?", ids).Order("id").Find(&data)
+	if query.Error != nil {
+		return nil, internal.ErServiceUnavailable
+	}
+	return data, nil
+}
+
+func (r *ReminderRepository) Claim(reminder model.Reminder) (model.Reminder, bool, error) {
+	// The unique index turns a reminder claimed by another instance into a no-op
+	query := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&reminder)
+	if query.Error != nil {
+		return model.Reminder{}, false, internal.ErServiceUnavailable
+	}
+	return reminder, query.RowsAffected > 0, nil
+}
+
+func (r *ReminderRepository) Retry(id uint, staleBefore time.Time, now time.Time) (bool, error) {
+	// A sending reminder without claimed_at is stale too
+	query := r.db.Model(&model.Reminder{}).
+		Where("id = ? AND (status = ? OR (status = ? AND (claimed_at IS NULL OR claimed_at <= ?)))",
+			id, model.StatusFailed, model.StatusSending, staleBefore).
+		Updates(map[string]interface{}{
+			"status":     model.StatusSending,
+			"attempts":   gorm.Expr("attempts + 1"),
+			"claimed_at": now,
+		})
+	if query.Error != nil {
+		return false, internal.ErServiceUnavailable
+	}
+	return query.RowsAffected > 0, nil
+}
+
+func (r *ReminderRepository) Update(reminder model.Reminder) (model.Reminder, error) {
+	query := r.db.Save(&reminder)
+	if query.Error != nil {
+		return model.Reminder{}, internal.ErServiceUnavailable
+	}
+	return reminder, nil
+}
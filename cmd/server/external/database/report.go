@@ -0,0 +1,126 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/appointment"
+	model "github.com/10Daniel10/web-server-go-ExamenFinal/internal/report"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/schedule"
+	"gorm.io/gorm"
+)
+
+type ReportRepository struct {
+	db *gorm.DB
+}
+
+func NewReportRepository(db *gorm.DB) *ReportRepository {
+	return &ReportRepository{db: db}
+}
+
+func (r *ReportRepository) GetBooked(filter model.Filter) ([]model.Booked, error) {
+	var data []model.Booked
+	query := r.db.Table("dentists").
+		Select("dentists.id AS dentist_id, dentists.name, dentists.lastname, "+
+			"COALESCE(SUM("+r.overlap("appointments.date", "appointments.end_date")+"), 0) AS minutes",
+			map[string]interface{}{"from": filter.From, "to": filter.To}).
+		Joins("LEFT JOIN appointments ON appointments.dentist_id = dentists.id AND appointments.status <> ? "+
+			"AND appointments.date < ? AND appointments.end_date > ?", appointment.StatusCancelled, filter.To, filter.From).
+		Group("dentists.id, dentists.name, dentists.lastname").
+		// Deleted dentists are only kept while they have appointments in the range
+		Having("MAX(dentists.deleted_at) IS NULL OR COUNT(appointments.id) > 0").
+		Order("dentists.id")
+	if filter.DentistID != 0 {
+		query = query.Where("dentists.id = ?", filter.DentistID)
+	}
+
+	query = query.Scan(&data)
+	if query.Error != nil {
+		return nil, internal.ErServiceUnavailable
+	}
+	return data, nil
+}
+
+func (r *ReportRepository) GetSchedules(dentistIDs []uint) ([]schedule.WorkingHours, error) {
+	var data []schedule.WorkingHours
+	query := r.db.Where("dentist_id IN ?", dentistIDs).Find(&data)
+	if query.Error != nil {
+		return nil, internal.ErServiceUnavailable
+	}
+	return data, nil
+}
+
+func (r *ReportRepository) GetAppointmentSlots(filter model.Filter) ([]model.SlotCount, error) {
+	var data []model.SlotCount
+	query := r.db.Table("appointments").
+		Select(r.slot("date")+" AS slot, status, COUNT(*) AS count").
+		Where("date >= ? AND date < ?", filter.From, filter.To).
+		Group("slot, status")
+	if filter.DentistID != 0 {
+		query = query.Where("dentist_id = ?", filter.DentistID)
+	}
+
+	query = query.Scan(&data)
+	if query.Error != nil {
+		return nil, internal.ErServiceUnavailable
+	}
+	return data, nil
+}
+
+func (r *ReportRepository) GetDentistCounts(filter model.Filter) ([]model.DentistCount, error) {
+	var data []model.DentistCount
+	query := r.db.Table("appointments").
+		Select("appointments.dentist_id, dentists.name, dentists.lastname, appointments.status, COUNT(*) AS count").
+		Joins("LEFT JOIN dentists ON dentists.id = appointments.dentist_id").
+		Where("appointments.date >= ? AND appointments.date < ?", filter.From, filter.To).
+		Group("appointments.dentist_id, dentists.name, dentists.lastname, appointments.status")
+	if filter.DentistID != 0 {
+		query = query.Where("appointments.dentist_id = ?", filter.DentistID)
+	}
+
+	query = query.Scan(&data)
+	if query.Error != nil {
+		return nil, internal.ErServiceUnavailable
+	}
+	return data, nil
+}
+
+func (r *ReportRepository) GetAdmissionSlots(filter model.Filter) ([]model.SlotCount, error) {
+	var data []model.SlotCount
+	query := r.db.Table("patients").
+		Select(r.slot("admission_date")+" AS slot, COUNT(*) AS count").
+		Where("admission_date >= ? AND admission_date < ?", filter.From, filter.To).
+		Group("slot").
+		Scan(&data)
+	if query.Error != nil {
+		return nil, internal.ErServiceUnavailable
+	}
+	return data, nil
+}
+
+// overlap is the SQL of the whole minutes a range of date columns overlaps with the range from @from to @to
+func (r *ReportRepository) overlap(start string, end string) string {
+	switch r.db.Dialector.Name() {
+	case DriverPostgres:
+		return fmt.Sprintf("CAST(FLOOR(EXTRACT(EPOCH FROM LEAST(%s, @to) - GREATEST(%s, @from)) / 60) AS BIGINT)", end, start)
+	case DriverSQLite:
+		return fmt.Sprintf("(CAST(strftime('%%s', MIN(%s, @to)) AS INTEGER) - CAST(strftime('%%s', MAX(%s, @from)) AS INTEGER)) / 60", end, start)
+	default:
+		return fmt.Sprintf("TIMESTAMPDIFF(MINUTE, GREATEST(%s, @from), LEAST(%s, @to))", start, end)
+	}
+}
+
+// slot numbers the report slot a date column falls in, the dates are stored in UTC so the slots are counted
+// from the Unix epoch without the time zone of the session
+func (r *ReportRepository) slot(column string) string {
+	length := int64(model.SlotLength / time.Second)
+	switch r.db.Dialector.Name() {
+	case DriverPostgres:
+		return fmt.Sprintf("CAST(FLOOR(EXTRACT(EPOCH FROM %s) / %d) AS BIGINT)", column, length)
+	case DriverSQLite:
+		return fmt.Sprintf("CAST(strftime('%%s', %s) AS INTEGER) / %d", column, length)
+	default:
+		return fmt.Sprintf("TIMESTAMPDIFF(SECOND, '1970-01-01', %s) DIV %d", column, length)
+	}
+}
@@ -0,0 +1,76 @@
+package database
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// sqliteDriver is the name of the SQLite driver that writes dates in UTC
+const sqliteDriver = "sqlite3_utc"
+
+// sqliteMemory is the name of the database that is only kept in memory
+const sqliteMemory = ":memory:"
+
+// sqliteMemories numbers the in-memory databases, each Connect gets its own
+var sqliteMemories atomic.Int64
+
+func init() {
+	sql.Register(sqliteDriver, &utcDriver{})
+}
+
+// sqliteDSN returns the DSN of the database file, dates are read in UTC and writers wait for each other instead
+// of failing. The connections of an in-memory database share its cache, so they all see the same database
+func sqliteDSN(database string) string {
+	params := url.Values{
+		"_loc":          {"UTC"},
+		"_busy_timeout": {"5000"},
+		"_txlock":       {"immediate"},
+	}
+
+	if database == sqliteMemory {
+		params.Set("mode", "memory")
+		params.Set("cache", "shared")
+		return fmt.Sprintf("file:memory%d?%s", sqliteMemories.Add(1), params.Encode())
+	}
+
+	params.Set("_journal_mode", "WAL")
+	return fmt.Sprintf("file:%s?%s", database, params.Encode())
+}
+
+// utcDriver stores dates as text in their own zone, so they are converted to UTC before they are written and
+// compare like the dates already stored
+type utcDriver struct {
+	sqlite3.SQLiteDriver
+}
+
+func (d *utcDriver) Open(dsn string) (driver.Conn, error) {
+	conn, err := d.SQLiteDriver.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &utcConn{SQLiteConn: conn.(*sqlite3.SQLiteConn)}, nil
+}
+
+type utcConn struct {
+	*sqlite3.SQLiteConn
+}
+
+// CheckNamedValue converts the args like database/sql does, dates included those of pointers and valuers
+func (c *utcConn) CheckNamedValue(value *driver.NamedValue) error {
+	converted, err := driver.DefaultParameterConverter.ConvertValue(value.Value)
+	if err != nil {
+		return err
+	}
+
+	if date, ok := converted.(time.Time); ok {
+		converted = date.UTC()
+	}
+	value.Value = converted
+	return nil
+}
@@ -0,0 +1,93 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	model "github.com/10Daniel10/web-server-go-ExamenFinal/internal/user"
+	"gorm.io/gorm"
+)
+
+type UserRepository struct {
+	db *gorm.DB
+}
+
+func NewUserRepository(db *gorm.DB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+func (u *UserRepository) Create(user model.User) (model.User, error) {
+	query := u.db.Create(&user)
+	if query.Error != nil {
+		return model.User{}, query.Error
+	}
+	return user, nil
+}
+
+func (u *UserRepository) GetAll() ([]model.User, error) {
+	var data []model.User
+	query := u.db.Order("id").Find(&data)
+	if query.Error != nil {
+		return nil, internal.ErServiceUnavailable
+	}
+	return data, nil
+}
+
+func (u *UserRepository) GetByID(id uint) (model.User, error) {
+	var data model.User
+	query := u.db.First(&data, id)
+	if query.Error != nil {
+		switch {
+		case errors.Is(query.Error, gorm.ErrRecordNotFound):
+			return model.User{}, internal.ErNotFound
+		}
+		return model.User{}, internal.ErServiceUnavailable
+	}
+	return data, nil
+}
+
+func (u *UserRepository) GetByUsername(username string) (model.User, error) {
+	var data model.User
+	query := u.db.Where("username = ?", username).First(&data)
+	if query.Error != nil {
+		switch {
+		case errors.Is(query.Error, gorm.ErrRecordNotFound):
+			return model.User{}, internal.ErNotFound
+		}
+		return model.User{}, internal.ErServiceUnavailable
+	}
+	return data, nil
+}
+
+func (u *UserRepository) GetByFeedToken(hash string) (model.User, error) {
+	var data model.User
+	query := u.db.Where("feed_token_hash = ?", hash).First(&data)
+	if query.Error != nil {
+		switch {
+		case errors.Is(query.Error, gorm.ErrRecordNotFound):
+			return model.User{}, internal.ErNotFound
+		}
+		return model.User{}, internal.ErServiceUnavailable
+	}
+	return data, nil
+}
+
+func (u *UserRepository) SetFeedToken(id uint, hash string) error {
+	query := u.db.Model(&model.User{}).Where("id = ?", id).Update("feed_token_hash", hash)
+	if query.Error != nil {
+		return internal.ErServiceUnavailable
+	}
+	if query.RowsAffected == 0 {
+		return internal.ErNotFound
+	}
+	return nil
+}
+
+func (u *UserRepository) Count() (int64, error) {
+	var count int64
+	query := u.db.Model(&model.User{}).Count(&count)
+	if query.Error != nil {
+		return 0, internal.ErServiceUnavailable
+	}
+	return count, nil
+}
@@ -0,0 +1,162 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/appointment"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/pagination"
+	model "github.com/10Daniel10/web-server-go-ExamenFinal/internal/waitlist"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WaitlistRepository struct {
+	db *gorm.DB
+}
+
+func NewWaitlistRepository(db *gorm.DB) *WaitlistRepository {
+	return &WaitlistRepository{db: db}
+}
+
+func (w *WaitlistRepository) GetAll(filter model.Filter, page pagination.Request) (pagination.Page[model.Entry], error) {
+	query := w.db.Model(&model.Entry{})
+	if filter.PatientID != 0 {
+		query = query.Where("patient_id = ?", filter.PatientID)
+	}
+	if filter.DentistID != 0 {
+		query = query.Where("dentist_id = ?", filter.DentistID)
+	}
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
+	}
+
+	return findPage[model.Entry](query, page)
+}
+
+func (w *WaitlistRepository) GetByID(id uint) (model.Entry, error) {
+	var data model.Entry
+	query := w.db.Preload("Offers", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id DESC")
+	}).First(&data, id)
+	if query.Error != nil {
+		switch {
+		case errors.Is(query.Error, gorm.ErrRecordNotFound):
+			return model.Entry{}, internal.ErNotFound
+		}
+		return model.Entry{}, internal.ErServiceUnavailable
+	}
+	return data, nil
+}
+
+func (w *WaitlistRepository) Create(entry model.Entry) (model.Entry, error) {
+	query := w.db.Omit(clause.Associations).Create(&entry)
+	if query.Error != nil {
+		return model.Entry{}, internal.ErServiceUnavailable
+	}
+	return entry, nil
+}
+
+func (w *WaitlistRepository) Update(entry model.Entry) (model.Entry, error) {
+	// Offers change on their own, saving them along with the entry would write back stale copies
+	query := w.db.Omit(clause.Associations).Save(&entry)
+	if query.Error != nil {
+		return model.Entry{}, internal.ErServiceUnavailable
+	}
+	return entry, nil
+}
+
+func (w *WaitlistRepository) GetWaiting(dentistID uint) ([]model.Entry, error) {
+	var data []model.Entry
+	// Entries of deleted dentists or patients stay in the queue in case they are restored, but get no offers
+	query := w.db.Where("dentist_id = ? AND status = ?", dentistID, model.EntryWaiting).
+		Where("dentist_id IN (SELECT id FROM dentists WHERE deleted_at IS NULL)").
+		Where("patient_id IN (SELECT id FROM patients WHERE deleted_at IS NULL)").
+		Order("created_at").Order("id").
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Find(&data)
+	if query.Error != nil {
+		return nil, internal.ErServiceUnavailable
+	}
+	return data, nil
+}
+
+func (w *WaitlistRepository) GetOffer(id uint) (model.Offer, error) {
+	var data model.Offer
+	query := w.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&data, id)
+	if query.Error != nil {
+		switch {
+		case errors.Is(query.Error, gorm.ErrRecordNotFound):
+			return model.Offer{}, internal.ErNotFound
+		}
+		return model.Offer{}, internal.ErServiceUnavailable
+	}
+	return data, nil
+}
+
+func (w *WaitlistRepository) CreateOffer(offer model.Offer) (model.Offer, error) {
+	query := w.db.Create(&offer)
+	if query.Error != nil {
+		return model.Offer{}, internal.ErServiceUnavailable
+	}
+	return offer, nil
+}
+
+func (w *WaitlistRepository) UpdateOffer(offer model.Offer) (model.Offer, error) {
+	query := w.db.Save(&offer)
+	if query.Error != nil {
+		return model.Offer{}, internal.ErServiceUnavailable
+	}
+	return offer, nil
+}
+
+func (w *WaitlistRepository) GetPendingOffers(dentistID uint, start time.Time, end time.Time) ([]model.Offer, error) {
+	var data []model.Offer
+	query := w.db.Where("dentist_id = ? AND status = ?", dentistID, model.OfferPending).
+		Where("start_date < ? AND end_date > ?", end, start).
+		Find(&data)
+	if query.Error != nil {
+		return nil, internal.ErServiceUnavailable
+	}
+	return data, nil
+}
+
+func (w *WaitlistRepository) GetOfferedEntries(dentistID uint, start time.Time) ([]uint, error) {
+	var ids []uint
+	query := w.db.Model(&model.Offer{}).Where("dentist_id = ? AND start_date = ?", dentistID, start).
+		Distinct().Pluck("entry_id", &ids)
+	if query.Error != nil {
+		return nil, internal.ErServiceUnavailable
+	}
+	return ids, nil
+}
+
+func (w *WaitlistRepository) GetExpiredOffers(now time.Time) ([]model.Offer, error) {
+	var data []model.Offer
+	query := w.db.Where("status = ? AND expires_at <= ?", model.OfferPending, now).Order("id").Find(&data)
+	if query.Error != nil {
+		return nil, internal.ErServiceUnavailable
+	}
+	return data, nil
+}
+
+func (w *WaitlistRepository) ExpireEntries(now time.Time) error {
+	query := w.db.Model(&model.Entry{}).
+		Where("status = ? AND to_date <= ?", model.EntryWaiting, now).
+		Update("status", model.EntryExpired)
+	if query.Error != nil {
+		return internal.ErServiceUnavailable
+	}
+	return nil
+}
+
+func (w *WaitlistRepository) Appointments() appointment.Repository {
+	return NewOtherAppointmentRepository(w.db)
+}
+
+func (w *WaitlistRepository) Transaction(fn func(repository model.Repository) error) error {
+	return w.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&WaitlistRepository{db: tx})
+	})
+}
@@ -0,0 +1,186 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/event"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/pagination"
+	model "github.com/10Daniel10/web-server-go-ExamenFinal/internal/webhook"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// skipLocked locks the selected rows until the transaction ends, leaving out the rows other transactions locked
+// so several instances can work on the outbox at once
+var skipLocked = clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}
+
+type WebhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+func (w *WebhookRepository) GetAll() ([]model.Subscription, error) {
+	var data []model.Subscription
+	query := w.db.Order("id").Find(&data)
+	if query.Error != nil {
+		return nil, internal.ErServiceUnavailable
+	}
+	return data, nil
+}
+
+func (w *WebhookRepository) GetByID(id uint) (model.Subscription, error) {
+	var data model.Subscription
+	query := w.db.First(&data, id)
+	if query.Error != nil {
+		switch {
+		case errors.Is(query.Error, gorm.ErrRecordNotFound):
+			return model.Subscription{}, internal.ErNotFound
+		}
+		return model.Subscription{}, internal.ErServiceUnavailable
+	}
+	return data, nil
+}
+
+func (w *WebhookRepository) Create(subscription model.Subscription) (model.Subscription, error) {
+	query := w.db.Create(&subscription)
+	if query.Error != nil {
+		return model.Subscription{}, internal.ErServiceUnavailable
+	}
+	return subscription, nil
+}
+
+func (w *WebhookRepository) Update(subscription model.Subscription) (model.Subscription, error) {
+	query := w.db.Save(&subscription)
+	if query.Error != nil {
+		return model.Subscription{}, internal.ErServiceUnavailable
+	}
+	return subscription, nil
+}
+
+func (w *WebhookRepository) Delete(id uint) error {
+	return w.db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&model.Delivery{}).
+			Where("subscription_id = ? AND status = ?", id, model.DeliveryPending).
+			Updates(map[string]interface{}{"status": model.DeliveryDead, "last_error": "subscription deleted"})
+		if query.Error != nil {
+			return internal.ErServiceUnavailable
+		}
+
+		query = tx.Delete(&model.Subscription{}, id)
+		if query.Error != nil {
+			return internal.ErServiceUnavailable
+		}
+		return nil
+	})
+}
+
+func (w *WebhookRepository) GetDeliveries(filter model.DeliveryFilter, page pagination.Request) (pagination.Page[model.Delivery], error) {
+	query := w.db.Model(&model.Delivery{})
+	if filter.SubscriptionID != 0 {
+		query = query.Where("subscription_id = ?", filter.SubscriptionID)
+	}
+	if filter.EventID != 0 {
+		query = query.Where("event_id = ?", filter.EventID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	return findPage[model.Delivery](query, page)
+}
+
+func (w *WebhookRepository) GetDelivery(id uint) (model.Delivery, error) {
+	var data model.Delivery
+	query := w.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&data, id)
+	if query.Error != nil {
+		switch {
+		case errors.Is(query.Error, gorm.ErrRecordNotFound):
+			return model.Delivery{}, internal.ErNotFound
+		}
+		return model.Delivery{}, internal.ErServiceUnavailable
+	}
+	return data, nil
+}
+
+func (w *WebhookRepository) UpdateDelivery(delivery model.Delivery) (model.Delivery, error) {
+	query := w.db.Save(&delivery)
+	if query.Error != nil {
+		return model.Delivery{}, internal.ErServiceUnavailable
+	}
+	return delivery, nil
+}
+
+func (w *WebhookRepository) GetUndispatched(limit int) ([]event.Event, error) {
+	var data []event.Event
+	query := w.db.Where("dispatched_at IS NULL").Order("id").Limit(limit).Clauses(skipLocked).Find(&data)
+	if query.Error != nil {
+		return nil, internal.ErServiceUnavailable
+	}
+	return data, nil
+}
+
+func (w *WebhookRepository) Dispatch(deliveries []model.Delivery, eventIDs []uint, now time.Time) error {
+	if len(deliveries) > 0 {
+		// An event is dispatched once, the unique index keeps a retried dispatch from duplicating deliveries
+		query := w.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&deliveries)
+		if query.Error != nil {
+			return internal.ErServiceUnavailable
+		}
+	}
+
+	query := w.db.Model(&event.Event{}).Where("id IN ?", eventIDs).Update("dispatched_at", now)
+	if query.Error != nil {
+		return internal.ErServiceUnavailable
+	}
+	return nil
+}
+
+func (w *WebhookRepository) GetDue(now time.Time, limit int) ([]model.Delivery, error) {
+	var data []model.Delivery
+	query := w.db.Where("status = ? AND next_attempt_at <= ?", model.DeliveryPending, now).
+		Order("next_attempt_at").Order("id").Limit(limit).
+		Clauses(skipLocked).
+		Find(&data)
+	if query.Error != nil {
+		return nil, internal.ErServiceUnavailable
+	}
+	return data, nil
+}
+
+func (w *WebhookRepository) Postpone(ids []uint, until time.Time) error {
+	query := w.db.Model(&model.Delivery{}).Where("id IN ?", ids).Update("next_attempt_at", until)
+	if query.Error != nil {
+		return internal.ErServiceUnavailable
+	}
+	return nil
+}
+
+func (w *WebhookRepository) GetEvents(ids []uint) ([]event.Event, error) {
+	var data []event.Event
+	query := w.db.Where("id IN ?", ids).Find(&data)
+	if query.Error != nil {
+		return nil, internal.ErServiceUnavailable
+	}
+	return data, nil
+}
+
+func (w *WebhookRepository) Transaction(fn func(repository model.Repository) error) error {
+	return w.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&WebhookRepository{db: tx})
+	})
+}
+
+// publish stores an outbox event with the given connection, repositories call it with their transaction
+// so the event is only kept when the change it describes is committed
+func publish(db *gorm.DB, data event.Event) error {
+	query := db.Create(&data)
+	if query.Error != nil {
+		return internal.ErServiceUnavailable
+	}
+	return nil
+}
@@ -0,0 +1,253 @@
+package memory
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	model "github.com/10Daniel10/web-server-go-ExamenFinal/internal/appointment"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/audit"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/event"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/pagination"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/schedule"
+)
+
+var appointmentColumns = columns[model.Appointment]{
+	"id":         func(a, b model.Appointment) int { return cmp.Compare(a.ID, b.ID) },
+	"date":       func(a, b model.Appointment) int { return a.Date.Compare(b.Date) },
+	"duration":   func(a, b model.Appointment) int { return cmp.Compare(a.Duration, b.Duration) },
+	"patient_id": func(a, b model.Appointment) int { return cmp.Compare(a.PatientID, b.PatientID) },
+	"dentist_id": func(a, b model.Appointment) int { return cmp.Compare(a.DentistID, b.DentistID) },
+	"status":     func(a, b model.Appointment) int { return strings.Compare(string(a.Status), string(b.Status)) },
+}
+
+type AppointmentRepository struct {
+	store *Store
+	tx    *tables
+}
+
+func NewAppointmentRepository(store *Store) *AppointmentRepository {
+	return &AppointmentRepository{store: store}
+}
+
+func (a *AppointmentRepository) GetAll(filter model.Filter, page pagination.Request) (pagination.Page[model.Appointment], error) {
+	var data pagination.Page[model.Appointment]
+	err := a.store.run(a.tx, func(t *tables) error {
+		// Deleted patients and dentists are still found by their dni and license, like in the database
+		patientIDs := map[uint]bool{}
+		for _, current := range t.patients {
+			patientIDs[current.ID] = current.DNI == filter.PatientDNI
+		}
+		dentistIDs := map[uint]bool{}
+		for _, current := range t.dentists {
+			dentistIDs[current.ID] = current.License == filter.DentistLicense
+		}
+
+		data = findPage(t.appointments, func(appointment model.Appointment) bool {
+			return (filter.PatientID == 0 || appointment.PatientID == filter.PatientID) &&
+				(filter.DentistID == 0 || appointment.DentistID == filter.DentistID) &&
+				(filter.PatientDNI == "" || patientIDs[appointment.PatientID]) &&
+				(filter.DentistLicense == "" || dentistIDs[appointment.DentistID]) &&
+				(filter.From.IsZero() || appointment.EndDate.After(filter.From)) &&
+				(filter.To.IsZero() || appointment.Date.Before(filter.To)) &&
+				(len(filter.Statuses) == 0 || slices.Contains(filter.Statuses, appointment.Status))
+		}, appointmentColumns, page)
+		return nil
+	})
+	return data, err
+}
+
+func (a *AppointmentRepository) GetByID(id uint) (model.Appointment, error) {
+	var data model.Appointment
+	err := a.store.run(a.tx, func(t *tables) error {
+		var ok bool
+		data, ok = t.appointments[id]
+		if !ok {
+			return internal.ErNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return model.Appointment{}, err
+	}
+	return data, nil
+}
+
+func (a *AppointmentRepository) GetOverlapping(appointment model.Appointment) ([]model.Appointment, error) {
+	var data []model.Appointment
+	err := a.store.run(a.tx, func(t *tables) error {
+		for _, current := range t.appointments {
+			if (current.DentistID == appointment.DentistID || current.PatientID == appointment.PatientID) &&
+				current.Date.Before(appointment.EndDate) && current.EndDate.After(appointment.Date) &&
+				current.ID != appointment.ID && current.Status != model.StatusCancelled {
+				data = append(data, current)
+			}
+		}
+		return nil
+	})
+	return byDate(data), err
+}
+
+func (a *AppointmentRepository) GetSchedule(dentistID uint) ([]schedule.WorkingHours, error) {
+	var data []schedule.WorkingHours
+	err := a.store.run(a.tx, func(t *tables) error {
+		data = getSchedule(t, dentistID)
+		return nil
+	})
+	return data, err
+}
+
+func (a *AppointmentRepository) Create(appointment model.Appointment) (model.Appointment, error) {
+	err := a.store.run(a.tx, func(t *tables) error {
+		// The defaults of the columns
+		if appointment.Duration == 0 {
+			appointment.Duration = model.DefaultDuration
+		}
+		if appointment.Status == "" {
+			appointment.Status = model.StatusScheduled
+		}
+
+		appointment.ID = t.nextID("appointments", appointment.ID)
+		appointment.UpdatedAt = now()
+		t.appointments[appointment.ID] = storedAppointment(appointment)
+		return nil
+	})
+	if err != nil {
+		return model.Appointment{}, err
+	}
+	return appointment, nil
+}
+
+// Update stores every field of the appointment and counts the change in its sequence
+func (a *AppointmentRepository) Update(appointment model.Appointment) (model.Appointment, error) {
+	appointment.Sequence++
+	err := a.store.run(a.tx, func(t *tables) error {
+		appointment.ID = t.nextID("appointments", appointment.ID)
+		appointment.UpdatedAt = now()
+		t.appointments[appointment.ID] = storedAppointment(appointment)
+		return nil
+	})
+	if err != nil {
+		return model.Appointment{}, err
+	}
+
+	return appointment, nil
+}
+
+func (a *AppointmentRepository) Delete(id uint) error {
+	return a.store.run(a.tx, func(t *tables) error {
+		delete(t.appointments, id)
+		return nil
+	})
+}
+
+func (a *AppointmentRepository) LockSchedule(dentistID uint, patientID uint) error {
+	// Transactions already run one at a time, it only checks the dentist and the patient aren't deleted
+	return a.store.run(a.tx, func(t *tables) error {
+		dentist, ok := t.dentists[dentistID]
+		if !ok || dentist.DeletedAt.Valid {
+			return internal.ErNotFound
+		}
+
+		patient, ok := t.patients[patientID]
+		if !ok || patient.DeletedAt.Valid {
+			return internal.ErNotFound
+		}
+		return nil
+	})
+}
+
+func (a *AppointmentRepository) Transaction(fn func(repository model.Repository) error) error {
+	return a.store.transaction(a.tx, func(t *tables) error {
+		return fn(&AppointmentRepository{store: a.store, tx: t})
+	})
+}
+
+func (a *AppointmentRepository) CreateSeries(series model.Series) (model.Series, error) {
+	err := a.store.run(a.tx, func(t *tables) error {
+		series.ID = t.nextID("appointment_series", series.ID)
+		if series.CreatedAt.IsZero() {
+			series.CreatedAt = now()
+		}
+
+		data := series
+		data.Appointments = nil
+		data.Start = stored(data.Start)
+		data.Until = storedPtr(data.Until)
+		data.CreatedAt = stored(data.CreatedAt)
+		t.series[series.ID] = data
+		return nil
+	})
+	if err != nil {
+		return model.Series{}, err
+	}
+	return series, nil
+}
+
+func (a *AppointmentRepository) GetSeries(id uint) (model.Series, error) {
+	var data model.Series
+	err := a.store.run(a.tx, func(t *tables) error {
+		var ok bool
+		data, ok = t.series[id]
+		if !ok {
+			return internal.ErNotFound
+		}
+
+		data.Appointments = getSeriesAppointments(t, id, time.Time{})
+		return nil
+	})
+	if err != nil {
+		return model.Series{}, err
+	}
+	return data, nil
+}
+
+func (a *AppointmentRepository) GetSeriesAppointments(seriesID uint, from time.Time) ([]model.Appointment, error) {
+	var data []model.Appointment
+	err := a.store.run(a.tx, func(t *tables) error {
+		data = getSeriesAppointments(t, seriesID, from)
+		return nil
+	})
+	return data, err
+}
+
+func (a *AppointmentRepository) Record(entry audit.Entry) error {
+	return a.store.run(a.tx, func(t *tables) error {
+		return record(t, entry)
+	})
+}
+
+func (a *AppointmentRepository) Publish(event event.Event) error {
+	return a.store.run(a.tx, func(t *tables) error {
+		return publish(t, event)
+	})
+}
+
+// getSeriesAppointments returns the appointments of a series ordered by date, from the given time unless it is zero
+func getSeriesAppointments(t *tables, seriesID uint, from time.Time) []model.Appointment {
+	data := []model.Appointment{}
+	for _, current := range t.appointments {
+		if current.SeriesID != nil && *current.SeriesID == seriesID && (from.IsZero() || !current.Date.Before(from)) {
+			data = append(data, current)
+		}
+	}
+	return byDate(data)
+}
+
+// storedAppointment keeps the dates of the appointment the way the database stores them
+func storedAppointment(appointment model.Appointment) model.Appointment {
+	appointment.Date = stored(appointment.Date)
+	appointment.EndDate = stored(appointment.EndDate)
+	appointment.ConfirmedAt = storedPtr(appointment.ConfirmedAt)
+	appointment.CheckedInAt = storedPtr(appointment.CheckedInAt)
+	appointment.CompletedAt = storedPtr(appointment.CompletedAt)
+	appointment.CancelledAt = storedPtr(appointment.CancelledAt)
+	appointment.NoShowAt = storedPtr(appointment.NoShowAt)
+	if appointment.SeriesID != nil {
+		seriesID := *appointment.SeriesID
+		appointment.SeriesID = &seriesID
+	}
+	return appointment
+}
@@ -0,0 +1,299 @@
+package memory
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/appointment"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/audit"
+	model "github.com/10Daniel10/web-server-go-ExamenFinal/internal/dentist"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/event"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/pagination"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/schedule"
+	"gorm.io/gorm"
+)
+
+var dentistColumns = columns[model.Dentist]{
+	"id":       func(a, b model.Dentist) int { return cmp.Compare(a.ID, b.ID) },
+	"name":     func(a, b model.Dentist) int { return strings.Compare(a.Name, b.Name) },
+	"lastname": func(a, b model.Dentist) int { return strings.Compare(a.Lastname, b.Lastname) },
+	"license":  func(a, b model.Dentist) int { return strings.Compare(a.License, b.License) },
+}
+
+type DentistRepository struct {
+	store *Store
+	tx    *tables
+	// unscoped also finds the deleted dentists
+	unscoped bool
+}
+
+func NewDentistRepository(store *Store) *DentistRepository {
+	return &DentistRepository{store: store}
+}
+
+func (d *DentistRepository) Create(dentist model.Dentist) (model.Dentist, error) {
+	err := d.store.run(d.tx, func(t *tables) error {
+		for _, current := range t.dentists {
+			if current.License == dentist.License {
+				return internal.ErLicenseAlreadyExists
+			}
+		}
+
+		dentist.ID = t.nextID("dentists", dentist.ID)
+		t.dentists[dentist.ID] = stripDentist(dentist)
+		return nil
+	})
+	if err != nil {
+		return model.Dentist{}, err
+	}
+	return dentist, nil
+}
+
+func (d *DentistRepository) GetAll(filter model.Filter, page pagination.Request) (pagination.Page[model.Dentist], error) {
+	var data pagination.Page[model.Dentist]
+	err := d.store.run(d.tx, func(t *tables) error {
+		data = findPage(t.dentists, func(dentist model.Dentist) bool {
+			return (d.unscoped || filter.IncludeDeleted || !dentist.DeletedAt.Valid) &&
+				hasPrefix(dentist.Name, filter.Name) &&
+				hasPrefix(dentist.Lastname, filter.Lastname) &&
+				(filter.License == "" || dentist.License == filter.License)
+		}, dentistColumns, page)
+		return nil
+	})
+	return data, err
+}
+
+func (d *DentistRepository) GetByID(id uint) (model.Dentist, error) {
+	return d.find(func(dentist model.Dentist) bool {
+		return dentist.ID == id
+	})
+}
+
+func (d *DentistRepository) GetByLicense(license string) (model.Dentist, error) {
+	return d.find(func(dentist model.Dentist) bool {
+		return dentist.License == license
+	})
+}
+
+func (d *DentistRepository) Update(dentist model.Dentist) (model.Dentist, error) {
+	err := d.store.run(d.tx, func(t *tables) error {
+		for _, current := range t.dentists {
+			if current.License == dentist.License && current.ID != dentist.ID {
+				return internal.ErLicenseAlreadyExists
+			}
+		}
+
+		dentist.ID = t.nextID("dentists", dentist.ID)
+		t.dentists[dentist.ID] = stripDentist(dentist)
+		return nil
+	})
+	if err != nil {
+		return model.Dentist{}, err
+	}
+	return dentist, nil
+}
+
+func (d *DentistRepository) Delete(id uint) error {
+	// Working hours are kept so a restored dentist gets them back
+	return d.store.run(d.tx, func(t *tables) error {
+		dentist, ok := t.dentists[id]
+		switch {
+		case !ok:
+		case d.unscoped:
+			delete(t.dentists, id)
+		case !dentist.DeletedAt.Valid:
+			dentist.DeletedAt = gorm.DeletedAt{Time: now(), Valid: true}
+			t.dentists[id] = dentist
+		}
+		return nil
+	})
+}
+
+func (d *DentistRepository) Restore(id uint) (model.Dentist, error) {
+	err := d.store.run(d.tx, func(t *tables) error {
+		dentist, ok := t.dentists[id]
+		if !ok {
+			return internal.ErNotFound
+		}
+
+		dentist.DeletedAt = gorm.DeletedAt{}
+		t.dentists[id] = dentist
+		return nil
+	})
+	if err != nil {
+		return model.Dentist{}, err
+	}
+	return d.GetByID(id)
+}
+
+func (d *DentistRepository) Unscoped() model.Repository {
+	return &DentistRepository{store: d.store, tx: d.tx, unscoped: true}
+}
+
+func (d *DentistRepository) GetSchedule(dentistID uint) ([]schedule.WorkingHours, error) {
+	data := []schedule.WorkingHours{}
+	err := d.store.run(d.tx, func(t *tables) error {
+		data = append(data, getSchedule(t, dentistID)...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(data, func(a schedule.WorkingHours, b schedule.WorkingHours) int {
+		if result := cmp.Compare(a.Weekday, b.Weekday); result != 0 {
+			return result
+		}
+		return strings.Compare(a.Start, b.Start)
+	})
+	return data, nil
+}
+
+func (d *DentistRepository) UpdateSchedule(dentistID uint, hours []schedule.WorkingHours) ([]schedule.WorkingHours, error) {
+	err := d.store.transaction(d.tx, func(t *tables) error {
+		for id, current := range t.hours {
+			if current.DentistID == dentistID {
+				delete(t.hours, id)
+			}
+		}
+
+		for _, current := range hours {
+			current.ID = t.nextID("working_hours", current.ID)
+			t.hours[current.ID] = current
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, internal.ErServiceUnavailable
+	}
+
+	return d.GetSchedule(dentistID)
+}
+
+func (d *DentistRepository) GetAppointments(dentistID uint, from time.Time, to time.Time) ([]appointment.Appointment, error) {
+	var data []appointment.Appointment
+	err := d.store.run(d.tx, func(t *tables) error {
+		for _, current := range t.appointments {
+			if current.DentistID == dentistID && current.Date.Before(to) && current.EndDate.After(from) &&
+				current.Status != appointment.StatusCancelled {
+				data = append(data, current)
+			}
+		}
+		return nil
+	})
+	return byDate(data), err
+}
+
+func (d *DentistRepository) Lock(ids ...uint) error {
+	// Transactions already run one at a time, deleted dentists are locked too like in the database
+	return d.store.run(d.tx, func(t *tables) error {
+		for _, id := range ids {
+			if _, ok := t.dentists[id]; !ok {
+				return internal.ErNotFound
+			}
+		}
+		return nil
+	})
+}
+
+func (d *DentistRepository) GetUpcoming(dentistID uint, from time.Time) ([]appointment.Appointment, error) {
+	var data []appointment.Appointment
+	err := d.store.run(d.tx, func(t *tables) error {
+		data = getUpcoming(t, func(current appointment.Appointment) bool {
+			return current.DentistID == dentistID
+		}, from)
+		return nil
+	})
+	return data, err
+}
+
+func (d *DentistRepository) CancelAppointments(ids []uint, reason string, at time.Time) error {
+	return d.store.run(d.tx, func(t *tables) error {
+		return cancelAppointments(t, ids, reason, at)
+	})
+}
+
+func (d *DentistRepository) ReassignAppointments(ids []uint, dentistID uint) error {
+	return d.store.run(d.tx, func(t *tables) error {
+		for _, id := range ids {
+			current, ok := t.appointments[id]
+			if !ok {
+				continue
+			}
+
+			current.DentistID = dentistID
+			current.Sequence++
+			current.UpdatedAt = now()
+			t.appointments[id] = current
+		}
+		return nil
+	})
+}
+
+func (d *DentistRepository) Record(entry audit.Entry) error {
+	return d.store.run(d.tx, func(t *tables) error {
+		return record(t, entry)
+	})
+}
+
+func (d *DentistRepository) Publish(event event.Event) error {
+	return d.store.run(d.tx, func(t *tables) error {
+		return publish(t, event)
+	})
+}
+
+func (d *DentistRepository) Transaction(fn func(repository model.Repository) error) error {
+	return d.store.transaction(d.tx, func(t *tables) error {
+		return fn(&DentistRepository{store: d.store, tx: t, unscoped: d.unscoped})
+	})
+}
+
+// find returns the first dentist by id that matches
+func (d *DentistRepository) find(match func(dentist model.Dentist) bool) (model.Dentist, error) {
+	var data model.Dentist
+	err := d.store.run(d.tx, func(t *tables) error {
+		found := false
+		for _, current := range t.dentists {
+			if (d.unscoped || !current.DeletedAt.Valid) && match(current) && (!found || current.ID < data.ID) {
+				data, found = current, true
+			}
+		}
+		if !found {
+			return internal.ErNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return model.Dentist{}, err
+	}
+	return data, nil
+}
+
+// stripDentist leaves out the relations, they are stored in their own tables
+func stripDentist(dentist model.Dentist) model.Dentist {
+	dentist.Appointments = nil
+	dentist.WorkingHours = nil
+	if dentist.DeletedAt.Valid {
+		dentist.DeletedAt.Time = stored(dentist.DeletedAt.Time)
+	}
+	return dentist
+}
+
+// getSchedule returns the working hours of a dentist in no particular order
+func getSchedule(t *tables, dentistID uint) []schedule.WorkingHours {
+	var data []schedule.WorkingHours
+	for _, current := range t.hours {
+		if current.DentistID == dentistID {
+			data = append(data, current)
+		}
+	}
+	return data
+}
+
+// hasPrefix matches the filters on names, which ignore the case like LIKE does in the database
+func hasPrefix(value string, prefix string) bool {
+	return strings.HasPrefix(strings.ToLower(value), strings.ToLower(prefix))
+}
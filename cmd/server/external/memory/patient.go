@@ -0,0 +1,233 @@
+package memory
+
+import (
+	"cmp"
+	"strings"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/appointment"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/audit"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/event"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/pagination"
+	model "github.com/10Daniel10/web-server-go-ExamenFinal/internal/patient"
+	"gorm.io/gorm"
+)
+
+var patientColumns = columns[model.Patient]{
+	"id":             func(a, b model.Patient) int { return cmp.Compare(a.ID, b.ID) },
+	"name":           func(a, b model.Patient) int { return strings.Compare(a.Name, b.Name) },
+	"lastname":       func(a, b model.Patient) int { return strings.Compare(a.Lastname, b.Lastname) },
+	"dni":            func(a, b model.Patient) int { return strings.Compare(a.DNI, b.DNI) },
+	"email":          func(a, b model.Patient) int { return strings.Compare(a.Email, b.Email) },
+	"admission_date": func(a, b model.Patient) int { return a.AdmissionDate.Compare(b.AdmissionDate) },
+}
+
+type PatientRepository struct {
+	store *Store
+	tx    *tables
+	// unscoped also finds the deleted patients
+	unscoped bool
+}
+
+func NewPatientRepository(store *Store) *PatientRepository {
+	return &PatientRepository{store: store}
+}
+
+func (dr *PatientRepository) Create(patient model.Patient) (model.Patient, error) {
+	err := dr.store.run(dr.tx, func(t *tables) error {
+		for _, current := range t.patients {
+			if current.DNI == patient.DNI {
+				return internal.ErDniAlreadyExists
+			}
+		}
+
+		patient.ID = t.nextID("patients", patient.ID)
+		t.patients[patient.ID] = stripPatient(patient)
+		return nil
+	})
+	if err != nil {
+		return patient, err
+	}
+	return patient, nil
+}
+
+func (dr *PatientRepository) GetAll(filter model.Filter, page pagination.Request) (pagination.Page[model.Patient], error) {
+	var data pagination.Page[model.Patient]
+	err := dr.store.run(dr.tx, func(t *tables) error {
+		withDentist := map[uint]bool{}
+		if filter.DentistID != 0 {
+			for _, current := range t.appointments {
+				if current.DentistID == filter.DentistID {
+					withDentist[current.PatientID] = true
+				}
+			}
+		}
+
+		data = findPage(t.patients, func(patient model.Patient) bool {
+			return (dr.unscoped || filter.IncludeDeleted || !patient.DeletedAt.Valid) &&
+				hasPrefix(patient.Name, filter.Name) &&
+				hasPrefix(patient.Lastname, filter.Lastname) &&
+				(filter.DNI == "" || patient.DNI == filter.DNI) &&
+				(filter.Email == "" || patient.Email == filter.Email) &&
+				(filter.AdmittedAfter.IsZero() || !patient.AdmissionDate.Before(filter.AdmittedAfter)) &&
+				(filter.AdmittedBefore.IsZero() || patient.AdmissionDate.Before(filter.AdmittedBefore)) &&
+				(filter.DentistID == 0 || withDentist[patient.ID])
+		}, patientColumns, page)
+		return nil
+	})
+	return data, err
+}
+
+func (dr *PatientRepository) GetByID(id uint) (model.Patient, error) {
+	return dr.find(func(patient model.Patient) bool {
+		return patient.ID == id
+	})
+}
+
+func (dr *PatientRepository) GetByDNI(dni string) (model.Patient, error) {
+	return dr.find(func(patient model.Patient) bool {
+		return patient.DNI == dni
+	})
+}
+
+func (dr *PatientRepository) HasDentist(patientID uint, dentistID uint) (bool, error) {
+	found := false
+	err := dr.store.run(dr.tx, func(t *tables) error {
+		for _, current := range t.appointments {
+			found = found || (current.PatientID == patientID && current.DentistID == dentistID)
+		}
+		return nil
+	})
+	return found, err
+}
+
+func (dr *PatientRepository) Update(patient model.Patient) (model.Patient, error) {
+	err := dr.store.run(dr.tx, func(t *tables) error {
+		for _, current := range t.patients {
+			if current.DNI == patient.DNI && current.ID != patient.ID {
+				return internal.ErDniAlreadyExists
+			}
+		}
+
+		patient.ID = t.nextID("patients", patient.ID)
+		t.patients[patient.ID] = stripPatient(patient)
+		return nil
+	})
+	if err != nil {
+		return model.Patient{}, err
+	}
+	return patient, nil
+}
+
+func (dr *PatientRepository) Delete(id uint) error {
+	return dr.store.run(dr.tx, func(t *tables) error {
+		patient, ok := t.patients[id]
+		switch {
+		case !ok:
+		case dr.unscoped:
+			delete(t.patients, id)
+		case !patient.DeletedAt.Valid:
+			patient.DeletedAt = gorm.DeletedAt{Time: now(), Valid: true}
+			t.patients[id] = patient
+		}
+		return nil
+	})
+}
+
+func (dr *PatientRepository) Restore(id uint) (model.Patient, error) {
+	err := dr.store.run(dr.tx, func(t *tables) error {
+		patient, ok := t.patients[id]
+		if !ok {
+			return internal.ErNotFound
+		}
+
+		patient.DeletedAt = gorm.DeletedAt{}
+		t.patients[id] = patient
+		return nil
+	})
+	if err != nil {
+		return model.Patient{}, err
+	}
+	return dr.GetByID(id)
+}
+
+func (dr *PatientRepository) Unscoped() model.Repository {
+	return &PatientRepository{store: dr.store, tx: dr.tx, unscoped: true}
+}
+
+func (dr *PatientRepository) Lock(id uint) error {
+	// Transactions already run one at a time, deleted patients are locked too like in the database
+	return dr.store.run(dr.tx, func(t *tables) error {
+		if _, ok := t.patients[id]; !ok {
+			return internal.ErNotFound
+		}
+		return nil
+	})
+}
+
+func (dr *PatientRepository) GetUpcoming(patientID uint, from time.Time) ([]appointment.Appointment, error) {
+	var data []appointment.Appointment
+	err := dr.store.run(dr.tx, func(t *tables) error {
+		data = getUpcoming(t, func(current appointment.Appointment) bool {
+			return current.PatientID == patientID
+		}, from)
+		return nil
+	})
+	return data, err
+}
+
+func (dr *PatientRepository) CancelAppointments(ids []uint, reason string, at time.Time) error {
+	return dr.store.run(dr.tx, func(t *tables) error {
+		return cancelAppointments(t, ids, reason, at)
+	})
+}
+
+func (dr *PatientRepository) Record(entry audit.Entry) error {
+	return dr.store.run(dr.tx, func(t *tables) error {
+		return record(t, entry)
+	})
+}
+
+func (dr *PatientRepository) Publish(event event.Event) error {
+	return dr.store.run(dr.tx, func(t *tables) error {
+		return publish(t, event)
+	})
+}
+
+func (dr *PatientRepository) Transaction(fn func(repository model.Repository) error) error {
+	return dr.store.transaction(dr.tx, func(t *tables) error {
+		return fn(&PatientRepository{store: dr.store, tx: t, unscoped: dr.unscoped})
+	})
+}
+
+// find returns the first patient by id that matches
+func (dr *PatientRepository) find(match func(patient model.Patient) bool) (model.Patient, error) {
+	var data model.Patient
+	err := dr.store.run(dr.tx, func(t *tables) error {
+		found := false
+		for _, current := range t.patients {
+			if (dr.unscoped || !current.DeletedAt.Valid) && match(current) && (!found || current.ID < data.ID) {
+				data, found = current, true
+			}
+		}
+		if !found {
+			return internal.ErNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return model.Patient{}, err
+	}
+	return data, nil
+}
+
+// stripPatient leaves out the appointments, they are stored in their own table
+func stripPatient(patient model.Patient) model.Patient {
+	patient.Appointments = nil
+	patient.AdmissionDate = stored(patient.AdmissionDate)
+	if patient.DeletedAt.Valid {
+		patient.DeletedAt.Time = stored(patient.DeletedAt.Time)
+	}
+	return patient
+}
@@ -0,0 +1,247 @@
+package memory
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/appointment"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/audit"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/dentist"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/event"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/pagination"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/patient"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/schedule"
+)
+
+// Store holds the records of the in-memory repositories, the repositories of a store see each other's changes
+// like the repositories of a database do. Transactions run one at a time on a copy of the records that replaces
+// them when the transaction commits, so the rest of the store waits for them and never sees them half done
+type Store struct {
+	mu     sync.Mutex
+	tables *tables
+}
+
+func NewStore() *Store {
+	return &Store{tables: newTables()}
+}
+
+type tables struct {
+	dentists     map[uint]dentist.Dentist
+	patients     map[uint]patient.Patient
+	appointments map[uint]appointment.Appointment
+	series       map[uint]appointment.Series
+	hours        map[uint]schedule.WorkingHours
+	entries      []audit.Entry
+	events       []event.Event
+	// lastID is the last id given in each table, ids aren't reused after deletions
+	lastID map[string]uint
+}
+
+func newTables() *tables {
+	return &tables{
+		dentists:     map[uint]dentist.Dentist{},
+		patients:     map[uint]patient.Patient{},
+		appointments: map[uint]appointment.Appointment{},
+		series:       map[uint]appointment.Series{},
+		hours:        map[uint]schedule.WorkingHours{},
+		lastID:       map[string]uint{},
+	}
+}
+
+// clone copies the tables, the records are values and are always replaced instead of changed in place, so
+// they can be shared
+func (t *tables) clone() *tables {
+	return &tables{
+		dentists:     clone(t.dentists),
+		patients:     clone(t.patients),
+		appointments: clone(t.appointments),
+		series:       clone(t.series),
+		hours:        clone(t.hours),
+		entries:      slices.Clone(t.entries),
+		events:       slices.Clone(t.events),
+		lastID:       clone(t.lastID),
+	}
+}
+
+// nextID returns the id of a new record of the table, or id when it is given
+func (t *tables) nextID(table string, id uint) uint {
+	if id == 0 {
+		id = t.lastID[table] + 1
+	}
+	if id > t.lastID[table] {
+		t.lastID[table] = id
+	}
+	return id
+}
+
+func clone[K comparable, V any](records map[K]V) map[K]V {
+	copied := make(map[K]V, len(records))
+	for key, value := range records {
+		copied[key] = value
+	}
+	return copied
+}
+
+// run calls fn with the tables of the transaction, or with the tables of the store while no transaction runs
+func (s *Store) run(tx *tables, fn func(t *tables) error) error {
+	if tx != nil {
+		return fn(tx)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fn(s.tables)
+}
+
+// transaction calls fn with a copy of the tables and keeps it when fn succeeds, a transaction inside another
+// one works on a copy of the outer transaction like a savepoint
+func (s *Store) transaction(tx *tables, fn func(t *tables) error) error {
+	if tx != nil {
+		copied := tx.clone()
+		err := fn(copied)
+		if err != nil {
+			return err
+		}
+		*tx = *copied
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	copied := s.tables.clone()
+	err := fn(copied)
+	if err != nil {
+		return err
+	}
+	s.tables = copied
+	return nil
+}
+
+// columns compares the records of a table by the columns they can be sorted by, it must have the id
+type columns[T any] map[string]func(a T, b T) int
+
+// findPage counts the records that match and returns the requested page, ordered by the sort columns and by id
+// last like the database does
+func findPage[T any](records map[uint]T, match func(record T) bool, sortColumns columns[T], page pagination.Request) pagination.Page[T] {
+	var data []T
+	for _, record := range records {
+		if match(record) {
+			data = append(data, record)
+		}
+	}
+
+	compare := func(a T, b T) int {
+		for _, sort := range page.Sort {
+			compare, ok := sortColumns[sort.Column]
+			if !ok {
+				continue
+			}
+
+			result := compare(a, b)
+			if sort.Desc {
+				result = -result
+			}
+			if result != 0 {
+				return result
+			}
+		}
+		return sortColumns["id"](a, b)
+	}
+	slices.SortFunc(data, compare)
+
+	// After the last item of the previous page, like the database the total isn't counted
+	if after, ok := page.After.(T); ok {
+		start := len(data)
+		for i, record := range data {
+			if compare(record, after) > 0 {
+				start = i
+				break
+			}
+		}
+		end := min(start+page.Size, len(data))
+		return pagination.NewPage(data[start:end], 0, page)
+	}
+
+	total := int64(len(data))
+	start := min(page.Offset(), len(data))
+	end := min(start+page.Size, len(data))
+	return pagination.NewPage(data[start:end], total, page)
+}
+
+// byDate orders appointments by date, and by id when they start at the same time
+func byDate(data []appointment.Appointment) []appointment.Appointment {
+	slices.SortFunc(data, func(a appointment.Appointment, b appointment.Appointment) int {
+		if result := a.Date.Compare(b.Date); result != 0 {
+			return result
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return data
+}
+
+// now is the time of the changes, dates are kept in UTC with the milliseconds the database stores
+func now() time.Time {
+	return stored(time.Now())
+}
+
+func stored(date time.Time) time.Time {
+	return date.Round(time.Millisecond).UTC()
+}
+
+// storedPtr is stored for the optional dates
+func storedPtr(date *time.Time) *time.Time {
+	if date == nil {
+		return nil
+	}
+	value := stored(*date)
+	return &value
+}
+
+func record(t *tables, entry audit.Entry) error {
+	entry.ID = t.nextID("audit_entries", entry.ID)
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = now()
+	}
+	t.entries = append(t.entries, entry)
+	return nil
+}
+
+func publish(t *tables, data event.Event) error {
+	data.ID = t.nextID("outbox_events", data.ID)
+	if data.CreatedAt.IsZero() {
+		data.CreatedAt = now()
+	}
+	t.events = append(t.events, data)
+	return nil
+}
+
+// getUpcoming returns the pending appointments starting from the given time that match
+func getUpcoming(t *tables, match func(current appointment.Appointment) bool, from time.Time) []appointment.Appointment {
+	var data []appointment.Appointment
+	for _, current := range t.appointments {
+		if match(current) && !current.Date.Before(from) && slices.Contains(appointment.PendingStatuses, current.Status) {
+			data = append(data, current)
+		}
+	}
+	return byDate(data)
+}
+
+func cancelAppointments(t *tables, ids []uint, reason string, at time.Time) error {
+	for _, id := range ids {
+		current, ok := t.appointments[id]
+		if !ok {
+			continue
+		}
+
+		current.Status = appointment.StatusCancelled
+		current.CancelledAt = storedPtr(&at)
+		current.CancelReason = reason
+		current.Sequence++
+		current.UpdatedAt = now()
+		t.appointments[id] = current
+	}
+	return nil
+}
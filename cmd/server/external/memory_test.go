@@ -0,0 +1,19 @@
+package external_test
+
+import (
+	"testing"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/cmd/server/external/memory"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/repositorytest"
+)
+
+func TestMemoryConformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repositorytest.Repositories {
+		store := memory.NewStore()
+		return repositorytest.Repositories{
+			Dentists:     memory.NewDentistRepository(store),
+			Patients:     memory.NewPatientRepository(store),
+			Appointments: memory.NewAppointmentRepository(store),
+		}
+	})
+}
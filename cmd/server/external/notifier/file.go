@@ -0,0 +1,55 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/reminder"
+)
+
+// FileNotifier appends the messages to a file instead of sending them, for local testing, the messages go to
+// the log when there is no path
+type FileNotifier struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileNotifier(path string) *FileNotifier {
+	return &FileNotifier{path: path}
+}
+
+func (f *FileNotifier) Channel() string {
+	if f.path == "" {
+		return "log"
+	}
+	return "file"
+}
+
+func (f *FileNotifier) Notify(_ context.Context, message reminder.Message) error {
+	if f.path == "" {
+		log.Printf("notifier: to %s: %s\n%s", message.To, message.Subject, message.Body)
+		return nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// The file is opened for every message so it can be rotated or removed while the server runs
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(file, "Date: %s\nTo: %s\nSubject: %s\n\n%s\n---\n",
+		time.Now().Format(time.RFC3339), message.To, message.Subject, message.Body)
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	return file.Close()
+}
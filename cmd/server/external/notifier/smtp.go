@@ -0,0 +1,129 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/reminder"
+)
+
+type SMTPParams struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	// From is the sender address, optionally with a name like "Dental Clinic <no-reply@example.com>"
+	From string
+}
+
+// SMTPNotifier sends the messages as plain text emails, upgrading the connection with STARTTLS when the server
+// supports it and authenticating when there is a username
+type SMTPNotifier struct {
+	params SMTPParams
+}
+
+func NewSMTPNotifier(params SMTPParams) *SMTPNotifier {
+	return &SMTPNotifier{params: params}
+}
+
+func (s *SMTPNotifier) Channel() string {
+	return "email"
+}
+
+func (s *SMTPNotifier) Notify(ctx context.Context, message reminder.Message) error {
+	from, err := mail.ParseAddress(s.params.From)
+	if err != nil {
+		return err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(s.params.Host, s.params.Port))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(30 * time.Second)
+	}
+	err = conn.SetDeadline(deadline)
+	if err != nil {
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, s.params.Host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		err = client.StartTLS(&tls.Config{ServerName: s.params.Host})
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.params.Username != "" {
+		err = client.Auth(smtp.PlainAuth("", s.params.Username, s.params.Password, s.params.Host))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = client.Mail(from.Address)
+	if err != nil {
+		return err
+	}
+
+	err = client.Rcpt(message.To)
+	if err != nil {
+		return err
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	_, err = writer.Write(s.email(from, message))
+	if err != nil {
+		return err
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// email writes the message with its headers, the body is quoted-printable so any text is safe to send
+func (s *SMTPNotifier) email(from *mail.Address, message reminder.Message) []byte {
+	var email bytes.Buffer
+	fmt.Fprintf(&email, "From: %s\r\n", from.String())
+	fmt.Fprintf(&email, "To: %s\r\n", message.To)
+	fmt.Fprintf(&email, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&email, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	email.WriteString("MIME-Version: 1.0\r\n")
+	email.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	email.WriteString("Content-Transfer-Encoding: quoted-printable\r\n")
+	email.WriteString("\r\n")
+
+	body := quotedprintable.NewWriter(&email)
+	// Writing to a buffer never fails
+	_, _ = body.Write([]byte(message.Body))
+	_ = body.Close()
+
+	return email.Bytes()
+}
@@ -0,0 +1,45 @@
+package external_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/cmd/server/external/database"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/repositorytest"
+)
+
+// TestSQLiteConformance runs the suite on a new SQLite file per test, migrated like the server does
+func TestSQLiteConformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repositorytest.Repositories {
+		db, err := database.Connect(database.ConnectionParams{
+			Driver:   database.DriverSQLite,
+			Database: filepath.Join(t.TempDir(), "clinic.db"),
+			LogLevel: "error",
+		})
+		if err != nil {
+			t.Fatalf("connecting to database: %v", err)
+		}
+		t.Cleanup(func() {
+			sqlDB, err := db.DB()
+			if err == nil {
+				sqlDB.Close()
+			}
+		})
+
+		migrator, err := database.NewMigrator(db, time.UTC)
+		if err != nil {
+			t.Fatalf("loading migrations: %v", err)
+		}
+		_, err = migrator.Up()
+		if err != nil {
+			t.Fatalf("migrating database: %v", err)
+		}
+
+		return repositorytest.Repositories{
+			Dentists:     database.NewDentistRepository(db),
+			Patients:     database.NewPatientRepository(db),
+			Appointments: database.NewOtherAppointmentRepository(db),
+		}
+	})
+}
@@ -3,39 +3,79 @@ package handler
 import (
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
 	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/appointment"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/auth"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/dentist"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/pagination"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/patient"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 )
 
 // AppointmentResponse model for, response a Appointment
 type AppointmentResponse struct {
-	Id          uint      `json:"id"`
-	PatientID   uint      `json:"patient_id"`
-	DentistID   uint      `json:"dentist_id"`
-	Date        time.Time `json:"date"`
-	Description string    `json:"description"`
+	Id           uint       `json:"id"`
+	PatientID    uint       `json:"patient_id"`
+	DentistID    uint       `json:"dentist_id"`
+	Date         time.Time  `json:"date"`
+	Duration     uint       `json:"duration"`
+	EndDate      time.Time  `json:"end_date"`
+	Description  string     `json:"description"`
+	Status       string     `json:"status"`
+	ConfirmedAt  *time.Time `json:"confirmed_at,omitempty"`
+	CheckedInAt  *time.Time `json:"checked_in_at,omitempty"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+	CancelledAt  *time.Time `json:"cancelled_at,omitempty"`
+	NoShowAt     *time.Time `json:"no_show_at,omitempty"`
+	CancelReason string     `json:"cancel_reason,omitempty"`
 } //	@name	AppointmentResponse
 
 // AppointmentDetailResponse model for, response a Appointment
 type AppointmentDetailResponse struct {
-	Id          uint            `json:"id"`
-	Patient     PatientResponse `json:"patient"`
-	Dentist     DentistResponse `json:"dentist"`
-	Date        time.Time       `json:"date"`
-	Description string          `json:"description"`
+	Id           uint            `json:"id"`
+	Patient      PatientResponse `json:"patient"`
+	Dentist      DentistResponse `json:"dentist"`
+	Date         time.Time       `json:"date"`
+	Duration     uint            `json:"duration"`
+	EndDate      time.Time       `json:"end_date"`
+	Description  string          `json:"description"`
+	Status       string          `json:"status"`
+	CancelReason string          `json:"cancel_reason,omitempty"`
 } //	@name	AppointmentDetailResponse
 
+// appointmentExportRow is a row of the detailed exports of Appointments, with the names of the Patient and the
+// Dentist like AppointmentDetailResponse but flat so each is a column
+type appointmentExportRow struct {
+	Id              uint      `json:"id"`
+	PatientID       uint      `json:"patient_id"`
+	PatientName     string    `json:"patient_name"`
+	PatientLastName string    `json:"patient_last_name"`
+	PatientDNI      string    `json:"patient_dni"`
+	DentistID       uint      `json:"dentist_id"`
+	DentistName     string    `json:"dentist_name"`
+	DentistLastName string    `json:"dentist_last_name"`
+	DentistLicense  string    `json:"dentist_license"`
+	Date            time.Time `json:"date"`
+	Duration        uint      `json:"duration"`
+	EndDate         time.Time `json:"end_date"`
+	Description     string    `json:"description"`
+	Status          string    `json:"status"`
+	CancelReason    string    `json:"cancel_reason"`
+}
+
 // AppointmentPost model for creating a Appointment
 type AppointmentPost struct {
 	PatientDNI     string `json:"patient_dni" binding:"required"`
 	DentistLicense string `json:"dentist_license" binding:"required"`
 	Date           string `json:"date" binding:"required"`
+	Duration       uint   `json:"duration" binding:"omitempty,min=1,max=480"`
 	Description    string `json:"description" binding:"required"`
 } //	@name	AppointmentPost
 
@@ -44,6 +84,7 @@ type AppointmentPut struct {
 	PatientID   uint   `json:"patient_id" binding:"required"`
 	DentistID   uint   `json:"dentist_id" binding:"required"`
 	Date        string `json:"date" binding:"required"`
+	Duration    uint   `json:"duration" binding:"omitempty,min=1,max=480"`
 	Description string `json:"description" binding:"required"`
 } //	@name	AppointmentPut
 
@@ -52,17 +93,30 @@ type AppointmentPatch struct {
 	PatientID   uint   `json:"patient_id"`
 	DentistID   uint   `json:"dentist_id"`
 	Date        string `json:"date"`
+	Duration    uint   `json:"duration" binding:"omitempty,min=1,max=480"`
 	Description string `json:"description"`
 } //	@name	AppointmentPatch
 
+// CancelPost model for cancelling a Appointment
+type CancelPost struct {
+	Reason string `json:"reason" binding:"required,max=255"`
+} //	@name	CancelPost
+
 type AppointmentService interface {
-	GetAll() ([]appointment.Appointment, error)
-	GetByID(id uint) (appointment.Appointment, error)
-	GetByDNI(dni string) (appointment.Appointment, error)
-	Create(appointment appointment.Appointment) (appointment.Appointment, error)
-	Update(appointment appointment.Appointment) (appointment.Appointment, error)
-	Patch(appointment appointment.Appointment) (appointment.Appointment, error)
-	Delete(id uint) error
+	GetAll(principal auth.Principal, filter appointment.Filter, page pagination.Request) (pagination.Page[appointment.Appointment], error)
+	GetByID(principal auth.Principal, id uint) (appointment.Appointment, error)
+	GetByDNI(principal auth.Principal, dni string, page pagination.Request) (pagination.Page[appointment.Appointment], error)
+	GetByLicense(principal auth.Principal, license string, page pagination.Request) (pagination.Page[appointment.Appointment], error)
+	Create(principal auth.Principal, appointment appointment.Appointment) (appointment.Appointment, error)
+	Update(principal auth.Principal, appointment appointment.Appointment) (appointment.Appointment, error)
+	Patch(principal auth.Principal, appointment appointment.Appointment) (appointment.Appointment, error)
+	Delete(principal auth.Principal, id uint) error
+	GetCalendar(principal auth.Principal, filter appointment.Filter) ([]appointment.Appointment, error)
+	Transition(principal auth.Principal, id uint, status appointment.Status, reason string) (appointment.Appointment, error)
+	CreateSeries(principal auth.Principal, series appointment.Series, skipConflicts bool) (appointment.Series, []internal.OccurrenceError, error)
+	GetSeries(principal auth.Principal, id uint) (appointment.Series, error)
+	UpdateSeries(principal auth.Principal, changes appointment.Appointment, scope appointment.Scope) ([]appointment.Appointment, error)
+	CancelSeries(principal auth.Principal, id uint, scope appointment.Scope, reason string) ([]appointment.Appointment, error)
 }
 
 type AppointmentHandler struct {
@@ -78,42 +132,54 @@ func NewAppointmentHandler(service AppointmentService, patient PatientService, d
 // GetAll function to get all Appointments
 //
 //	@Summary		Get all Appointments
-//	@Description	Get all Appointments
+//	@Description	Get a page of Appointments, filtered and sorted by the query params
 //	@Tags			Appointment
-//	@Success		200	{array}		AppointmentResponse
-//	@Failure		503	{object}	ErrorResponse
+//	@Security		BearerAuth
+//	@Param			dentist_id	query		int		false	"Dentist ID"
+//	@Param			patient_id	query		int		false	"Patient ID"
+//	@Param			from		query		string	false	"Appointments ending after this date, in format RFC3339"
+//	@Param			to			query		string	false	"Appointments starting before this date, in format RFC3339"
+//	@Param			status		query		string	false	"Comma separated statuses, e.g. scheduled,confirmed"
+//	@Param			sort		query		string	false	"Fields to sort by, prefixed with '-' for descending order, e.g. -date"
+//	@Param			page		query		int		false	"Page number, starting at 1"
+//	@Param			size		query		int		false	"Page size, 20 by default and 100 at most"
+//	@Success		200			{object}	PageResponse[AppointmentResponse]
+//	@Failure		400			{object}	ErrorResponse
+//	@Failure		503			{object}	ErrorResponse
 //	@Router			/appointments [get]
 func (a *AppointmentHandler) GetAll(ctx *gin.Context) {
-	appointments, err := a.service.GetAll()
-	if err != nil {
-		switch {
-		case errors.Is(err, internal.ErServiceUnavailable):
-			ctx.JSON(http.StatusServiceUnavailable, ErrorResponse{
-				Timestamp: time.Now().Format(time.RFC3339),
-				Status:    http.StatusServiceUnavailable,
-				Message:   err.Error(),
-				Path:      ctx.Request.URL.Path,
-			})
-		}
+	page, errs := parsePageRequest(ctx, appointment.SortColumns)
+	filter, filterErrs := appointmentFilter(ctx)
+	errs = append(errs, filterErrs...)
+
+	if len(errs) > 0 {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   "invalid query params",
+			Path:      ctx.Request.URL.Path,
+			Errors:    errs,
+		})
+		return
 	}
 
-	if len(appointments) == 0 {
-		ctx.JSON(http.StatusOK, appointments)
+	appointments, err := a.service.GetAll(principal(ctx), filter, page)
+	if err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusServiceUnavailable,
+			Message:   err.Error(),
+			Path:      ctx.Request.URL.Path,
+		})
 		return
 	}
 
 	var body []AppointmentResponse
-	for _, currentAppointment := range appointments {
-		body = append(body, AppointmentResponse{
-			Id:          currentAppointment.ID,
-			PatientID:   currentAppointment.PatientID,
-			DentistID:   currentAppointment.DentistID,
-			Date:        currentAppointment.Date,
-			Description: currentAppointment.Description,
-		})
+	for _, currentAppointment := range appointments.Items {
+		body = append(body, appointmentBody(currentAppointment, zone(ctx)))
 	}
 
-	ctx.JSON(http.StatusOK, body)
+	ctx.JSON(http.StatusOK, newPageResponse(ctx, appointments, body))
 }
 
 // GetById function to get Appointment by id
@@ -121,6 +187,7 @@ func (a *AppointmentHandler) GetAll(ctx *gin.Context) {
 //	@Summary		Get Appointment by id
 //	@Description	Get Appointment by id
 //	@Tags			Appointment
+//	@Security		BearerAuth
 //	@Param			id	path		int	true	"Appointment ID"
 //	@Success		200	{object}	AppointmentResponse
 //	@Failure		400	{object}	ErrorResponse
@@ -150,7 +217,7 @@ func (a *AppointmentHandler) GetById(ctx *gin.Context) {
 		return
 	}
 
-	data, err := a.service.GetByID(uint(id))
+	data, err := a.service.GetByID(principal(ctx), uint(id))
 	if err != nil {
 		switch {
 		case errors.Is(err, internal.ErNotFound):
@@ -173,146 +240,230 @@ func (a *AppointmentHandler) GetById(ctx *gin.Context) {
 		}
 	}
 
-	body := AppointmentResponse{
-		Id:          data.ID,
-		PatientID:   data.PatientID,
-		DentistID:   data.DentistID,
-		Date:        data.Date,
-		Description: data.Description,
-	}
+	body := appointmentBody(data, zone(ctx))
 
 	ctx.JSON(http.StatusOK, body)
 }
 
-// GetByDNI function to get Appointment by DNI
+// GetByDNI function to get the Appointments of a Patient by DNI or of a Dentist by License
 //
-//	@Summary		Get Appointment by DNI
-//	@Description	Get Appointment by DNI
+//	@Summary		Get Appointments by DNI or License
+//	@Description	Get the Appointment history, upcoming and past, of a Patient by DNI or of a Dentist by License
 //	@Tags			Appointment
-//	@Param			dni	query		string	true	"Patient DNI"
-//	@Success		200	{object}	AppointmentResponse
-//	@Failure		400	{object}	ErrorResponse
-//	@Failure		404	{object}	ErrorResponse
-//	@Failure		503	{object}	ErrorResponse
+//	@Security		BearerAuth
+//	@Param			dni		query		string	false	"Patient DNI"
+//	@Param			license	query		string	false	"Dentist License"
+//	@Param			sort	query		string	false	"Fields to sort by, prefixed with '-' for descending order, date by default"
+//	@Param			page	query		int		false	"Page number, starting at 1"
+//	@Param			size	query		int		false	"Page size, 20 by default and 100 at most"
+//	@Success		200		{object}	PageResponse[AppointmentDetailResponse]
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		404		{object}	ErrorResponse
+//	@Failure		503		{object}	ErrorResponse
 //	@Router			/appointments/q [get]
 func (a *AppointmentHandler) GetByDNI(ctx *gin.Context) {
-	dniQuery := ctx.Query("dni")
-	if dniQuery == "" {
+	dniQuery := strings.ToLower(strings.TrimSpace(ctx.Query("dni")))
+	licenseQuery := strings.ToLower(strings.TrimSpace(ctx.Query("license")))
+	if (dniQuery == "") == (licenseQuery == "") {
 		ctx.JSON(http.StatusBadRequest, ErrorResponse{
 			Timestamp: time.Now().Format(time.RFC3339),
 			Status:    http.StatusBadRequest,
-			Message:   "value of 'dni' query param is required",
+			Message:   "value of either 'dni' or 'license' query param is required",
 			Path:      ctx.Request.URL.Path,
 		})
 		return
 	}
 
-	appointmentSearched, err := a.service.GetByDNI(dniQuery)
+	page, errs := parsePageRequest(ctx, appointment.SortColumns)
+	if len(errs) > 0 {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   "invalid query params",
+			Path:      ctx.Request.URL.Path,
+			Errors:    errs,
+		})
+		return
+	}
+
+	parties := a.parties(ctx)
+
+	var appointments pagination.Page[appointment.Appointment]
+	var err error
+	if dniQuery != "" {
+		var patientSearched patient.Patient
+		patientSearched, err = a.patientService.GetByDNI(principal(ctx), dniQuery)
+		if err == nil {
+			parties.patients[patientSearched.ID] = patientBody(patientSearched, zone(ctx))
+			appointments, err = a.service.GetByDNI(principal(ctx), dniQuery, page)
+		}
+	} else {
+		var dentistSearched dentist.Dentist
+		dentistSearched, err = a.dentistService.GetByLicense(licenseQuery)
+		if err == nil {
+			parties.dentists[dentistSearched.ID] = dentistBody(dentistSearched, zone(ctx))
+			appointments, err = a.service.GetByLicense(principal(ctx), licenseQuery, page)
+		}
+	}
 	if err != nil {
 		switch {
-		case errors.Is(err, internal.ErNotFound):
+		case errors.Is(err, internal.ErNotFound) && dniQuery != "":
 			ctx.JSON(http.StatusNotFound, ErrorResponse{
 				Timestamp: time.Now().Format(time.RFC3339),
 				Status:    http.StatusNotFound,
-				Message:   fmt.Sprintf("appointment for patient with dni %s %s", dniQuery, err.Error()),
-				Path:      ctx.Request.URL.Path,
-			})
-
-		default:
-			ctx.JSON(http.StatusInternalServerError, ErrorResponse{
-				Timestamp: time.Now().Format(time.RFC3339),
-				Status:    http.StatusInternalServerError,
-				Message:   err.Error(),
+				Message:   fmt.Sprintf("patient with dni %s %s", dniQuery, err.Error()),
 				Path:      ctx.Request.URL.Path,
 			})
-		}
-		return
-	}
 
-	patient, err := a.patientService.GetByID(appointmentSearched.PatientID)
-	if err != nil {
-		switch {
 		case errors.Is(err, internal.ErNotFound):
-			ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+			ctx.JSON(http.StatusNotFound, ErrorResponse{
 				Timestamp: time.Now().Format(time.RFC3339),
-				Status:    http.StatusInternalServerError,
-				Message:   err.Error(),
+				Status:    http.StatusNotFound,
+				Message:   fmt.Sprintf("dentist with license %s %s", licenseQuery, err.Error()),
 				Path:      ctx.Request.URL.Path,
 			})
-			return
 
-		case errors.Is(err, internal.ErServiceUnavailable):
+		default:
 			ctx.JSON(http.StatusServiceUnavailable, ErrorResponse{
 				Timestamp: time.Now().Format(time.RFC3339),
 				Status:    http.StatusServiceUnavailable,
 				Message:   err.Error(),
 				Path:      ctx.Request.URL.Path,
 			})
-			return
-
-		default:
-			ctx.JSON(http.StatusInternalServerError, ErrorResponse{
-				Timestamp: time.Now().Format(time.RFC3339),
-				Status:    http.StatusInternalServerError,
-				Message:   "internal server error, please try again later",
-				Path:      ctx.Request.URL.Path,
-			})
 		}
+		return
 	}
 
-	dentist, err := a.dentistService.GetByID(appointmentSearched.DentistID)
-	if err != nil {
-		switch {
-		case errors.Is(err, internal.ErNotFound):
-			ctx.JSON(http.StatusInternalServerError, ErrorResponse{
+	var body []AppointmentDetailResponse
+	for _, currentAppointment := range appointments.Items {
+		patientFound, err := parties.patient(currentAppointment.PatientID)
+		if err != nil {
+			ctx.JSON(http.StatusServiceUnavailable, ErrorResponse{
 				Timestamp: time.Now().Format(time.RFC3339),
-				Status:    http.StatusInternalServerError,
-				Message:   err.Error(),
+				Status:    http.StatusServiceUnavailable,
+				Message:   internal.ErServiceUnavailable.Error(),
 				Path:      ctx.Request.URL.Path,
 			})
 			return
+		}
 
-		case errors.Is(err, internal.ErServiceUnavailable):
+		dentistFound, err := parties.dentist(currentAppointment.DentistID)
+		if err != nil {
 			ctx.JSON(http.StatusServiceUnavailable, ErrorResponse{
 				Timestamp: time.Now().Format(time.RFC3339),
 				Status:    http.StatusServiceUnavailable,
-				Message:   err.Error(),
+				Message:   internal.ErServiceUnavailable.Error(),
 				Path:      ctx.Request.URL.Path,
 			})
 			return
-
-		default:
-			ctx.JSON(http.StatusInternalServerError, ErrorResponse{
-				Timestamp: time.Now().Format(time.RFC3339),
-				Status:    http.StatusInternalServerError,
-				Message:   "internal server error, please try again later",
-				Path:      ctx.Request.URL.Path,
-			})
 		}
+
+		body = append(body, AppointmentDetailResponse{
+			Id:           currentAppointment.ID,
+			Patient:      patientFound,
+			Dentist:      dentistFound,
+			Date:         currentAppointment.Date.In(zone(ctx)),
+			Duration:     currentAppointment.Duration,
+			EndDate:      currentAppointment.EndDate.In(zone(ctx)),
+			Description:  currentAppointment.Description,
+			Status:       string(currentAppointment.Status),
+			CancelReason: currentAppointment.CancelReason,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, newPageResponse(ctx, appointments, body))
+}
+
+// Export function to download Appointments as a file
+//
+//	@Summary		Export Appointments
+//	@Description	Download every Appointment matching the filters of the list as CSV, JSON Lines or XLSX, with a column per field of AppointmentResponse.
+//	@Description	With detail the rows have the names of the Patient and the Dentist instead of the dates of each status, like AppointmentDetailResponse.
+//	@Description	The format is read from the format query param, or else from the Accept header, CSV by default. The file is streamed, so exports of any size are downloaded at once.
+//	@Tags			Appointment
+//	@Security		BearerAuth
+//	@Produce		text/csv
+//	@Produce		application/x-ndjson
+//	@Produce		application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+//	@Param			format		query		string	false	"csv, jsonl or xlsx"
+//	@Param			detail		query		bool	false	"Add the names of the Patient and the Dentist"
+//	@Param			dentist_id	query		int		false	"Dentist ID"
+//	@Param			patient_id	query		int		false	"Patient ID"
+//	@Param			from		query		string	false	"Appointments ending after this date, in format RFC3339"
+//	@Param			to			query		string	false	"Appointments starting before this date, in format RFC3339"
+//	@Param			status		query		string	false	"Comma separated statuses, e.g. scheduled,confirmed"
+//	@Param			sort		query		string	false	"Fields to sort by, prefixed with '-' for descending order, e.g. dentist_id,date"
+//	@Success		200			{file}		file
+//	@Failure		400			{object}	ErrorResponse
+//	@Failure		403			{object}	ErrorResponse
+//	@Failure		503			{object}	ErrorResponse
+//	@Router			/appointments/export [get]
+func (a *AppointmentHandler) Export(ctx *gin.Context) {
+	filter, errs := appointmentFilter(ctx)
+	detail, err := queryBool(ctx, "detail")
+	if err != nil {
+		errs = append(errs, "'detail' query param must be true or false")
 	}
 
-	body := AppointmentDetailResponse{
-		Id: appointmentSearched.ID,
-		Patient: PatientResponse{
-			Id:            patient.ID,
-			Name:          patient.Name,
-			LastName:      patient.Lastname,
-			Address:       patient.Address,
-			DNI:           patient.DNI,
-			Email:         patient.Email,
-			AdmissionDate: patient.AdmissionDate,
-		},
-		Dentist: DentistResponse{
-			Id:       dentist.ID,
-			Name:     dentist.Name,
-			Lastname: dentist.Lastname,
-			License:  dentist.License,
-		},
-		Date:        appointmentSearched.Date,
-		Description: appointmentSearched.Description,
+	sort, format, ok := exportQuery(ctx, appointment.SortColumns, errs)
+	if !ok {
+		return
 	}
 
-	ctx.JSON(http.StatusOK, body)
+	columns := jsonColumns(AppointmentResponse{})
+	if detail {
+		columns = jsonColumns(appointmentExportRow{})
+	}
+
+	parties := a.parties(ctx)
+	writeExport(ctx, format, "appointments", columns, func(write func(row interface{}) error) error {
+		fetch := func(page pagination.Request) (pagination.Page[appointment.Appointment], error) {
+			return a.service.GetAll(principal(ctx), filter, page)
+		}
+		return pagination.Each(sort, fetch, func(current appointment.Appointment) error {
+			if !detail {
+				return write(appointmentBody(current, zone(ctx)))
+			}
+
+			patientFound, err := parties.patient(current.PatientID)
+			if err != nil {
+				return err
+			}
+			dentistFound, err := parties.dentist(current.DentistID)
+			if err != nil {
+				return err
+			}
+
+			return write(appointmentExportRow{
+				Id:              current.ID,
+				PatientID:       current.PatientID,
+				PatientName:     patientFound.Name,
+				PatientLastName: patientFound.LastName,
+				PatientDNI:      patientFound.DNI,
+				DentistID:       current.DentistID,
+				DentistName:     dentistFound.Name,
+				DentistLastName: dentistFound.Lastname,
+				DentistLicense:  dentistFound.License,
+				Date:            current.Date.In(zone(ctx)),
+				Duration:        current.Duration,
+				EndDate:         current.EndDate.In(zone(ctx)),
+				Description:     current.Description,
+				Status:          string(current.Status),
+				CancelReason:    current.CancelReason,
+			})
+		})
+	})
+}
+
+// ExportTo writes every Appointment to w in the format, like Export without filters nor detail, for the export
+// command
+func (a *AppointmentHandler) ExportTo(w io.Writer, format string, location *time.Location) error {
+	fetch := func(page pagination.Request) (pagination.Page[appointment.Appointment], error) {
+		return a.service.GetAll(auth.System, appointment.Filter{}, page)
+	}
+	return exportTo(w, format, "appointments", jsonColumns(AppointmentResponse{}), fetch, func(current appointment.Appointment) interface{} {
+		return appointmentBody(current, location)
+	})
 }
 
 // Create function to create a Appointment
@@ -320,8 +471,7 @@ func (a *AppointmentHandler) GetByDNI(ctx *gin.Context) {
 //	@Summary		Create a Appointment
 //	@Description	Create a Appointment
 //	@Tags			Appointment
-//	@security		APIKey
-//	@Param			PUB_KEY		header		string			true	"Public Key"
+//	@Security		BearerAuth
 //	@Param			Appointment	body		AppointmentPost	true	"AppointmentResponse"
 //	@Success		201			{object}	AppointmentResponse
 //	@Failure		400			{object}	ErrorResponse
@@ -350,7 +500,7 @@ func (a *AppointmentHandler) Create(ctx *gin.Context) {
 	}
 
 	timeLayout := "RFC3339"
-	date, err := time.Parse(time.RFC3339, appointmentToPost.Date)
+	date, err := parseTime(appointmentToPost.Date)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, ErrorResponse{
 			Timestamp: time.Now().Format(time.RFC3339),
@@ -365,7 +515,7 @@ func (a *AppointmentHandler) Create(ctx *gin.Context) {
 		return
 	}
 
-	patientExist, err := a.patientService.GetByDNI(appointmentToPost.PatientDNI)
+	patientExist, err := a.patientService.GetByDNI(principal(ctx), appointmentToPost.PatientDNI)
 	if err != nil {
 		switch {
 		case errors.Is(err, internal.ErNotFound):
@@ -413,22 +563,62 @@ func (a *AppointmentHandler) Create(ctx *gin.Context) {
 		PatientID:   patientExist.ID,
 		DentistID:   dentistExist.ID,
 		Date:        date,
+		Duration:    appointmentToPost.Duration,
 		Description: appointmentToPost.Description,
 	}
 
-	data, err := a.service.Create(appointmentToCreate)
+	data, err := a.service.Create(principal(ctx), appointmentToCreate)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, data)
+		switch {
+		case errors.Is(err, internal.ErForbidden):
+			ctx.JSON(http.StatusForbidden, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusForbidden,
+				Message:   err.Error(),
+				Path:      ctx.Request.URL.Path,
+			})
+			return
+
+		case errors.Is(err, internal.ErAppointmentConflict):
+			ctx.JSON(http.StatusConflict, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusConflict,
+				Message:   err.Error(),
+				Path:      ctx.Request.URL.Path,
+				Errors:    conflictErrors(err),
+			})
+			return
+
+		case errors.Is(err, internal.ErInvalidDuration):
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusBadRequest,
+				Message:   err.Error(),
+				Path:      ctx.Request.URL.Path,
+			})
+			return
+
+		case errors.Is(err, internal.ErOutsideWorkingHours):
+			ctx.JSON(http.StatusConflict, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusConflict,
+				Message:   err.Error(),
+				Path:      ctx.Request.URL.Path,
+			})
+			return
+
+		default:
+			ctx.JSON(http.StatusServiceUnavailable, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusServiceUnavailable,
+				Message:   err.Error(),
+				Path:      ctx.Request.URL.Path,
+			})
+		}
 		return
 	}
 
-	body := AppointmentResponse{
-		Id:          data.ID,
-		PatientID:   data.PatientID,
-		DentistID:   data.DentistID,
-		Date:        data.Date,
-		Description: data.Description,
-	}
+	body := appointmentBody(data, zone(ctx))
 
 	ctx.JSON(http.StatusCreated, body)
 }
@@ -438,8 +628,7 @@ func (a *AppointmentHandler) Create(ctx *gin.Context) {
 //	@Summary		Update a Appointment
 //	@Description	Update a Appointment
 //	@Tags			Appointment
-//	@security		APIKey
-//	@Param			PUB_KEY		header		string			true	"Public Key"
+//	@Security		BearerAuth
 //	@Param			id			path		int				true	"Appointment ID"
 //	@Param			Appointment	body		AppointmentPut	true	"AppointmentResponse"
 //	@Success		200			{object}	AppointmentResponse
@@ -491,7 +680,7 @@ func (a *AppointmentHandler) Update(ctx *gin.Context) {
 	}
 
 	timeLayout := "RFC3339"
-	date, err := time.Parse(time.RFC3339, appointmentToPut.Date)
+	date, err := parseTime(appointmentToPut.Date)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, ErrorResponse{
 			Timestamp: time.Now().Format(time.RFC3339),
@@ -506,7 +695,7 @@ func (a *AppointmentHandler) Update(ctx *gin.Context) {
 		return
 	}
 
-	_, err = a.patientService.GetByID(appointmentToPut.PatientID)
+	_, err = a.patientService.GetByID(principal(ctx), appointmentToPut.PatientID)
 	if err != nil {
 		switch {
 		case errors.Is(err, internal.ErNotFound):
@@ -555,10 +744,11 @@ func (a *AppointmentHandler) Update(ctx *gin.Context) {
 		PatientID:   appointmentToPut.PatientID,
 		DentistID:   appointmentToPut.DentistID,
 		Date:        date,
+		Duration:    appointmentToPut.Duration,
 		Description: appointmentToPut.Description,
 	}
 
-	appointmentUpdated, err := a.service.Update(appointmentToUpdate)
+	appointmentUpdated, err := a.service.Update(principal(ctx), appointmentToUpdate)
 	if err != nil {
 		switch {
 		case errors.Is(err, internal.ErNotFound):
@@ -570,7 +760,44 @@ func (a *AppointmentHandler) Update(ctx *gin.Context) {
 			})
 			return
 
-		case errors.Is(err, internal.ErServiceUnavailable):
+		case errors.Is(err, internal.ErForbidden):
+			ctx.JSON(http.StatusForbidden, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusForbidden,
+				Message:   err.Error(),
+				Path:      ctx.Request.URL.Path,
+			})
+			return
+
+		case errors.Is(err, internal.ErAppointmentConflict):
+			ctx.JSON(http.StatusConflict, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusConflict,
+				Message:   err.Error(),
+				Path:      ctx.Request.URL.Path,
+				Errors:    conflictErrors(err),
+			})
+			return
+
+		case errors.Is(err, internal.ErInvalidDuration):
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusBadRequest,
+				Message:   err.Error(),
+				Path:      ctx.Request.URL.Path,
+			})
+			return
+
+		case errors.Is(err, internal.ErOutsideWorkingHours), errors.Is(err, internal.ErAppointmentClosed):
+			ctx.JSON(http.StatusConflict, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusConflict,
+				Message:   err.Error(),
+				Path:      ctx.Request.URL.Path,
+			})
+			return
+
+		default:
 			ctx.JSON(http.StatusServiceUnavailable, ErrorResponse{
 				Timestamp: time.Now().Format(time.RFC3339),
 				Status:    http.StatusServiceUnavailable,
@@ -578,15 +805,10 @@ func (a *AppointmentHandler) Update(ctx *gin.Context) {
 				Path:      ctx.Request.URL.Path,
 			})
 		}
+		return
 	}
 
-	body := AppointmentResponse{
-		Id:          appointmentUpdated.ID,
-		PatientID:   appointmentUpdated.PatientID,
-		DentistID:   appointmentUpdated.DentistID,
-		Date:        appointmentUpdated.Date,
-		Description: appointmentUpdated.Description,
-	}
+	body := appointmentBody(appointmentUpdated, zone(ctx))
 
 	ctx.JSON(http.StatusOK, body)
 }
@@ -596,8 +818,7 @@ func (a *AppointmentHandler) Update(ctx *gin.Context) {
 //	@Summary		Patch a Appointment
 //	@Description	Patch a Appointment
 //	@Tags			Appointment
-//	@security		APIKey
-//	@Param			PUB_KEY		header		string				true	"Public Key"
+//	@Security		BearerAuth
 //	@Param			id			path		int					true	"Appointment ID"
 //	@Param			Appointment	body		AppointmentPatch	true	"AppointmentResponse"
 //	@Success		200			{object}	AppointmentResponse
@@ -641,54 +862,59 @@ func (a *AppointmentHandler) Patch(ctx *gin.Context) {
 		return
 	}
 
-	_, err = a.patientService.GetByID(appointmentToPatch.PatientID)
-	if err != nil {
-		switch {
-		case errors.Is(err, internal.ErNotFound):
-			ctx.JSON(http.StatusNotFound, ErrorResponse{
-				Timestamp: time.Now().Format(time.RFC3339),
-				Status:    http.StatusNotFound,
-				Message:   fmt.Sprintf("patientService with id %d %s", appointmentToPatch.PatientID, err.Error()),
-				Path:      ctx.Request.URL.Path,
-			})
+	// Only the patient and dentist given in the body are checked, the rest are kept as they are
+	if appointmentToPatch.PatientID != 0 {
+		_, err = a.patientService.GetByID(principal(ctx), appointmentToPatch.PatientID)
+		if err != nil {
+			switch {
+			case errors.Is(err, internal.ErNotFound):
+				ctx.JSON(http.StatusNotFound, ErrorResponse{
+					Timestamp: time.Now().Format(time.RFC3339),
+					Status:    http.StatusNotFound,
+					Message:   fmt.Sprintf("patientService with id %d %s", appointmentToPatch.PatientID, err.Error()),
+					Path:      ctx.Request.URL.Path,
+				})
+				return
+			default:
+				ctx.JSON(http.StatusServiceUnavailable, ErrorResponse{
+					Timestamp: time.Now().Format(time.RFC3339),
+					Status:    http.StatusServiceUnavailable,
+					Message:   err.Error(),
+					Path:      ctx.Request.URL.Path,
+				})
+			}
 			return
-		default:
-			ctx.JSON(http.StatusServiceUnavailable, ErrorResponse{
-				Timestamp: time.Now().Format(time.RFC3339),
-				Status:    http.StatusServiceUnavailable,
-				Message:   err.Error(),
-				Path:      ctx.Request.URL.Path,
-			})
 		}
-		return
 	}
 
-	_, err = a.dentistService.GetByID(appointmentToPatch.DentistID)
-	if err != nil {
-		switch {
-		case errors.Is(err, internal.ErNotFound):
-			ctx.JSON(http.StatusNotFound, ErrorResponse{
-				Timestamp: time.Now().Format(time.RFC3339),
-				Status:    http.StatusNotFound,
-				Message:   fmt.Sprintf("dentistService with id %d %s", appointmentToPatch.DentistID, err.Error()),
-				Path:      ctx.Request.URL.Path,
-			})
+	if appointmentToPatch.DentistID != 0 {
+		_, err = a.dentistService.GetByID(appointmentToPatch.DentistID)
+		if err != nil {
+			switch {
+			case errors.Is(err, internal.ErNotFound):
+				ctx.JSON(http.StatusNotFound, ErrorResponse{
+					Timestamp: time.Now().Format(time.RFC3339),
+					Status:    http.StatusNotFound,
+					Message:   fmt.Sprintf("dentistService with id %d %s", appointmentToPatch.DentistID, err.Error()),
+					Path:      ctx.Request.URL.Path,
+				})
+				return
+			default:
+				ctx.JSON(http.StatusServiceUnavailable, ErrorResponse{
+					Timestamp: time.Now().Format(time.RFC3339),
+					Status:    http.StatusServiceUnavailable,
+					Message:   err.Error(),
+					Path:      ctx.Request.URL.Path,
+				})
+			}
 			return
-		default:
-			ctx.JSON(http.StatusServiceUnavailable, ErrorResponse{
-				Timestamp: time.Now().Format(time.RFC3339),
-				Status:    http.StatusServiceUnavailable,
-				Message:   err.Error(),
-				Path:      ctx.Request.URL.Path,
-			})
 		}
-		return
 	}
 
 	timeLayout := "RFC3339"
 	var date time.Time
 	if appointmentToPatch.Date != "" {
-		date, err = time.Parse(time.RFC3339, appointmentToPatch.Date)
+		date, err = parseTime(appointmentToPatch.Date)
 		if err != nil {
 			ctx.JSON(http.StatusBadRequest, ErrorResponse{
 				Timestamp: time.Now().Format(time.RFC3339),
@@ -709,10 +935,11 @@ func (a *AppointmentHandler) Patch(ctx *gin.Context) {
 		PatientID:   appointmentToPatch.PatientID,
 		DentistID:   appointmentToPatch.DentistID,
 		Date:        date,
+		Duration:    appointmentToPatch.Duration,
 		Description: appointmentToPatch.Description,
 	}
 
-	appointmentUpdated, err := a.service.Patch(appointmentToUpdate)
+	appointmentUpdated, err := a.service.Patch(principal(ctx), appointmentToUpdate)
 	if err != nil {
 		switch {
 		case errors.Is(err, internal.ErNotFound):
@@ -724,7 +951,44 @@ func (a *AppointmentHandler) Patch(ctx *gin.Context) {
 			})
 			return
 
-		case errors.Is(err, internal.ErServiceUnavailable):
+		case errors.Is(err, internal.ErForbidden):
+			ctx.JSON(http.StatusForbidden, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusForbidden,
+				Message:   err.Error(),
+				Path:      ctx.Request.URL.Path,
+			})
+			return
+
+		case errors.Is(err, internal.ErAppointmentConflict):
+			ctx.JSON(http.StatusConflict, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusConflict,
+				Message:   err.Error(),
+				Path:      ctx.Request.URL.Path,
+				Errors:    conflictErrors(err),
+			})
+			return
+
+		case errors.Is(err, internal.ErInvalidDuration):
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusBadRequest,
+				Message:   err.Error(),
+				Path:      ctx.Request.URL.Path,
+			})
+			return
+
+		case errors.Is(err, internal.ErOutsideWorkingHours), errors.Is(err, internal.ErAppointmentClosed):
+			ctx.JSON(http.StatusConflict, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusConflict,
+				Message:   err.Error(),
+				Path:      ctx.Request.URL.Path,
+			})
+			return
+
+		default:
 			ctx.JSON(http.StatusServiceUnavailable, ErrorResponse{
 				Timestamp: time.Now().Format(time.RFC3339),
 				Status:    http.StatusServiceUnavailable,
@@ -732,15 +996,10 @@ func (a *AppointmentHandler) Patch(ctx *gin.Context) {
 				Path:      ctx.Request.URL.Path,
 			})
 		}
+		return
 	}
 
-	body := AppointmentResponse{
-		Id:          appointmentUpdated.ID,
-		PatientID:   appointmentUpdated.PatientID,
-		DentistID:   appointmentUpdated.DentistID,
-		Date:        appointmentUpdated.Date,
-		Description: appointmentUpdated.Description,
-	}
+	body := appointmentBody(appointmentUpdated, zone(ctx))
 
 	ctx.JSON(http.StatusOK, body)
 }
@@ -750,12 +1009,11 @@ func (a *AppointmentHandler) Patch(ctx *gin.Context) {
 //	@Summary		Delete a Appointment
 //	@Description	Delete a Appointment
 //	@Tags			Appointment
-//	@security		APIKey
-//	@Param			PUB_KEY	header		string	true	"Public Key"
-//	@Param			id		path		int		true	"Appointment ID"
-//	@Failure		400		{object}	ErrorResponse
-//	@Failure		404		{object}	ErrorResponse
-//	@Failure		503		{object}	ErrorResponse
+//	@Security		BearerAuth
+//	@Param			id	path		int	true	"Appointment ID"
+//	@Failure		400	{object}	ErrorResponse
+//	@Failure		404	{object}	ErrorResponse
+//	@Failure		503	{object}	ErrorResponse
 //	@Router			/appointments/{id} [delete]
 func (a *AppointmentHandler) Delete(ctx *gin.Context) {
 	idParam := ctx.Param("id")
@@ -780,7 +1038,7 @@ func (a *AppointmentHandler) Delete(ctx *gin.Context) {
 		return
 	}
 
-	err = a.service.Delete(uint(id))
+	err = a.service.Delete(principal(ctx), uint(id))
 	if err != nil {
 		switch {
 		case errors.Is(err, internal.ErNotFound):
@@ -804,3 +1062,295 @@ func (a *AppointmentHandler) Delete(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusNoContent, nil)
 }
+
+// conflictErrors lists the appointments that caused a scheduling conflict
+// Confirm function to confirm a Appointment
+//
+//	@Summary		Confirm a Appointment
+//	@Description	Move a scheduled Appointment to confirmed
+//	@Tags			Appointment
+//	@Security		BearerAuth
+//	@Param			id	path		int	true	"Appointment ID"
+//	@Success		200	{object}	AppointmentResponse
+//	@Failure		400	{object}	ErrorResponse
+//	@Failure		404	{object}	ErrorResponse
+//	@Failure		409	{object}	ErrorResponse
+//	@Failure		503	{object}	ErrorResponse
+//	@Router			/appointments/{id}/confirm [post]
+func (a *AppointmentHandler) Confirm(ctx *gin.Context) {
+	a.transition(ctx, appointment.StatusConfirmed, "")
+}
+
+// CheckIn function to check in the Patient of a Appointment
+//
+//	@Summary		Check in a Appointment
+//	@Description	Move a scheduled or confirmed Appointment to checked_in when the Patient arrives
+//	@Tags			Appointment
+//	@Security		BearerAuth
+//	@Param			id	path		int	true	"Appointment ID"
+//	@Success		200	{object}	AppointmentResponse
+//	@Failure		400	{object}	ErrorResponse
+//	@Failure		404	{object}	ErrorResponse
+//	@Failure		409	{object}	ErrorResponse
+//	@Failure		503	{object}	ErrorResponse
+//	@Router			/appointments/{id}/check-in [post]
+func (a *AppointmentHandler) CheckIn(ctx *gin.Context) {
+	a.transition(ctx, appointment.StatusCheckedIn, "")
+}
+
+// Complete function to complete a Appointment
+//
+//	@Summary		Complete a Appointment
+//	@Description	Move a checked_in Appointment to completed
+//	@Tags			Appointment
+//	@Security		BearerAuth
+//	@Param			id	path		int	true	"Appointment ID"
+//	@Success		200	{object}	AppointmentResponse
+//	@Failure		400	{object}	ErrorResponse
+//	@Failure		404	{object}	ErrorResponse
+//	@Failure		409	{object}	ErrorResponse
+//	@Failure		503	{object}	ErrorResponse
+//	@Router			/appointments/{id}/complete [post]
+func (a *AppointmentHandler) Complete(ctx *gin.Context) {
+	a.transition(ctx, appointment.StatusCompleted, "")
+}
+
+// NoShow function to mark a Appointment the Patient didn't come to
+//
+//	@Summary		Mark a Appointment as no-show
+//	@Description	Move a scheduled or confirmed Appointment to no_show
+//	@Tags			Appointment
+//	@Security		BearerAuth
+//	@Param			id	path		int	true	"Appointment ID"
+//	@Success		200	{object}	AppointmentResponse
+//	@Failure		400	{object}	ErrorResponse
+//	@Failure		404	{object}	ErrorResponse
+//	@Failure		409	{object}	ErrorResponse
+//	@Failure		503	{object}	ErrorResponse
+//	@Router			/appointments/{id}/no-show [post]
+func (a *AppointmentHandler) NoShow(ctx *gin.Context) {
+	a.transition(ctx, appointment.StatusNoShow, "")
+}
+
+// Cancel function to cancel a Appointment
+//
+//	@Summary		Cancel a Appointment
+//	@Description	Move a scheduled or confirmed Appointment to cancelled, keeping it in the history and freeing its slot
+//	@Tags			Appointment
+//	@Security		BearerAuth
+//	@Param			id		path		int			true	"Appointment ID"
+//	@Param			Reason	body		CancelPost	true	"Reason of the cancellation"
+//	@Success		200		{object}	AppointmentResponse
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		404		{object}	ErrorResponse
+//	@Failure		409		{object}	ErrorResponse
+//	@Failure		503		{object}	ErrorResponse
+//	@Router			/appointments/{id}/cancel [post]
+func (a *AppointmentHandler) Cancel(ctx *gin.Context) {
+	cancelToPost := CancelPost{}
+	err := ctx.ShouldBindJSON(&cancelToPost)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   "invalid body",
+			Path:      ctx.Request.URL.Path,
+			Errors:    []string{"reason field is required and must have at most 255 characters"},
+		})
+		return
+	}
+
+	a.transition(ctx, appointment.StatusCancelled, strings.TrimSpace(cancelToPost.Reason))
+}
+
+// transition moves the appointment of the id param to the status and writes the response
+func (a *AppointmentHandler) transition(ctx *gin.Context, status appointment.Status, reason string) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   "id param must be a number greater than 0",
+			Path:      ctx.Request.URL.Path,
+		})
+		return
+	}
+
+	appointmentMoved, err := a.service.Transition(principal(ctx), uint(id), status, reason)
+	if err != nil {
+		var transitionErr *internal.TransitionError
+		switch {
+		case errors.As(err, &transitionErr):
+			ctx.JSON(http.StatusConflict, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusConflict,
+				Message:   fmt.Sprintf("appointment with id %d can't move from %s to %s", id, transitionErr.From, transitionErr.To),
+				Path:      ctx.Request.URL.Path,
+			})
+
+		case errors.Is(err, internal.ErNotFound):
+			ctx.JSON(http.StatusNotFound, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusNotFound,
+				Message:   fmt.Sprintf("appointment with id %d %s", id, err.Error()),
+				Path:      ctx.Request.URL.Path,
+			})
+
+		case errors.Is(err, internal.ErForbidden):
+			ctx.JSON(http.StatusForbidden, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusForbidden,
+				Message:   err.Error(),
+				Path:      ctx.Request.URL.Path,
+			})
+
+		default:
+			ctx.JSON(http.StatusServiceUnavailable, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusServiceUnavailable,
+				Message:   internal.ErServiceUnavailable.Error(),
+				Path:      ctx.Request.URL.Path,
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, appointmentBody(appointmentMoved, zone(ctx)))
+}
+
+// appointmentParties looks up the patients and dentists of a list of appointments once per id, a list usually
+// repeats the same few
+type appointmentParties struct {
+	ctx            *gin.Context
+	patientService PatientService
+	dentistService DentistService
+	patients       map[uint]PatientResponse
+	dentists       map[uint]DentistResponse
+}
+
+func (a *AppointmentHandler) parties(ctx *gin.Context) *appointmentParties {
+	return &appointmentParties{
+		ctx:            ctx,
+		patientService: a.patientService,
+		dentistService: a.dentistService,
+		patients:       map[uint]PatientResponse{},
+		dentists:       map[uint]DentistResponse{},
+	}
+}
+
+func (p *appointmentParties) patient(id uint) (PatientResponse, error) {
+	found, ok := p.patients[id]
+	if ok {
+		return found, nil
+	}
+
+	searched, err := p.patientService.GetByID(principal(p.ctx), id)
+	// Deleted patients keep their history, only their id is shown
+	if errors.Is(err, internal.ErNotFound) {
+		searched, err = patient.Patient{ID: id}, nil
+	}
+	if err != nil {
+		return PatientResponse{}, err
+	}
+
+	found = patientBody(searched, zone(p.ctx))
+	p.patients[id] = found
+	return found, nil
+}
+
+func (p *appointmentParties) dentist(id uint) (DentistResponse, error) {
+	found, ok := p.dentists[id]
+	if ok {
+		return found, nil
+	}
+
+	searched, err := p.dentistService.GetByID(id)
+	// Deleted dentists keep their history, only their id is shown
+	if errors.Is(err, internal.ErNotFound) {
+		searched, err = dentist.Dentist{ID: id}, nil
+	}
+	if err != nil {
+		return DentistResponse{}, err
+	}
+
+	found = dentistBody(searched, zone(p.ctx))
+	p.dentists[id] = found
+	return found, nil
+}
+
+func appointmentBody(data appointment.Appointment, location *time.Location) AppointmentResponse {
+	return AppointmentResponse{
+		Id:           data.ID,
+		PatientID:    data.PatientID,
+		DentistID:    data.DentistID,
+		Date:         data.Date.In(location),
+		Duration:     data.Duration,
+		EndDate:      data.EndDate.In(location),
+		Description:  data.Description,
+		Status:       string(data.Status),
+		ConfirmedAt:  inZone(data.ConfirmedAt, location),
+		CheckedInAt:  inZone(data.CheckedInAt, location),
+		CompletedAt:  inZone(data.CompletedAt, location),
+		CancelledAt:  inZone(data.CancelledAt, location),
+		NoShowAt:     inZone(data.NoShowAt, location),
+		CancelReason: data.CancelReason,
+	}
+}
+
+func conflictErrors(err error) []string {
+	var conflictErr *internal.AppointmentConflictError
+	if !errors.As(err, &conflictErr) {
+		return nil
+	}
+
+	var errs []string
+	for _, id := range conflictErr.IDs {
+		errs = append(errs, fmt.Sprintf("appointment with id %d overlaps", id))
+	}
+	return errs
+}
+
+// appointmentFilter reads the filters of a list of appointments from the query params
+func appointmentFilter(ctx *gin.Context) (appointment.Filter, []string) {
+	var errs []string
+
+	dentistID, err := queryUint(ctx, "dentist_id")
+	if err != nil {
+		errs = append(errs, "'dentist_id' query param must be a number greater than 0")
+	}
+
+	patientID, err := queryUint(ctx, "patient_id")
+	if err != nil {
+		errs = append(errs, "'patient_id' query param must be a number greater than 0")
+	}
+
+	from, err := queryTime(ctx, "from")
+	if err != nil {
+		errs = append(errs, "'from' query param must be in format RFC3339")
+	}
+
+	to, err := queryTime(ctx, "to")
+	if err != nil {
+		errs = append(errs, "'to' query param must be in format RFC3339")
+	}
+
+	var statuses []appointment.Status
+	if raw := ctx.Query("status"); raw != "" {
+		for _, value := range strings.Split(raw, ",") {
+			status := appointment.Status(strings.ToLower(strings.TrimSpace(value)))
+			if !status.Valid() {
+				errs = append(errs, fmt.Sprintf("'status' query param must be a list of %v", appointment.Statuses))
+				break
+			}
+			statuses = append(statuses, status)
+		}
+	}
+
+	return appointment.Filter{
+		PatientID: patientID,
+		DentistID: dentistID,
+		From:      from,
+		To:        to,
+		Statuses:  statuses,
+	}, errs
+}
@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/audit"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/pagination"
+	"github.com/gin-gonic/gin"
+)
+
+// AuditEntryResponse model for, response an entry of the audit log
+type AuditEntryResponse struct {
+	Id        uint                      `json:"id"`
+	Entity    string                    `json:"entity"`
+	EntityID  uint                      `json:"entity_id"`
+	Action    string                    `json:"action"`
+	ActorID   uint                      `json:"actor_id"`
+	Actor     string                    `json:"actor"`
+	CreatedAt time.Time                 `json:"created_at"`
+	Changes   map[string]ChangeResponse `json:"changes"`
+} //	@name	AuditEntryResponse
+
+// ChangeResponse model for, response the value of a field before and after a change
+type ChangeResponse struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+} //	@name	ChangeResponse
+
+type AuditService interface {
+	GetAll(filter audit.Filter, page pagination.Request) (pagination.Page[audit.Entry], error)
+}
+
+type AuditHandler struct {
+	service AuditService
+}
+
+func NewAuditHandler(service AuditService) *AuditHandler {
+	return &AuditHandler{service: service}
+}
+
+// auditEntities are the values accepted by the entity query param
+var auditEntities = []string{audit.EntityPatient, audit.EntityDentist, audit.EntityAppointment}
+
+// GetAll function to get the audit log
+//
+//	@Summary		Get the audit log
+//	@Description	Get a page of the audit log, newest first unless another order is requested, each entry holds the fields that changed
+//	@Tags			Audit
+//	@Security		BearerAuth
+//	@Param			entity		query		string	false	"Changed entity"	Enums(patient, dentist, appointment)
+//	@Param			id			query		int		false	"Changed entity ID"
+//	@Param			actor_id	query		int		false	"ID of the User that made the change"
+//	@Param			from		query		string	false	"Changed on or after this date, in format RFC3339"
+//	@Param			to			query		string	false	"Changed before this date, in format RFC3339"
+//	@Param			sort		query		string	false	"Fields to sort by, prefixed with '-' for descending order, e.g. created_at"
+//	@Param			page		query		int		false	"Page number, starting at 1"
+//	@Param			size		query		int		false	"Page size, 20 by default and 100 at most"
+//	@Success		200			{object}	PageResponse[AuditEntryResponse]
+//	@Failure		400			{object}	ErrorResponse
+//	@Failure		401			{object}	ErrorResponse
+//	@Failure		403			{object}	ErrorResponse
+//	@Failure		503			{object}	ErrorResponse
+//	@Router			/audit [get]
+func (a *AuditHandler) GetAll(ctx *gin.Context) {
+	page, errs := parsePageRequest(ctx, audit.SortColumns)
+
+	entity := strings.ToLower(strings.TrimSpace(ctx.Query("entity")))
+	if entity != "" && !contains(auditEntities, entity) {
+		errs = append(errs, fmt.Sprintf("'entity' query param must be one of %v", auditEntities))
+	}
+
+	entityID, err := queryUint(ctx, "id")
+	if err != nil {
+		errs = append(errs, "'id' query param must be a number")
+	}
+	if entityID != 0 && entity == "" {
+		errs = append(errs, "'id' query param requires the 'entity' query param")
+	}
+
+	actorID, err := queryUint(ctx, "actor_id")
+	if err != nil {
+		errs = append(errs, "'actor_id' query param must be a number")
+	}
+
+	from, err := queryTime(ctx, "from")
+	if err != nil {
+		errs = append(errs, "'from' query param must be in format RFC3339")
+	}
+
+	to, err := queryTime(ctx, "to")
+	if err != nil {
+		errs = append(errs, "'to' query param must be in format RFC3339")
+	}
+
+	if len(errs) > 0 {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   "invalid query params",
+			Path:      ctx.Request.URL.Path,
+			Errors:    errs,
+		})
+		return
+	}
+
+	filter := audit.Filter{
+		Entity:   entity,
+		EntityID: entityID,
+		ActorID:  actorID,
+		From:     from,
+		To:       to,
+	}
+
+	entries, err := a.service.GetAll(filter, page)
+	if err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusServiceUnavailable,
+			Message:   err.Error(),
+			Path:      ctx.Request.URL.Path,
+		})
+		return
+	}
+
+	var body []AuditEntryResponse
+	for _, currentEntry := range entries.Items {
+		changes := map[string]ChangeResponse{}
+		for field, change := range currentEntry.Decode() {
+			changes[field] = ChangeResponse{Before: change.Before, After: change.After}
+		}
+
+		body = append(body, AuditEntryResponse{
+			Id:        currentEntry.ID,
+			Entity:    currentEntry.Entity,
+			EntityID:  currentEntry.EntityID,
+			Action:    string(currentEntry.Action),
+			ActorID:   currentEntry.ActorID,
+			Actor:     currentEntry.Actor,
+			CreatedAt: currentEntry.CreatedAt.In(zone(ctx)),
+			Changes:   changes,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, newPageResponse(ctx, entries, body))
+}
+
+func contains(values []string, value string) bool {
+	for _, current := range values {
+		if current == value {
+			return true
+		}
+	}
+	return false
+}
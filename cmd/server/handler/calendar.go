@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/appointment"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/calendar"
+	"github.com/gin-gonic/gin"
+)
+
+// calendarContentType is the media type of iCalendar documents
+const calendarContentType = "text/calendar; charset=utf-8"
+
+// DentistCalendar function to get the calendar feed of a Dentist
+//
+//	@Summary		Get the calendar of a Dentist
+//	@Description	Get the Appointments of a Dentist as an iCalendar feed, from 90 days ago on, calendar apps can subscribe to it with a feed token
+//	@Tags			Dentist
+//	@Security		BearerAuth
+//	@Security		FeedToken
+//	@Produce		text/calendar
+//	@Param			id	path		int	true	"Dentist ID"
+//	@Success		200	{string}	string
+//	@Failure		400	{object}	ErrorResponse
+//	@Failure		401	{object}	ErrorResponse
+//	@Failure		404	{object}	ErrorResponse
+//	@Failure		503	{object}	ErrorResponse
+//	@Router			/dentists/{id}/calendar.ics [get]
+func (a *AppointmentHandler) DentistCalendar(ctx *gin.Context) {
+	id, ok := calendarID(ctx)
+	if !ok {
+		return
+	}
+
+	dentistSearched, err := a.dentistService.GetByID(id)
+	if err != nil {
+		a.notFoundOrUnavailable(ctx, err, fmt.Sprintf("dentist with id %d", id))
+		return
+	}
+
+	appointments, err := a.service.GetCalendar(principal(ctx), appointment.Filter{
+		DentistID: id,
+		From:      time.Now().Add(-calendar.History),
+	})
+	if err != nil {
+		a.notFoundOrUnavailable(ctx, err, fmt.Sprintf("dentist with id %d", id))
+		return
+	}
+
+	names := map[uint]string{}
+	events := make([]calendar.Event, 0, len(appointments))
+	for _, currentAppointment := range appointments {
+		name, found := names[currentAppointment.PatientID]
+		if !found {
+			name = fmt.Sprintf("Patient %d", currentAppointment.PatientID)
+			patientSearched, err := a.patientService.GetByID(principal(ctx), currentAppointment.PatientID)
+			if err == nil {
+				name = fmt.Sprintf("%s %s", patientSearched.Name, patientSearched.Lastname)
+			}
+			names[currentAppointment.PatientID] = name
+		}
+
+		events = append(events, calendar.FromAppointment(currentAppointment, name))
+	}
+
+	writeCalendar(ctx, calendar.Calendar{
+		Name:   fmt.Sprintf("%s %s", dentistSearched.Name, dentistSearched.Lastname),
+		Events: events,
+	})
+}
+
+// PatientCalendar function to get the calendar feed of a Patient
+//
+//	@Summary		Get the calendar of a Patient
+//	@Description	Get the Appointments of a Patient as an iCalendar feed, from 90 days ago on, calendar apps can subscribe to it with a feed token
+//	@Tags			Patient
+//	@Security		BearerAuth
+//	@Security		FeedToken
+//	@Produce		text/calendar
+//	@Param			id	path		int	true	"Patient ID"
+//	@Success		200	{string}	string
+//	@Failure		400	{object}	ErrorResponse
+//	@Failure		401	{object}	ErrorResponse
+//	@Failure		404	{object}	ErrorResponse
+//	@Failure		503	{object}	ErrorResponse
+//	@Router			/patients/{id}/calendar.ics [get]
+func (a *AppointmentHandler) PatientCalendar(ctx *gin.Context) {
+	id, ok := calendarID(ctx)
+	if !ok {
+		return
+	}
+
+	patientSearched, err := a.patientService.GetByID(principal(ctx), id)
+	if err != nil {
+		a.notFoundOrUnavailable(ctx, err, fmt.Sprintf("patient with id %d", id))
+		return
+	}
+
+	appointments, err := a.service.GetCalendar(principal(ctx), appointment.Filter{
+		PatientID: id,
+		From:      time.Now().Add(-calendar.History),
+	})
+	if err != nil {
+		a.notFoundOrUnavailable(ctx, err, fmt.Sprintf("patient with id %d", id))
+		return
+	}
+
+	names := map[uint]string{}
+	events := make([]calendar.Event, 0, len(appointments))
+	for _, currentAppointment := range appointments {
+		name, found := names[currentAppointment.DentistID]
+		if !found {
+			name = fmt.Sprintf("Dentist %d", currentAppointment.DentistID)
+			dentistSearched, err := a.dentistService.GetByID(currentAppointment.DentistID)
+			if err == nil {
+				name = fmt.Sprintf("Dentist %s %s", dentistSearched.Name, dentistSearched.Lastname)
+			}
+			names[currentAppointment.DentistID] = name
+		}
+
+		events = append(events, calendar.FromAppointment(currentAppointment, name))
+	}
+
+	writeCalendar(ctx, calendar.Calendar{
+		Name:   fmt.Sprintf("%s %s", patientSearched.Name, patientSearched.Lastname),
+		Events: events,
+	})
+}
+
+// calendarID reads the id param, writing the response when it's invalid
+func calendarID(ctx *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   "id param must be a number greater than 0",
+			Path:      ctx.Request.URL.Path,
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func writeCalendar(ctx *gin.Context, data calendar.Calendar) {
+	var body bytes.Buffer
+	err := calendar.Encode(&body, data, time.Now())
+	if err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusServiceUnavailable,
+			Message:   internal.ErServiceUnavailable.Error(),
+			Path:      ctx.Request.URL.Path,
+		})
+		return
+	}
+
+	// Feeds carry personal data and calendar apps poll them, so they aren't kept by shared caches
+	ctx.Header("Cache-Control", "private, no-cache")
+	ctx.Data(http.StatusOK, calendarContentType, body.Bytes())
+}
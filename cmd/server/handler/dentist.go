@@ -3,22 +3,30 @@ package handler
 import (
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/appointment"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/auth"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/bulk"
 	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/dentist"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/pagination"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/schedule"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 )
 
 // DentistResponse model for, response a Dentist
 type DentistResponse struct {
-	Id       uint   `json:"id"`
-	Lastname string `json:"last_name"`
-	Name     string `json:"name"`
-	License  string `json:"license"`
+	Id        uint       `json:"id"`
+	Lastname  string     `json:"last_name"`
+	Name      string     `json:"name"`
+	License   string     `json:"license"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 } //	@name	DentistResponse
 
 // DentistPost model for creating a Dentist
@@ -43,13 +51,18 @@ type DentistPatch struct {
 } //	@name	DentistPatch
 
 type DentistService interface {
-	GetAll() ([]dentist.Dentist, error)
+	GetAll(principal auth.Principal, filter dentist.Filter, page pagination.Request) (pagination.Page[dentist.Dentist], error)
 	GetByID(id uint) (dentist.Dentist, error)
 	GetByLicense(license string) (dentist.Dentist, error)
-	Create(dentist dentist.Dentist) (dentist.Dentist, error)
-	Update(dentist dentist.Dentist) (dentist.Dentist, error)
-	Patch(dentist dentist.Dentist) (dentist.Dentist, error)
-	Delete(id uint) error
+	Create(principal auth.Principal, dentist dentist.Dentist) (dentist.Dentist, error)
+	Update(principal auth.Principal, dentist dentist.Dentist) (dentist.Dentist, error)
+	Patch(principal auth.Principal, dentist dentist.Dentist) (dentist.Dentist, error)
+	Delete(principal auth.Principal, id uint, policy appointment.DeletePolicy) error
+	Restore(principal auth.Principal, id uint) (dentist.Dentist, error)
+	GetSchedule(id uint) ([]schedule.WorkingHours, error)
+	UpdateSchedule(principal auth.Principal, id uint, hours []schedule.WorkingHours) ([]schedule.WorkingHours, error)
+	GetAvailability(id uint, from time.Time, to time.Time, slot time.Duration) ([]schedule.Slot, error)
+	Import(principal auth.Principal, next func() (dentist.Dentist, int, error), dryRun bool) (bulk.Report, error)
 }
 
 type DentistHandler struct {
@@ -63,15 +76,47 @@ func NewDentistHandler(service DentistService) *DentistHandler {
 // GetAll function to get all Dentists
 //
 //	@Summary		Get all Dentists
-//	@Description	Get all Dentists
+//	@Description	Get a page of Dentists, filtered and sorted by the query params
 //	@Tags			Dentist
-//	@Success		200	{array}		DentistResponse
-//	@Failure		503	{object}	ErrorResponse
+//	@Security		BearerAuth
+//	@Param			name			query		string	false	"Dentist name prefix"
+//	@Param			last_name		query		string	false	"Dentist last name prefix"
+//	@Param			license			query		string	false	"Dentist License"
+//	@Param			include_deleted	query		bool	false	"Also list deleted Dentists, only for admins"
+//	@Param			sort			query		string	false	"Fields to sort by, prefixed with '-' for descending order, e.g. last_name,-id"
+//	@Param			page			query		int		false	"Page number, starting at 1"
+//	@Param			size			query		int		false	"Page size, 20 by default and 100 at most"
+//	@Success		200				{object}	PageResponse[DentistResponse]
+//	@Failure		400				{object}	ErrorResponse
+//	@Failure		403				{object}	ErrorResponse
+//	@Failure		503				{object}	ErrorResponse
 //	@Router			/dentists [get]
 func (d *DentistHandler) GetAll(ctx *gin.Context) {
-	dentists, err := d.service.GetAll()
+	page, errs := parsePageRequest(ctx, dentist.SortColumns)
+	filter, filterErrs := dentistFilter(ctx)
+	errs = append(errs, filterErrs...)
+
+	if len(errs) > 0 {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   "invalid query params",
+			Path:      ctx.Request.URL.Path,
+			Errors:    errs,
+		})
+		return
+	}
+
+	dentists, err := d.service.GetAll(principal(ctx), filter, page)
 	if err != nil {
 		switch {
+		case errors.Is(err, internal.ErForbidden):
+			ctx.JSON(http.StatusForbidden, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusForbidden,
+				Message:   err.Error(),
+				Path:      ctx.Request.URL.Path,
+			})
 		case errors.Is(err, internal.ErServiceUnavailable):
 			ctx.JSON(http.StatusServiceUnavailable, ErrorResponse{
 				Timestamp: time.Now().Format(time.RFC3339),
@@ -83,22 +128,12 @@ func (d *DentistHandler) GetAll(ctx *gin.Context) {
 		return
 	}
 
-	if len(dentists) == 0 {
-		ctx.JSON(http.StatusOK, dentists)
-		return
-	}
-
 	var body []DentistResponse
-	for _, currentDentist := range dentists {
-		body = append(body, DentistResponse{
-			Id:       currentDentist.ID,
-			Lastname: currentDentist.Lastname,
-			Name:     currentDentist.Name,
-			License:  currentDentist.License,
-		})
+	for _, currentDentist := range dentists.Items {
+		body = append(body, dentistBody(currentDentist, zone(ctx)))
 	}
 
-	ctx.JSON(http.StatusOK, body)
+	ctx.JSON(http.StatusOK, newPageResponse(ctx, dentists, body))
 }
 
 // GetByID function to get Dentist by id
@@ -106,6 +141,7 @@ func (d *DentistHandler) GetAll(ctx *gin.Context) {
 //	@Summary		Get Dentist by id
 //	@Description	Get Dentist by id
 //	@Tags			Dentist
+//	@Security		BearerAuth
 //	@Param			id	path		int	true	"Dentist ID"
 //	@Success		200	{object}	DentistResponse
 //	@Failure		400	{object}	ErrorResponse
@@ -172,6 +208,7 @@ func (d *DentistHandler) GetById(ctx *gin.Context) {
 //	@Summary		Get Dentist by License
 //	@Description	Get Dentist by License
 //	@Tags			Dentist
+//	@Security		BearerAuth
 //	@Param			license	query		string	true	"Dentist License"
 //	@Success		200		{object}	DentistResponse
 //	@Failure		400		{object}	ErrorResponse
@@ -226,8 +263,7 @@ func (d *DentistHandler) GetByLicense(ctx *gin.Context) {
 //	@Summary		Create a Dentist
 //	@Description	Create a Dentist
 //	@Tags			Dentist
-//	@security		APIKey
-//	@Param			PUB_KEY	header		string	true		"Public Key"
+//	@Security		BearerAuth
 //	@Param			Dentist	Body		body	DentistPost	true	"DentistResponse"
 //	@Success		201		{object}	DentistResponse
 //	@Failure		400		{object}	ErrorResponse
@@ -261,9 +297,17 @@ func (d *DentistHandler) Create(ctx *gin.Context) {
 		License:  dentistToCreated.License,
 	}
 
-	dentistCreated, err := d.service.Create(dentistToCreate)
+	dentistCreated, err := d.service.Create(principal(ctx), dentistToCreate)
 	if err != nil {
 		switch {
+		case errors.Is(err, internal.ErLicenseBelongsToDeleted):
+			ctx.JSON(http.StatusConflict, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusConflict,
+				Message:   err.Error(),
+				Path:      ctx.Request.URL.Path,
+			})
+			return
 		case errors.Is(err, internal.ErLicenseAlreadyExists):
 			ctx.JSON(http.StatusConflict, ErrorResponse{
 				Timestamp: time.Now().Format(time.RFC3339),
@@ -298,8 +342,7 @@ func (d *DentistHandler) Create(ctx *gin.Context) {
 //	@Summary		Update a Dentist
 //	@Description	Update a Dentist
 //	@Tags			Dentist
-//	@security		APIKey
-//	@Param			PUB_KEY	header		string		true	"Public Key"
+//	@Security		BearerAuth
 //	@Param			Dentist	body		DentistPut	true	"DentistResponse"
 //	@Success		200		{object}	DentistResponse
 //	@Failure		400		{object}	ErrorResponse
@@ -369,7 +412,7 @@ func (d *DentistHandler) Update(ctx *gin.Context) {
 		License:  dentistToUpdate.License,
 	}
 
-	dentistUpdated, err = d.service.Update(dentistUpdated)
+	dentistUpdated, err = d.service.Update(principal(ctx), dentistUpdated)
 	if err != nil {
 		switch {
 		case errors.Is(err, internal.ErNotFound):
@@ -380,6 +423,14 @@ func (d *DentistHandler) Update(ctx *gin.Context) {
 				Path:      ctx.Request.URL.Path,
 			})
 			return
+		case errors.Is(err, internal.ErLicenseBelongsToDeleted):
+			ctx.JSON(http.StatusConflict, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusConflict,
+				Message:   err.Error(),
+				Path:      ctx.Request.URL.Path,
+			})
+			return
 		case errors.Is(err, internal.ErLicenseAlreadyExists):
 			ctx.JSON(http.StatusConflict, ErrorResponse{
 				Timestamp: time.Now().Format(time.RFC3339),
@@ -413,8 +464,7 @@ func (d *DentistHandler) Update(ctx *gin.Context) {
 //	@Summary		Patch a Dentist
 //	@Description	Patch a Dentist
 //	@Tags			Dentist
-//	@security		APIKey
-//	@Param			PUB_KEY	header		string			true	"Public Key"
+//	@Security		BearerAuth
 //	@Param			Dentist	body		DentistPatch	true	"DentistResponse"
 //	@Success		200		{object}	DentistResponse
 //	@Failure		400		{object}	ErrorResponse
@@ -458,7 +508,7 @@ func (d *DentistHandler) Patch(ctx *gin.Context) {
 		License:  dentistToUpdate.License,
 	}
 
-	dentistUpdated, err = d.service.Patch(dentistUpdated)
+	dentistUpdated, err = d.service.Patch(principal(ctx), dentistUpdated)
 	if err != nil {
 		switch {
 		case errors.Is(err, internal.ErNotFound):
@@ -469,6 +519,14 @@ func (d *DentistHandler) Patch(ctx *gin.Context) {
 				Path:      ctx.Request.URL.Path,
 			})
 			return
+		case errors.Is(err, internal.ErLicenseBelongsToDeleted):
+			ctx.JSON(http.StatusConflict, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusConflict,
+				Message:   err.Error(),
+				Path:      ctx.Request.URL.Path,
+			})
+			return
 		case errors.Is(err, internal.ErLicenseAlreadyExists):
 			ctx.JSON(http.StatusConflict, ErrorResponse{
 				Timestamp: time.Now().Format(time.RFC3339),
@@ -500,14 +558,17 @@ func (d *DentistHandler) Patch(ctx *gin.Context) {
 // Delete function to delete a Dentist
 //
 //	@Summary		Delete a Dentist
-//	@Description	Delete a Dentist
+//	@Description	Delete a Dentist, upcoming appointments block the deletion unless the strategy is cascade, which deletes them too,
+//	@Description	or reassign, which moves them to the Dentist with the given license if they fit in its schedule
 //	@Tags			Dentist
-//	@security		APIKey
-//	@Param			PUB_KEY	header		string	true	"Public Key"
-//	@Param			id		path		int		true	"Dentist ID"
-//	@Failure		400		{object}	ErrorResponse
-//	@Failure		404		{object}	ErrorResponse
-//	@Failure		503		{object}	ErrorResponse
+//	@Security		BearerAuth
+//	@Param			id			path		int		true	"Dentist ID"
+//	@Param			strategy	query		string	false	"What happens to the upcoming appointments, reject by default"	Enums(reject, cascade, reassign)
+//	@Param			license		query		string	false	"License of the Dentist that gets the appointments with the reassign strategy"
+//	@Failure		400			{object}	ErrorResponse
+//	@Failure		404			{object}	ErrorResponse
+//	@Failure		409			{object}	ErrorResponse
+//	@Failure		503			{object}	ErrorResponse
 //	@Router			/dentists/{id} [delete]
 func (d *DentistHandler) Delete(ctx *gin.Context) {
 	idParam := ctx.Param("id")
@@ -532,9 +593,50 @@ func (d *DentistHandler) Delete(ctx *gin.Context) {
 		return
 	}
 
-	err = d.service.Delete(uint(id))
+	policy := appointment.DeletePolicy{
+		Strategy: appointment.DeleteStrategy(strings.ToLower(ctx.Query("strategy"))),
+		License:  ctx.Query("license"),
+	}
+
+	err = d.service.Delete(principal(ctx), uint(id), policy)
 	if err != nil {
 		switch {
+		case errors.Is(err, internal.ErInvalidStrategy), errors.Is(err, internal.ErReassignTarget):
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusBadRequest,
+				Message:   err.Error(),
+				Path:      ctx.Request.URL.Path,
+			})
+			return
+		case errors.Is(err, internal.ErHasAppointments):
+			var dependentErr *internal.DependentAppointmentsError
+			errors.As(err, &dependentErr)
+			ctx.JSON(http.StatusConflict, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusConflict,
+				Message:   fmt.Sprintf("dentist with id %d has %d upcoming appointments", id, dependentErr.Count),
+				Path:      ctx.Request.URL.Path,
+				Errors:    []string{"delete it with a strategy that handles the appointments, e.g. ?strategy=cascade or ?strategy=reassign&license="},
+			})
+			return
+		case errors.Is(err, internal.ErAppointmentConflict):
+			ctx.JSON(http.StatusConflict, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusConflict,
+				Message:   err.Error(),
+				Path:      ctx.Request.URL.Path,
+				Errors:    conflictErrors(err),
+			})
+			return
+		case errors.Is(err, internal.ErOutsideWorkingHours):
+			ctx.JSON(http.StatusConflict, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusConflict,
+				Message:   err.Error(),
+				Path:      ctx.Request.URL.Path,
+			})
+			return
 		case errors.Is(err, internal.ErNotFound):
 			ctx.JSON(http.StatusNotFound, ErrorResponse{
 				Timestamp: time.Now().Format(time.RFC3339),
@@ -556,3 +658,199 @@ func (d *DentistHandler) Delete(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusNoContent, nil)
 }
+
+// Restore function to restore a deleted Dentist
+//
+//	@Summary		Restore a Dentist
+//	@Description	Restore a deleted Dentist along with its working hours
+//	@Tags			Dentist
+//	@Security		BearerAuth
+//	@Param			id	path		int	true	"Dentist ID"
+//	@Success		200	{object}	DentistResponse
+//	@Failure		400	{object}	ErrorResponse
+//	@Failure		403	{object}	ErrorResponse
+//	@Failure		404	{object}	ErrorResponse
+//	@Failure		409	{object}	ErrorResponse
+//	@Failure		503	{object}	ErrorResponse
+//	@Router			/dentists/{id}/restore [post]
+func (d *DentistHandler) Restore(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   "id param must be a number greater than 0",
+			Path:      ctx.Request.URL.Path,
+		})
+		return
+	}
+
+	dentistRestored, err := d.service.Restore(principal(ctx), uint(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, internal.ErNotFound):
+			ctx.JSON(http.StatusNotFound, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusNotFound,
+				Message:   fmt.Sprintf("dentist with id %d %s", id, err.Error()),
+				Path:      ctx.Request.URL.Path,
+			})
+		case errors.Is(err, internal.ErNotDeleted):
+			ctx.JSON(http.StatusConflict, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusConflict,
+				Message:   fmt.Sprintf("dentist with id %d %s", id, err.Error()),
+				Path:      ctx.Request.URL.Path,
+			})
+		case errors.Is(err, internal.ErForbidden):
+			ctx.JSON(http.StatusForbidden, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusForbidden,
+				Message:   err.Error(),
+				Path:      ctx.Request.URL.Path,
+			})
+		default:
+			ctx.JSON(http.StatusServiceUnavailable, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusServiceUnavailable,
+				Message:   internal.ErServiceUnavailable.Error(),
+				Path:      ctx.Request.URL.Path,
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, dentistBody(dentistRestored, zone(ctx)))
+}
+
+func dentistBody(data dentist.Dentist, location *time.Location) DentistResponse {
+	body := DentistResponse{
+		Id:       data.ID,
+		Lastname: data.Lastname,
+		Name:     data.Name,
+		License:  data.License,
+	}
+	if data.DeletedAt.Valid {
+		body.DeletedAt = inZone(&data.DeletedAt.Time, location)
+	}
+	return body
+}
+
+// Export function to download Dentists as a file
+//
+//	@Summary		Export Dentists
+//	@Description	Download every Dentist matching the filters of the list as CSV, JSON Lines or XLSX, with a column per field of DentistResponse.
+//	@Description	The format is read from the format query param, or else from the Accept header, CSV by default. The file is streamed, so exports of any size are downloaded at once.
+//	@Tags			Dentist
+//	@Security		BearerAuth
+//	@Produce		text/csv
+//	@Produce		application/x-ndjson
+//	@Produce		application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+//	@Param			format			query		string	false	"csv, jsonl or xlsx"
+//	@Param			name			query		string	false	"Dentist name prefix"
+//	@Param			last_name		query		string	false	"Dentist last name prefix"
+//	@Param			license			query		string	false	"Dentist License"
+//	@Param			include_deleted	query		bool	false	"Also export deleted Dentists, only for admins"
+//	@Param			sort			query		string	false	"Fields to sort by, prefixed with '-' for descending order, e.g. last_name"
+//	@Success		200				{file}		file
+//	@Failure		400				{object}	ErrorResponse
+//	@Failure		403				{object}	ErrorResponse
+//	@Failure		503				{object}	ErrorResponse
+//	@Router			/dentists/export [get]
+func (d *DentistHandler) Export(ctx *gin.Context) {
+	filter, errs := dentistFilter(ctx)
+	sort, format, ok := exportQuery(ctx, dentist.SortColumns, errs)
+	if !ok {
+		return
+	}
+
+	writeExport(ctx, format, "dentists", jsonColumns(DentistResponse{}), func(write func(row interface{}) error) error {
+		fetch := func(page pagination.Request) (pagination.Page[dentist.Dentist], error) {
+			return d.service.GetAll(principal(ctx), filter, page)
+		}
+		return pagination.Each(sort, fetch, func(current dentist.Dentist) error {
+			return write(dentistBody(current, zone(ctx)))
+		})
+	})
+}
+
+// ExportTo writes every Dentist to w in the format, like Export without filters, for the export command
+func (d *DentistHandler) ExportTo(w io.Writer, format string, location *time.Location, includeDeleted bool) error {
+	fetch := func(page pagination.Request) (pagination.Page[dentist.Dentist], error) {
+		return d.service.GetAll(auth.System, dentist.Filter{IncludeDeleted: includeDeleted}, page)
+	}
+	return exportTo(w, format, "dentists", jsonColumns(DentistResponse{}), fetch, func(current dentist.Dentist) interface{} {
+		return dentistBody(current, location)
+	})
+}
+
+// Import function to create Dentists from a file
+//
+//	@Summary		Import Dentists
+//	@Description	Create the Dentists of a CSV file, with a header naming the columns like the fields of DentistPost, or of a JSON Lines file with a DentistPost per line.
+//	@Description	Every row is checked like in Create, licenses repeated in the file or already taken are rejected too.
+//	@Description	The Dentists are only created when every row is valid, otherwise the report lists the invalid rows by line; dry_run only checks the file.
+//	@Tags			Dentist
+//	@Security		BearerAuth
+//	@Accept			text/csv
+//	@Accept			application/x-ndjson
+//	@Param			dry_run	query		bool	false	"Check the file without creating the Dentists"
+//	@Param			format	query		string	false	"csv or jsonl, read from the Content-Type when it is empty"
+//	@Param			file	body		string	true	"CSV or JSON Lines file"
+//	@Success		200		{object}	ImportReportResponse
+//	@Success		201		{object}	ImportReportResponse
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		422		{object}	ImportReportResponse
+//	@Failure		503		{object}	ErrorResponse
+//	@Router			/dentists/import [post]
+func (d *DentistHandler) Import(ctx *gin.Context) {
+	rows, dryRun, ok := importQuery(ctx, jsonColumns(DentistPost{}))
+	if !ok {
+		return
+	}
+
+	report, err := d.service.Import(principal(ctx), func() (dentist.Dentist, int, error) {
+		row := DentistPost{}
+		line, err := rows.next(&row)
+		if err != nil {
+			return dentist.Dentist{}, line, err
+		}
+
+		errs := validateRow(row)
+		if len(errs) > 0 {
+			return dentist.Dentist{}, line, &bulk.RowError{Line: line, Errors: errs}
+		}
+
+		return dentist.Dentist{
+			Lastname: row.LastName,
+			Name:     row.Name,
+			License:  row.License,
+		}, line, nil
+	}, dryRun)
+	if err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusServiceUnavailable,
+			Message:   err.Error(),
+			Path:      ctx.Request.URL.Path,
+		})
+		return
+	}
+
+	writeImportReport(ctx, report)
+}
+
+// dentistFilter reads the filters of a list of dentists from the query params
+func dentistFilter(ctx *gin.Context) (dentist.Filter, []string) {
+	includeDeleted, err := queryBool(ctx, "include_deleted")
+	if err != nil {
+		return dentist.Filter{}, []string{"'include_deleted' query param must be true or false"}
+	}
+
+	return dentist.Filter{
+		Name:           strings.ToLower(strings.TrimSpace(ctx.Query("name"))),
+		Lastname:       strings.ToLower(strings.TrimSpace(ctx.Query("last_name"))),
+		License:        strings.ToLower(strings.TrimSpace(ctx.Query("license"))),
+		IncludeDeleted: includeDeleted,
+	}, nil
+}
@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/export"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/pagination"
+	"github.com/gin-gonic/gin"
+)
+
+// exportMediaTypes maps the media types an Accept header can ask for to the formats of the exports
+var exportMediaTypes = map[string]string{
+	"text/csv":                export.CSV,
+	"application/x-ndjson":    export.JSONL,
+	"application/jsonl":       export.JSONL,
+	"application/x-jsonlines": export.JSONL,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": export.XLSX,
+}
+
+// exportFormat reads the format from the format query param, or else from the first media type of the Accept
+// header that is a format, CSV when none is
+func exportFormat(ctx *gin.Context) (string, error) {
+	format := strings.ToLower(strings.TrimSpace(ctx.Query("format")))
+	if format != "" {
+		if !contains(export.Formats, format) {
+			return "", fmt.Errorf("'format' query param must be one of %v", export.Formats)
+		}
+		return format, nil
+	}
+
+	for _, accepted := range strings.Split(ctx.GetHeader("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+		if format, ok := exportMediaTypes[mediaType]; ok {
+			return format, nil
+		}
+	}
+	return export.Formats[0], nil
+}
+
+// exportValues returns the fields of a response struct in the order of its jsonColumns
+func exportValues(row interface{}) []interface{} {
+	rowValue := reflect.ValueOf(row)
+	rowType := rowValue.Type()
+	values := make([]interface{}, 0, rowType.NumField())
+	for i := 0; i < rowType.NumField(); i++ {
+		name, _, _ := strings.Cut(rowType.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			values = append(values, rowValue.Field(i).Interface())
+		}
+	}
+	return values
+}
+
+// writeExport streams the rows to a file named after the export, the rows are response structs passed to write.
+// The response only starts with the first row, so a failure before it is answered with an error, a failure
+// after it can only cut the file short
+func writeExport(ctx *gin.Context, format string, name string, columns []string, rows func(write func(row interface{}) error) error) {
+	var writer export.Writer
+	start := func() error {
+		filename := fmt.Sprintf("%s-%s.%s", name, time.Now().In(zone(ctx)).Format("20060102"), format)
+		ctx.Header("Content-Type", export.ContentTypes[format])
+		ctx.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+		ctx.Status(http.StatusOK)
+
+		var err error
+		writer, err = export.NewWriter(format, ctx.Writer, name, columns)
+		return err
+	}
+
+	err := rows(func(row interface{}) error {
+		if writer == nil {
+			err := start()
+			if err != nil {
+				return err
+			}
+		}
+		return writer.Write(exportValues(row))
+	})
+	if err == nil && writer == nil {
+		err = start()
+	}
+
+	switch {
+	case err != nil && !ctx.Writer.Written():
+		ctx.Writer.Header().Del("Content-Type")
+		ctx.Writer.Header().Del("Content-Disposition")
+		status := http.StatusServiceUnavailable
+		message := internal.ErServiceUnavailable.Error()
+		if errors.Is(err, internal.ErForbidden) {
+			status, message = http.StatusForbidden, err.Error()
+		}
+		ctx.JSON(status, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    status,
+			Message:   message,
+			Path:      ctx.Request.URL.Path,
+		})
+
+	case err != nil:
+		log.Printf("handler: export of %s stopped: %v", name, err)
+
+	default:
+		err = writer.Close()
+		if err != nil {
+			log.Printf("handler: export of %s not closed: %v", name, err)
+		}
+	}
+}
+
+// exportTo writes every item fetched to w in the format, the body of an item is its response struct. It is the
+// counterpart of writeExport outside of a request
+func exportTo[T any](w io.Writer, format string, name string, columns []string, fetch func(page pagination.Request) (pagination.Page[T], error), body func(item T) interface{}) error {
+	writer, err := export.NewWriter(format, w, name, columns)
+	if err != nil {
+		return err
+	}
+
+	err = pagination.Each(nil, fetch, func(item T) error {
+		return writer.Write(exportValues(body(item)))
+	})
+	if err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// exportQuery reads the sort and format query params along with the errors of the filters, writing the response
+// when they're invalid
+func exportQuery(ctx *gin.Context, sortColumns map[string]string, errs []string) ([]pagination.Sort, string, bool) {
+	sort, sortErrs := parseSort(ctx, sortColumns)
+	errs = append(errs, sortErrs...)
+
+	format, err := exportFormat(ctx)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	if len(errs) > 0 {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   "invalid query params",
+			Path:      ctx.Request.URL.Path,
+			Errors:    errs,
+		})
+		return nil, "", false
+	}
+	return sort, format, true
+}
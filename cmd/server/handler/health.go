@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	healthOK          = "ok"
+	healthFailed      = "failed"
+	healthUnavailable = "unavailable"
+	healthDraining    = "draining"
+)
+
+// HealthResponse model for, response the readiness of the server and of each dependency it checked
+type HealthResponse struct {
+	Status string                         `json:"status"`
+	Checks map[string]HealthCheckResponse `json:"checks,omitempty"`
+}
+
+// HealthCheckResponse model for, response the result of a check and how long it took
+type HealthCheckResponse struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+}
+
+// HealthCheck is a dependency the server needs to take requests, Check fails when it can't be used
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// HealthHandler answers the probes of the orchestrator, Live while the process runs and Ready while its
+// dependencies work and it isn't shutting down
+type HealthHandler struct {
+	checks   []HealthCheck
+	timeout  time.Duration
+	draining atomic.Bool
+}
+
+// NewHealthHandler returns a handler that runs the checks on every readiness probe, each one has timeout to answer
+func NewHealthHandler(timeout time.Duration, checks ...HealthCheck) *HealthHandler {
+	return &HealthHandler{checks: checks, timeout: timeout}
+}
+
+// Drain marks the server as shutting down, Ready fails from then on so no new traffic is routed to it
+func (h *HealthHandler) Drain() {
+	h.draining.Store(true)
+}
+
+// Live function to tell the process is running, it doesn't check the dependencies so a database outage doesn't
+// get the server restarted
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, HealthResponse{Status: healthOK})
+}
+
+// Ready function to tell the server can take requests, it runs every check at once and answers 503 when one fails
+// or the server is draining. The probe isn't authenticated so why a check failed is only logged
+func (h *HealthHandler) Ready(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	results := make([]HealthCheckResponse, len(h.checks))
+	var wg sync.WaitGroup
+	for i, check := range h.checks {
+		wg.Add(1)
+		go func(i int, check HealthCheck) {
+			defer wg.Done()
+			start := time.Now()
+			err := check.Check(ctx)
+			results[i] = HealthCheckResponse{
+				Status:    healthOK,
+				LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				results[i].Status = healthFailed
+				log.Printf("handler: readiness check %s failed: %v", check.Name, err)
+			}
+		}(i, check)
+	}
+	wg.Wait()
+
+	data := HealthResponse{Status: healthOK, Checks: map[string]HealthCheckResponse{}}
+	for i, check := range h.checks {
+		data.Checks[check.Name] = results[i]
+		if results[i].Status != healthOK {
+			data.Status = healthUnavailable
+		}
+	}
+	if h.draining.Load() {
+		data.Status = healthDraining
+	}
+
+	status := http.StatusOK
+	if data.Status != healthOK {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, data)
+}
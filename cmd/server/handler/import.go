@@ -0,0 +1,278 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/bulk"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Formats of the import files
+const (
+	formatCSV   = "csv"
+	formatJSONL = "jsonl"
+)
+
+// maxJSONLLine is the size of the longest line of a JSON Lines import, longer lines are rejected without being kept
+// in memory
+const maxJSONLLine = 64 * 1024
+
+// ImportReportResponse model for, response the outcome of an import
+type ImportReportResponse struct {
+	DryRun  bool             `json:"dry_run"`
+	Rows    int              `json:"rows"`
+	Invalid int              `json:"invalid"`
+	Created int              `json:"created"`
+	Errors  []RowErrorDetail `json:"errors,omitempty"`
+} //	@name	ImportReportResponse
+
+// RowErrorDetail model for, response why a row of an import is invalid
+type RowErrorDetail struct {
+	Line   int      `json:"line"`
+	Errors []string `json:"errors"`
+} //	@name	RowErrorDetail
+
+// rowReader reads the rows of an import file one at a time, so files of any size are streamed, as CSV with a
+// header naming the columns or as JSON Lines with an object per line
+type rowReader struct {
+	csv    *csv.Reader
+	header []string
+	lines  *bufio.Reader
+	line   int
+}
+
+// newRowReader reads the format from the format query param, or else from the Content-Type header, and checks the
+// CSV header against the columns
+func newRowReader(ctx *gin.Context, columns []string) (*rowReader, error) {
+	format := strings.ToLower(ctx.Query("format"))
+	if format == "" {
+		mediaType, _, _ := mime.ParseMediaType(ctx.GetHeader("Content-Type"))
+		switch mediaType {
+		case "text/csv":
+			format = formatCSV
+		case "application/x-ndjson", "application/jsonl", "application/x-jsonlines":
+			format = formatJSONL
+		}
+	}
+
+	switch format {
+	case formatCSV:
+		reader := csv.NewReader(ctx.Request.Body)
+		reader.TrimLeadingSpace = true
+		reader.ReuseRecord = true
+
+		header, err := reader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("the csv header can't be read: %w", err)
+		}
+
+		rows := &rowReader{csv: reader}
+		seen := map[string]bool{}
+		for i, column := range header {
+			if i == 0 {
+				column = strings.TrimPrefix(column, "\ufeff")
+			}
+			column = strings.ToLower(strings.TrimSpace(column))
+			if !contains(columns, column) {
+				return nil, fmt.Errorf("unknown column %q, the columns are %s", column, strings.Join(columns, ","))
+			}
+			if seen[column] {
+				return nil, fmt.Errorf("column %q is repeated", column)
+			}
+			seen[column] = true
+			rows.header = append(rows.header, column)
+		}
+		return rows, nil
+
+	case formatJSONL:
+		return &rowReader{lines: bufio.NewReader(ctx.Request.Body)}, nil
+
+	default:
+		return nil, errors.New("the file must be text/csv or application/x-ndjson, or set the format query param to csv or jsonl")
+	}
+}
+
+// next decodes the next row into target, it returns a *bulk.RowError when the row can't be read and io.EOF
+// at the end of the file
+func (r *rowReader) next(target interface{}) (int, error) {
+	if r.csv != nil {
+		return r.nextCSV(target)
+	}
+	return r.nextJSONL(target)
+}
+
+func (r *rowReader) nextCSV(target interface{}) (int, error) {
+	record, err := r.csv.Read()
+	if errors.Is(err, io.EOF) {
+		return 0, io.EOF
+	}
+
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return parseErr.StartLine, &bulk.RowError{Line: parseErr.StartLine, Errors: []string{parseErr.Err.Error()}}
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	line, _ := r.csv.FieldPos(0)
+	row := make(map[string]string, len(record))
+	for i, value := range record {
+		row[r.header[i]] = value
+	}
+
+	// The row goes through JSON so both formats fill the target the same way
+	encoded, err := json.Marshal(row)
+	if err != nil {
+		return line, &bulk.RowError{Line: line, Errors: []string{err.Error()}}
+	}
+	err = json.Unmarshal(encoded, target)
+	if err != nil {
+		return line, &bulk.RowError{Line: line, Errors: []string{err.Error()}}
+	}
+
+	return line, nil
+}
+
+func (r *rowReader) nextJSONL(target interface{}) (int, error) {
+	for {
+		raw, tooLong, err := r.readLine()
+		if err != nil && !errors.Is(err, io.EOF) {
+			return 0, err
+		}
+		r.line++
+
+		if tooLong {
+			return r.line, &bulk.RowError{Line: r.line, Errors: []string{fmt.Sprintf("the line is longer than %d bytes", maxJSONLLine)}}
+		}
+
+		raw = bytes.TrimSpace(raw)
+		if len(raw) == 0 {
+			if errors.Is(err, io.EOF) {
+				return 0, io.EOF
+			}
+			continue
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.DisallowUnknownFields()
+		decodeErr := decoder.Decode(target)
+		if decodeErr != nil {
+			return r.line, &bulk.RowError{Line: r.line, Errors: []string{fmt.Sprintf("invalid json: %s", decodeErr.Error())}}
+		}
+		return r.line, nil
+	}
+}
+
+// readLine reads up to the end of the next line, the lines longer than maxJSONLLine are read to their end but
+// discarded, so tooLong is set and only the next line is returned after them
+func (r *rowReader) readLine() (line []byte, tooLong bool, err error) {
+	for {
+		chunk, err := r.lines.ReadSlice('\n')
+		if len(line)+len(chunk) > maxJSONLLine {
+			tooLong = true
+			line = nil
+		}
+		if !tooLong {
+			line = append(line, chunk...)
+		}
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			return line, tooLong, err
+		}
+	}
+}
+
+// validateRow checks a row with the binding rules of its struct, the same ones the JSON endpoints apply
+func validateRow(row interface{}) []string {
+	err := binding.Validator.ValidateStruct(row)
+	if err == nil {
+		return nil
+	}
+
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return []string{err.Error()}
+	}
+
+	var errs []string
+	for _, err := range validationErrs {
+		errs = append(errs, fmt.Sprintf("%s field is %s", extractJSONTag(err.Field(), reflect.Indirect(reflect.ValueOf(row)).Interface()), err.Tag()))
+	}
+	return errs
+}
+
+// jsonColumns returns the json names of the fields of a struct, the columns of its import files
+func jsonColumns(row interface{}) []string {
+	rowType := reflect.TypeOf(row)
+	columns := make([]string, 0, rowType.NumField())
+	for i := 0; i < rowType.NumField(); i++ {
+		name, _, _ := strings.Cut(rowType.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			columns = append(columns, name)
+		}
+	}
+	return columns
+}
+
+// importQuery reads the dry_run query param and opens the rows of the file, writing the response when they're invalid
+func importQuery(ctx *gin.Context, columns []string) (*rowReader, bool, bool) {
+	dryRun, err := queryBool(ctx, "dry_run")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   "'dry_run' query param must be true or false",
+			Path:      ctx.Request.URL.Path,
+		})
+		return nil, false, false
+	}
+
+	rows, err := newRowReader(ctx, columns)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   "invalid file",
+			Path:      ctx.Request.URL.Path,
+			Errors:    []string{err.Error()},
+		})
+		return nil, false, false
+	}
+
+	return rows, dryRun, true
+}
+
+// writeImportReport answers 200 for dry runs, 201 when the rows were created and 422 when some are invalid
+func writeImportReport(ctx *gin.Context, report bulk.Report) {
+	body := ImportReportResponse{
+		DryRun:  report.DryRun,
+		Rows:    report.Rows,
+		Invalid: report.Invalid,
+		Created: report.Created,
+	}
+	for _, current := range report.Errors {
+		body.Errors = append(body.Errors, RowErrorDetail{Line: current.Line, Errors: current.Errors})
+	}
+
+	switch {
+	case report.Invalid > 0:
+		ctx.JSON(http.StatusUnprocessableEntity, body)
+	case report.DryRun:
+		ctx.JSON(http.StatusOK, body)
+	default:
+		ctx.JSON(http.StatusCreated, body)
+	}
+}
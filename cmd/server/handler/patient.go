@@ -3,11 +3,17 @@ package handler
 import (
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/appointment"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/auth"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/bulk"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/pagination"
 	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/patient"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
@@ -15,13 +21,14 @@ import (
 
 // PatientResponse model for, response a Patient
 type PatientResponse struct {
-	Id            uint      `json:"id"`
-	Name          string    `json:"name"`
-	LastName      string    `json:"last_name"`
-	Address       string    `json:"address"`
-	DNI           string    `json:"dni"`
-	Email         string    `json:"email"`
-	AdmissionDate time.Time `json:"admission_date"`
+	Id            uint       `json:"id"`
+	Name          string     `json:"name"`
+	LastName      string     `json:"last_name"`
+	Address       string     `json:"address"`
+	DNI           string     `json:"dni"`
+	Email         string     `json:"email"`
+	AdmissionDate time.Time  `json:"admission_date"`
+	DeletedAt     *time.Time `json:"deleted_at,omitempty"`
 } //	@name	PatientResponse
 
 // PatientPost model for creating a Patient
@@ -55,13 +62,15 @@ type PatientPatch struct {
 } //	@name	PatientPatch
 
 type PatientService interface {
-	GetAll() ([]patient.Patient, error)
-	GetByID(id uint) (patient.Patient, error)
-	GetByDNI(dni string) (patient.Patient, error)
-	Create(patient patient.Patient) (patient.Patient, error)
-	Update(patient patient.Patient) (patient.Patient, error)
-	Patch(patient patient.Patient) (patient.Patient, error)
-	Delete(id uint) error
+	GetAll(principal auth.Principal, filter patient.Filter, page pagination.Request) (pagination.Page[patient.Patient], error)
+	GetByID(principal auth.Principal, id uint) (patient.Patient, error)
+	GetByDNI(principal auth.Principal, dni string) (patient.Patient, error)
+	Create(principal auth.Principal, patient patient.Patient) (patient.Patient, error)
+	Update(principal auth.Principal, patient patient.Patient) (patient.Patient, error)
+	Patch(principal auth.Principal, patient patient.Patient) (patient.Patient, error)
+	Delete(principal auth.Principal, id uint, policy appointment.DeletePolicy) error
+	Restore(principal auth.Principal, id uint) (patient.Patient, error)
+	Import(principal auth.Principal, next func() (patient.Patient, int, error), dryRun bool) (bulk.Report, error)
 }
 
 type PatientHandler struct {
@@ -75,45 +84,67 @@ func NewPatientHandler(service PatientService) *PatientHandler {
 // GetAll function to get all Patients
 //
 //	@Summary		Get all Patients
-//	@Description	Get all Patients
+//	@Description	Get a page of Patients, filtered and sorted by the query params
 //	@Tags			Patient
-//	@Success		200	{array}		PatientResponse
-//	@Failure		503	{object}	ErrorResponse
+//	@Security		BearerAuth
+//	@Param			name			query		string	false	"Patient name prefix"
+//	@Param			last_name		query		string	false	"Patient last name prefix"
+//	@Param			dni				query		string	false	"Patient DNI"
+//	@Param			email			query		string	false	"Patient email"
+//	@Param			admitted_after	query		string	false	"Admitted on or after this date, in format RFC3339"
+//	@Param			admitted_before	query		string	false	"Admitted before this date, in format RFC3339"
+//	@Param			include_deleted	query		bool	false	"Also list deleted Patients, only for admins"
+//	@Param			sort			query		string	false	"Fields to sort by, prefixed with '-' for descending order, e.g. -admission_date"
+//	@Param			page			query		int		false	"Page number, starting at 1"
+//	@Param			size			query		int		false	"Page size, 20 by default and 100 at most"
+//	@Success		200				{object}	PageResponse[PatientResponse]
+//	@Failure		400				{object}	ErrorResponse
+//	@Failure		403				{object}	ErrorResponse
+//	@Failure		503				{object}	ErrorResponse
 //	@Router			/patients [get]
 func (p *PatientHandler) GetAll(ctx *gin.Context) {
-	patients, err := p.service.GetAll()
+	page, errs := parsePageRequest(ctx, patient.SortColumns)
+	filter, filterErrs := patientFilter(ctx)
+	errs = append(errs, filterErrs...)
+
+	if len(errs) > 0 {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   "invalid query params",
+			Path:      ctx.Request.URL.Path,
+			Errors:    errs,
+		})
+		return
+	}
+
+	patients, err := p.service.GetAll(principal(ctx), filter, page)
 	if err != nil {
-		if errors.Is(err, internal.ErServiceUnavailable) {
+		switch {
+		case errors.Is(err, internal.ErForbidden):
+			ctx.JSON(http.StatusForbidden, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusForbidden,
+				Message:   err.Error(),
+				Path:      ctx.Request.URL.Path,
+			})
+		default:
 			ctx.JSON(http.StatusServiceUnavailable, ErrorResponse{
 				Timestamp: time.Now().Format(time.RFC3339),
 				Status:    http.StatusServiceUnavailable,
-				Message:   err.Error(),
+				Message:   internal.ErServiceUnavailable.Error(),
 				Path:      ctx.Request.URL.Path,
 			})
-			return
 		}
 		return
 	}
 
-	if len(patients) == 0 {
-		ctx.JSON(http.StatusOK, patients)
-		return
-	}
-
 	var body []PatientResponse
-	for _, currentPatient := range patients {
-		body = append(body, PatientResponse{
-			Id:            currentPatient.ID,
-			Name:          currentPatient.Name,
-			LastName:      currentPatient.Lastname,
-			Address:       currentPatient.Address,
-			DNI:           currentPatient.DNI,
-			Email:         currentPatient.Email,
-			AdmissionDate: currentPatient.AdmissionDate,
-		})
+	for _, currentPatient := range patients.Items {
+		body = append(body, patientBody(currentPatient, zone(ctx)))
 	}
 
-	ctx.JSON(http.StatusOK, body)
+	ctx.JSON(http.StatusOK, newPageResponse(ctx, patients, body))
 }
 
 // GetByID function to get Patient by id
@@ -121,6 +152,7 @@ func (p *PatientHandler) GetAll(ctx *gin.Context) {
 //	@Summary		Get Patient by id
 //	@Description	Get Patient by id
 //	@Tags			Patient
+//	@Security		BearerAuth
 //	@Param			id	path		int	true	"Patient ID"
 //	@Success		200	{object}	PatientResponse
 //	@Failure		400	{object}	ErrorResponse
@@ -149,7 +181,7 @@ func (p *PatientHandler) GetById(ctx *gin.Context) {
 		return
 	}
 
-	data, err := p.service.GetByID(uint(id))
+	data, err := p.service.GetByID(principal(ctx), uint(id))
 	if err != nil {
 		if errors.Is(err, internal.ErNotFound) {
 			ctx.JSON(http.StatusNotFound, ErrorResponse{
@@ -171,7 +203,7 @@ func (p *PatientHandler) GetById(ctx *gin.Context) {
 		Address:       data.Address,
 		DNI:           data.DNI,
 		Email:         data.Email,
-		AdmissionDate: data.AdmissionDate,
+		AdmissionDate: data.AdmissionDate.In(zone(ctx)),
 	}
 
 	ctx.JSON(http.StatusOK, body)
@@ -182,6 +214,7 @@ func (p *PatientHandler) GetById(ctx *gin.Context) {
 //	@Summary		Get Patient by DNI
 //	@Description	Get Patient by DNI
 //	@Tags			Patient
+//	@Security		BearerAuth
 //	@Param			dni	query		string	true	"Patient DNI"
 //	@Success		200	{object}	PatientResponse
 //	@Failure		400	{object}	ErrorResponse
@@ -200,7 +233,7 @@ func (p *PatientHandler) GetByDNI(ctx *gin.Context) {
 		return
 	}
 
-	patientSearched, err := p.service.GetByDNI(dniQuery)
+	patientSearched, err := p.service.GetByDNI(principal(ctx), dniQuery)
 	if err != nil {
 		switch {
 		case errors.Is(err, internal.ErNotFound):
@@ -229,7 +262,7 @@ func (p *PatientHandler) GetByDNI(ctx *gin.Context) {
 		Address:       patientSearched.Address,
 		DNI:           patientSearched.DNI,
 		Email:         patientSearched.Email,
-		AdmissionDate: patientSearched.AdmissionDate,
+		AdmissionDate: patientSearched.AdmissionDate.In(zone(ctx)),
 	}
 
 	ctx.JSON(http.StatusOK, body)
@@ -240,8 +273,7 @@ func (p *PatientHandler) GetByDNI(ctx *gin.Context) {
 //	@Summary		Create a Patient
 //	@Description	Create a Patient
 //	@Tags			Patient
-//	@security		APIKey
-//	@Param			PUB_KEY	header		string	true		"Public Key"
+//	@Security		BearerAuth
 //	@Param			Patient	Body		body	PatientPost	true	"PatientResponse"
 //	@Success		201		{object}	PatientResponse
 //	@Failure		400		{object}	ErrorResponse
@@ -273,7 +305,7 @@ func (p *PatientHandler) Create(ctx *gin.Context) {
 	}
 
 	timeLayout := "RFC3339"
-	admissionDate, err := time.Parse(time.RFC3339, patientToPost.AdmissionDate)
+	admissionDate, err := parseTime(patientToPost.AdmissionDate)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, ErrorResponse{
 			Timestamp: time.Now().Format(time.RFC3339),
@@ -297,9 +329,17 @@ func (p *PatientHandler) Create(ctx *gin.Context) {
 		AdmissionDate: admissionDate,
 	}
 
-	patientCreated, err := p.service.Create(patientToCreate)
+	patientCreated, err := p.service.Create(principal(ctx), patientToCreate)
 	if err != nil {
 		switch {
+		case errors.Is(err, internal.ErDniBelongsToDeleted):
+			ctx.JSON(http.StatusConflict, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusConflict,
+				Message:   err.Error(),
+				Path:      ctx.Request.URL.Path,
+			})
+			return
 		case errors.Is(err, internal.ErDniAlreadyExists):
 			ctx.JSON(http.StatusConflict, ErrorResponse{
 				Timestamp: time.Now().Format(time.RFC3339),
@@ -326,7 +366,7 @@ func (p *PatientHandler) Create(ctx *gin.Context) {
 		Address:       patientCreated.Address,
 		DNI:           patientCreated.DNI,
 		Email:         patientCreated.Email,
-		AdmissionDate: patientCreated.AdmissionDate,
+		AdmissionDate: patientCreated.AdmissionDate.In(zone(ctx)),
 	}
 
 	ctx.JSON(http.StatusCreated, body)
@@ -337,8 +377,7 @@ func (p *PatientHandler) Create(ctx *gin.Context) {
 //	@Summary		Update a Patient
 //	@Description	Update a Patient
 //	@Tags			Patient
-//	@security		APIKey
-//	@Param			PUB_KEY	header		string	true		"Public Key"
+//	@Security		BearerAuth
 //	@Param			id		path		int		true		"Patient ID"
 //	@Param			Patient	Body		body	PatientPut	true	"PatientResponse"
 //	@Success		200		{object}	PatientResponse
@@ -391,7 +430,7 @@ func (p *PatientHandler) Update(ctx *gin.Context) {
 	}
 
 	timeLayout := "RFC3339"
-	admissionDate, err := time.Parse(time.RFC3339, patientToUpdate.AdmissionDate)
+	admissionDate, err := parseTime(patientToUpdate.AdmissionDate)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, ErrorResponse{
 			Timestamp: time.Now().Format(time.RFC3339),
@@ -416,7 +455,7 @@ func (p *PatientHandler) Update(ctx *gin.Context) {
 		AdmissionDate: admissionDate,
 	}
 
-	patientUpdated, err = p.service.Update(patientUpdated)
+	patientUpdated, err = p.service.Update(principal(ctx), patientUpdated)
 	if err != nil {
 		switch {
 		case errors.Is(err, internal.ErNotFound):
@@ -427,6 +466,14 @@ func (p *PatientHandler) Update(ctx *gin.Context) {
 				Path:      ctx.Request.URL.Path,
 			})
 			return
+		case errors.Is(err, internal.ErDniBelongsToDeleted):
+			ctx.JSON(http.StatusConflict, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusConflict,
+				Message:   err.Error(),
+				Path:      ctx.Request.URL.Path,
+			})
+			return
 		case errors.Is(err, internal.ErDniAlreadyExists):
 			ctx.JSON(http.StatusConflict, ErrorResponse{
 				Timestamp: time.Now().Format(time.RFC3339),
@@ -453,7 +500,7 @@ func (p *PatientHandler) Update(ctx *gin.Context) {
 		Address:       patientUpdated.Address,
 		DNI:           patientUpdated.DNI,
 		Email:         patientUpdated.Email,
-		AdmissionDate: patientUpdated.AdmissionDate,
+		AdmissionDate: patientUpdated.AdmissionDate.In(zone(ctx)),
 	}
 
 	ctx.JSON(http.StatusOK, body)
@@ -464,8 +511,7 @@ func (p *PatientHandler) Update(ctx *gin.Context) {
 //	@Summary		Patch a Patient
 //	@Description	Patch a Patient
 //	@Tags			Patient
-//	@security		APIKey
-//	@Param			PUB_KEY	header		string	true			"Public Key"
+//	@Security		BearerAuth
 //	@Param			id		path		int		true			"Patient ID"
 //	@Param			Patient	Body		body	PatientPatch	true	"PatientResponse"
 //	@Success		200		{object}	PatientResponse
@@ -519,7 +565,7 @@ func (p *PatientHandler) Patch(ctx *gin.Context) {
 	var admissionDate time.Time
 	if patientToUpdateParsed.AdmissionDate != "" {
 		timeLayout := "RFC3339"
-		admissionDate, err = time.Parse(time.RFC3339, patientToUpdateParsed.AdmissionDate)
+		admissionDate, err = parseTime(patientToUpdateParsed.AdmissionDate)
 		if err != nil {
 			ctx.JSON(http.StatusBadRequest, ErrorResponse{
 				Timestamp: time.Now().Format(time.RFC3339),
@@ -545,7 +591,7 @@ func (p *PatientHandler) Patch(ctx *gin.Context) {
 		AdmissionDate: admissionDate,
 	}
 
-	patientUpdated, err := p.service.Patch(patientToUpdate)
+	patientUpdated, err := p.service.Patch(principal(ctx), patientToUpdate)
 	if err != nil {
 		switch {
 		case errors.Is(err, internal.ErNotFound):
@@ -556,6 +602,14 @@ func (p *PatientHandler) Patch(ctx *gin.Context) {
 				Path:      ctx.Request.URL.Path,
 			})
 			return
+		case errors.Is(err, internal.ErDniBelongsToDeleted):
+			ctx.JSON(http.StatusConflict, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusConflict,
+				Message:   err.Error(),
+				Path:      ctx.Request.URL.Path,
+			})
+			return
 		case errors.Is(err, internal.ErDniAlreadyExists):
 			ctx.JSON(http.StatusConflict, ErrorResponse{
 				Timestamp: time.Now().Format(time.RFC3339),
@@ -582,7 +636,7 @@ func (p *PatientHandler) Patch(ctx *gin.Context) {
 		Address:       patientUpdated.Address,
 		DNI:           patientUpdated.DNI,
 		Email:         patientUpdated.Email,
-		AdmissionDate: patientUpdated.AdmissionDate,
+		AdmissionDate: patientUpdated.AdmissionDate.In(zone(ctx)),
 	}
 
 	ctx.JSON(http.StatusOK, body)
@@ -591,14 +645,15 @@ func (p *PatientHandler) Patch(ctx *gin.Context) {
 // Delete function to delete a Patient
 //
 //	@Summary		Delete a Patient
-//	@Description	Delete a Patient
+//	@Description	Delete a Patient, upcoming appointments block the deletion unless the strategy is cascade, which deletes them too
 //	@Tags			Patient
-//	@security		APIKey
-//	@Param			PUB_KEY	header		string	true	"Public Key"
-//	@Param			id		path		int		true	"Patient ID"
-//	@Failure		400		{object}	ErrorResponse
-//	@Failure		404		{object}	ErrorResponse
-//	@Failure		503		{object}	ErrorResponse
+//	@Security		BearerAuth
+//	@Param			id			path		int		true	"Patient ID"
+//	@Param			strategy	query		string	false	"What happens to the upcoming appointments, reject by default"	Enums(reject, cascade)
+//	@Failure		400			{object}	ErrorResponse
+//	@Failure		404			{object}	ErrorResponse
+//	@Failure		409			{object}	ErrorResponse
+//	@Failure		503			{object}	ErrorResponse
 //	@Router			/patients/{id} [delete]
 func (p *PatientHandler) Delete(ctx *gin.Context) {
 	idParam := ctx.Param("id")
@@ -623,9 +678,32 @@ func (p *PatientHandler) Delete(ctx *gin.Context) {
 		return
 	}
 
-	err = p.service.Delete(uint(id))
+	policy := appointment.DeletePolicy{
+		Strategy: appointment.DeleteStrategy(strings.ToLower(ctx.Query("strategy"))),
+	}
+
+	err = p.service.Delete(principal(ctx), uint(id), policy)
 	if err != nil {
 		switch {
+		case errors.Is(err, internal.ErInvalidStrategy), errors.Is(err, internal.ErReassignTarget):
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusBadRequest,
+				Message:   err.Error(),
+				Path:      ctx.Request.URL.Path,
+			})
+			return
+		case errors.Is(err, internal.ErHasAppointments):
+			var dependentErr *internal.DependentAppointmentsError
+			errors.As(err, &dependentErr)
+			ctx.JSON(http.StatusConflict, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusConflict,
+				Message:   fmt.Sprintf("patient with id %d has %d upcoming appointments", id, dependentErr.Count),
+				Path:      ctx.Request.URL.Path,
+				Errors:    []string{"delete it with a strategy that handles the appointments, e.g. ?strategy=cascade"},
+			})
+			return
 		case errors.Is(err, internal.ErNotFound):
 			ctx.JSON(http.StatusNotFound, ErrorResponse{
 				Timestamp: time.Now().Format(time.RFC3339),
@@ -647,3 +725,230 @@ func (p *PatientHandler) Delete(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusNoContent, nil)
 }
+
+// Restore function to restore a deleted Patient
+//
+//	@Summary		Restore a Patient
+//	@Description	Restore a deleted Patient
+//	@Tags			Patient
+//	@Security		BearerAuth
+//	@Param			id	path		int	true	"Patient ID"
+//	@Success		200	{object}	PatientResponse
+//	@Failure		400	{object}	ErrorResponse
+//	@Failure		403	{object}	ErrorResponse
+//	@Failure		404	{object}	ErrorResponse
+//	@Failure		409	{object}	ErrorResponse
+//	@Failure		503	{object}	ErrorResponse
+//	@Router			/patients/{id}/restore [post]
+func (p *PatientHandler) Restore(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   "id param must be a number greater than 0",
+			Path:      ctx.Request.URL.Path,
+		})
+		return
+	}
+
+	patientRestored, err := p.service.Restore(principal(ctx), uint(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, internal.ErNotFound):
+			ctx.JSON(http.StatusNotFound, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusNotFound,
+				Message:   fmt.Sprintf("patient with id %d %s", id, err.Error()),
+				Path:      ctx.Request.URL.Path,
+			})
+		case errors.Is(err, internal.ErNotDeleted):
+			ctx.JSON(http.StatusConflict, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusConflict,
+				Message:   fmt.Sprintf("patient with id %d %s", id, err.Error()),
+				Path:      ctx.Request.URL.Path,
+			})
+		case errors.Is(err, internal.ErForbidden):
+			ctx.JSON(http.StatusForbidden, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusForbidden,
+				Message:   err.Error(),
+				Path:      ctx.Request.URL.Path,
+			})
+		default:
+			ctx.JSON(http.StatusServiceUnavailable, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusServiceUnavailable,
+				Message:   internal.ErServiceUnavailable.Error(),
+				Path:      ctx.Request.URL.Path,
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, patientBody(patientRestored, zone(ctx)))
+}
+
+func patientBody(data patient.Patient, location *time.Location) PatientResponse {
+	body := PatientResponse{
+		Id:            data.ID,
+		Name:          data.Name,
+		LastName:      data.Lastname,
+		Address:       data.Address,
+		DNI:           data.DNI,
+		Email:         data.Email,
+		AdmissionDate: data.AdmissionDate.In(location),
+	}
+	if data.DeletedAt.Valid {
+		body.DeletedAt = inZone(&data.DeletedAt.Time, location)
+	}
+	return body
+}
+
+// Export function to download Patients as a file
+//
+//	@Summary		Export Patients
+//	@Description	Download every Patient matching the filters of the list as CSV, JSON Lines or XLSX, with a column per field of PatientResponse.
+//	@Description	The format is read from the format query param, or else from the Accept header, CSV by default. The file is streamed, so exports of any size are downloaded at once.
+//	@Tags			Patient
+//	@Security		BearerAuth
+//	@Produce		text/csv
+//	@Produce		application/x-ndjson
+//	@Produce		application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+//	@Param			format			query		string	false	"csv, jsonl or xlsx"
+//	@Param			name			query		string	false	"Patient name prefix"
+//	@Param			last_name		query		string	false	"Patient last name prefix"
+//	@Param			dni				query		string	false	"Patient DNI"
+//	@Param			email			query		string	false	"Patient email"
+//	@Param			admitted_after	query		string	false	"Admitted on or after this date, in format RFC3339"
+//	@Param			admitted_before	query		string	false	"Admitted before this date, in format RFC3339"
+//	@Param			include_deleted	query		bool	false	"Also export deleted Patients, only for admins"
+//	@Param			sort			query		string	false	"Fields to sort by, prefixed with '-' for descending order, e.g. -admission_date"
+//	@Success		200				{file}		file
+//	@Failure		400				{object}	ErrorResponse
+//	@Failure		403				{object}	ErrorResponse
+//	@Failure		503				{object}	ErrorResponse
+//	@Router			/patients/export [get]
+func (p *PatientHandler) Export(ctx *gin.Context) {
+	filter, errs := patientFilter(ctx)
+	sort, format, ok := exportQuery(ctx, patient.SortColumns, errs)
+	if !ok {
+		return
+	}
+
+	writeExport(ctx, format, "patients", jsonColumns(PatientResponse{}), func(write func(row interface{}) error) error {
+		fetch := func(page pagination.Request) (pagination.Page[patient.Patient], error) {
+			return p.service.GetAll(principal(ctx), filter, page)
+		}
+		return pagination.Each(sort, fetch, func(current patient.Patient) error {
+			return write(patientBody(current, zone(ctx)))
+		})
+	})
+}
+
+// ExportTo writes every Patient to w in the format, like Export without filters, for the export command
+func (p *PatientHandler) ExportTo(w io.Writer, format string, location *time.Location, includeDeleted bool) error {
+	fetch := func(page pagination.Request) (pagination.Page[patient.Patient], error) {
+		return p.service.GetAll(auth.System, patient.Filter{IncludeDeleted: includeDeleted}, page)
+	}
+	return exportTo(w, format, "patients", jsonColumns(PatientResponse{}), fetch, func(current patient.Patient) interface{} {
+		return patientBody(current, location)
+	})
+}
+
+// Import function to create Patients from a file
+//
+//	@Summary		Import Patients
+//	@Description	Create the Patients of a CSV file, with a header naming the columns like the fields of PatientPost, or of a JSON Lines file with a PatientPost per line.
+//	@Description	Every row is checked like in Create, dnis repeated in the file or already taken are rejected too.
+//	@Description	The Patients are only created when every row is valid, otherwise the report lists the invalid rows by line; dry_run only checks the file.
+//	@Tags			Patient
+//	@Security		BearerAuth
+//	@Accept			text/csv
+//	@Accept			application/x-ndjson
+//	@Param			dry_run	query		bool	false	"Check the file without creating the Patients"
+//	@Param			format	query		string	false	"csv or jsonl, read from the Content-Type when it is empty"
+//	@Param			file	body		string	true	"CSV or JSON Lines file"
+//	@Success		200		{object}	ImportReportResponse
+//	@Success		201		{object}	ImportReportResponse
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		422		{object}	ImportReportResponse
+//	@Failure		503		{object}	ErrorResponse
+//	@Router			/patients/import [post]
+func (p *PatientHandler) Import(ctx *gin.Context) {
+	rows, dryRun, ok := importQuery(ctx, jsonColumns(PatientPost{}))
+	if !ok {
+		return
+	}
+
+	report, err := p.service.Import(principal(ctx), func() (patient.Patient, int, error) {
+		row := PatientPost{}
+		line, err := rows.next(&row)
+		if err != nil {
+			return patient.Patient{}, line, err
+		}
+
+		errs := validateRow(row)
+		var admissionDate time.Time
+		if row.AdmissionDate != "" {
+			admissionDate, err = parseTime(row.AdmissionDate)
+			if err != nil {
+				errs = append(errs, "admission_date field must be in format RFC3339")
+			}
+		}
+		if len(errs) > 0 {
+			return patient.Patient{}, line, &bulk.RowError{Line: line, Errors: errs}
+		}
+
+		return patient.Patient{
+			Name:          row.Name,
+			Lastname:      row.LastName,
+			Address:       row.Address,
+			DNI:           row.DNI,
+			Email:         row.Email,
+			AdmissionDate: admissionDate,
+		}, line, nil
+	}, dryRun)
+	if err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusServiceUnavailable,
+			Message:   err.Error(),
+			Path:      ctx.Request.URL.Path,
+		})
+		return
+	}
+
+	writeImportReport(ctx, report)
+}
+
+// patientFilter reads the filters of a list of patients from the query params
+func patientFilter(ctx *gin.Context) (patient.Filter, []string) {
+	var errs []string
+
+	admittedAfter, err := queryTime(ctx, "admitted_after")
+	if err != nil {
+		errs = append(errs, "'admitted_after' query param must be in format RFC3339")
+	}
+
+	admittedBefore, err := queryTime(ctx, "admitted_before")
+	if err != nil {
+		errs = append(errs, "'admitted_before' query param must be in format RFC3339")
+	}
+
+	includeDeleted, err := queryBool(ctx, "include_deleted")
+	if err != nil {
+		errs = append(errs, "'include_deleted' query param must be true or false")
+	}
+
+	return patient.Filter{
+		Name:           strings.ToLower(strings.TrimSpace(ctx.Query("name"))),
+		Lastname:       strings.ToLower(strings.TrimSpace(ctx.Query("last_name"))),
+		DNI:            strings.ToLower(strings.TrimSpace(ctx.Query("dni"))),
+		Email:          strings.ToLower(strings.TrimSpace(ctx.Query("email"))),
+		AdmittedAfter:  admittedAfter,
+		AdmittedBefore: admittedBefore,
+		IncludeDeleted: includeDeleted,
+	}, errs
+}
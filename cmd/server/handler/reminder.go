@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/reminder"
+	"github.com/gin-gonic/gin"
+)
+
+// ReminderResponse model for, response a Reminder sent for an Appointment
+type ReminderResponse struct {
+	Id            uint       `json:"id"`
+	AppointmentID uint       `json:"appointment_id"`
+	Date          time.Time  `json:"date"`
+	Offset        string     `json:"offset"`
+	Channel       string     `json:"channel"`
+	Recipient     string     `json:"recipient"`
+	Status        string     `json:"status"`
+	Attempts      uint       `json:"attempts"`
+	Error         string     `json:"error,omitempty"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+} //	@name	ReminderResponse
+
+type ReminderService interface {
+	GetByAppointment(id uint) ([]reminder.Reminder, error)
+}
+
+type ReminderHandler struct {
+	service            ReminderService
+	appointmentService AppointmentService
+}
+
+func NewReminderHandler(service ReminderService, appointment AppointmentService) *ReminderHandler {
+	return &ReminderHandler{service: service, appointmentService: appointment}
+}
+
+// GetByAppointment function to get the Reminders of an Appointment
+//
+//	@Summary		Get the Reminders of an Appointment
+//	@Description	Get the Reminders sent for an Appointment, date is the date of the Appointment each one was sent for
+//	@Tags			Appointment
+//	@Security		BearerAuth
+//	@Param			id	path		int	true	"Appointment ID"
+//	@Success		200	{array}		ReminderResponse
+//	@Failure		400	{object}	ErrorResponse
+//	@Failure		404	{object}	ErrorResponse
+//	@Failure		503	{object}	ErrorResponse
+//	@Router			/appointments/{id}/reminders [get]
+func (r *ReminderHandler) GetByAppointment(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   "id param must be a number greater than 0",
+			Path:      ctx.Request.URL.Path,
+		})
+		return
+	}
+
+	// Looking the appointment up checks that the principal can see it
+	_, err = r.appointmentService.GetByID(principal(ctx), uint(id))
+	if err != nil {
+		reminderError(ctx, err, uint(id))
+		return
+	}
+
+	reminders, err := r.service.GetByAppointment(uint(id))
+	if err != nil {
+		reminderError(ctx, err, uint(id))
+		return
+	}
+
+	body := make([]ReminderResponse, 0, len(reminders))
+	for _, currentReminder := range reminders {
+		body = append(body, reminderBody(currentReminder, zone(ctx)))
+	}
+
+	ctx.JSON(http.StatusOK, body)
+}
+
+func reminderError(ctx *gin.Context, err error, id uint) {
+	if errors.Is(err, internal.ErNotFound) {
+		ctx.JSON(http.StatusNotFound, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusNotFound,
+			Message:   fmt.Sprintf("appointment with id %d %s", id, err.Error()),
+			Path:      ctx.Request.URL.Path,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusServiceUnavailable, ErrorResponse{
+		Timestamp: time.Now().Format(time.RFC3339),
+		Status:    http.StatusServiceUnavailable,
+		Message:   internal.ErServiceUnavailable.Error(),
+		Path:      ctx.Request.URL.Path,
+	})
+}
+
+func reminderBody(data reminder.Reminder, location *time.Location) ReminderResponse {
+	return ReminderResponse{
+		Id:            data.ID,
+		AppointmentID: data.AppointmentID,
+		Date:          data.Date.In(location),
+		Offset:        (time.Duration(data.OffsetMinutes) * time.Minute).String(),
+		Channel:       data.Channel,
+		Recipient:     data.Recipient,
+		Status:        string(data.Status),
+		Attempts:      data.Attempts,
+		Error:         data.Error,
+		SentAt:        inZone(data.SentAt, location),
+	}
+}
@@ -7,3 +7,19 @@ type ErrorResponse struct {
 	Path      string   `json:"path"`
 	Errors    []string `json:"errors,omitempty"`
 } // @name ErrorResponse
+
+// PageResponse model for, response a page of a list
+type PageResponse[T any] struct {
+	Items []T       `json:"items"`
+	Total int64     `json:"total"`
+	Page  int       `json:"page"`
+	Size  int       `json:"size"`
+	Links PageLinks `json:"links"`
+} //	@name	PageResponse
+
+// PageLinks model for, response the links to the pages next to the current one
+type PageLinks struct {
+	Self string `json:"self"`
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+} //	@name	PageLinks
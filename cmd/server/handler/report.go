@@ -0,0 +1,365 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/auth"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/report"
+	"github.com/gin-gonic/gin"
+)
+
+// CountsResponse model for, response the Appointments in each status with the cancellation and no-show rates
+type CountsResponse struct {
+	Total            int64   `json:"total"`
+	Scheduled        int64   `json:"scheduled"`
+	Confirmed        int64   `json:"confirmed"`
+	CheckedIn        int64   `json:"checked_in"`
+	Completed        int64   `json:"completed"`
+	Cancelled        int64   `json:"cancelled"`
+	NoShow           int64   `json:"no_show"`
+	CancellationRate float64 `json:"cancellation_rate"`
+	NoShowRate       float64 `json:"no_show_rate"`
+} //	@name	CountsResponse
+
+// UtilizationResponse model for, response the booked and available hours of a Dentist
+type UtilizationResponse struct {
+	DentistID      uint    `json:"dentist_id"`
+	Name           string  `json:"name"`
+	LastName       string  `json:"last_name"`
+	BookedHours    float64 `json:"booked_hours"`
+	AvailableHours float64 `json:"available_hours"`
+	Utilization    float64 `json:"utilization"`
+} //	@name	UtilizationResponse
+
+// UtilizationReportResponse model for, response the utilization of the Dentists and of the clinic
+type UtilizationReportResponse struct {
+	From           time.Time             `json:"from"`
+	To             time.Time             `json:"to"`
+	BookedHours    float64               `json:"booked_hours"`
+	AvailableHours float64               `json:"available_hours"`
+	Utilization    float64               `json:"utilization"`
+	Dentists       []UtilizationResponse `json:"dentists"`
+} //	@name	UtilizationReportResponse
+
+// AppointmentBucketResponse model for, response the Appointments starting in a period
+type AppointmentBucketResponse struct {
+	Start  time.Time      `json:"start"`
+	Counts CountsResponse `json:"counts"`
+} //	@name	AppointmentBucketResponse
+
+// AppointmentReportResponse model for, response the Appointments by period
+type AppointmentReportResponse struct {
+	From    time.Time                   `json:"from"`
+	To      time.Time                   `json:"to"`
+	Period  string                      `json:"period"`
+	Total   CountsResponse              `json:"total"`
+	Buckets []AppointmentBucketResponse `json:"buckets"`
+} //	@name	AppointmentReportResponse
+
+// AttendanceResponse model for, response the Appointments of a Dentist
+type AttendanceResponse struct {
+	DentistID uint           `json:"dentist_id"`
+	Name      string         `json:"name"`
+	LastName  string         `json:"last_name"`
+	Counts    CountsResponse `json:"counts"`
+} //	@name	AttendanceResponse
+
+// AttendanceReportResponse model for, response the Appointments by Dentist
+type AttendanceReportResponse struct {
+	From     time.Time            `json:"from"`
+	To       time.Time            `json:"to"`
+	Total    CountsResponse       `json:"total"`
+	Dentists []AttendanceResponse `json:"dentists"`
+} //	@name	AttendanceReportResponse
+
+// AdmissionBucketResponse model for, response the Patients admitted in a period
+type AdmissionBucketResponse struct {
+	Start    time.Time `json:"start"`
+	Patients int64     `json:"patients"`
+} //	@name	AdmissionBucketResponse
+
+// AdmissionReportResponse model for, response the Patients admitted by period
+type AdmissionReportResponse struct {
+	From    time.Time                 `json:"from"`
+	To      time.Time                 `json:"to"`
+	Period  string                    `json:"period"`
+	Total   int64                     `json:"total"`
+	Buckets []AdmissionBucketResponse `json:"buckets"`
+} //	@name	AdmissionReportResponse
+
+type ReportService interface {
+	Utilization(principal auth.Principal, filter report.Filter) ([]report.Utilization, error)
+	Appointments(principal auth.Principal, filter report.Filter, period report.Period) ([]report.Bucket, error)
+	Attendance(principal auth.Principal, filter report.Filter) ([]report.DentistCounts, error)
+	Admissions(principal auth.Principal, filter report.Filter, period report.Period) ([]report.Admissions, error)
+}
+
+type ReportHandler struct {
+	service ReportService
+}
+
+func NewReportHandler(service ReportService) *ReportHandler {
+	return &ReportHandler{service: service}
+}
+
+// Utilization function to get the utilization of the Dentists
+//
+//	@Summary		Get the utilization of the Dentists
+//	@Description	Get the hours each Dentist had booked between from and to against the hours of its current working hours, cancelled Appointments aren't booked hours.
+//	@Description	Utilization is booked over available hours, it goes over 1 with Appointments outside the working hours. Dentists only get their own.
+//	@Tags			Report
+//	@Security		BearerAuth
+//	@Param			from		query		string	true	"Start of the report, in format RFC3339"
+//	@Param			to			query		string	true	"End of the report, in format RFC3339"
+//	@Param			dentist_id	query		int		false	"Dentist ID"
+//	@Success		200			{object}	UtilizationReportResponse
+//	@Failure		400			{object}	ErrorResponse
+//	@Failure		403			{object}	ErrorResponse
+//	@Failure		503			{object}	ErrorResponse
+//	@Router			/reports/utilization [get]
+func (r *ReportHandler) Utilization(ctx *gin.Context) {
+	filter, _, ok := reportQuery(ctx, "")
+	if !ok {
+		return
+	}
+
+	utilization, err := r.service.Utilization(principal(ctx), filter)
+	if err != nil {
+		reportError(ctx, err)
+		return
+	}
+
+	var total report.Utilization
+	body := UtilizationReportResponse{From: filter.From.In(zone(ctx)), To: filter.To.In(zone(ctx)), Dentists: []UtilizationResponse{}}
+	for _, current := range utilization {
+		total.Booked += current.Booked
+		total.Available += current.Available
+		body.Dentists = append(body.Dentists, UtilizationResponse{
+			DentistID:      current.DentistID,
+			Name:           current.Name,
+			LastName:       current.Lastname,
+			BookedHours:    round(current.Booked.Hours(), 2),
+			AvailableHours: round(current.Available.Hours(), 2),
+			Utilization:    round(current.Rate(), 4),
+		})
+	}
+	body.BookedHours = round(total.Booked.Hours(), 2)
+	body.AvailableHours = round(total.Available.Hours(), 2)
+	body.Utilization = round(total.Rate(), 4)
+
+	ctx.JSON(http.StatusOK, body)
+}
+
+// Appointments function to count the Appointments by period
+//
+//	@Summary		Count the Appointments by period
+//	@Description	Count the Appointments by status and by the day, week or month they start in, in the time zone of the request, with the cancellation and no-show rates.
+//	@Description	Every period of the range has a bucket, weeks start on Monday. The no-show rate leaves out the cancelled and upcoming Appointments. Dentists only get their own.
+//	@Tags			Report
+//	@Security		BearerAuth
+//	@Param			from		query		string	true	"Start of the report, in format RFC3339"
+//	@Param			to			query		string	true	"End of the report, in format RFC3339"
+//	@Param			period		query		string	false	"day, week or month, day by default"
+//	@Param			dentist_id	query		int		false	"Dentist ID"
+//	@Success		200			{object}	AppointmentReportResponse
+//	@Failure		400			{object}	ErrorResponse
+//	@Failure		403			{object}	ErrorResponse
+//	@Failure		503			{object}	ErrorResponse
+//	@Router			/reports/appointments [get]
+func (r *ReportHandler) Appointments(ctx *gin.Context) {
+	filter, period, ok := reportQuery(ctx, report.PeriodDay)
+	if !ok {
+		return
+	}
+
+	buckets, err := r.service.Appointments(principal(ctx), filter, period)
+	if err != nil {
+		reportError(ctx, err)
+		return
+	}
+
+	var total report.Counts
+	body := AppointmentReportResponse{From: filter.From.In(zone(ctx)), To: filter.To.In(zone(ctx)), Period: string(period), Buckets: []AppointmentBucketResponse{}}
+	for _, current := range buckets {
+		total.Merge(current.Counts)
+		body.Buckets = append(body.Buckets, AppointmentBucketResponse{Start: current.Start, Counts: countsBody(current.Counts)})
+	}
+	body.Total = countsBody(total)
+
+	ctx.JSON(http.StatusOK, body)
+}
+
+// Attendance function to get the cancellation and no-show rates of the Dentists
+//
+//	@Summary		Get the cancellation and no-show rates of the Dentists
+//	@Description	Count the Appointments starting between from and to by Dentist and status, with the cancellation and no-show rates.
+//	@Description	The no-show rate leaves out the cancelled and upcoming Appointments. Dentists only get their own.
+//	@Tags			Report
+//	@Security		BearerAuth
+//	@Param			from		query		string	true	"Start of the report, in format RFC3339"
+//	@Param			to			query		string	true	"End of the report, in format RFC3339"
+//	@Param			dentist_id	query		int		false	"Dentist ID"
+//	@Success		200			{object}	AttendanceReportResponse
+//	@Failure		400			{object}	ErrorResponse
+//	@Failure		403			{object}	ErrorResponse
+//	@Failure		503			{object}	ErrorResponse
+//	@Router			/reports/attendance [get]
+func (r *ReportHandler) Attendance(ctx *gin.Context) {
+	filter, _, ok := reportQuery(ctx, "")
+	if !ok {
+		return
+	}
+
+	dentists, err := r.service.Attendance(principal(ctx), filter)
+	if err != nil {
+		reportError(ctx, err)
+		return
+	}
+
+	var total report.Counts
+	body := AttendanceReportResponse{From: filter.From.In(zone(ctx)), To: filter.To.In(zone(ctx)), Dentists: []AttendanceResponse{}}
+	for _, current := range dentists {
+		total.Merge(current.Counts)
+		body.Dentists = append(body.Dentists, AttendanceResponse{
+			DentistID: current.DentistID,
+			Name:      current.Name,
+			LastName:  current.Lastname,
+			Counts:    countsBody(current.Counts),
+		})
+	}
+	body.Total = countsBody(total)
+
+	ctx.JSON(http.StatusOK, body)
+}
+
+// Admissions function to count the new Patients by period
+//
+//	@Summary		Count the new Patients by period
+//	@Description	Count the Patients by the day, week or month of their admission date, in the time zone of the request, deleted Patients included.
+//	@Description	Every period of the range has a bucket, weeks start on Monday. Only for admins and receptionists.
+//	@Tags			Report
+//	@Security		BearerAuth
+//	@Param			from	query		string	true	"Start of the report, in format RFC3339"
+//	@Param			to		query		string	true	"End of the report, in format RFC3339"
+//	@Param			period	query		string	false	"day, week or month, month by default"
+//	@Success		200		{object}	AdmissionReportResponse
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		403		{object}	ErrorResponse
+//	@Failure		503		{object}	ErrorResponse
+//	@Router			/reports/admissions [get]
+func (r *ReportHandler) Admissions(ctx *gin.Context) {
+	filter, period, ok := reportQuery(ctx, report.PeriodMonth)
+	if !ok {
+		return
+	}
+
+	admissions, err := r.service.Admissions(principal(ctx), filter, period)
+	if err != nil {
+		reportError(ctx, err)
+		return
+	}
+
+	body := AdmissionReportResponse{From: filter.From.In(zone(ctx)), To: filter.To.In(zone(ctx)), Period: string(period), Buckets: []AdmissionBucketResponse{}}
+	for _, current := range admissions {
+		body.Total += current.Patients
+		body.Buckets = append(body.Buckets, AdmissionBucketResponse{Start: current.Start, Patients: current.Patients})
+	}
+
+	ctx.JSON(http.StatusOK, body)
+}
+
+// reportQuery reads the range, the dentist and, for the reports by period, the period of a report, writing the
+// response when they're invalid. An empty defaultPeriod means the report isn't by period
+func reportQuery(ctx *gin.Context, defaultPeriod report.Period) (report.Filter, report.Period, bool) {
+	var errs []string
+
+	from, err := queryTime(ctx, "from")
+	if err != nil || from.IsZero() {
+		errs = append(errs, "'from' query param is required in format RFC3339")
+	}
+
+	to, err := queryTime(ctx, "to")
+	if err != nil || to.IsZero() {
+		errs = append(errs, "'to' query param is required in format RFC3339")
+	}
+
+	dentistID, err := queryUint(ctx, "dentist_id")
+	if err != nil {
+		errs = append(errs, "'dentist_id' query param must be a number greater than 0")
+	}
+
+	period := defaultPeriod
+	if raw := strings.ToLower(strings.TrimSpace(ctx.Query("period"))); raw != "" && defaultPeriod != "" {
+		period = report.Period(raw)
+		if !period.Valid() {
+			errs = append(errs, fmt.Sprintf("'period' query param must be one of %v", report.Periods))
+		}
+	}
+
+	if len(errs) > 0 {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   "invalid query params",
+			Path:      ctx.Request.URL.Path,
+			Errors:    errs,
+		})
+		return report.Filter{}, "", false
+	}
+
+	return report.Filter{From: from, To: to, DentistID: dentistID, Location: zone(ctx)}, period, true
+}
+
+func reportError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, internal.ErInvalidReport):
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   err.Error(),
+			Path:      ctx.Request.URL.Path,
+		})
+
+	case errors.Is(err, internal.ErForbidden):
+		ctx.JSON(http.StatusForbidden, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusForbidden,
+			Message:   err.Error(),
+			Path:      ctx.Request.URL.Path,
+		})
+
+	default:
+		ctx.JSON(http.StatusServiceUnavailable, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusServiceUnavailable,
+			Message:   internal.ErServiceUnavailable.Error(),
+			Path:      ctx.Request.URL.Path,
+		})
+	}
+}
+
+func countsBody(data report.Counts) CountsResponse {
+	return CountsResponse{
+		Total:            data.Total,
+		Scheduled:        data.Scheduled,
+		Confirmed:        data.Confirmed,
+		CheckedIn:        data.CheckedIn,
+		Completed:        data.Completed,
+		Cancelled:        data.Cancelled,
+		NoShow:           data.NoShow,
+		CancellationRate: round(data.CancellationRate(), 4),
+		NoShowRate:       round(data.NoShowRate(), 4),
+	}
+}
+
+// round rounds a value to the decimal places, so hours and rates read well
+func round(value float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(value*scale) / scale
+}
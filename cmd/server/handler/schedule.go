@@ -0,0 +1,316 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/schedule"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// maxAvailabilityRange is the longest period that can be searched for free slots at once
+const maxAvailabilityRange = 31 * 24 * time.Hour
+
+// WorkingHoursResponse model for, response a range of Working Hours
+type WorkingHoursResponse struct {
+	Weekday int    `json:"weekday"`
+	Start   string `json:"start"`
+	End     string `json:"end"`
+} //	@name	WorkingHoursResponse
+
+// WorkingHoursPut model for updating a range of Working Hours, weekday goes from 0 (sunday) to 6 (saturday)
+type WorkingHoursPut struct {
+	Weekday *int   `json:"weekday" binding:"required,min=0,max=6"`
+	Start   string `json:"start" binding:"required"`
+	End     string `json:"end" binding:"required"`
+} //	@name	WorkingHoursPut
+
+// SlotResponse model for, response a free Slot
+type SlotResponse struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+} //	@name	SlotResponse
+
+// AvailabilityResponse model for, response the Availability of a Dentist
+type AvailabilityResponse struct {
+	DentistID uint           `json:"dentist_id"`
+	From      time.Time      `json:"from"`
+	To        time.Time      `json:"to"`
+	Slot      string         `json:"slot"`
+	Slots     []SlotResponse `json:"slots"`
+} //	@name	AvailabilityResponse
+
+// GetSchedule function to get the Working Hours of a Dentist
+//
+//	@Summary		Get Dentist Working Hours
+//	@Description	Get the weekly Working Hours of a Dentist
+//	@Tags			Dentist
+//	@Security		BearerAuth
+//	@Param			id	path		int	true	"Dentist ID"
+//	@Success		200	{array}		WorkingHoursResponse
+//	@Failure		400	{object}	ErrorResponse
+//	@Failure		404	{object}	ErrorResponse
+//	@Failure		503	{object}	ErrorResponse
+//	@Router			/dentists/{id}/schedule [get]
+func (d *DentistHandler) GetSchedule(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   "id param must be a number greater than 0",
+			Path:      ctx.Request.URL.Path,
+		})
+		return
+	}
+
+	hours, err := d.service.GetSchedule(uint(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, internal.ErNotFound):
+			ctx.JSON(http.StatusNotFound, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusNotFound,
+				Message:   fmt.Sprintf("dentist with id %d %s", id, err.Error()),
+				Path:      ctx.Request.URL.Path,
+			})
+
+		default:
+			ctx.JSON(http.StatusServiceUnavailable, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusServiceUnavailable,
+				Message:   err.Error(),
+				Path:      ctx.Request.URL.Path,
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, workingHoursBody(hours))
+}
+
+// UpdateSchedule function to replace the Working Hours of a Dentist
+//
+//	@Summary		Update Dentist Working Hours
+//	@Description	Replace the weekly Working Hours of a Dentist, days without ranges are days off
+//	@Tags			Dentist
+//	@Security		BearerAuth
+//	@Param			id				path		int					true	"Dentist ID"
+//	@Param			WorkingHours	body		[]WorkingHoursPut	true	"WorkingHoursResponse"
+//	@Success		200				{array}		WorkingHoursResponse
+//	@Failure		400				{object}	ErrorResponse
+//	@Failure		404				{object}	ErrorResponse
+//	@Failure		503				{object}	ErrorResponse
+//	@Router			/dentists/{id}/schedule [put]
+func (d *DentistHandler) UpdateSchedule(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   "id param must be a number greater than 0",
+			Path:      ctx.Request.URL.Path,
+		})
+		return
+	}
+
+	var hoursToPut []WorkingHoursPut
+	err = ctx.ShouldBindJSON(&hoursToPut)
+	if err != nil {
+		// Each invalid range of the list comes with its own validation errors
+		var errs []string
+		var sliceErrs binding.SliceValidationError
+		if errors.As(err, &sliceErrs) {
+			for _, sliceErr := range sliceErrs {
+				var validationErrs validator.ValidationErrors
+				if !errors.As(sliceErr, &validationErrs) {
+					continue
+				}
+				for _, err := range validationErrs {
+					errs = append(errs, fmt.Sprintf("'%s' field is: %s",
+						extractJSONTag(err.Field(), WorkingHoursPut{}), err.Tag()))
+				}
+			}
+		}
+
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   "invalid body",
+			Path:      ctx.Request.URL.Path,
+			Errors:    errs,
+		})
+		return
+	}
+
+	hours := make([]schedule.WorkingHours, 0, len(hoursToPut))
+	for _, current := range hoursToPut {
+		hours = append(hours, schedule.WorkingHours{
+			Weekday: time.Weekday(*current.Weekday),
+			Start:   current.Start,
+			End:     current.End,
+		})
+	}
+
+	hoursUpdated, err := d.service.UpdateSchedule(principal(ctx), uint(id), hours)
+	if err != nil {
+		switch {
+		case errors.Is(err, internal.ErNotFound):
+			ctx.JSON(http.StatusNotFound, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusNotFound,
+				Message:   fmt.Sprintf("dentist with id %d %s", id, err.Error()),
+				Path:      ctx.Request.URL.Path,
+			})
+
+		case errors.Is(err, internal.ErInvalidSchedule):
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusBadRequest,
+				Message:   "invalid body",
+				Path:      ctx.Request.URL.Path,
+				Errors: []string{
+					err.Error(),
+					fmt.Sprintf("start and end fields must be in format %s", schedule.TimeLayout),
+				},
+			})
+
+		default:
+			ctx.JSON(http.StatusServiceUnavailable, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusServiceUnavailable,
+				Message:   err.Error(),
+				Path:      ctx.Request.URL.Path,
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, workingHoursBody(hoursUpdated))
+}
+
+// GetAvailability function to get the free Slots of a Dentist
+//
+//	@Summary		Get Dentist Availability
+//	@Description	Get the free Slots of a Dentist, computed from the Working Hours minus the booked Appointments
+//	@Tags			Dentist
+//	@Security		BearerAuth
+//	@Param			id		path		int		true	"Dentist ID"
+//	@Param			from	query		string	false	"Start of the search in format RFC3339, now by default"
+//	@Param			to		query		string	false	"End of the search in format RFC3339, a week after from by default"
+//	@Param			slot	query		string	false	"Length of the slots, 30m by default"
+//	@Success		200		{object}	AvailabilityResponse
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		404		{object}	ErrorResponse
+//	@Failure		503		{object}	ErrorResponse
+//	@Router			/dentists/{id}/availability [get]
+func (d *DentistHandler) GetAvailability(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   "id param must be a number greater than 0",
+			Path:      ctx.Request.URL.Path,
+		})
+		return
+	}
+
+	var errs []string
+
+	from := time.Now()
+	if fromQuery := ctx.Query("from"); fromQuery != "" {
+		from, err = parseTime(fromQuery)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("'from' query param must be in format %s", "RFC3339"))
+		}
+	}
+
+	to := from.AddDate(0, 0, 7)
+	if toQuery := ctx.Query("to"); toQuery != "" {
+		to, err = parseTime(toQuery)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("'to' query param must be in format %s", "RFC3339"))
+		}
+	}
+
+	slot, err := time.ParseDuration(ctx.DefaultQuery("slot", "30m"))
+	if err != nil || slot < 5*time.Minute {
+		errs = append(errs, "'slot' query param must be a duration of at least 5m, like 30m or 1h")
+	}
+
+	if len(errs) == 0 && !from.Before(to) {
+		errs = append(errs, "'from' query param must be before 'to'")
+	}
+
+	if len(errs) == 0 && to.Sub(from) > maxAvailabilityRange {
+		errs = append(errs, fmt.Sprintf("the search can't be longer than %d days", int(maxAvailabilityRange.Hours()/24)))
+	}
+
+	if len(errs) > 0 {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   "invalid query params",
+			Path:      ctx.Request.URL.Path,
+			Errors:    errs,
+		})
+		return
+	}
+
+	slots, err := d.service.GetAvailability(uint(id), from, to, slot)
+	if err != nil {
+		switch {
+		case errors.Is(err, internal.ErNotFound):
+			ctx.JSON(http.StatusNotFound, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusNotFound,
+				Message:   fmt.Sprintf("dentist with id %d %s", id, err.Error()),
+				Path:      ctx.Request.URL.Path,
+			})
+
+		default:
+			ctx.JSON(http.StatusServiceUnavailable, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusServiceUnavailable,
+				Message:   err.Error(),
+				Path:      ctx.Request.URL.Path,
+			})
+		}
+		return
+	}
+
+	body := AvailabilityResponse{
+		DentistID: uint(id),
+		From:      from.In(zone(ctx)),
+		To:        to.In(zone(ctx)),
+		Slot:      slot.String(),
+		Slots:     make([]SlotResponse, 0, len(slots)),
+	}
+	for _, current := range slots {
+		body.Slots = append(body.Slots, SlotResponse{
+			Start: current.Start.In(zone(ctx)),
+			End:   current.End.In(zone(ctx)),
+		})
+	}
+
+	ctx.JSON(http.StatusOK, body)
+}
+
+func workingHoursBody(hours []schedule.WorkingHours) []WorkingHoursResponse {
+	body := make([]WorkingHoursResponse, 0, len(hours))
+	for _, current := range hours {
+		body = append(body, WorkingHoursResponse{
+			Weekday: int(current.Weekday),
+			Start:   current.Start,
+			End:     current.End,
+		})
+	}
+	return body
+}
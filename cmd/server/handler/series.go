@@ -0,0 +1,475 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/appointment"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// SeriesPost model for creating a recurring series of Appointments
+type SeriesPost struct {
+	PatientDNI     string `json:"patient_dni" binding:"required"`
+	DentistLicense string `json:"dentist_license" binding:"required"`
+	Date           string `json:"date" binding:"required"`
+	Duration       uint   `json:"duration" binding:"omitempty,min=1,max=480"`
+	Description    string `json:"description" binding:"required"`
+	Frequency      string `json:"frequency" binding:"required,oneof=daily weekly monthly"`
+	Interval       uint   `json:"interval"`
+	Count          uint   `json:"count"`
+	Until          string `json:"until"`
+	OnConflict     string `json:"on_conflict" binding:"omitempty,oneof=fail skip"`
+} //	@name	SeriesPost
+
+// SeriesResponse model for, response a recurring series of Appointments
+type SeriesResponse struct {
+	Id           uint                  `json:"id"`
+	PatientID    uint                  `json:"patient_id"`
+	DentistID    uint                  `json:"dentist_id"`
+	Date         time.Time             `json:"date"`
+	Duration     uint                  `json:"duration"`
+	Description  string                `json:"description"`
+	Frequency    string                `json:"frequency"`
+	Interval     uint                  `json:"interval"`
+	Count        uint                  `json:"count,omitempty"`
+	Until        *time.Time            `json:"until,omitempty"`
+	Appointments []AppointmentResponse `json:"appointments"`
+	Skipped      []OccurrenceResponse  `json:"skipped,omitempty"`
+} //	@name	SeriesResponse
+
+// OccurrenceResponse model for, response an occurrence of a series that couldn't be booked or changed
+type OccurrenceResponse struct {
+	Date   time.Time `json:"date"`
+	Reason string    `json:"reason"`
+	Errors []string  `json:"errors,omitempty"`
+} //	@name	OccurrenceResponse
+
+// SeriesConflictResponse model for, response a series with occurrences that couldn't be booked or changed
+type SeriesConflictResponse struct {
+	ErrorResponse
+	Occurrences []OccurrenceResponse `json:"occurrences"`
+} //	@name	SeriesConflictResponse
+
+// CreateSeries function to create a recurring series of Appointments
+//
+//	@Summary		Create a series of Appointments
+//	@Description	Book an Appointment that repeats every interval days, weeks or months, until it has count occurrences or reaches the until date.
+//	@Description	Occurrences that overlap other Appointments or fall outside the working hours fail the whole series, or are skipped and reported when on_conflict is skip.
+//	@Tags			Appointment
+//	@Security		BearerAuth
+//	@Param			Series	body		SeriesPost	true	"Series"
+//	@Success		201		{object}	SeriesResponse
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		403		{object}	ErrorResponse
+//	@Failure		404		{object}	ErrorResponse
+//	@Failure		409		{object}	SeriesConflictResponse
+//	@Failure		503		{object}	ErrorResponse
+//	@Router			/appointments/series [post]
+func (a *AppointmentHandler) CreateSeries(ctx *gin.Context) {
+	seriesToPost := SeriesPost{}
+	err := ctx.ShouldBindJSON(&seriesToPost)
+	if err != nil {
+		var errs []string
+		var validationErrs validator.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			for _, err := range validationErrs {
+				errs = append(errs, fmt.Sprintf("'%s' field is: %s", extractJSONTag(err.Field(), seriesToPost), err.Tag()))
+			}
+		}
+
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   "invalid body",
+			Path:      ctx.Request.URL.Path,
+			Errors:    errs,
+		})
+		return
+	}
+
+	var errs []string
+	date, err := parseTime(seriesToPost.Date)
+	if err != nil {
+		errs = append(errs, "date field must be in format RFC3339")
+	}
+
+	var until *time.Time
+	if seriesToPost.Until != "" {
+		untilDate, err := parseTime(seriesToPost.Until)
+		if err != nil {
+			errs = append(errs, "until field must be in format RFC3339")
+		}
+		until = &untilDate
+	}
+
+	if len(errs) > 0 {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   "invalid body",
+			Path:      ctx.Request.URL.Path,
+			Errors:    errs,
+		})
+		return
+	}
+
+	patientExist, err := a.patientService.GetByDNI(principal(ctx), seriesToPost.PatientDNI)
+	if err != nil {
+		a.notFoundOrUnavailable(ctx, err, fmt.Sprintf("patient with dni %s", seriesToPost.PatientDNI))
+		return
+	}
+
+	dentistExist, err := a.dentistService.GetByLicense(seriesToPost.DentistLicense)
+	if err != nil {
+		a.notFoundOrUnavailable(ctx, err, fmt.Sprintf("dentist with license %s", seriesToPost.DentistLicense))
+		return
+	}
+
+	interval := seriesToPost.Interval
+	if interval == 0 {
+		interval = 1
+	}
+
+	seriesToCreate := appointment.Series{
+		PatientID:   patientExist.ID,
+		DentistID:   dentistExist.ID,
+		Start:       date,
+		Duration:    seriesToPost.Duration,
+		Description: seriesToPost.Description,
+		Frequency:   appointment.Frequency(seriesToPost.Frequency),
+		Interval:    interval,
+		Count:       seriesToPost.Count,
+		Until:       until,
+	}
+
+	seriesCreated, skipped, err := a.service.CreateSeries(principal(ctx), seriesToCreate, seriesToPost.OnConflict == "skip")
+	if err != nil {
+		a.seriesError(ctx, err)
+		return
+	}
+
+	body := seriesBody(seriesCreated, zone(ctx))
+	body.Skipped = occurrencesBody(skipped, zone(ctx))
+
+	ctx.JSON(http.StatusCreated, body)
+}
+
+// GetSeries function to get a recurring series of Appointments
+//
+//	@Summary		Get a series of Appointments
+//	@Description	Get a recurring series with all its Appointments ordered by date
+//	@Tags			Appointment
+//	@Security		BearerAuth
+//	@Param			id	path		int	true	"Series ID"
+//	@Success		200	{object}	SeriesResponse
+//	@Failure		400	{object}	ErrorResponse
+//	@Failure		404	{object}	ErrorResponse
+//	@Failure		503	{object}	ErrorResponse
+//	@Router			/appointments/series/{id} [get]
+func (a *AppointmentHandler) GetSeries(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   "id param must be a number greater than 0",
+			Path:      ctx.Request.URL.Path,
+		})
+		return
+	}
+
+	data, err := a.service.GetSeries(principal(ctx), uint(id))
+	if err != nil {
+		a.notFoundOrUnavailable(ctx, err, fmt.Sprintf("series with id %d", id))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, seriesBody(data, zone(ctx)))
+}
+
+// UpdateSeries function to update the Appointments of a series
+//
+//	@Summary		Update the Appointments of a series
+//	@Description	Apply the given fields to this Appointment, this and the following ones or all the Appointments of its series.
+//	@Description	A new date moves every Appointment by the same number of days to the same time, completed, cancelled and no-show ones are left as they are.
+//	@Tags			Appointment
+//	@Security		BearerAuth
+//	@Param			id			path		int					true	"Appointment ID"
+//	@Param			scope		query		string				false	"Appointments of the series to update, this by default"	Enums(this, following, all)
+//	@Param			Appointment	body		AppointmentPatch	true	"Fields to update"
+//	@Success		200			{array}		AppointmentResponse
+//	@Failure		400			{object}	ErrorResponse
+//	@Failure		403			{object}	ErrorResponse
+//	@Failure		404			{object}	ErrorResponse
+//	@Failure		409			{object}	SeriesConflictResponse
+//	@Failure		503			{object}	ErrorResponse
+//	@Router			/appointments/{id}/series [patch]
+func (a *AppointmentHandler) UpdateSeries(ctx *gin.Context) {
+	id, scope, ok := a.seriesParams(ctx)
+	if !ok {
+		return
+	}
+
+	appointmentToPatch := AppointmentPatch{}
+	err := ctx.ShouldBindJSON(&appointmentToPatch)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   "invalid body",
+			Path:      ctx.Request.URL.Path,
+		})
+		return
+	}
+
+	var date time.Time
+	if appointmentToPatch.Date != "" {
+		date, err = parseTime(appointmentToPatch.Date)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusBadRequest,
+				Message:   "invalid body",
+				Path:      ctx.Request.URL.Path,
+				Errors:    []string{"date field must be in format RFC3339"},
+			})
+			return
+		}
+	}
+
+	if appointmentToPatch.PatientID != 0 {
+		_, err = a.patientService.GetByID(principal(ctx), appointmentToPatch.PatientID)
+		if err != nil {
+			a.notFoundOrUnavailable(ctx, err, fmt.Sprintf("patient with id %d", appointmentToPatch.PatientID))
+			return
+		}
+	}
+
+	if appointmentToPatch.DentistID != 0 {
+		_, err = a.dentistService.GetByID(appointmentToPatch.DentistID)
+		if err != nil {
+			a.notFoundOrUnavailable(ctx, err, fmt.Sprintf("dentist with id %d", appointmentToPatch.DentistID))
+			return
+		}
+	}
+
+	changes := appointment.Appointment{
+		ID:          id,
+		PatientID:   appointmentToPatch.PatientID,
+		DentistID:   appointmentToPatch.DentistID,
+		Date:        date,
+		Duration:    appointmentToPatch.Duration,
+		Description: appointmentToPatch.Description,
+	}
+
+	appointmentsUpdated, err := a.service.UpdateSeries(principal(ctx), changes, scope)
+	if err != nil {
+		a.seriesError(ctx, err)
+		return
+	}
+
+	body := []AppointmentResponse{}
+	for _, currentAppointment := range appointmentsUpdated {
+		body = append(body, appointmentBody(currentAppointment, zone(ctx)))
+	}
+
+	ctx.JSON(http.StatusOK, body)
+}
+
+// CancelSeries function to cancel the Appointments of a series
+//
+//	@Summary		Cancel the Appointments of a series
+//	@Description	Cancel this Appointment, this and the following ones or all the Appointments of its series that haven't started yet
+//	@Tags			Appointment
+//	@Security		BearerAuth
+//	@Param			id		path		int			true	"Appointment ID"
+//	@Param			scope	query		string		false	"Appointments of the series to cancel, this by default"	Enums(this, following, all)
+//	@Param			Reason	body		CancelPost	true	"Reason of the cancellation"
+//	@Success		200		{array}		AppointmentResponse
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		403		{object}	ErrorResponse
+//	@Failure		404		{object}	ErrorResponse
+//	@Failure		409		{object}	ErrorResponse
+//	@Failure		503		{object}	ErrorResponse
+//	@Router			/appointments/{id}/series/cancel [post]
+func (a *AppointmentHandler) CancelSeries(ctx *gin.Context) {
+	id, scope, ok := a.seriesParams(ctx)
+	if !ok {
+		return
+	}
+
+	cancelToPost := CancelPost{}
+	err := ctx.ShouldBindJSON(&cancelToPost)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   "invalid body",
+			Path:      ctx.Request.URL.Path,
+			Errors:    []string{"reason field is required and must have at most 255 characters"},
+		})
+		return
+	}
+
+	appointmentsCancelled, err := a.service.CancelSeries(principal(ctx), id, scope, strings.TrimSpace(cancelToPost.Reason))
+	if err != nil {
+		a.seriesError(ctx, err)
+		return
+	}
+
+	body := []AppointmentResponse{}
+	for _, currentAppointment := range appointmentsCancelled {
+		body = append(body, appointmentBody(currentAppointment, zone(ctx)))
+	}
+
+	ctx.JSON(http.StatusOK, body)
+}
+
+// seriesParams reads the id param and the scope query param, writing the response when they are invalid
+func (a *AppointmentHandler) seriesParams(ctx *gin.Context) (uint, appointment.Scope, bool) {
+	var errs []string
+
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		errs = append(errs, "id param must be a number greater than 0")
+	}
+
+	scope := appointment.Scope(strings.ToLower(ctx.DefaultQuery("scope", string(appointment.ScopeThis))))
+	if scope != appointment.ScopeThis && scope != appointment.ScopeFollowing && scope != appointment.ScopeAll {
+		errs = append(errs, "'scope' query param must be this, following or all")
+	}
+
+	if len(errs) > 0 {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   "invalid params",
+			Path:      ctx.Request.URL.Path,
+			Errors:    errs,
+		})
+		return 0, "", false
+	}
+
+	return uint(id), scope, true
+}
+
+// seriesError writes the response of an error of the series operations
+func (a *AppointmentHandler) seriesError(ctx *gin.Context, err error) {
+	var seriesErr *internal.SeriesConflictError
+	var transitionErr *internal.TransitionError
+	switch {
+	case errors.As(err, &seriesErr):
+		ctx.JSON(http.StatusConflict, SeriesConflictResponse{
+			ErrorResponse: ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusConflict,
+				Message:   err.Error(),
+				Path:      ctx.Request.URL.Path,
+			},
+			Occurrences: occurrencesBody(seriesErr.Occurrences, zone(ctx)),
+		})
+
+	case errors.As(err, &transitionErr):
+		ctx.JSON(http.StatusConflict, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusConflict,
+			Message:   fmt.Sprintf("appointment can't move from %s to %s", transitionErr.From, transitionErr.To),
+			Path:      ctx.Request.URL.Path,
+		})
+
+	case errors.Is(err, internal.ErAppointmentConflict):
+		ctx.JSON(http.StatusConflict, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusConflict,
+			Message:   err.Error(),
+			Path:      ctx.Request.URL.Path,
+			Errors:    conflictErrors(err),
+		})
+
+	case errors.Is(err, internal.ErOutsideWorkingHours), errors.Is(err, internal.ErAppointmentClosed):
+		ctx.JSON(http.StatusConflict, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusConflict,
+			Message:   err.Error(),
+			Path:      ctx.Request.URL.Path,
+		})
+
+	case errors.Is(err, internal.ErInvalidRecurrence), errors.Is(err, internal.ErNotInSeries), errors.Is(err, internal.ErInvalidDuration):
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   err.Error(),
+			Path:      ctx.Request.URL.Path,
+		})
+
+	case errors.Is(err, internal.ErForbidden):
+		ctx.JSON(http.StatusForbidden, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusForbidden,
+			Message:   err.Error(),
+			Path:      ctx.Request.URL.Path,
+		})
+
+	default:
+		a.notFoundOrUnavailable(ctx, err, "appointment")
+	}
+}
+
+// notFoundOrUnavailable writes a 404 response naming what wasn't found, or a 503 response for other errors
+func (a *AppointmentHandler) notFoundOrUnavailable(ctx *gin.Context, err error, subject string) {
+	if errors.Is(err, internal.ErNotFound) {
+		ctx.JSON(http.StatusNotFound, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusNotFound,
+			Message:   fmt.Sprintf("%s %s", subject, err.Error()),
+			Path:      ctx.Request.URL.Path,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusServiceUnavailable, ErrorResponse{
+		Timestamp: time.Now().Format(time.RFC3339),
+		Status:    http.StatusServiceUnavailable,
+		Message:   internal.ErServiceUnavailable.Error(),
+		Path:      ctx.Request.URL.Path,
+	})
+}
+
+func seriesBody(data appointment.Series, location *time.Location) SeriesResponse {
+	body := SeriesResponse{
+		Id:           data.ID,
+		PatientID:    data.PatientID,
+		DentistID:    data.DentistID,
+		Date:         data.Start.In(location),
+		Duration:     data.Duration,
+		Description:  data.Description,
+		Frequency:    string(data.Frequency),
+		Interval:     data.Interval,
+		Count:        data.Count,
+		Until:        inZone(data.Until, location),
+		Appointments: []AppointmentResponse{},
+	}
+	for _, currentAppointment := range data.Appointments {
+		body.Appointments = append(body.Appointments, appointmentBody(currentAppointment, location))
+	}
+	return body
+}
+
+func occurrencesBody(occurrences []internal.OccurrenceError, location *time.Location) []OccurrenceResponse {
+	var body []OccurrenceResponse
+	for _, occurrence := range occurrences {
+		body = append(body, OccurrenceResponse{
+			Date:   occurrence.Date.In(location),
+			Reason: occurrence.Err.Error(),
+			Errors: conflictErrors(occurrence.Err),
+		})
+	}
+	return body
+}
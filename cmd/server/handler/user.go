@@ -0,0 +1,373 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/cmd/server/middleware"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/auth"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/user"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// UserResponse model for, response a User
+type UserResponse struct {
+	Id        uint      `json:"id"`
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	DentistID *uint     `json:"dentist_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+} //	@name	UserResponse
+
+// UserPost model for creating a User, dentist users are linked to a dentist by license
+type UserPost struct {
+	Username       string `json:"username" binding:"required"`
+	Password       string `json:"password" binding:"required"`
+	Role           string `json:"role" binding:"required,oneof=admin receptionist dentist"`
+	DentistLicense string `json:"dentist_license"`
+} //	@name	UserPost
+
+// LoginPost model for logging in
+type LoginPost struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+} //	@name	LoginPost
+
+// RefreshPost model for refreshing the tokens
+type RefreshPost struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+} //	@name	RefreshPost
+
+// TokensResponse model for, response the Tokens of a session
+type TokensResponse struct {
+	TokenType        string    `json:"token_type"`
+	AccessToken      string    `json:"access_token"`
+	AccessExpiresAt  time.Time `json:"access_expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+} //	@name	TokensResponse
+
+// FeedTokenResponse model for, response the feed token of a User, Calendar is the feed of dentist users
+type FeedTokenResponse struct {
+	FeedToken string `json:"feed_token"`
+	Calendar  string `json:"calendar,omitempty"`
+} //	@name	FeedTokenResponse
+
+type UserService interface {
+	GetAll() ([]user.User, error)
+	Create(user user.User, password string) (user.User, error)
+	Login(username string, password string) (user.Tokens, error)
+	Refresh(refreshToken string) (user.Tokens, error)
+	RotateFeedToken(userID uint) (string, error)
+}
+
+type UserHandler struct {
+	service        UserService
+	dentistService DentistService
+}
+
+func NewUserHandler(service UserService, dentist DentistService) *UserHandler {
+	return &UserHandler{service: service, dentistService: dentist}
+}
+
+// Login function to get the Tokens of a User
+//
+//	@Summary		Login
+//	@Description	Check the credentials of a User and issue an access token and a refresh token
+//	@Tags			Auth
+//	@Param			Credentials	body		LoginPost	true	"Credentials"
+//	@Success		200			{object}	TokensResponse
+//	@Failure		400			{object}	ErrorResponse
+//	@Failure		401			{object}	ErrorResponse
+//	@Failure		503			{object}	ErrorResponse
+//	@Router			/auth/login [post]
+func (u *UserHandler) Login(ctx *gin.Context) {
+	credentials := LoginPost{}
+	err := ctx.ShouldBindJSON(&credentials)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   "invalid body",
+			Path:      ctx.Request.URL.Path,
+			Errors:    []string{"username and password fields are required"},
+		})
+		return
+	}
+
+	tokens, err := u.service.Login(credentials.Username, credentials.Password)
+	if err != nil {
+		switch {
+		case errors.Is(err, internal.ErInvalidCredentials):
+			ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusUnauthorized,
+				Message:   err.Error(),
+				Path:      ctx.Request.URL.Path,
+			})
+
+		default:
+			ctx.JSON(http.StatusServiceUnavailable, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusServiceUnavailable,
+				Message:   internal.ErServiceUnavailable.Error(),
+				Path:      ctx.Request.URL.Path,
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, tokensBody(tokens, zone(ctx)))
+}
+
+// Refresh function to get new Tokens from a refresh token
+//
+//	@Summary		Refresh
+//	@Description	Issue a new access token and refresh token from a valid refresh token
+//	@Tags			Auth
+//	@Param			Token	body		RefreshPost	true	"Refresh token"
+//	@Success		200		{object}	TokensResponse
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		401		{object}	ErrorResponse
+//	@Failure		503		{object}	ErrorResponse
+//	@Router			/auth/refresh [post]
+func (u *UserHandler) Refresh(ctx *gin.Context) {
+	tokenToRefresh := RefreshPost{}
+	err := ctx.ShouldBindJSON(&tokenToRefresh)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   "invalid body",
+			Path:      ctx.Request.URL.Path,
+			Errors:    []string{"refresh_token field is required"},
+		})
+		return
+	}
+
+	tokens, err := u.service.Refresh(tokenToRefresh.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, internal.ErInvalidToken), errors.Is(err, internal.ErTokenExpired):
+			ctx.JSON(http.StatusUnauthorized, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusUnauthorized,
+				Message:   err.Error(),
+				Path:      ctx.Request.URL.Path,
+			})
+
+		default:
+			ctx.JSON(http.StatusServiceUnavailable, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusServiceUnavailable,
+				Message:   internal.ErServiceUnavailable.Error(),
+				Path:      ctx.Request.URL.Path,
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, tokensBody(tokens, zone(ctx)))
+}
+
+// GetAll function to get all Users
+//
+//	@Summary		Get all Users
+//	@Description	Get all Users
+//	@Tags			User
+//	@Security		BearerAuth
+//	@Success		200	{array}		UserResponse
+//	@Failure		401	{object}	ErrorResponse
+//	@Failure		403	{object}	ErrorResponse
+//	@Failure		503	{object}	ErrorResponse
+//	@Router			/users [get]
+func (u *UserHandler) GetAll(ctx *gin.Context) {
+	users, err := u.service.GetAll()
+	if err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusServiceUnavailable,
+			Message:   err.Error(),
+			Path:      ctx.Request.URL.Path,
+		})
+		return
+	}
+
+	body := make([]UserResponse, 0, len(users))
+	for _, currentUser := range users {
+		body = append(body, userBody(currentUser, zone(ctx)))
+	}
+
+	ctx.JSON(http.StatusOK, body)
+}
+
+// Create function to create a User
+//
+//	@Summary		Create a User
+//	@Description	Create a User, dentist users must give the license of the dentist they are
+//	@Tags			User
+//	@Security		BearerAuth
+//	@Param			User	body		UserPost	true	"UserResponse"
+//	@Success		201		{object}	UserResponse
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		401		{object}	ErrorResponse
+//	@Failure		403		{object}	ErrorResponse
+//	@Failure		404		{object}	ErrorResponse
+//	@Failure		409		{object}	ErrorResponse
+//	@Failure		503		{object}	ErrorResponse
+//	@Router			/users [post]
+func (u *UserHandler) Create(ctx *gin.Context) {
+	userToPost := UserPost{}
+	err := ctx.ShouldBindJSON(&userToPost)
+	if err != nil {
+		var errs []string
+		var validationErrs validator.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			for _, err := range validationErrs {
+				errs = append(errs, fmt.Sprintf("'%s' field is: %s",
+					extractJSONTag(err.Field(), userToPost), err.Tag()))
+			}
+		}
+
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   "invalid body",
+			Path:      ctx.Request.URL.Path,
+			Errors:    errs,
+		})
+		return
+	}
+
+	userToCreate := user.User{
+		Username: userToPost.Username,
+		Role:     auth.Role(userToPost.Role),
+	}
+
+	if userToPost.DentistLicense != "" {
+		dentistExist, err := u.dentistService.GetByLicense(userToPost.DentistLicense)
+		if err != nil {
+			switch {
+			case errors.Is(err, internal.ErNotFound):
+				ctx.JSON(http.StatusNotFound, ErrorResponse{
+					Timestamp: time.Now().Format(time.RFC3339),
+					Status:    http.StatusNotFound,
+					Message:   fmt.Sprintf("dentist with license %s %s", userToPost.DentistLicense, err.Error()),
+					Path:      ctx.Request.URL.Path,
+				})
+
+			default:
+				ctx.JSON(http.StatusServiceUnavailable, ErrorResponse{
+					Timestamp: time.Now().Format(time.RFC3339),
+					Status:    http.StatusServiceUnavailable,
+					Message:   err.Error(),
+					Path:      ctx.Request.URL.Path,
+				})
+			}
+			return
+		}
+		userToCreate.DentistID = &dentistExist.ID
+	}
+
+	userCreated, err := u.service.Create(userToCreate, userToPost.Password)
+	if err != nil {
+		switch {
+		case errors.Is(err, internal.ErUsernameAlreadyExists):
+			ctx.JSON(http.StatusConflict, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusConflict,
+				Message:   fmt.Sprintf("username %s already exists", userToPost.Username),
+				Path:      ctx.Request.URL.Path,
+			})
+
+		case errors.Is(err, internal.ErInvalidRole), errors.Is(err, internal.ErWeakPassword):
+			ctx.JSON(http.StatusBadRequest, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusBadRequest,
+				Message:   "invalid body",
+				Path:      ctx.Request.URL.Path,
+				Errors:    []string{err.Error()},
+			})
+
+		default:
+			ctx.JSON(http.StatusServiceUnavailable, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusServiceUnavailable,
+				Message:   err.Error(),
+				Path:      ctx.Request.URL.Path,
+			})
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, userBody(userCreated, zone(ctx)))
+}
+
+// RotateFeedToken function to get a new feed token for the calendar feeds
+//
+//	@Summary		Rotate the feed token
+//	@Description	Issue a new feed token for the authenticated User, it gives calendar apps read access to the calendar feeds with the permissions of the User.
+//	@Description	Only the latest feed token works, the previous one is revoked.
+//	@Tags			Auth
+//	@Security		BearerAuth
+//	@Success		200	{object}	FeedTokenResponse
+//	@Failure		401	{object}	ErrorResponse
+//	@Failure		404	{object}	ErrorResponse
+//	@Failure		503	{object}	ErrorResponse
+//	@Router			/auth/feed-token [post]
+func (u *UserHandler) RotateFeedToken(ctx *gin.Context) {
+	currentPrincipal := principal(ctx)
+
+	feedToken, err := u.service.RotateFeedToken(currentPrincipal.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, internal.ErNotFound):
+			ctx.JSON(http.StatusNotFound, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusNotFound,
+				Message:   fmt.Sprintf("user with id %d %s", currentPrincipal.UserID, err.Error()),
+				Path:      ctx.Request.URL.Path,
+			})
+
+		default:
+			ctx.JSON(http.StatusServiceUnavailable, ErrorResponse{
+				Timestamp: time.Now().Format(time.RFC3339),
+				Status:    http.StatusServiceUnavailable,
+				Message:   internal.ErServiceUnavailable.Error(),
+				Path:      ctx.Request.URL.Path,
+			})
+		}
+		return
+	}
+
+	body := FeedTokenResponse{FeedToken: feedToken}
+	if dentistID, ok := currentPrincipal.Dentist(); ok {
+		body.Calendar = fmt.Sprintf("/dentists/%d/calendar.ics?%s=%s", dentistID, middleware.FeedTokenParam, feedToken)
+	}
+
+	ctx.JSON(http.StatusOK, body)
+}
+
+func userBody(data user.User, location *time.Location) UserResponse {
+	return UserResponse{
+		Id:        data.ID,
+		Username:  data.Username,
+		Role:      string(data.Role),
+		DentistID: data.DentistID,
+		CreatedAt: data.CreatedAt.In(location),
+	}
+}
+
+func tokensBody(tokens user.Tokens, location *time.Location) TokensResponse {
+	return TokensResponse{
+		TokenType:        "Bearer",
+		AccessToken:      tokens.AccessToken,
+		AccessExpiresAt:  tokens.AccessExpiresAt.In(location),
+		RefreshToken:     tokens.RefreshToken,
+		RefreshExpiresAt: tokens.RefreshExpiresAt.In(location),
+	}
+}
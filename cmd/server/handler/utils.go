@@ -1,7 +1,16 @@
 package handler
 
 import (
+	"fmt"
+	"net/url"
 	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/cmd/server/middleware"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/auth"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/pagination"
+	"github.com/gin-gonic/gin"
 )
 
 func extractJSONTag(field string, s interface{}) (value string) {
@@ -22,3 +31,153 @@ func extractJSONTag(field string, s interface{}) (value string) {
 
 	return tag
 }
+
+// parsePageRequest reads the page, size and sort query params, sizes above pagination.MaxSize are rejected
+// instead of clamped and sort fields must be keys of sortColumns
+func parsePageRequest(ctx *gin.Context, sortColumns map[string]string) (pagination.Request, []string) {
+	var errs []string
+
+	page, err := queryInt(ctx, "page")
+	if err != nil {
+		errs = append(errs, "'page' query param must be a number greater than 0")
+	}
+
+	size, err := queryInt(ctx, "size")
+	if err != nil || size > pagination.MaxSize {
+		errs = append(errs, fmt.Sprintf("'size' query param must be a number between 1 and %d", pagination.MaxSize))
+	}
+
+	sort, sortErrs := parseSort(ctx, sortColumns)
+	errs = append(errs, sortErrs...)
+
+	return pagination.NewRequest(page, size, sort), errs
+}
+
+// parseSort reads the sort query param, for the lists that aren't paged like the exports
+func parseSort(ctx *gin.Context, sortColumns map[string]string) ([]pagination.Sort, []string) {
+	sort, err := pagination.ParseSort(ctx.Query("sort"), sortColumns)
+	if err != nil {
+		var fields []string
+		for field := range sortColumns {
+			fields = append(fields, field)
+		}
+		return nil, []string{fmt.Sprintf("'sort' query param must be a list of %v, prefixed with '-' for descending order", fields)}
+	}
+	return sort, nil
+}
+
+// queryInt reads an optional query param holding a positive number, 0 when it is empty
+func queryInt(ctx *gin.Context, key string) (int, error) {
+	raw := ctx.Query(key)
+	if raw == "" {
+		return 0, nil
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < 1 {
+		return 0, fmt.Errorf("invalid %s", key)
+	}
+	return value, nil
+}
+
+// queryUint reads an optional query param holding an id, 0 when it is empty
+func queryUint(ctx *gin.Context, key string) (uint, error) {
+	raw := ctx.Query(key)
+	if raw == "" {
+		return 0, nil
+	}
+
+	value, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return uint(value), nil
+}
+
+// queryBool reads an optional query param holding true or false, false when it is empty
+func queryBool(ctx *gin.Context, key string) (bool, error) {
+	raw := ctx.Query(key)
+	if raw == "" {
+		return false, nil
+	}
+
+	return strconv.ParseBool(raw)
+}
+
+// queryTime reads an optional query param holding a date in format RFC3339, zero time when it is empty
+func queryTime(ctx *gin.Context, key string) (time.Time, error) {
+	raw := ctx.Query(key)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+
+	return parseTime(raw)
+}
+
+// parseTime reads a date in format RFC3339 and returns it in UTC, the offset of the date is kept in the
+// instant so a date sent from any zone is stored the same
+func parseTime(raw string) (time.Time, error) {
+	date, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return date.UTC(), nil
+}
+
+// newPageResponse wraps the items of a page with the total count and the links to the next and previous pages
+func newPageResponse[T any, R any](ctx *gin.Context, page pagination.Page[T], items []R) PageResponse[R] {
+	if items == nil {
+		items = []R{}
+	}
+
+	body := PageResponse[R]{
+		Items: items,
+		Total: page.Total,
+		Page:  page.Page,
+		Size:  page.Size,
+		Links: PageLinks{Self: pageLink(ctx, page.Page, page.Size)},
+	}
+
+	if page.HasNext() {
+		body.Links.Next = pageLink(ctx, page.Page+1, page.Size)
+	}
+	if page.Page > 1 {
+		body.Links.Prev = pageLink(ctx, page.Page-1, page.Size)
+	}
+
+	return body
+}
+
+// pageLink rebuilds the requested url, keeping the filters and sort, for another page
+func pageLink(ctx *gin.Context, page int, size int) string {
+	link := url.URL{Path: ctx.Request.URL.Path}
+
+	query := ctx.Request.URL.Query()
+	query.Set("page", strconv.Itoa(page))
+	query.Set("size", strconv.Itoa(size))
+	link.RawQuery = query.Encode()
+
+	return link.String()
+}
+
+// principal returns the identity of the caller stored by the auth middleware,
+// services refuse the zero value so unauthenticated routes can't reach scoped data
+func principal(ctx *gin.Context) auth.Principal {
+	found, _ := middleware.Principal(ctx)
+	return found
+}
+
+// zone returns the time zone the dates of the response are rendered in
+func zone(ctx *gin.Context) *time.Location {
+	return middleware.Location(ctx)
+}
+
+// inZone returns an optional date in the location, nil when it is nil
+func inZone(date *time.Time, location *time.Location) *time.Time {
+	if date == nil {
+		return nil
+	}
+
+	inLocation := date.In(location)
+	return &inLocation
+}
@@ -0,0 +1,454 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/auth"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/pagination"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/waitlist"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// WaitlistPost model for queueing a Patient for a Dentist, the window is a daily time range in the clinic time zone
+type WaitlistPost struct {
+	PatientDNI     string `json:"patient_dni" binding:"required"`
+	DentistLicense string `json:"dentist_license" binding:"required"`
+	From           string `json:"from" binding:"required"`
+	To             string `json:"to" binding:"required"`
+	WindowStart    string `json:"window_start" binding:"required"`
+	WindowEnd      string `json:"window_end" binding:"required"`
+	Duration       uint   `json:"duration" binding:"omitempty,min=1,max=480"`
+	Description    string `json:"description" binding:"required"`
+} //	@name	WaitlistPost
+
+// WaitlistEntryResponse model for, response a Waitlist Entry
+type WaitlistEntryResponse struct {
+	Id          uint            `json:"id"`
+	PatientID   uint            `json:"patient_id"`
+	DentistID   uint            `json:"dentist_id"`
+	From        time.Time       `json:"from"`
+	To          time.Time       `json:"to"`
+	WindowStart string          `json:"window_start"`
+	WindowEnd   string          `json:"window_end"`
+	Duration    uint            `json:"duration"`
+	Description string          `json:"description"`
+	Status      string          `json:"status"`
+	CreatedAt   time.Time       `json:"created_at"`
+	Offers      []OfferResponse `json:"offers,omitempty"`
+} //	@name	WaitlistEntryResponse
+
+// OfferResponse model for, response an Offer of a freed slot
+type OfferResponse struct {
+	Id            uint       `json:"id"`
+	EntryID       uint       `json:"entry_id"`
+	PatientID     uint       `json:"patient_id"`
+	DentistID     uint       `json:"dentist_id"`
+	Start         time.Time  `json:"start"`
+	End           time.Time  `json:"end"`
+	Status        string     `json:"status"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	AppointmentID *uint      `json:"appointment_id,omitempty"`
+	RespondedAt   *time.Time `json:"responded_at,omitempty"`
+} //	@name	OfferResponse
+
+type WaitlistService interface {
+	GetAll(principal auth.Principal, filter waitlist.Filter, page pagination.Request) (pagination.Page[waitlist.Entry], error)
+	GetByID(principal auth.Principal, id uint) (waitlist.Entry, error)
+	Create(principal auth.Principal, entry waitlist.Entry) (waitlist.Entry, error)
+	Cancel(principal auth.Principal, id uint) (waitlist.Entry, error)
+	Accept(principal auth.Principal, id uint) (waitlist.Offer, error)
+	Decline(principal auth.Principal, id uint) (waitlist.Offer, error)
+}
+
+type WaitlistHandler struct {
+	service        WaitlistService
+	patientService PatientService
+	dentistService DentistService
+}
+
+func NewWaitlistHandler(service WaitlistService, patient PatientService, dentist DentistService) *WaitlistHandler {
+	return &WaitlistHandler{service: service, patientService: patient, dentistService: dentist}
+}
+
+// GetAll function to get all Waitlist Entries
+//
+//	@Summary		Get all Waitlist Entries
+//	@Description	Get a page of Waitlist Entries, filtered and sorted by the query params
+//	@Tags			Waitlist
+//	@Security		BearerAuth
+//	@Param			dentist_id	query		int		false	"Dentist ID"
+//	@Param			patient_id	query		int		false	"Patient ID"
+//	@Param			status		query		string	false	"Comma separated statuses, e.g. waiting,offered"
+//	@Param			sort		query		string	false	"Fields to sort by, prefixed with '-' for descending order, e.g. -created_at"
+//	@Param			page		query		int		false	"Page number, starting at 1"
+//	@Param			size		query		int		false	"Page size, 20 by default and 100 at most"
+//	@Success		200			{object}	PageResponse[WaitlistEntryResponse]
+//	@Failure		400			{object}	ErrorResponse
+//	@Failure		503			{object}	ErrorResponse
+//	@Router			/waitlist [get]
+func (w *WaitlistHandler) GetAll(ctx *gin.Context) {
+	page, errs := parsePageRequest(ctx, waitlist.SortColumns)
+	if len(page.Sort) == 0 {
+		page.Sort = []pagination.Sort{{Column: "created_at"}, {Column: "id"}}
+	}
+
+	dentistID, err := queryUint(ctx, "dentist_id")
+	if err != nil {
+		errs = append(errs, "'dentist_id' query param must be a number greater than 0")
+	}
+
+	patientID, err := queryUint(ctx, "patient_id")
+	if err != nil {
+		errs = append(errs, "'patient_id' query param must be a number greater than 0")
+	}
+
+	var statuses []waitlist.EntryStatus
+	if raw := ctx.Query("status"); raw != "" {
+		for _, value := range strings.Split(raw, ",") {
+			status := waitlist.EntryStatus(strings.ToLower(strings.TrimSpace(value)))
+			if !status.Valid() {
+				errs = append(errs, fmt.Sprintf("'status' query param must be a list of %v", waitlist.EntryStatuses))
+				break
+			}
+			statuses = append(statuses, status)
+		}
+	}
+
+	if len(errs) > 0 {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   "invalid query params",
+			Path:      ctx.Request.URL.Path,
+			Errors:    errs,
+		})
+		return
+	}
+
+	filter := waitlist.Filter{
+		PatientID: patientID,
+		DentistID: dentistID,
+		Statuses:  statuses,
+	}
+
+	entries, err := w.service.GetAll(principal(ctx), filter, page)
+	if err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusServiceUnavailable,
+			Message:   err.Error(),
+			Path:      ctx.Request.URL.Path,
+		})
+		return
+	}
+
+	var body []WaitlistEntryResponse
+	for _, currentEntry := range entries.Items {
+		body = append(body, waitlistEntryBody(currentEntry, zone(ctx)))
+	}
+
+	ctx.JSON(http.StatusOK, newPageResponse(ctx, entries, body))
+}
+
+// GetById function to get a Waitlist Entry by ID
+//
+//	@Summary		Get Waitlist Entry by ID
+//	@Description	Get a Waitlist Entry with its Offers, newest first
+//	@Tags			Waitlist
+//	@Security		BearerAuth
+//	@Param			id	path		int	true	"Waitlist Entry ID"
+//	@Success		200	{object}	WaitlistEntryResponse
+//	@Failure		400	{object}	ErrorResponse
+//	@Failure		404	{object}	ErrorResponse
+//	@Failure		503	{object}	ErrorResponse
+//	@Router			/waitlist/{id} [get]
+func (w *WaitlistHandler) GetById(ctx *gin.Context) {
+	id, ok := waitlistID(ctx)
+	if !ok {
+		return
+	}
+
+	data, err := w.service.GetByID(principal(ctx), id)
+	if err != nil {
+		waitlistError(ctx, err, fmt.Sprintf("waitlist entry with id %d", id))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, waitlistEntryBody(data, zone(ctx)))
+}
+
+// Create function to queue a Patient for a Dentist
+//
+//	@Summary		Create a Waitlist Entry
+//	@Description	Queue a Patient for a Dentist, any time between from and to that starts and ends inside the daily window.
+//	@Description	When an Appointment of the Dentist that fits is deleted or cancelled, the first Entry of the queue gets an Offer of its slot.
+//	@Tags			Waitlist
+//	@Security		BearerAuth
+//	@Param			Entry	body		WaitlistPost	true	"Waitlist Entry"
+//	@Success		201		{object}	WaitlistEntryResponse
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		403		{object}	ErrorResponse
+//	@Failure		404		{object}	ErrorResponse
+//	@Failure		503		{object}	ErrorResponse
+//	@Router			/waitlist [post]
+func (w *WaitlistHandler) Create(ctx *gin.Context) {
+	entryToPost := WaitlistPost{}
+	err := ctx.ShouldBindJSON(&entryToPost)
+	if err != nil {
+		var errs []string
+		var validationErrs validator.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			for _, err := range validationErrs {
+				errs = append(errs, fmt.Sprintf("'%s' field is: %s", extractJSONTag(err.Field(), entryToPost), err.Tag()))
+			}
+		}
+
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   "invalid body",
+			Path:      ctx.Request.URL.Path,
+			Errors:    errs,
+		})
+		return
+	}
+
+	var errs []string
+	from, err := parseTime(entryToPost.From)
+	if err != nil {
+		errs = append(errs, "from field must be in format RFC3339")
+	}
+
+	to, err := parseTime(entryToPost.To)
+	if err != nil {
+		errs = append(errs, "to field must be in format RFC3339")
+	}
+
+	if len(errs) > 0 {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   "invalid body",
+			Path:      ctx.Request.URL.Path,
+			Errors:    errs,
+		})
+		return
+	}
+
+	patientExist, err := w.patientService.GetByDNI(principal(ctx), entryToPost.PatientDNI)
+	if err != nil {
+		waitlistError(ctx, err, fmt.Sprintf("patient with dni %s", entryToPost.PatientDNI))
+		return
+	}
+
+	dentistExist, err := w.dentistService.GetByLicense(entryToPost.DentistLicense)
+	if err != nil {
+		waitlistError(ctx, err, fmt.Sprintf("dentist with license %s", entryToPost.DentistLicense))
+		return
+	}
+
+	entryToCreate := waitlist.Entry{
+		PatientID:   patientExist.ID,
+		DentistID:   dentistExist.ID,
+		From:        from,
+		To:          to,
+		WindowStart: entryToPost.WindowStart,
+		WindowEnd:   entryToPost.WindowEnd,
+		Duration:    entryToPost.Duration,
+		Description: entryToPost.Description,
+	}
+
+	entryCreated, err := w.service.Create(principal(ctx), entryToCreate)
+	if err != nil {
+		waitlistError(ctx, err, "waitlist entry")
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, waitlistEntryBody(entryCreated, zone(ctx)))
+}
+
+// Cancel function to take a Waitlist Entry out of the queue
+//
+//	@Summary		Cancel a Waitlist Entry
+//	@Description	Take a Waitlist Entry out of the queue, its pending Offer is declined and moves on to the next Entry
+//	@Tags			Waitlist
+//	@Security		BearerAuth
+//	@Param			id	path		int	true	"Waitlist Entry ID"
+//	@Success		200	{object}	WaitlistEntryResponse
+//	@Failure		400	{object}	ErrorResponse
+//	@Failure		404	{object}	ErrorResponse
+//	@Failure		409	{object}	ErrorResponse
+//	@Failure		503	{object}	ErrorResponse
+//	@Router			/waitlist/{id} [delete]
+func (w *WaitlistHandler) Cancel(ctx *gin.Context) {
+	id, ok := waitlistID(ctx)
+	if !ok {
+		return
+	}
+
+	entryCancelled, err := w.service.Cancel(principal(ctx), id)
+	if err != nil {
+		waitlistError(ctx, err, fmt.Sprintf("waitlist entry with id %d", id))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, waitlistEntryBody(entryCancelled, zone(ctx)))
+}
+
+// Accept function to accept an Offer
+//
+//	@Summary		Accept an Offer
+//	@Description	Book the Appointment of a pending Offer, when the slot was booked by someone else meanwhile the Entry goes back to the queue
+//	@Tags			Waitlist
+//	@Security		BearerAuth
+//	@Param			id	path		int	true	"Offer ID"
+//	@Success		200	{object}	OfferResponse
+//	@Failure		400	{object}	ErrorResponse
+//	@Failure		404	{object}	ErrorResponse
+//	@Failure		409	{object}	ErrorResponse
+//	@Failure		503	{object}	ErrorResponse
+//	@Router			/waitlist/offers/{id}/accept [post]
+func (w *WaitlistHandler) Accept(ctx *gin.Context) {
+	id, ok := waitlistID(ctx)
+	if !ok {
+		return
+	}
+
+	offerAccepted, err := w.service.Accept(principal(ctx), id)
+	if err != nil {
+		waitlistError(ctx, err, fmt.Sprintf("offer with id %d", id))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, offerBody(offerAccepted, zone(ctx)))
+}
+
+// Decline function to decline an Offer
+//
+//	@Summary		Decline an Offer
+//	@Description	Decline a pending Offer, its Entry goes back to the queue and the slot is offered to the next Entry
+//	@Tags			Waitlist
+//	@Security		BearerAuth
+//	@Param			id	path		int	true	"Offer ID"
+//	@Success		200	{object}	OfferResponse
+//	@Failure		400	{object}	ErrorResponse
+//	@Failure		404	{object}	ErrorResponse
+//	@Failure		409	{object}	ErrorResponse
+//	@Failure		503	{object}	ErrorResponse
+//	@Router			/waitlist/offers/{id}/decline [post]
+func (w *WaitlistHandler) Decline(ctx *gin.Context) {
+	id, ok := waitlistID(ctx)
+	if !ok {
+		return
+	}
+
+	offerDeclined, err := w.service.Decline(principal(ctx), id)
+	if err != nil {
+		waitlistError(ctx, err, fmt.Sprintf("offer with id %d", id))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, offerBody(offerDeclined, zone(ctx)))
+}
+
+// waitlistID reads the id param, writing the response when it's invalid
+func waitlistID(ctx *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   "id param must be a number greater than 0",
+			Path:      ctx.Request.URL.Path,
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// waitlistError writes the response of an error of the waitlist, subject names what wasn't found
+func waitlistError(ctx *gin.Context, err error, subject string) {
+	switch {
+	case errors.Is(err, internal.ErNotFound):
+		ctx.JSON(http.StatusNotFound, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusNotFound,
+			Message:   fmt.Sprintf("%s %s", subject, err.Error()),
+			Path:      ctx.Request.URL.Path,
+		})
+
+	case errors.Is(err, internal.ErInvalidWaitlistEntry):
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   err.Error(),
+			Path:      ctx.Request.URL.Path,
+		})
+
+	case errors.Is(err, internal.ErForbidden):
+		ctx.JSON(http.StatusForbidden, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusForbidden,
+			Message:   err.Error(),
+			Path:      ctx.Request.URL.Path,
+		})
+
+	case errors.Is(err, internal.ErEntryClosed), errors.Is(err, internal.ErOfferClosed), errors.Is(err, internal.ErOfferTaken):
+		ctx.JSON(http.StatusConflict, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusConflict,
+			Message:   err.Error(),
+			Path:      ctx.Request.URL.Path,
+		})
+
+	default:
+		ctx.JSON(http.StatusServiceUnavailable, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusServiceUnavailable,
+			Message:   internal.ErServiceUnavailable.Error(),
+			Path:      ctx.Request.URL.Path,
+		})
+	}
+}
+
+func waitlistEntryBody(data waitlist.Entry, location *time.Location) WaitlistEntryResponse {
+	body := WaitlistEntryResponse{
+		Id:          data.ID,
+		PatientID:   data.PatientID,
+		DentistID:   data.DentistID,
+		From:        data.From.In(location),
+		To:          data.To.In(location),
+		WindowStart: data.WindowStart,
+		WindowEnd:   data.WindowEnd,
+		Duration:    data.Duration,
+		Description: data.Description,
+		Status:      string(data.Status),
+		CreatedAt:   data.CreatedAt.In(location),
+	}
+	for _, currentOffer := range data.Offers {
+		body.Offers = append(body.Offers, offerBody(currentOffer, location))
+	}
+	return body
+}
+
+func offerBody(data waitlist.Offer, location *time.Location) OfferResponse {
+	return OfferResponse{
+		Id:            data.ID,
+		EntryID:       data.EntryID,
+		PatientID:     data.PatientID,
+		DentistID:     data.DentistID,
+		Start:         data.Start.In(location),
+		End:           data.End.In(location),
+		Status:        string(data.Status),
+		ExpiresAt:     data.ExpiresAt.In(location),
+		AppointmentID: data.AppointmentID,
+		RespondedAt:   inZone(data.RespondedAt, location),
+	}
+}
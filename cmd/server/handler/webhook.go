@@ -0,0 +1,434 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/pagination"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/webhook"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// WebhookPost model for subscribing a URL to the events, no events means every event and the secret is
+// generated when it isn't given
+type WebhookPost struct {
+	URL    string   `json:"url" binding:"required"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret"`
+} //	@name	WebhookPost
+
+// WebhookPatch model for changing a subscription, the fields left out keep their value
+type WebhookPatch struct {
+	URL    *string   `json:"url"`
+	Events *[]string `json:"events"`
+	Active *bool     `json:"active"`
+} //	@name	WebhookPatch
+
+// WebhookResponse model for, response a Webhook subscription, the secret is only returned when it is created
+type WebhookResponse struct {
+	Id        uint      `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+} //	@name	WebhookResponse
+
+// DeliveryResponse model for, response a Delivery of an event to a Webhook
+type DeliveryResponse struct {
+	Id             uint       `json:"id"`
+	EventID        uint       `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	SubscriptionID uint       `json:"subscription_id"`
+	Status         string     `json:"status"`
+	Attempts       uint       `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+} //	@name	DeliveryResponse
+
+type WebhookService interface {
+	GetAll() ([]webhook.Subscription, error)
+	GetByID(id uint) (webhook.Subscription, error)
+	Create(subscription webhook.Subscription) (webhook.Subscription, error)
+	Update(subscription webhook.Subscription) (webhook.Subscription, error)
+	Delete(id uint) error
+	GetDeliveries(filter webhook.DeliveryFilter, page pagination.Request) (pagination.Page[webhook.Delivery], error)
+	Retry(id uint) (webhook.Delivery, error)
+}
+
+type WebhookHandler struct {
+	service WebhookService
+}
+
+func NewWebhookHandler(service WebhookService) *WebhookHandler {
+	return &WebhookHandler{service: service}
+}
+
+// GetAll function to get all Webhooks
+//
+//	@Summary		Get all Webhooks
+//	@Description	Get the Webhook subscriptions, without their secrets
+//	@Tags			Webhook
+//	@Security		BearerAuth
+//	@Success		200	{array}		WebhookResponse
+//	@Failure		503	{object}	ErrorResponse
+//	@Router			/webhooks [get]
+func (w *WebhookHandler) GetAll(ctx *gin.Context) {
+	subscriptions, err := w.service.GetAll()
+	if err != nil {
+		webhookError(ctx, err, "webhooks")
+		return
+	}
+
+	body := make([]WebhookResponse, 0, len(subscriptions))
+	for _, currentSubscription := range subscriptions {
+		body = append(body, webhookBody(currentSubscription, zone(ctx), false))
+	}
+
+	ctx.JSON(http.StatusOK, body)
+}
+
+// GetById function to get a Webhook by ID
+//
+//	@Summary		Get Webhook by ID
+//	@Description	Get a Webhook subscription by ID, without its secret
+//	@Tags			Webhook
+//	@Security		BearerAuth
+//	@Param			id	path		int	true	"Webhook ID"
+//	@Success		200	{object}	WebhookResponse
+//	@Failure		400	{object}	ErrorResponse
+//	@Failure		404	{object}	ErrorResponse
+//	@Failure		503	{object}	ErrorResponse
+//	@Router			/webhooks/{id} [get]
+func (w *WebhookHandler) GetById(ctx *gin.Context) {
+	id, ok := webhookID(ctx)
+	if !ok {
+		return
+	}
+
+	subscription, err := w.service.GetByID(id)
+	if err != nil {
+		webhookError(ctx, err, fmt.Sprintf("webhook with id %d", id))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, webhookBody(subscription, zone(ctx), false))
+}
+
+// Create function to subscribe a URL to the events
+//
+//	@Summary		Create a Webhook
+//	@Description	Subscribe a URL to the events of Patients, Dentists and Appointments, each event is POSTed as JSON signed with the secret.
+//	@Description	The X-Webhook-Signature header is sha256= and the hex HMAC-SHA256 of the X-Webhook-Timestamp header, a dot and the body.
+//	@Description	The secret is only returned by this endpoint.
+//	@Tags			Webhook
+//	@Security		BearerAuth
+//	@Param			Webhook	body		WebhookPost	true	"Webhook"
+//	@Success		201		{object}	WebhookResponse
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		503		{object}	ErrorResponse
+//	@Router			/webhooks [post]
+func (w *WebhookHandler) Create(ctx *gin.Context) {
+	webhookToPost := WebhookPost{}
+	err := ctx.ShouldBindJSON(&webhookToPost)
+	if err != nil {
+		var errs []string
+		var validationErrs validator.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			for _, err := range validationErrs {
+				errs = append(errs, fmt.Sprintf("'%s' field is: %s", extractJSONTag(err.Field(), webhookToPost), err.Tag()))
+			}
+		}
+
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   "invalid body",
+			Path:      ctx.Request.URL.Path,
+			Errors:    errs,
+		})
+		return
+	}
+
+	subscriptionCreated, err := w.service.Create(webhook.Subscription{
+		URL:    webhookToPost.URL,
+		Events: webhook.JoinEvents(webhookToPost.Events),
+		Secret: webhookToPost.Secret,
+		Active: true,
+	})
+	if err != nil {
+		webhookError(ctx, err, "webhook")
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, webhookBody(subscriptionCreated, zone(ctx), true))
+}
+
+// Patch function to change a Webhook
+//
+//	@Summary		Patch a Webhook
+//	@Description	Change the url, the events or whether a Webhook is active, deliveries due while it is inactive become dead
+//	@Tags			Webhook
+//	@Security		BearerAuth
+//	@Param			id		path		int				true	"Webhook ID"
+//	@Param			Webhook	body		WebhookPatch	true	"Webhook"
+//	@Success		200		{object}	WebhookResponse
+//	@Failure		400		{object}	ErrorResponse
+//	@Failure		404		{object}	ErrorResponse
+//	@Failure		503		{object}	ErrorResponse
+//	@Router			/webhooks/{id} [patch]
+func (w *WebhookHandler) Patch(ctx *gin.Context) {
+	id, ok := webhookID(ctx)
+	if !ok {
+		return
+	}
+
+	webhookToPatch := WebhookPatch{}
+	err := ctx.ShouldBindJSON(&webhookToPatch)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   "invalid body",
+			Path:      ctx.Request.URL.Path,
+		})
+		return
+	}
+
+	subscription, err := w.service.GetByID(id)
+	if err != nil {
+		webhookError(ctx, err, fmt.Sprintf("webhook with id %d", id))
+		return
+	}
+
+	if webhookToPatch.URL != nil {
+		subscription.URL = *webhookToPatch.URL
+	}
+	if webhookToPatch.Events != nil {
+		subscription.Events = webhook.JoinEvents(*webhookToPatch.Events)
+	}
+	if webhookToPatch.Active != nil {
+		subscription.Active = *webhookToPatch.Active
+	}
+
+	subscriptionUpdated, err := w.service.Update(subscription)
+	if err != nil {
+		webhookError(ctx, err, fmt.Sprintf("webhook with id %d", id))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, webhookBody(subscriptionUpdated, zone(ctx), false))
+}
+
+// Delete function to delete a Webhook
+//
+//	@Summary		Delete a Webhook
+//	@Description	Delete a Webhook subscription, its pending deliveries become dead
+//	@Tags			Webhook
+//	@Security		BearerAuth
+//	@Param			id	path	int	true	"Webhook ID"
+//	@Success		204
+//	@Failure		400	{object}	ErrorResponse
+//	@Failure		404	{object}	ErrorResponse
+//	@Failure		503	{object}	ErrorResponse
+//	@Router			/webhooks/{id} [delete]
+func (w *WebhookHandler) Delete(ctx *gin.Context) {
+	id, ok := webhookID(ctx)
+	if !ok {
+		return
+	}
+
+	err := w.service.Delete(id)
+	if err != nil {
+		webhookError(ctx, err, fmt.Sprintf("webhook with id %d", id))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// GetDeliveries function to get the Deliveries of the events
+//
+//	@Summary		Get the Deliveries
+//	@Description	Get a page of the Deliveries of the events to the Webhooks, status=dead lists the dead letters that failed every attempt
+//	@Tags			Webhook
+//	@Security		BearerAuth
+//	@Param			subscription_id	query		int		false	"Webhook ID"
+//	@Param			event_id		query		int		false	"Event ID"
+//	@Param			status			query		string	false	"pending, delivered or dead"
+//	@Param			sort			query		string	false	"Fields to sort by, prefixed with '-' for descending order, e.g. -created_at"
+//	@Param			page			query		int		false	"Page number, starting at 1"
+//	@Param			size			query		int		false	"Page size, 20 by default and 100 at most"
+//	@Success		200				{object}	PageResponse[DeliveryResponse]
+//	@Failure		400				{object}	ErrorResponse
+//	@Failure		503				{object}	ErrorResponse
+//	@Router			/webhooks/deliveries [get]
+func (w *WebhookHandler) GetDeliveries(ctx *gin.Context) {
+	page, errs := parsePageRequest(ctx, webhook.DeliverySortColumns)
+
+	subscriptionID, err := queryUint(ctx, "subscription_id")
+	if err != nil {
+		errs = append(errs, "'subscription_id' query param must be a number greater than 0")
+	}
+
+	eventID, err := queryUint(ctx, "event_id")
+	if err != nil {
+		errs = append(errs, "'event_id' query param must be a number greater than 0")
+	}
+
+	status := webhook.DeliveryStatus(ctx.Query("status"))
+	if status != "" && !status.Valid() {
+		errs = append(errs, fmt.Sprintf("'status' query param must be one of %v", webhook.DeliveryStatuses))
+	}
+
+	if len(errs) > 0 {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   "invalid query params",
+			Path:      ctx.Request.URL.Path,
+			Errors:    errs,
+		})
+		return
+	}
+
+	deliveries, err := w.service.GetDeliveries(webhook.DeliveryFilter{
+		SubscriptionID: subscriptionID,
+		EventID:        eventID,
+		Status:         status,
+	}, page)
+	if err != nil {
+		webhookError(ctx, err, "deliveries")
+		return
+	}
+
+	var body []DeliveryResponse
+	for _, currentDelivery := range deliveries.Items {
+		body = append(body, deliveryBody(currentDelivery, zone(ctx)))
+	}
+
+	ctx.JSON(http.StatusOK, newPageResponse(ctx, deliveries, body))
+}
+
+// RetryDelivery function to retry a dead Delivery
+//
+//	@Summary		Retry a Delivery
+//	@Description	Send a dead Delivery again, with its attempts reset
+//	@Tags			Webhook
+//	@Security		BearerAuth
+//	@Param			id	path		int	true	"Delivery ID"
+//	@Success		200	{object}	DeliveryResponse
+//	@Failure		400	{object}	ErrorResponse
+//	@Failure		404	{object}	ErrorResponse
+//	@Failure		409	{object}	ErrorResponse
+//	@Failure		503	{object}	ErrorResponse
+//	@Router			/webhooks/deliveries/{id}/retry [post]
+func (w *WebhookHandler) RetryDelivery(ctx *gin.Context) {
+	id, ok := webhookID(ctx)
+	if !ok {
+		return
+	}
+
+	deliveryRetried, err := w.service.Retry(id)
+	if err != nil {
+		webhookError(ctx, err, fmt.Sprintf("delivery with id %d", id))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, deliveryBody(deliveryRetried, zone(ctx)))
+}
+
+// webhookID reads the id param, writing the response when it's invalid
+func webhookID(ctx *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   "id param must be a number greater than 0",
+			Path:      ctx.Request.URL.Path,
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// webhookError writes the response of an error of the webhooks, subject names what wasn't found
+func webhookError(ctx *gin.Context, err error, subject string) {
+	switch {
+	case errors.Is(err, internal.ErNotFound):
+		ctx.JSON(http.StatusNotFound, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusNotFound,
+			Message:   fmt.Sprintf("%s %s", subject, err.Error()),
+			Path:      ctx.Request.URL.Path,
+		})
+
+	case errors.Is(err, internal.ErInvalidWebhook):
+		ctx.JSON(http.StatusBadRequest, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusBadRequest,
+			Message:   err.Error(),
+			Path:      ctx.Request.URL.Path,
+		})
+
+	case errors.Is(err, internal.ErDeliveryNotDead):
+		ctx.JSON(http.StatusConflict, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusConflict,
+			Message:   err.Error(),
+			Path:      ctx.Request.URL.Path,
+		})
+
+	default:
+		ctx.JSON(http.StatusServiceUnavailable, ErrorResponse{
+			Timestamp: time.Now().Format(time.RFC3339),
+			Status:    http.StatusServiceUnavailable,
+			Message:   internal.ErServiceUnavailable.Error(),
+			Path:      ctx.Request.URL.Path,
+		})
+	}
+}
+
+func webhookBody(data webhook.Subscription, location *time.Location, withSecret bool) WebhookResponse {
+	body := WebhookResponse{
+		Id:        data.ID,
+		URL:       data.URL,
+		Events:    data.EventTypes(),
+		Active:    data.Active,
+		CreatedAt: data.CreatedAt.In(location),
+		UpdatedAt: data.UpdatedAt.In(location),
+	}
+	if body.Events == nil {
+		body.Events = []string{}
+	}
+	if withSecret {
+		body.Secret = data.Secret
+	}
+	return body
+}
+
+func deliveryBody(data webhook.Delivery, location *time.Location) DeliveryResponse {
+	return DeliveryResponse{
+		Id:             data.ID,
+		EventID:        data.EventID,
+		EventType:      data.EventType,
+		SubscriptionID: data.SubscriptionID,
+		Status:         string(data.Status),
+		Attempts:       data.Attempts,
+		NextAttemptAt:  data.NextAttemptAt.In(location),
+		LastStatusCode: data.LastStatusCode,
+		LastError:      data.LastError,
+		DeliveredAt:    inZone(data.DeliveredAt, location),
+		CreatedAt:      data.CreatedAt.In(location),
+	}
+}
@@ -1,30 +1,119 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/auth"
 	"github.com/gin-gonic/gin"
 )
 
-type AuthKeys struct {
-	SecretKey string
-	PubKey    string
+// principalKey is the key of the authenticated principal in the gin context
+const principalKey = "principal"
+
+// FeedTokenParam is the query param with the feed token of the calendar feeds
+const FeedTokenParam = "token"
+
+type Authenticator interface {
+	Authenticate(accessToken string) (auth.Principal, error)
+	AuthenticateFeed(feedToken string) (auth.Principal, error)
+}
+
+type Auth struct {
+	authenticator Authenticator
+}
+
+func NewAuth(authenticator Authenticator) *Auth {
+	return &Auth{authenticator: authenticator}
 }
 
-func NewAuthKeys(secretKey string, pubKey string) *AuthKeys {
-	return &AuthKeys{
-		SecretKey: secretKey,
-		PubKey:    pubKey,
+// Validate requires a valid access token in the Authorization header and stores its principal in the context
+func (v *Auth) Validate(ctx *gin.Context) {
+	header := ctx.GetHeader("Authorization")
+	token, found := strings.CutPrefix(header, "Bearer ")
+	if !found || token == "" {
+		abort(ctx, http.StatusUnauthorized, "a bearer access token is required")
+		return
 	}
+
+	principal, err := v.authenticator.Authenticate(token)
+	if err != nil {
+		switch {
+		case errors.Is(err, internal.ErTokenExpired):
+			abort(ctx, http.StatusUnauthorized, err.Error())
+
+		default:
+			abort(ctx, http.StatusUnauthorized, internal.ErInvalidToken.Error())
+		}
+		return
+	}
+
+	ctx.Set(principalKey, principal)
+	ctx.Next()
 }
 
-func (v *AuthKeys) Validate(ctx *gin.Context) {
-	secretKey := ctx.GetHeader("SECRET_KEY")
-	pubKey := ctx.GetHeader("PUBLIC_KEY")
+// ValidateFeed requires a valid feed token in the token query param, so calendar apps that can't send
+// headers can subscribe, and stores its principal in the context, without the param it works like Validate
+func (v *Auth) ValidateFeed(ctx *gin.Context) {
+	token, found := ctx.GetQuery(FeedTokenParam)
+	if !found {
+		v.Validate(ctx)
+		return
+	}
+
+	principal, err := v.authenticator.AuthenticateFeed(token)
+	if err != nil {
+		switch {
+		case errors.Is(err, internal.ErInvalidToken):
+			abort(ctx, http.StatusUnauthorized, err.Error())
+
+		default:
+			abort(ctx, http.StatusServiceUnavailable, internal.ErServiceUnavailable.Error())
+		}
+		return
+	}
+
+	ctx.Set(principalKey, principal)
+	ctx.Next()
+}
+
+// Require allows the request only to principals with any of the given roles, it must run after Validate
+func (v *Auth) Require(roles ...auth.Role) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		principal, ok := Principal(ctx)
+		if !ok {
+			abort(ctx, http.StatusUnauthorized, "a bearer access token is required")
+			return
+		}
+
+		if !principal.Is(roles...) {
+			abort(ctx, http.StatusForbidden, internal.ErForbidden.Error())
+			return
+		}
 
-	if secretKey == v.SecretKey && pubKey == v.PubKey {
 		ctx.Next()
 	}
+}
+
+// Principal returns the identity stored by Validate
+func Principal(ctx *gin.Context) (auth.Principal, bool) {
+	value, ok := ctx.Get(principalKey)
+	if !ok {
+		return auth.Principal{}, false
+	}
+
+	principal, ok := value.(auth.Principal)
+	return principal, ok
+}
 
-	ctx.AbortWithStatus(http.StatusUnauthorized)
+func abort(ctx *gin.Context, status int, message string) {
+	ctx.AbortWithStatusJSON(status, gin.H{
+		"timestamp": time.Now().Format(time.RFC3339),
+		"status":    status,
+		"message":   message,
+		"path":      ctx.Request.URL.Path,
+	})
 }
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deadline gives the request timeout from now to read its body and to write its response, in place of the
+// timeouts of the server, for the routes that stream files too large for them
+func Deadline(timeout time.Duration) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		deadline := time.Now().Add(timeout)
+		controller := http.NewResponseController(ctx.Writer)
+		err := controller.SetReadDeadline(deadline)
+		if err == nil {
+			err = controller.SetWriteDeadline(deadline)
+		}
+		if err != nil {
+			log.Printf("middleware: deadline of %s not extended: %v", ctx.FullPath(), err)
+		}
+
+		ctx.Next()
+	}
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// locationKey is the key of the time zone of the response in the gin context
+const locationKey = "location"
+
+// TimeZoneParam is the query param with the IANA time zone the dates of a response are rendered in
+const TimeZoneParam = "tz"
+
+// TimeZone stores in the context the time zone the dates of the response are rendered in, the one given
+// by the tz query param or the default one
+func TimeZone(defaultLocation *time.Location) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		location := defaultLocation
+		if name := ctx.Query(TimeZoneParam); name != "" {
+			var err error
+			location, err = time.LoadLocation(name)
+			if err != nil {
+				abort(ctx, http.StatusBadRequest, "'tz' query param must be an IANA time zone like America/Argentina/Buenos_Aires")
+				return
+			}
+		}
+
+		ctx.Set(locationKey, location)
+		ctx.Next()
+	}
+}
+
+// Location returns the time zone stored by TimeZone, UTC when there is none
+func Location(ctx *gin.Context) *time.Location {
+	value, ok := ctx.Get(locationKey)
+	if !ok {
+		return time.UTC
+	}
+
+	location, ok := value.(*time.Location)
+	if !ok {
+		return time.UTC
+	}
+	return location
+}
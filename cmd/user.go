@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/auth"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/user"
+)
+
+// userCommand runs the user subcommands, only create for now
+func userCommand(args []string) error {
+	if len(args) == 0 || args[0] != "create" {
+		return errors.New("usage: user create -username <name> [-role admin|receptionist|dentist] [-license <license>] [-password <password>]")
+	}
+	return createUser(args[1:])
+}
+
+// createUser creates a user like POST /users does, without needing an admin to log in first. The password is read
+// from the first line of the standard input when the flag is empty, so it doesn't stay in the shell history
+func createUser(args []string) error {
+	set, loader := configFlags("user create")
+	username := set.String("username", "", "name the user logs in with")
+	role := set.String("role", string(auth.RoleAdmin), "admin, receptionist or dentist")
+	license := set.String("license", "", "license of the dentist a dentist user is linked to")
+	password := set.String("password", "", "password of the user, read from the standard input when empty")
+	err := set.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *username == "" || set.NArg() > 0 {
+		return errors.New("user create needs a -username and no other arguments")
+	}
+
+	if *password == "" {
+		fmt.Fprint(os.Stderr, "Password: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("reading password: %w", err)
+		}
+		*password = strings.TrimRight(line, "\r\n")
+	}
+
+	envConfig, err := loadConfig(loader)
+	if err != nil {
+		return err
+	}
+
+	a, err := newApp(envConfig)
+	if err != nil {
+		return err
+	}
+
+	data := user.User{Username: *username, Role: auth.Role(*role)}
+	if *license != "" {
+		dentist, err := a.dentists.GetByLicense(*license)
+		if err != nil {
+			return fmt.Errorf("dentist %s: %w", *license, err)
+		}
+		data.DentistID = &dentist.ID
+	}
+
+	created, err := a.users.Create(data, *password)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Created %s %s with id %d\n", created.Role, created.Username, created.ID)
+	return nil
+}
@@ -32,19 +32,77 @@ const docTemplate = `{
     "paths": {
         "/appointments": {
             "get": {
-                "description": "Get all Appointments",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get a page of Appointments, filtered and sorted by the query params",
                 "tags": [
                     "Appointment"
                 ],
                 "summary": "Get all Appointments",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Dentist ID",
+                        "name": "dentist_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Patient ID",
+                        "name": "patient_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Appointments ending after this date, in format RFC3339",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Appointments starting before this date, in format RFC3339",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated statuses, e.g. scheduled,confirmed",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Fields to sort by, prefixed with '-' for descending order, e.g. -date",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number, starting at 1",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size, 20 by default and 100 at most",
+                        "name": "size",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/AppointmentResponse"
-                            }
+                            "$ref": "#/definitions/PageResponse-AppointmentResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
                         }
                     },
                     "503": {
@@ -58,7 +116,7 @@ const docTemplate = `{
             "post": {
                 "security": [
                     {
-                        "APIKey": []
+                        "BearerAuth": []
                     }
                 ],
                 "description": "Create a Appointment",
@@ -67,13 +125,6 @@ const docTemplate = `{
                 ],
                 "summary": "Create a Appointment",
                 "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Public Key",
-                        "name": "PUB_KEY",
-                        "in": "header",
-                        "required": true
-                    },
                     {
                         "description": "AppointmentResponse",
                         "name": "Appointment",
@@ -112,19 +163,254 @@ const docTemplate = `{
                 }
             }
         },
+        "/appointments/export": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Download every Appointment matching the filters of the list as CSV, JSON Lines or XLSX, with a column per field of AppointmentResponse.\nWith detail the rows have the names of the Patient and the Dentist instead of the dates of each status, like AppointmentDetailResponse.\nThe format is read from the format query param, or else from the Accept header, CSV by default. The file is streamed, so exports of any size are downloaded at once.",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "Appointment"
+                ],
+                "summary": "Export Appointments",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "csv, jsonl or xlsx",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Add the names of the Patient and the Dentist",
+                        "name": "detail",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Dentist ID",
+                        "name": "dentist_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Patient ID",
+                        "name": "patient_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Appointments ending after this date, in format RFC3339",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Appointments starting before this date, in format RFC3339",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated statuses, e.g. scheduled,confirmed",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Fields to sort by, prefixed with '-' for descending order, e.g. dentist_id,date",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/appointments/q": {
             "get": {
-                "description": "Get Appointment by DNI",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the Appointment history, upcoming and past, of a Patient by DNI or of a Dentist by License",
                 "tags": [
                     "Appointment"
                 ],
-                "summary": "Get Appointment by DNI",
+                "summary": "Get Appointments by DNI or License",
                 "parameters": [
                     {
                         "type": "string",
                         "description": "Patient DNI",
                         "name": "dni",
-                        "in": "query",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Dentist License",
+                        "name": "license",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Fields to sort by, prefixed with '-' for descending order, date by default",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number, starting at 1",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size, 20 by default and 100 at most",
+                        "name": "size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/PageResponse-AppointmentDetailResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/appointments/series": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Book an Appointment that repeats every interval days, weeks or months, until it has count occurrences or reaches the until date.\nOccurrences that overlap other Appointments or fall outside the working hours fail the whole series, or are skipped and reported when on_conflict is skip.",
+                "tags": [
+                    "Appointment"
+                ],
+                "summary": "Create a series of Appointments",
+                "parameters": [
+                    {
+                        "description": "Series",
+                        "name": "Series",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/SeriesPost"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/SeriesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/SeriesConflictResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/appointments/series/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get a recurring series with all its Appointments ordered by date",
+                "tags": [
+                    "Appointment"
+                ],
+                "summary": "Get a series of Appointments",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Series ID",
+                        "name": "id",
+                        "in": "path",
                         "required": true
                     }
                 ],
@@ -132,7 +418,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/AppointmentResponse"
+                            "$ref": "#/definitions/SeriesResponse"
                         }
                     },
                     "400": {
@@ -158,6 +444,11 @@ const docTemplate = `{
         },
         "/appointments/{id}": {
             "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
                 "description": "Get Appointment by id",
                 "tags": [
                     "Appointment"
@@ -202,7 +493,7 @@ const docTemplate = `{
             "put": {
                 "security": [
                     {
-                        "APIKey": []
+                        "BearerAuth": []
                     }
                 ],
                 "description": "Update a Appointment",
@@ -211,13 +502,6 @@ const docTemplate = `{
                 ],
                 "summary": "Update a Appointment",
                 "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Public Key",
-                        "name": "PUB_KEY",
-                        "in": "header",
-                        "required": true
-                    },
                     {
                         "type": "integer",
                         "description": "Appointment ID",
@@ -271,7 +555,7 @@ const docTemplate = `{
             "delete": {
                 "security": [
                     {
-                        "APIKey": []
+                        "BearerAuth": []
                     }
                 ],
                 "description": "Delete a Appointment",
@@ -280,13 +564,6 @@ const docTemplate = `{
                 ],
                 "summary": "Delete a Appointment",
                 "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Public Key",
-                        "name": "PUB_KEY",
-                        "in": "header",
-                        "required": true
-                    },
                     {
                         "type": "integer",
                         "description": "Appointment ID",
@@ -319,7 +596,7 @@ const docTemplate = `{
             "patch": {
                 "security": [
                     {
-                        "APIKey": []
+                        "BearerAuth": []
                     }
                 ],
                 "description": "Patch a Appointment",
@@ -328,13 +605,6 @@ const docTemplate = `{
                 ],
                 "summary": "Patch a Appointment",
                 "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Public Key",
-                        "name": "PUB_KEY",
-                        "in": "header",
-                        "required": true
-                    },
                     {
                         "type": "integer",
                         "description": "Appointment ID",
@@ -386,23 +656,61 @@ const docTemplate = `{
                 }
             }
         },
-        "/dentists": {
-            "get": {
-                "description": "Get all Dentists",
+        "/appointments/{id}/cancel": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Move a scheduled or confirmed Appointment to cancelled, keeping it in the history and freeing its slot",
                 "tags": [
-                    "Dentist"
+                    "Appointment"
                 ],
-                "summary": "Get all Dentists",
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/DentistResponse"
-                            }
-                        }
-                    },
+                "summary": "Cancel a Appointment",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Appointment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Reason of the cancellation",
+                        "name": "Reason",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/CancelPost"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/AppointmentResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
                     "503": {
                         "description": "Service Unavailable",
                         "schema": {
@@ -410,41 +718,34 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
+            }
+        },
+        "/appointments/{id}/check-in": {
             "post": {
                 "security": [
                     {
-                        "APIKey": []
+                        "BearerAuth": []
                     }
                 ],
-                "description": "Create a Dentist",
+                "description": "Move a scheduled or confirmed Appointment to checked_in when the Patient arrives",
                 "tags": [
-                    "Dentist"
+                    "Appointment"
                 ],
-                "summary": "Create a Dentist",
+                "summary": "Check in a Appointment",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Public Key",
-                        "name": "PUB_KEY",
-                        "in": "header",
+                        "type": "integer",
+                        "description": "Appointment ID",
+                        "name": "id",
+                        "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "DentistResponse",
-                        "name": "Body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/DentistPost"
-                        }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/DentistResponse"
+                            "$ref": "#/definitions/AppointmentResponse"
                         }
                     },
                     "400": {
@@ -453,6 +754,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/ErrorResponse"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
                     "409": {
                         "description": "Conflict",
                         "schema": {
@@ -468,19 +775,24 @@ const docTemplate = `{
                 }
             }
         },
-        "/dentists/q": {
-            "get": {
-                "description": "Get Dentist by License",
+        "/appointments/{id}/complete": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Move a checked_in Appointment to completed",
                 "tags": [
-                    "Dentist"
+                    "Appointment"
                 ],
-                "summary": "Get Dentist by License",
+                "summary": "Complete a Appointment",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Dentist License",
-                        "name": "license",
-                        "in": "query",
+                        "type": "integer",
+                        "description": "Appointment ID",
+                        "name": "id",
+                        "in": "path",
                         "required": true
                     }
                 ],
@@ -488,7 +800,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/DentistResponse"
+                            "$ref": "#/definitions/AppointmentResponse"
                         }
                     },
                     "400": {
@@ -502,21 +814,38 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/ErrorResponse"
                         }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/dentists/{id}": {
-            "get": {
-                "description": "Get Dentist by id",
+        "/appointments/{id}/confirm": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Move a scheduled Appointment to confirmed",
                 "tags": [
-                    "Dentist"
+                    "Appointment"
                 ],
-                "summary": "Get Dentist by id",
+                "summary": "Confirm a Appointment",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Dentist ID",
+                        "description": "Appointment ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -526,7 +855,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/DentistResponse"
+                            "$ref": "#/definitions/AppointmentResponse"
                         }
                     },
                     "400": {
@@ -541,6 +870,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/ErrorResponse"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
                     "503": {
                         "description": "Service Unavailable",
                         "schema": {
@@ -548,41 +883,34 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "put": {
+            }
+        },
+        "/appointments/{id}/no-show": {
+            "post": {
                 "security": [
                     {
-                        "APIKey": []
+                        "BearerAuth": []
                     }
                 ],
-                "description": "Update a Dentist",
+                "description": "Move a scheduled or confirmed Appointment to no_show",
                 "tags": [
-                    "Dentist"
+                    "Appointment"
                 ],
-                "summary": "Update a Dentist",
+                "summary": "Mark a Appointment as no-show",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Public Key",
-                        "name": "PUB_KEY",
-                        "in": "header",
+                        "type": "integer",
+                        "description": "Appointment ID",
+                        "name": "id",
+                        "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "DentistResponse",
-                        "name": "Dentist",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/DentistPut"
-                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/DentistResponse"
+                            "$ref": "#/definitions/AppointmentResponse"
                         }
                     },
                     "400": {
@@ -610,35 +938,39 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/appointments/{id}/reminders": {
+            "get": {
                 "security": [
                     {
-                        "APIKey": []
+                        "BearerAuth": []
                     }
                 ],
-                "description": "Delete a Dentist",
+                "description": "Get the Reminders sent for an Appointment, date is the date of the Appointment each one was sent for",
                 "tags": [
-                    "Dentist"
+                    "Appointment"
                 ],
-                "summary": "Delete a Dentist",
+                "summary": "Get the Reminders of an Appointment",
                 "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Public Key",
-                        "name": "PUB_KEY",
-                        "in": "header",
-                        "required": true
-                    },
                     {
                         "type": "integer",
-                        "description": "Dentist ID",
+                        "description": "Appointment ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/ReminderResponse"
+                            }
+                        }
+                    },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
@@ -658,33 +990,46 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
+            }
+        },
+        "/appointments/{id}/series": {
             "patch": {
                 "security": [
                     {
-                        "APIKey": []
+                        "BearerAuth": []
                     }
                 ],
-                "description": "Patch a Dentist",
+                "description": "Apply the given fields to this Appointment, this and the following ones or all the Appointments of its series.\nA new date moves every Appointment by the same number of days to the same time, completed, cancelled and no-show ones are left as they are.",
                 "tags": [
-                    "Dentist"
+                    "Appointment"
                 ],
-                "summary": "Patch a Dentist",
+                "summary": "Update the Appointments of a series",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Public Key",
-                        "name": "PUB_KEY",
-                        "in": "header",
+                        "type": "integer",
+                        "description": "Appointment ID",
+                        "name": "id",
+                        "in": "path",
                         "required": true
                     },
                     {
-                        "description": "DentistResponse",
-                        "name": "Dentist",
+                        "enum": [
+                            "this",
+                            "following",
+                            "all"
+                        ],
+                        "type": "string",
+                        "description": "Appointments of the series to update, this by default",
+                        "name": "scope",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Fields to update",
+                        "name": "Appointment",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/DentistPatch"
+                            "$ref": "#/definitions/AppointmentPatch"
                         }
                     }
                 ],
@@ -692,7 +1037,10 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/DentistResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/AppointmentResponse"
+                            }
                         }
                     },
                     "400": {
@@ -701,6 +1049,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/ErrorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -710,7 +1064,7 @@ const docTemplate = `{
                     "409": {
                         "description": "Conflict",
                         "schema": {
-                            "$ref": "#/definitions/ErrorResponse"
+                            "$ref": "#/definitions/SeriesConflictResponse"
                         }
                     },
                     "503": {
@@ -722,65 +1076,55 @@ const docTemplate = `{
                 }
             }
         },
-        "/patients": {
-            "get": {
-                "description": "Get all Patients",
-                "tags": [
-                    "Patient"
-                ],
-                "summary": "Get all Patients",
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/PatientResponse"
-                            }
-                        }
-                    },
-                    "503": {
-                        "description": "Service Unavailable",
-                        "schema": {
-                            "$ref": "#/definitions/ErrorResponse"
-                        }
-                    }
-                }
-            },
+        "/appointments/{id}/series/cancel": {
             "post": {
                 "security": [
                     {
-                        "APIKey": []
+                        "BearerAuth": []
                     }
                 ],
-                "description": "Create a Patient",
+                "description": "Cancel this Appointment, this and the following ones or all the Appointments of its series that haven't started yet",
                 "tags": [
-                    "Patient"
+                    "Appointment"
                 ],
-                "summary": "Create a Patient",
+                "summary": "Cancel the Appointments of a series",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Public Key",
-                        "name": "PUB_KEY",
-                        "in": "header",
+                        "type": "integer",
+                        "description": "Appointment ID",
+                        "name": "id",
+                        "in": "path",
                         "required": true
                     },
                     {
-                        "description": "PatientResponse",
-                        "name": "Body",
+                        "enum": [
+                            "this",
+                            "following",
+                            "all"
+                        ],
+                        "type": "string",
+                        "description": "Appointments of the series to cancel, this by default",
+                        "name": "scope",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Reason of the cancellation",
+                        "name": "Reason",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/PatientPost"
+                            "$ref": "#/definitions/CancelPost"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/PatientResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/AppointmentResponse"
+                            }
                         }
                     },
                     "400": {
@@ -789,42 +1133,105 @@ const docTemplate = `{
                             "$ref": "#/definitions/ErrorResponse"
                         }
                     },
-                    "409": {
-                        "description": "Conflict",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/ErrorResponse"
                         }
                     },
-                    "503": {
-                        "description": "Service Unavailable",
-                        "schema": {
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
                             "$ref": "#/definitions/ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/patients/q": {
+        "/audit": {
             "get": {
-                "description": "Get Patient by DNI",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get a page of the audit log, newest first unless another order is requested, each entry holds the fields that changed",
                 "tags": [
-                    "Patient"
+                    "Audit"
                 ],
-                "summary": "Get Patient by DNI",
+                "summary": "Get the audit log",
                 "parameters": [
                     {
+                        "enum": [
+                            "patient",
+                            "dentist",
+                            "appointment"
+                        ],
                         "type": "string",
-                        "description": "Patient DNI",
-                        "name": "dni",
-                        "in": "query",
-                        "required": true
+                        "description": "Changed entity",
+                        "name": "entity",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Changed entity ID",
+                        "name": "id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of the User that made the change",
+                        "name": "actor_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Changed on or after this date, in format RFC3339",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Changed before this date, in format RFC3339",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Fields to sort by, prefixed with '-' for descending order, e.g. created_at",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number, starting at 1",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size, 20 by default and 100 at most",
+                        "name": "size",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/PatientResponse"
+                            "$ref": "#/definitions/PageResponse-AuditEntryResponse"
                         }
                     },
                     "400": {
@@ -833,6 +1240,52 @@ const docTemplate = `{
                             "$ref": "#/definitions/ErrorResponse"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/feed-token": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Issue a new feed token for the authenticated User, it gives calendar apps read access to the calendar feeds with the permissions of the User.\nOnly the latest feed token works, the previous one is revoked.",
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Rotate the feed token",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/FeedTokenResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -848,27 +1301,29 @@ const docTemplate = `{
                 }
             }
         },
-        "/patients/{id}": {
-            "get": {
-                "description": "Get Patient by id",
+        "/auth/login": {
+            "post": {
+                "description": "Check the credentials of a User and issue an access token and a refresh token",
                 "tags": [
-                    "Patient"
+                    "Auth"
                 ],
-                "summary": "Get Patient by id",
+                "summary": "Login",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Patient ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Credentials",
+                        "name": "Credentials",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/LoginPost"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/PatientResponse"
+                            "$ref": "#/definitions/TokensResponse"
                         }
                     },
                     "400": {
@@ -877,47 +1332,36 @@ const docTemplate = `{
                             "$ref": "#/definitions/ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
                         "schema": {
                             "$ref": "#/definitions/ErrorResponse"
                         }
                     }
                 }
-            },
-            "put": {
-                "security": [
-                    {
-                        "APIKey": []
-                    }
-                ],
-                "description": "Update a Patient",
+            }
+        },
+        "/auth/refresh": {
+            "post": {
+                "description": "Issue a new access token and refresh token from a valid refresh token",
                 "tags": [
-                    "Patient"
+                    "Auth"
                 ],
-                "summary": "Update a Patient",
+                "summary": "Refresh",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Public Key",
-                        "name": "PUB_KEY",
-                        "in": "header",
-                        "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Patient ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "PatientResponse",
-                        "name": "Body",
+                        "description": "Refresh token",
+                        "name": "Token",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/PatientPut"
+                            "$ref": "#/definitions/RefreshPost"
                         }
                     }
                 ],
@@ -925,7 +1369,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/PatientResponse"
+                            "$ref": "#/definitions/TokensResponse"
                         }
                     },
                     "400": {
@@ -934,14 +1378,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/ErrorResponse"
-                        }
-                    },
-                    "409": {
-                        "description": "Conflict",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/ErrorResponse"
                         }
@@ -953,43 +1391,79 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/dentists": {
+            "get": {
                 "security": [
                     {
-                        "APIKey": []
+                        "BearerAuth": []
                     }
                 ],
-                "description": "Delete a Patient",
+                "description": "Get a page of Dentists, filtered and sorted by the query params",
                 "tags": [
-                    "Patient"
+                    "Dentist"
                 ],
-                "summary": "Delete a Patient",
+                "summary": "Get all Dentists",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Public Key",
-                        "name": "PUB_KEY",
-                        "in": "header",
-                        "required": true
+                        "description": "Dentist name prefix",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Dentist last name prefix",
+                        "name": "last_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Dentist License",
+                        "name": "license",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Also list deleted Dentists, only for admins",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Fields to sort by, prefixed with '-' for descending order, e.g. last_name,-id",
+                        "name": "sort",
+                        "in": "query"
                     },
                     {
                         "type": "integer",
-                        "description": "Patient ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Page number, starting at 1",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size, 20 by default and 100 at most",
+                        "name": "size",
+                        "in": "query"
                     }
                 ],
                 "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/PageResponse-DentistResponse"
+                        }
+                    },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/ErrorResponse"
                         }
@@ -1002,47 +1476,33 @@ const docTemplate = `{
                     }
                 }
             },
-            "patch": {
+            "post": {
                 "security": [
                     {
-                        "APIKey": []
+                        "BearerAuth": []
                     }
                 ],
-                "description": "Patch a Patient",
+                "description": "Create a Dentist",
                 "tags": [
-                    "Patient"
+                    "Dentist"
                 ],
-                "summary": "Patch a Patient",
+                "summary": "Create a Dentist",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Public Key",
-                        "name": "PUB_KEY",
-                        "in": "header",
-                        "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Patient ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "PatientResponse",
+                        "description": "DentistResponse",
                         "name": "Body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/PatientPatch"
+                            "$ref": "#/definitions/DentistPost"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/PatientResponse"
+                            "$ref": "#/definitions/DentistResponse"
                         }
                     },
                     "400": {
@@ -1051,12 +1511,6 @@ const docTemplate = `{
                             "$ref": "#/definitions/ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/ErrorResponse"
-                        }
-                    },
                     "409": {
                         "description": "Conflict",
                         "schema": {
@@ -1071,35 +1525,3500 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "AppointmentPatch": {
+        },
+        "/dentists/export": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Download every Dentist matching the filters of the list as CSV, JSON Lines or XLSX, with a column per field of DentistResponse.\nThe format is read from the format query param, or else from the Accept header, CSV by default. The file is streamed, so exports of any size are downloaded at once.",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "Dentist"
+                ],
+                "summary": "Export Dentists",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "csv, jsonl or xlsx",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Dentist name prefix",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Dentist last name prefix",
+                        "name": "last_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Dentist License",
+                        "name": "license",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Also export deleted Dentists, only for admins",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Fields to sort by, prefixed with '-' for descending order, e.g. last_name",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/dentists/import": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create the Dentists of a CSV file, with a header naming the columns like the fields of DentistPost, or of a JSON Lines file with a DentistPost per line.\nEvery row is checked like in Create, licenses repeated in the file or already taken are rejected too.\nThe Dentists are only created when every row is valid, otherwise the report lists the invalid rows by line; dry_run only checks the file.",
+                "consumes": [
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "Dentist"
+                ],
+                "summary": "Import Dentists",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Check the file without creating the Dentists",
+                        "name": "dry_run",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "csv or jsonl, read from the Content-Type when it is empty",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "description": "CSV or JSON Lines file",
+                        "name": "file",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/ImportReportResponse"
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/ImportReportResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/ImportReportResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/dentists/q": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get Dentist by License",
+                "tags": [
+                    "Dentist"
+                ],
+                "summary": "Get Dentist by License",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Dentist License",
+                        "name": "license",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/DentistResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/dentists/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get Dentist by id",
+                "tags": [
+                    "Dentist"
+                ],
+                "summary": "Get Dentist by id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Dentist ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/DentistResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update a Dentist",
+                "tags": [
+                    "Dentist"
+                ],
+                "summary": "Update a Dentist",
+                "parameters": [
+                    {
+                        "description": "DentistResponse",
+                        "name": "Dentist",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/DentistPut"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/DentistResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete a Dentist, upcoming appointments block the deletion unless the strategy is cascade, which deletes them too,\nor reassign, which moves them to the Dentist with the given license if they fit in its schedule",
+                "tags": [
+                    "Dentist"
+                ],
+                "summary": "Delete a Dentist",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Dentist ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "reject",
+                            "cascade",
+                            "reassign"
+                        ],
+                        "type": "string",
+                        "description": "What happens to the upcoming appointments, reject by default",
+                        "name": "strategy",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "License of the Dentist that gets the appointments with the reassign strategy",
+                        "name": "license",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Patch a Dentist",
+                "tags": [
+                    "Dentist"
+                ],
+                "summary": "Patch a Dentist",
+                "parameters": [
+                    {
+                        "description": "DentistResponse",
+                        "name": "Dentist",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/DentistPatch"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/DentistResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/dentists/{id}/availability": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the free Slots of a Dentist, computed from the Working Hours minus the booked Appointments",
+                "tags": [
+                    "Dentist"
+                ],
+                "summary": "Get Dentist Availability",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Dentist ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start of the search in format RFC3339, now by default",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End of the search in format RFC3339, a week after from by default",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Length of the slots, 30m by default",
+                        "name": "slot",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/AvailabilityResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/dentists/{id}/calendar.ics": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "FeedToken": []
+                    }
+                ],
+                "description": "Get the Appointments of a Dentist as an iCalendar feed, from 90 days ago on, calendar apps can subscribe to it with a feed token",
+                "produces": [
+                    "text/calendar"
+                ],
+                "tags": [
+                    "Dentist"
+                ],
+                "summary": "Get the calendar of a Dentist",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Dentist ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/dentists/{id}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Restore a deleted Dentist along with its working hours",
+                "tags": [
+                    "Dentist"
+                ],
+                "summary": "Restore a Dentist",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Dentist ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/DentistResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/dentists/{id}/schedule": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the weekly Working Hours of a Dentist",
+                "tags": [
+                    "Dentist"
+                ],
+                "summary": "Get Dentist Working Hours",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Dentist ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/WorkingHoursResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replace the weekly Working Hours of a Dentist, days without ranges are days off",
+                "tags": [
+                    "Dentist"
+                ],
+                "summary": "Update Dentist Working Hours",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Dentist ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "WorkingHoursResponse",
+                        "name": "WorkingHours",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/WorkingHoursPut"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/WorkingHoursResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/patients": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get a page of Patients, filtered and sorted by the query params",
+                "tags": [
+                    "Patient"
+                ],
+                "summary": "Get all Patients",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Patient name prefix",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Patient last name prefix",
+                        "name": "last_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Patient DNI",
+                        "name": "dni",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Patient email",
+                        "name": "email",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admitted on or after this date, in format RFC3339",
+                        "name": "admitted_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admitted before this date, in format RFC3339",
+                        "name": "admitted_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Also list deleted Patients, only for admins",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Fields to sort by, prefixed with '-' for descending order, e.g. -admission_date",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number, starting at 1",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size, 20 by default and 100 at most",
+                        "name": "size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/PageResponse-PatientResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a Patient",
+                "tags": [
+                    "Patient"
+                ],
+                "summary": "Create a Patient",
+                "parameters": [
+                    {
+                        "description": "PatientResponse",
+                        "name": "Body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/PatientPost"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/PatientResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/patients/export": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Download every Patient matching the filters of the list as CSV, JSON Lines or XLSX, with a column per field of PatientResponse.\nThe format is read from the format query param, or else from the Accept header, CSV by default. The file is streamed, so exports of any size are downloaded at once.",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "Patient"
+                ],
+                "summary": "Export Patients",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "csv, jsonl or xlsx",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Patient name prefix",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Patient last name prefix",
+                        "name": "last_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Patient DNI",
+                        "name": "dni",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Patient email",
+                        "name": "email",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admitted on or after this date, in format RFC3339",
+                        "name": "admitted_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admitted before this date, in format RFC3339",
+                        "name": "admitted_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Also export deleted Patients, only for admins",
+                        "name": "include_deleted",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Fields to sort by, prefixed with '-' for descending order, e.g. -admission_date",
+                        "name": "sort",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/patients/import": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create the Patients of a CSV file, with a header naming the columns like the fields of PatientPost, or of a JSON Lines file with a PatientPost per line.\nEvery row is checked like in Create, dnis repeated in the file or already taken are rejected too.\nThe Patients are only created when every row is valid, otherwise the report lists the invalid rows by line; dry_run only checks the file.",
+                "consumes": [
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "Patient"
+                ],
+                "summary": "Import Patients",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Check the file without creating the Patients",
+                        "name": "dry_run",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "csv or jsonl, read from the Content-Type when it is empty",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "description": "CSV or JSON Lines file",
+                        "name": "file",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/ImportReportResponse"
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/ImportReportResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/ImportReportResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/patients/q": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get Patient by DNI",
+                "tags": [
+                    "Patient"
+                ],
+                "summary": "Get Patient by DNI",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Patient DNI",
+                        "name": "dni",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/PatientResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/patients/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get Patient by id",
+                "tags": [
+                    "Patient"
+                ],
+                "summary": "Get Patient by id",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Patient ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/PatientResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update a Patient",
+                "tags": [
+                    "Patient"
+                ],
+                "summary": "Update a Patient",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Patient ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "PatientResponse",
+                        "name": "Body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/PatientPut"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/PatientResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete a Patient, upcoming appointments block the deletion unless the strategy is cascade, which deletes them too",
+                "tags": [
+                    "Patient"
+                ],
+                "summary": "Delete a Patient",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Patient ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "reject",
+                            "cascade"
+                        ],
+                        "type": "string",
+                        "description": "What happens to the upcoming appointments, reject by default",
+                        "name": "strategy",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Patch a Patient",
+                "tags": [
+                    "Patient"
+                ],
+                "summary": "Patch a Patient",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Patient ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "PatientResponse",
+                        "name": "Body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/PatientPatch"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/PatientResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/patients/{id}/calendar.ics": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    },
+                    {
+                        "FeedToken": []
+                    }
+                ],
+                "description": "Get the Appointments of a Patient as an iCalendar feed, from 90 days ago on, calendar apps can subscribe to it with a feed token",
+                "produces": [
+                    "text/calendar"
+                ],
+                "tags": [
+                    "Patient"
+                ],
+                "summary": "Get the calendar of a Patient",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Patient ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/patients/{id}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Restore a deleted Patient",
+                "tags": [
+                    "Patient"
+                ],
+                "summary": "Restore a Patient",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Patient ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/PatientResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/reports/admissions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Count the Patients by the day, week or month of their admission date, in the time zone of the request, deleted Patients included.\nEvery period of the range has a bucket, weeks start on Monday. Only for admins and receptionists.",
+                "tags": [
+                    "Report"
+                ],
+                "summary": "Count the new Patients by period",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Start of the report, in format RFC3339",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "End of the report, in format RFC3339",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "day, week or month, month by default",
+                        "name": "period",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/AdmissionReportResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/reports/appointments": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Count the Appointments by status and by the day, week or month they start in, in the time zone of the request, with the cancellation and no-show rates.\nEvery period of the range has a bucket, weeks start on Monday. The no-show rate leaves out the cancelled and upcoming Appointments. Dentists only get their own.",
+                "tags": [
+                    "Report"
+                ],
+                "summary": "Count the Appointments by period",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Start of the report, in format RFC3339",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "End of the report, in format RFC3339",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "day, week or month, day by default",
+                        "name": "period",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Dentist ID",
+                        "name": "dentist_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/AppointmentReportResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/reports/attendance": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Count the Appointments starting between from and to by Dentist and status, with the cancellation and no-show rates.\nThe no-show rate leaves out the cancelled and upcoming Appointments. Dentists only get their own.",
+                "tags": [
+                    "Report"
+                ],
+                "summary": "Get the cancellation and no-show rates of the Dentists",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Start of the report, in format RFC3339",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "End of the report, in format RFC3339",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Dentist ID",
+                        "name": "dentist_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/AttendanceReportResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/reports/utilization": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the hours each Dentist had booked between from and to against the hours of its current working hours, cancelled Appointments aren't booked hours.\nUtilization is booked over available hours, it goes over 1 with Appointments outside the working hours. Dentists only get their own.",
+                "tags": [
+                    "Report"
+                ],
+                "summary": "Get the utilization of the Dentists",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Start of the report, in format RFC3339",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "End of the report, in format RFC3339",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Dentist ID",
+                        "name": "dentist_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/UtilizationReportResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get all Users",
+                "tags": [
+                    "User"
+                ],
+                "summary": "Get all Users",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/UserResponse"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a User, dentist users must give the license of the dentist they are",
+                "tags": [
+                    "User"
+                ],
+                "summary": "Create a User",
+                "parameters": [
+                    {
+                        "description": "UserResponse",
+                        "name": "User",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/UserPost"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/UserResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/waitlist": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get a page of Waitlist Entries, filtered and sorted by the query params",
+                "tags": [
+                    "Waitlist"
+                ],
+                "summary": "Get all Waitlist Entries",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Dentist ID",
+                        "name": "dentist_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Patient ID",
+                        "name": "patient_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma separated statuses, e.g. waiting,offered",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Fields to sort by, prefixed with '-' for descending order, e.g. -created_at",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number, starting at 1",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size, 20 by default and 100 at most",
+                        "name": "size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/PageResponse-WaitlistEntryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Queue a Patient for a Dentist, any time between from and to that starts and ends inside the daily window.\nWhen an Appointment of the Dentist that fits is deleted or cancelled, the first Entry of the queue gets an Offer of its slot.",
+                "tags": [
+                    "Waitlist"
+                ],
+                "summary": "Create a Waitlist Entry",
+                "parameters": [
+                    {
+                        "description": "Waitlist Entry",
+                        "name": "Entry",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/WaitlistPost"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/WaitlistEntryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/waitlist/offers/{id}/accept": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Book the Appointment of a pending Offer, when the slot was booked by someone else meanwhile the Entry goes back to the queue",
+                "tags": [
+                    "Waitlist"
+                ],
+                "summary": "Accept an Offer",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Offer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/OfferResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/waitlist/offers/{id}/decline": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Decline a pending Offer, its Entry goes back to the queue and the slot is offered to the next Entry",
+                "tags": [
+                    "Waitlist"
+                ],
+                "summary": "Decline an Offer",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Offer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/OfferResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/waitlist/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get a Waitlist Entry with its Offers, newest first",
+                "tags": [
+                    "Waitlist"
+                ],
+                "summary": "Get Waitlist Entry by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Waitlist Entry ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/WaitlistEntryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Take a Waitlist Entry out of the queue, its pending Offer is declined and moves on to the next Entry",
+                "tags": [
+                    "Waitlist"
+                ],
+                "summary": "Cancel a Waitlist Entry",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Waitlist Entry ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/WaitlistEntryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the Webhook subscriptions, without their secrets",
+                "tags": [
+                    "Webhook"
+                ],
+                "summary": "Get all Webhooks",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/WebhookResponse"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Subscribe a URL to the events of Patients, Dentists and Appointments, each event is POSTed as JSON signed with the secret.\nThe X-Webhook-Signature header is sha256= and the hex HMAC-SHA256 of the X-Webhook-Timestamp header, a dot and the body.\nThe secret is only returned by this endpoint.",
+                "tags": [
+                    "Webhook"
+                ],
+                "summary": "Create a Webhook",
+                "parameters": [
+                    {
+                        "description": "Webhook",
+                        "name": "Webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/WebhookPost"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/WebhookResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/deliveries": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get a page of the Deliveries of the events to the Webhooks, status=dead lists the dead letters that failed every attempt",
+                "tags": [
+                    "Webhook"
+                ],
+                "summary": "Get the Deliveries",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "subscription_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Event ID",
+                        "name": "event_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "pending, delivered or dead",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Fields to sort by, prefixed with '-' for descending order, e.g. -created_at",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number, starting at 1",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size, 20 by default and 100 at most",
+                        "name": "size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/PageResponse-DeliveryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/deliveries/{id}/retry": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Send a dead Delivery again, with its attempts reset",
+                "tags": [
+                    "Webhook"
+                ],
+                "summary": "Retry a Delivery",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Delivery ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/DeliveryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get a Webhook subscription by ID, without its secret",
+                "tags": [
+                    "Webhook"
+                ],
+                "summary": "Get Webhook by ID",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/WebhookResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete a Webhook subscription, its pending deliveries become dead",
+                "tags": [
+                    "Webhook"
+                ],
+                "summary": "Delete a Webhook",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Change the url, the events or whether a Webhook is active, deliveries due while it is inactive become dead",
+                "tags": [
+                    "Webhook"
+                ],
+                "summary": "Patch a Webhook",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Webhook",
+                        "name": "Webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/WebhookPatch"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/WebhookResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "AdmissionBucketResponse": {
+            "type": "object",
+            "properties": {
+                "patients": {
+                    "type": "integer"
+                },
+                "start": {
+                    "type": "string"
+                }
+            }
+        },
+        "AdmissionReportResponse": {
+            "type": "object",
+            "properties": {
+                "buckets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/AdmissionBucketResponse"
+                    }
+                },
+                "from": {
+                    "type": "string"
+                },
+                "period": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "AppointmentBucketResponse": {
+            "type": "object",
+            "properties": {
+                "counts": {
+                    "$ref": "#/definitions/CountsResponse"
+                },
+                "start": {
+                    "type": "string"
+                }
+            }
+        },
+        "AppointmentDetailResponse": {
+            "type": "object",
+            "properties": {
+                "cancel_reason": {
+                    "type": "string"
+                },
+                "date": {
+                    "type": "string"
+                },
+                "dentist": {
+                    "$ref": "#/definitions/DentistResponse"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "duration": {
+                    "type": "integer"
+                },
+                "end_date": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "patient": {
+                    "$ref": "#/definitions/PatientResponse"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "AppointmentPatch": {
+            "type": "object",
+            "properties": {
+                "date": {
+                    "type": "string"
+                },
+                "dentist_id": {
+                    "type": "integer"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "duration": {
+                    "type": "integer",
+                    "maximum": 480,
+                    "minimum": 1
+                },
+                "patient_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "AppointmentPost": {
+            "type": "object",
+            "required": [
+                "date",
+                "dentist_license",
+                "description",
+                "patient_dni"
+            ],
+            "properties": {
+                "date": {
+                    "type": "string"
+                },
+                "dentist_license": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "duration": {
+                    "type": "integer",
+                    "maximum": 480,
+                    "minimum": 1
+                },
+                "patient_dni": {
+                    "type": "string"
+                }
+            }
+        },
+        "AppointmentPut": {
+            "type": "object",
+            "required": [
+                "date",
+                "dentist_id",
+                "description",
+                "patient_id"
+            ],
+            "properties": {
+                "date": {
+                    "type": "string"
+                },
+                "dentist_id": {
+                    "type": "integer"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "duration": {
+                    "type": "integer",
+                    "maximum": 480,
+                    "minimum": 1
+                },
+                "patient_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "AppointmentReportResponse": {
+            "type": "object",
+            "properties": {
+                "buckets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/AppointmentBucketResponse"
+                    }
+                },
+                "from": {
+                    "type": "string"
+                },
+                "period": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "total": {
+                    "$ref": "#/definitions/CountsResponse"
+                }
+            }
+        },
+        "AppointmentResponse": {
+            "type": "object",
+            "properties": {
+                "cancel_reason": {
+                    "type": "string"
+                },
+                "cancelled_at": {
+                    "type": "string"
+                },
+                "checked_in_at": {
+                    "type": "string"
+                },
+                "completed_at": {
+                    "type": "string"
+                },
+                "confirmed_at": {
+                    "type": "string"
+                },
+                "date": {
+                    "type": "string"
+                },
+                "dentist_id": {
+                    "type": "integer"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "duration": {
+                    "type": "integer"
+                },
+                "end_date": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "no_show_at": {
+                    "type": "string"
+                },
+                "patient_id": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "AttendanceReportResponse": {
+            "type": "object",
+            "properties": {
+                "dentists": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/AttendanceResponse"
+                    }
+                },
+                "from": {
+                    "type": "string"
+                },
+                "to": {
+                    "type": "string"
+                },
+                "total": {
+                    "$ref": "#/definitions/CountsResponse"
+                }
+            }
+        },
+        "AttendanceResponse": {
+            "type": "object",
+            "properties": {
+                "counts": {
+                    "$ref": "#/definitions/CountsResponse"
+                },
+                "dentist_id": {
+                    "type": "integer"
+                },
+                "last_name": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "AuditEntryResponse": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "actor": {
+                    "type": "string"
+                },
+                "actor_id": {
+                    "type": "integer"
+                },
+                "changes": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/ChangeResponse"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "entity": {
+                    "type": "string"
+                },
+                "entity_id": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "AvailabilityResponse": {
+            "type": "object",
+            "properties": {
+                "dentist_id": {
+                    "type": "integer"
+                },
+                "from": {
+                    "type": "string"
+                },
+                "slot": {
+                    "type": "string"
+                },
+                "slots": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/SlotResponse"
+                    }
+                },
+                "to": {
+                    "type": "string"
+                }
+            }
+        },
+        "CancelPost": {
+            "type": "object",
+            "required": [
+                "reason"
+            ],
+            "properties": {
+                "reason": {
+                    "type": "string",
+                    "maxLength": 255
+                }
+            }
+        },
+        "ChangeResponse": {
+            "type": "object",
+            "properties": {
+                "after": {},
+                "before": {}
+            }
+        },
+        "CountsResponse": {
+            "type": "object",
+            "properties": {
+                "cancellation_rate": {
+                    "type": "number"
+                },
+                "cancelled": {
+                    "type": "integer"
+                },
+                "checked_in": {
+                    "type": "integer"
+                },
+                "completed": {
+                    "type": "integer"
+                },
+                "confirmed": {
+                    "type": "integer"
+                },
+                "no_show": {
+                    "type": "integer"
+                },
+                "no_show_rate": {
+                    "type": "number"
+                },
+                "scheduled": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "DeliveryResponse": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "delivered_at": {
+                    "type": "string"
+                },
+                "event_id": {
+                    "type": "integer"
+                },
+                "event_type": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "last_status_code": {
+                    "type": "integer"
+                },
+                "next_attempt_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "subscription_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "DentistPatch": {
+            "type": "object",
+            "properties": {
+                "last_name": {
+                    "type": "string"
+                },
+                "license": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "DentistPost": {
+            "type": "object",
+            "required": [
+                "last_name",
+                "license",
+                "name"
+            ],
+            "properties": {
+                "last_name": {
+                    "type": "string"
+                },
+                "license": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "DentistPut": {
+            "type": "object",
+            "required": [
+                "last_name",
+                "license",
+                "name"
+            ],
+            "properties": {
+                "last_name": {
+                    "type": "string"
+                },
+                "license": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "DentistResponse": {
+            "type": "object",
+            "properties": {
+                "deleted_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_name": {
+                    "type": "string"
+                },
+                "license": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "timestamp": {
+                    "type": "string"
+                }
+            }
+        },
+        "FeedTokenResponse": {
+            "type": "object",
+            "properties": {
+                "calendar": {
+                    "type": "string"
+                },
+                "feed_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "ImportReportResponse": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "integer"
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/RowErrorDetail"
+                    }
+                },
+                "invalid": {
+                    "type": "integer"
+                },
+                "rows": {
+                    "type": "integer"
+                }
+            }
+        },
+        "LoginPost": {
+            "type": "object",
+            "required": [
+                "password",
+                "username"
+            ],
+            "properties": {
+                "password": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "OccurrenceResponse": {
+            "type": "object",
+            "properties": {
+                "date": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "OfferResponse": {
+            "type": "object",
+            "properties": {
+                "appointment_id": {
+                    "type": "integer"
+                },
+                "dentist_id": {
+                    "type": "integer"
+                },
+                "end": {
+                    "type": "string"
+                },
+                "entry_id": {
+                    "type": "integer"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "patient_id": {
+                    "type": "integer"
+                },
+                "responded_at": {
+                    "type": "string"
+                },
+                "start": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "PageLinks": {
+            "type": "object",
+            "properties": {
+                "next": {
+                    "type": "string"
+                },
+                "prev": {
+                    "type": "string"
+                },
+                "self": {
+                    "type": "string"
+                }
+            }
+        },
+        "PageResponse-AppointmentDetailResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/AppointmentDetailResponse"
+                    }
+                },
+                "links": {
+                    "$ref": "#/definitions/PageLinks"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "PageResponse-AppointmentResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/AppointmentResponse"
+                    }
+                },
+                "links": {
+                    "$ref": "#/definitions/PageLinks"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "PageResponse-AuditEntryResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/AuditEntryResponse"
+                    }
+                },
+                "links": {
+                    "$ref": "#/definitions/PageLinks"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "PageResponse-DeliveryResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/DeliveryResponse"
+                    }
+                },
+                "links": {
+                    "$ref": "#/definitions/PageLinks"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "PageResponse-DentistResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/DentistResponse"
+                    }
+                },
+                "links": {
+                    "$ref": "#/definitions/PageLinks"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "PageResponse-PatientResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/PatientResponse"
+                    }
+                },
+                "links": {
+                    "$ref": "#/definitions/PageLinks"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "PageResponse-WaitlistEntryResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/WaitlistEntryResponse"
+                    }
+                },
+                "links": {
+                    "$ref": "#/definitions/PageLinks"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "PatientPatch": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "admission_date": {
+                    "type": "string"
+                },
+                "dni": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "last_name": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "PatientPost": {
+            "type": "object",
+            "required": [
+                "address",
+                "admission_date",
+                "dni",
+                "email",
+                "last_name",
+                "name"
+            ],
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "admission_date": {
+                    "type": "string"
+                },
+                "dni": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "last_name": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "PatientPut": {
+            "type": "object",
+            "required": [
+                "address",
+                "admission_date",
+                "dni",
+                "email",
+                "last_name",
+                "name"
+            ],
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "admission_date": {
+                    "type": "string"
+                },
+                "dni": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "last_name": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "PatientResponse": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string"
+                },
+                "admission_date": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "dni": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_name": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "RefreshPost": {
+            "type": "object",
+            "required": [
+                "refresh_token"
+            ],
+            "properties": {
+                "refresh_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "ReminderResponse": {
+            "type": "object",
+            "properties": {
+                "appointment_id": {
+                    "type": "integer"
+                },
+                "attempts": {
+                    "type": "integer"
+                },
+                "channel": {
+                    "type": "string"
+                },
+                "date": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "string"
+                },
+                "recipient": {
+                    "type": "string"
+                },
+                "sent_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "RowErrorDetail": {
+            "type": "object",
+            "properties": {
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "line": {
+                    "type": "integer"
+                }
+            }
+        },
+        "SeriesConflictResponse": {
             "type": "object",
             "properties": {
-                "date": {
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "message": {
                     "type": "string"
                 },
-                "dentist_id": {
-                    "type": "integer"
+                "occurrences": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/OccurrenceResponse"
+                    }
                 },
-                "description": {
+                "path": {
                     "type": "string"
                 },
-                "patient_id": {
+                "status": {
                     "type": "integer"
+                },
+                "timestamp": {
+                    "type": "string"
                 }
             }
         },
-        "AppointmentPost": {
+        "SeriesPost": {
             "type": "object",
             "required": [
                 "date",
                 "dentist_license",
                 "description",
+                "frequency",
                 "patient_dni"
             ],
             "properties": {
+                "count": {
+                    "type": "integer"
+                },
                 "date": {
                     "type": "string"
                 },
@@ -1109,20 +5028,49 @@ const docTemplate = `{
                 "description": {
                     "type": "string"
                 },
+                "duration": {
+                    "type": "integer",
+                    "maximum": 480,
+                    "minimum": 1
+                },
+                "frequency": {
+                    "type": "string",
+                    "enum": [
+                        "daily",
+                        "weekly",
+                        "monthly"
+                    ]
+                },
+                "interval": {
+                    "type": "integer"
+                },
+                "on_conflict": {
+                    "type": "string",
+                    "enum": [
+                        "fail",
+                        "skip"
+                    ]
+                },
                 "patient_dni": {
                     "type": "string"
+                },
+                "until": {
+                    "type": "string"
                 }
             }
         },
-        "AppointmentPut": {
+        "SeriesResponse": {
             "type": "object",
-            "required": [
-                "date",
-                "dentist_id",
-                "description",
-                "patient_id"
-            ],
             "properties": {
+                "appointments": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/AppointmentResponse"
+                    }
+                },
+                "count": {
+                    "type": "integer"
+                },
                 "date": {
                     "type": "string"
                 },
@@ -1132,241 +5080,357 @@ const docTemplate = `{
                 "description": {
                     "type": "string"
                 },
-                "patient_id": {
-                    "type": "integer"
-                }
-            }
-        },
-        "AppointmentResponse": {
-            "type": "object",
-            "properties": {
-                "date": {
-                    "type": "string"
-                },
-                "dentist_id": {
+                "duration": {
                     "type": "integer"
                 },
-                "description": {
+                "frequency": {
                     "type": "string"
                 },
                 "id": {
                     "type": "integer"
                 },
+                "interval": {
+                    "type": "integer"
+                },
                 "patient_id": {
                     "type": "integer"
+                },
+                "skipped": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/OccurrenceResponse"
+                    }
+                },
+                "until": {
+                    "type": "string"
                 }
             }
         },
-        "DentistPatch": {
+        "SlotResponse": {
             "type": "object",
             "properties": {
-                "last_name": {
-                    "type": "string"
-                },
-                "license": {
+                "end": {
                     "type": "string"
                 },
-                "name": {
+                "start": {
                     "type": "string"
                 }
             }
         },
-        "DentistPost": {
+        "TokensResponse": {
             "type": "object",
-            "required": [
-                "last_name",
-                "license",
-                "name"
-            ],
             "properties": {
-                "last_name": {
+                "access_expires_at": {
                     "type": "string"
                 },
-                "license": {
+                "access_token": {
                     "type": "string"
                 },
-                "name": {
+                "refresh_expires_at": {
+                    "type": "string"
+                },
+                "refresh_token": {
+                    "type": "string"
+                },
+                "token_type": {
                     "type": "string"
                 }
             }
         },
-        "DentistPut": {
+        "UserPost": {
             "type": "object",
             "required": [
-                "last_name",
-                "license",
-                "name"
+                "password",
+                "role",
+                "username"
             ],
             "properties": {
-                "last_name": {
+                "dentist_license": {
                     "type": "string"
                 },
-                "license": {
+                "password": {
                     "type": "string"
                 },
-                "name": {
+                "role": {
+                    "type": "string",
+                    "enum": [
+                        "admin",
+                        "receptionist",
+                        "dentist"
+                    ]
+                },
+                "username": {
                     "type": "string"
                 }
             }
         },
-        "DentistResponse": {
+        "UserResponse": {
             "type": "object",
             "properties": {
-                "id": {
+                "created_at": {
+                    "type": "string"
+                },
+                "dentist_id": {
                     "type": "integer"
                 },
-                "last_name": {
-                    "type": "string"
+                "id": {
+                    "type": "integer"
                 },
-                "license": {
+                "role": {
                     "type": "string"
                 },
-                "name": {
+                "username": {
                     "type": "string"
                 }
             }
         },
-        "ErrorResponse": {
+        "UtilizationReportResponse": {
             "type": "object",
             "properties": {
-                "errors": {
+                "available_hours": {
+                    "type": "number"
+                },
+                "booked_hours": {
+                    "type": "number"
+                },
+                "dentists": {
                     "type": "array",
                     "items": {
-                        "type": "string"
+                        "$ref": "#/definitions/UtilizationResponse"
                     }
                 },
-                "message": {
+                "from": {
                     "type": "string"
                 },
-                "path": {
+                "to": {
                     "type": "string"
                 },
-                "status": {
+                "utilization": {
+                    "type": "number"
+                }
+            }
+        },
+        "UtilizationResponse": {
+            "type": "object",
+            "properties": {
+                "available_hours": {
+                    "type": "number"
+                },
+                "booked_hours": {
+                    "type": "number"
+                },
+                "dentist_id": {
                     "type": "integer"
                 },
-                "timestamp": {
+                "last_name": {
+                    "type": "string"
+                },
+                "name": {
                     "type": "string"
+                },
+                "utilization": {
+                    "type": "number"
                 }
             }
         },
-        "PatientPatch": {
+        "WaitlistEntryResponse": {
             "type": "object",
             "properties": {
-                "address": {
+                "created_at": {
                     "type": "string"
                 },
-                "admission_date": {
+                "dentist_id": {
+                    "type": "integer"
+                },
+                "description": {
                     "type": "string"
                 },
-                "dni": {
+                "duration": {
+                    "type": "integer"
+                },
+                "from": {
                     "type": "string"
                 },
-                "email": {
+                "id": {
+                    "type": "integer"
+                },
+                "offers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/OfferResponse"
+                    }
+                },
+                "patient_id": {
+                    "type": "integer"
+                },
+                "status": {
                     "type": "string"
                 },
-                "last_name": {
+                "to": {
                     "type": "string"
                 },
-                "name": {
+                "window_end": {
+                    "type": "string"
+                },
+                "window_start": {
                     "type": "string"
                 }
             }
         },
-        "PatientPost": {
+        "WaitlistPost": {
             "type": "object",
             "required": [
-                "address",
-                "admission_date",
-                "dni",
-                "email",
-                "last_name",
-                "name"
+                "dentist_license",
+                "description",
+                "from",
+                "patient_dni",
+                "to",
+                "window_end",
+                "window_start"
             ],
             "properties": {
-                "address": {
+                "dentist_license": {
                     "type": "string"
                 },
-                "admission_date": {
+                "description": {
                     "type": "string"
                 },
-                "dni": {
+                "duration": {
+                    "type": "integer",
+                    "maximum": 480,
+                    "minimum": 1
+                },
+                "from": {
                     "type": "string"
                 },
-                "email": {
+                "patient_dni": {
                     "type": "string"
                 },
-                "last_name": {
+                "to": {
                     "type": "string"
                 },
-                "name": {
+                "window_end": {
+                    "type": "string"
+                },
+                "window_start": {
                     "type": "string"
                 }
             }
         },
-        "PatientPut": {
+        "WebhookPatch": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "WebhookPost": {
             "type": "object",
             "required": [
-                "address",
-                "admission_date",
-                "dni",
-                "email",
-                "last_name",
-                "name"
+                "url"
             ],
             "properties": {
-                "address": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "secret": {
                     "type": "string"
                 },
-                "admission_date": {
+                "url": {
                     "type": "string"
+                }
+            }
+        },
+        "WebhookResponse": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
                 },
-                "dni": {
+                "created_at": {
                     "type": "string"
                 },
-                "email": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "secret": {
                     "type": "string"
                 },
-                "last_name": {
+                "updated_at": {
                     "type": "string"
                 },
-                "name": {
+                "url": {
                     "type": "string"
                 }
             }
         },
-        "PatientResponse": {
+        "WorkingHoursPut": {
             "type": "object",
+            "required": [
+                "end",
+                "start",
+                "weekday"
+            ],
             "properties": {
-                "address": {
+                "end": {
                     "type": "string"
                 },
-                "admission_date": {
+                "start": {
                     "type": "string"
                 },
-                "dni": {
+                "weekday": {
+                    "type": "integer",
+                    "maximum": 6,
+                    "minimum": 0
+                }
+            }
+        },
+        "WorkingHoursResponse": {
+            "type": "object",
+            "properties": {
+                "end": {
                     "type": "string"
                 },
-                "email": {
+                "start": {
                     "type": "string"
                 },
-                "id": {
+                "weekday": {
                     "type": "integer"
-                },
-                "last_name": {
-                    "type": "string"
-                },
-                "name": {
-                    "type": "string"
                 }
             }
         }
     },
     "securityDefinitions": {
-        "APIKey": {
-            "description": "Add secret key here",
+        "BearerAuth": {
+            "description": "Add \"Bearer \" followed by the access token given by /auth/login",
             "type": "apiKey",
-            "name": "SECRET_KEY",
+            "name": "Authorization",
             "in": "header"
+        },
+        "FeedToken": {
+            "description": "Feed token given by /auth/feed-token, only for the calendar feeds",
+            "type": "apiKey",
+            "name": "token",
+            "in": "query"
         }
     },
     "tags": [
@@ -1393,6 +5457,30 @@ const docTemplate = `{
                 "description": "Appointment operations for managing Appointment",
                 "url": "http://swagger.io/terms/"
             }
+        },
+        {
+            "description": "Login and token refresh",
+            "name": "Auth"
+        },
+        {
+            "description": "User operations for managing User",
+            "name": "User"
+        },
+        {
+            "description": "Queue of Patients waiting for a freed slot of a Dentist",
+            "name": "Waitlist"
+        },
+        {
+            "description": "Subscriptions of URLs to the events of Patients, Dentists and Appointments",
+            "name": "Webhook"
+        },
+        {
+            "description": "Utilization, attendance and admissions computed from the Appointments and Patients",
+            "name": "Report"
+        },
+        {
+            "description": "Log of the changes made to Patients, Dentists and Appointments",
+            "name": "Audit"
         }
     ],
     "externalDocs": {
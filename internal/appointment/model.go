@@ -4,10 +4,73 @@ import (
 	"time"
 )
 
+// DefaultDuration is the length in minutes given to appointments that don't specify one, and MaxDuration the
+// longest they can be, a full working day
+const (
+	DefaultDuration uint = 30
+	MaxDuration     uint = 480
+)
+
+// Appointment is a booking of a patient with a dentist, Sequence counts its changes so calendar
+// feeds can tell their copies are outdated
 type Appointment struct {
-	ID          uint      `gorm:"primaryKey"`
-	PatientID   uint      `gorm:"not null"`
-	DentistID   uint      `gorm:"not null"`
-	Date        time.Time `gorm:"not null;type:datetime(3)"`
-	Description string    `gorm:"type:longtext"`
+	ID           uint      `gorm:"primaryKey"`
+	PatientID    uint      `gorm:"not null;index:idx_appointments_patient_date,priority:1"`
+	DentistID    uint      `gorm:"not null;index:idx_appointments_dentist_date,priority:1"`
+	Date         time.Time `gorm:"not null;precision:3;index:idx_appointments_dentist_date,priority:2;index:idx_appointments_patient_date,priority:2"`
+	Duration     uint      `gorm:"not null;default:30"`
+	EndDate      time.Time `gorm:"precision:3"`
+	Description  string
+	Status       Status     `gorm:"not null;type:varchar(20);default:scheduled;index"`
+	ConfirmedAt  *time.Time `gorm:"precision:3"`
+	CheckedInAt  *time.Time `gorm:"precision:3"`
+	CompletedAt  *time.Time `gorm:"precision:3"`
+	CancelledAt  *time.Time `gorm:"precision:3"`
+	NoShowAt     *time.Time `gorm:"precision:3"`
+	CancelReason string     `gorm:"type:varchar(255)"`
+	SeriesID     *uint      `gorm:"index"`
+	Sequence     uint       `gorm:"not null;default:0" audit:"-"`
+	UpdatedAt    time.Time  `gorm:"precision:3" audit:"-"`
+}
+
+// DeleteStrategy is what happens to the upcoming appointments of a dentist or patient being deleted
+type DeleteStrategy string
+
+const (
+	// StrategyReject refuses to delete while there are upcoming appointments
+	StrategyReject DeleteStrategy = "reject"
+	// StrategyCascade cancels the upcoming appointments of the dentist or patient
+	StrategyCascade DeleteStrategy = "cascade"
+	// StrategyReassign moves the upcoming appointments of a dentist to another dentist
+	StrategyReassign DeleteStrategy = "reassign"
+)
+
+// DeletePolicy says how a deletion handles the upcoming appointments, License is the dentist
+// that gets them with StrategyReassign, past appointments are always kept as history
+type DeletePolicy struct {
+	Strategy DeleteStrategy
+	License  string
+}
+
+// Filter narrows a list of appointments, empty fields match every appointment,
+// From and To select the appointments that overlap with that range
+type Filter struct {
+	PatientID      uint
+	DentistID      uint
+	PatientDNI     string
+	DentistLicense string
+	From           time.Time
+	To             time.Time
+	// Statuses keeps only the appointments in any of these statuses
+	Statuses []Status
+}
+
+// SortColumns maps the fields a list of appointments can be sorted by to their columns
+var SortColumns = map[string]string{
+	"id":         "id",
+	"date":       "date",
+	"duration":   "duration",
+	"patient_id": "patient_id",
+	"dentist_id": "dentist_id",
+	"status":     "status",
 }
@@ -0,0 +1,126 @@
+package appointment
+
+import (
+	"time"
+)
+
+// MaxOccurrences is the most appointments a series can expand to
+const MaxOccurrences = 100
+
+// Frequency is how often the appointments of a series repeat
+type Frequency string
+
+const (
+	FrequencyDaily   Frequency = "daily"
+	FrequencyWeekly  Frequency = "weekly"
+	FrequencyMonthly Frequency = "monthly"
+)
+
+// Scope selects the occurrences of a series a change applies to, counting from a given occurrence
+type Scope string
+
+const (
+	ScopeThis      Scope = "this"
+	ScopeFollowing Scope = "following"
+	ScopeAll       Scope = "all"
+)
+
+// Series is a recurring appointment, like an RRULE it repeats every Interval days, weeks or months from
+// Start until it has Count occurrences or reaches Until, its occurrences are stored as appointments
+type Series struct {
+	ID           uint      `gorm:"primaryKey"`
+	PatientID    uint      `gorm:"not null;index"`
+	DentistID    uint      `gorm:"not null;index"`
+	Start        time.Time `gorm:"not null;precision:3"`
+	Duration     uint      `gorm:"not null;default:30"`
+	Description  string
+	Frequency    Frequency     `gorm:"not null;type:varchar(10)"`
+	Interval     uint          `gorm:"not null;default:1"`
+	Count        uint          `gorm:"not null;default:0"`
+	Until        *time.Time    `gorm:"precision:3"`
+	CreatedAt    time.Time     `gorm:"not null;precision:3"`
+	Appointments []Appointment `gorm:"foreignKey:SeriesID"`
+}
+
+func (Series) TableName() string {
+	return "appointment_series"
+}
+
+// Valid reports whether the rule of the series can be expanded, it needs a known frequency, an interval of
+// at least 1 and exactly one of Count or Until
+func (s Series) Valid() bool {
+	switch s.Frequency {
+	case FrequencyDaily, FrequencyWeekly, FrequencyMonthly:
+	default:
+		return false
+	}
+
+	if s.Interval == 0 || s.Start.IsZero() {
+		return false
+	}
+
+	if (s.Count == 0) == (s.Until == nil) {
+		return false
+	}
+
+	return s.Count <= MaxOccurrences && (s.Until == nil || !s.Until.Before(s.Start))
+}
+
+// Occurrences returns the start of every appointment of the series, the rule is applied to the wall clock
+// of loc so the appointments keep their time across daylight saving changes, months without the day of
+// Start are skipped like RRULE does, it returns nil when the series isn't valid or expands to too many
+func (s Series) Occurrences(loc *time.Location) []time.Time {
+	if !s.Valid() {
+		return nil
+	}
+
+	start := s.Start.In(loc)
+	year, month, day := start.Date()
+	hour, minute, second := start.Clock()
+
+	var dates []time.Time
+	// Skipped months don't count, the bound only stops rules that would never produce enough dates
+	for i := 0; i < 12*MaxOccurrences; i++ {
+		step := i * int(s.Interval)
+
+		var date time.Time
+		switch s.Frequency {
+		case FrequencyDaily:
+			date = time.Date(year, month, day+step, hour, minute, second, start.Nanosecond(), loc)
+		case FrequencyWeekly:
+			date = time.Date(year, month, day+7*step, hour, minute, second, start.Nanosecond(), loc)
+		case FrequencyMonthly:
+			date = time.Date(year, month+time.Month(step), day, hour, minute, second, start.Nanosecond(), loc)
+			if date.Day() != day {
+				// The month is shorter than the day of Start, time.Date moved it to the next month
+				continue
+			}
+		}
+
+		if s.Until != nil && date.After(*s.Until) {
+			break
+		}
+		if len(dates) == MaxOccurrences {
+			return nil
+		}
+
+		dates = append(dates, date)
+		if s.Count != 0 && len(dates) == int(s.Count) {
+			break
+		}
+	}
+
+	return dates
+}
+
+// Shift moves an occurrence of a series the way one of its occurrences was moved from from to to:
+// the same number of calendar days and to the same wall clock time in loc
+func Shift(occurrence time.Time, from time.Time, to time.Time, loc *time.Location) time.Time {
+	fromYear, fromMonth, fromDay := from.In(loc).Date()
+	toYear, toMonth, toDay := to.In(loc).Date()
+	days := int(time.Date(toYear, toMonth, toDay, 0, 0, 0, 0, time.UTC).Sub(time.Date(fromYear, fromMonth, fromDay, 0, 0, 0, 0, time.UTC)).Hours() / 24)
+
+	year, month, day := occurrence.In(loc).Date()
+	hour, minute, second := to.In(loc).Clock()
+	return time.Date(year, month, day+days, hour, minute, second, to.Nanosecond(), loc)
+}
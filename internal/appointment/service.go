@@ -2,35 +2,77 @@ package appointment
 
 import (
 	"errors"
+	"time"
+
 	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/audit"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/auth"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/event"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/pagination"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/schedule"
 )
 
 type Repository interface {
-	GetAll() ([]Appointment, error)
+	GetAll(filter Filter, page pagination.Request) (pagination.Page[Appointment], error)
 	GetByID(id uint) (Appointment, error)
-	GetByDNI(dni string) (Appointment, error)
+	GetOverlapping(appointment Appointment) ([]Appointment, error)
+	GetSchedule(dentistID uint) ([]schedule.WorkingHours, error)
 	Create(appointment Appointment) (Appointment, error)
 	Update(appointment Appointment) (Appointment, error)
 	Delete(id uint) error
+	// LockSchedule blocks concurrent bookings for the dentist and the patient until the transaction ends
+	LockSchedule(dentistID uint, patientID uint) error
+	CreateSeries(series Series) (Series, error)
+	// GetSeries returns a series with its appointments ordered by date
+	GetSeries(id uint) (Series, error)
+	// GetSeriesAppointments returns the appointments of a series starting from the given time, ordered by date
+	GetSeriesAppointments(seriesID uint, from time.Time) ([]Appointment, error)
+	// Record stores an audit entry, inside Transaction it is committed along with the change
+	Record(entry audit.Entry) error
+	// Publish stores an event in the outbox, inside Transaction it is committed along with the change
+	Publish(event event.Event) error
+	// Transaction runs fn with a repository bound to a single transaction
+	Transaction(fn func(repository Repository) error) error
 }
 
+// Service handles the business rules, location is the time zone of the clinic, working hours and
+// recurring bookings follow its wall clock
 type Service struct {
 	repository Repository
+	location   *time.Location
+	// slotFreed are called with the appointments whose slot was freed by a deletion or a cancellation
+	slotFreed []func(appointment Appointment)
+}
+
+func NewService(repository Repository, location *time.Location) *Service {
+	return &Service{repository: repository, location: location}
 }
 
-func NewService(repository Repository) *Service {
-	return &Service{repository: repository}
+// OnSlotFreed adds a listener called after an appointment is deleted or cancelled, once the change is committed
+func (s *Service) OnSlotFreed(listener func(appointment Appointment)) {
+	s.slotFreed = append(s.slotFreed, listener)
 }
 
-func (s *Service) GetAll() ([]Appointment, error) {
-	data, err := s.repository.GetAll()
+// GetAll returns a page of appointments, dentists only get their own appointments
+func (s *Service) GetAll(principal auth.Principal, filter Filter, page pagination.Request) (pagination.Page[Appointment], error) {
+	if !principal.Role.Valid() {
+		return pagination.Page[Appointment]{}, internal.ErForbidden
+	}
+
+	if dentistID, ok := principal.Dentist(); ok {
+		filter.DentistID = dentistID
+	}
+
+	data, err := s.repository.GetAll(filter, page)
 	if err != nil {
-		return nil, err
+		return pagination.Page[Appointment]{}, err
 	}
+
 	return data, nil
 }
 
-func (s *Service) GetByID(id uint) (Appointment, error) {
+// GetByID returns an appointment, appointments of other dentists are not found for dentists
+func (s *Service) GetByID(principal auth.Principal, id uint) (Appointment, error) {
 	data, err := s.repository.GetByID(id)
 	if err != nil {
 		switch {
@@ -41,22 +83,92 @@ func (s *Service) GetByID(id uint) (Appointment, error) {
 			return Appointment{}, internal.ErServiceUnavailable
 		}
 	}
+
+	if !CanAccess(principal, data) {
+		return Appointment{}, internal.ErNotFound
+	}
+
 	return data, nil
 }
 
-func (s *Service) GetByDNI(dni string) (Appointment, error) {
-	data, err := s.repository.GetByDNI(dni)
+// GetCalendar returns every appointment matching the filter ordered by date, for calendar feeds, dentists
+// can only get their own calendar and the ones of their patients
+func (s *Service) GetCalendar(principal auth.Principal, filter Filter) ([]Appointment, error) {
+	if dentistID, ok := principal.Dentist(); ok && filter.DentistID != 0 && filter.DentistID != dentistID {
+		return nil, internal.ErNotFound
+	}
+
+	var data []Appointment
+	page := pagination.NewRequest(1, pagination.MaxSize, []pagination.Sort{{Column: "date"}, {Column: "id"}})
+	for {
+		current, err := s.GetAll(principal, filter, page)
+		if err != nil {
+			switch {
+			case errors.Is(err, internal.ErForbidden):
+				return nil, internal.ErForbidden
+
+			default:
+				return nil, internal.ErServiceUnavailable
+			}
+		}
+
+		data = append(data, current.Items...)
+		if len(current.Items) < page.Size || int64(len(data)) >= current.Total {
+			return data, nil
+		}
+		page.Page++
+	}
+}
+
+// GetByDNI returns the appointment history of a patient, ordered by date unless another order is requested
+func (s *Service) GetByDNI(principal auth.Principal, dni string, page pagination.Request) (pagination.Page[Appointment], error) {
+	data, err := s.GetAll(principal, Filter{PatientDNI: dni}, byDate(page))
 	if err != nil {
-		return Appointment{}, err
+		switch {
+		case errors.Is(err, internal.ErForbidden):
+			return pagination.Page[Appointment]{}, internal.ErForbidden
+
+		default:
+			return pagination.Page[Appointment]{}, internal.ErServiceUnavailable
+		}
 	}
 	return data, nil
 }
 
-func (s *Service) Create(appointment Appointment) (Appointment, error) {
+// GetByLicense returns the appointment history of a dentist, ordered by date unless another order is requested
+func (s *Service) GetByLicense(principal auth.Principal, license string, page pagination.Request) (pagination.Page[Appointment], error) {
+	data, err := s.GetAll(principal, Filter{DentistLicense: license}, byDate(page))
+	if err != nil {
+		switch {
+		case errors.Is(err, internal.ErForbidden):
+			return pagination.Page[Appointment]{}, internal.ErForbidden
+
+		default:
+			return pagination.Page[Appointment]{}, internal.ErServiceUnavailable
+		}
+	}
+	return data, nil
+}
 
-	appointmentCreated, err := s.repository.Create(appointment)
+// Create books an appointment, dentists can only book for themselves
+func (s *Service) Create(principal auth.Principal, appointment Appointment) (Appointment, error) {
+	var appointmentCreated Appointment
+	err := s.repository.Transaction(func(repository Repository) error {
+		var err error
+		appointmentCreated, err = s.CreateIn(repository, principal, appointment)
+		return err
+	})
 	if err != nil {
 		switch {
+		case errors.Is(err, internal.ErForbidden), errors.Is(err, internal.ErInvalidDuration):
+			return Appointment{}, err
+
+		case errors.Is(err, internal.ErAppointmentConflict):
+			return Appointment{}, err
+
+		case errors.Is(err, internal.ErOutsideWorkingHours):
+			return Appointment{}, internal.ErOutsideWorkingHours
+
 		case errors.Is(err, internal.ErNotFound):
 			return Appointment{}, internal.ErNotFound
 
@@ -68,50 +180,455 @@ func (s *Service) Create(appointment Appointment) (Appointment, error) {
 	return appointmentCreated, nil
 }
 
-func (s *Service) Update(appointment Appointment) (Appointment, error) {
-	appointmentSearched, err := s.repository.GetByID(appointment.ID)
+// CreateIn books an appointment like Create in the transaction of repository, so it is committed along with the
+// changes of the caller. The errors of the repository are returned as they are
+func (s *Service) CreateIn(repository Repository, principal auth.Principal, appointment Appointment) (Appointment, error) {
+	if !CanAccess(principal, appointment) {
+		return Appointment{}, internal.ErForbidden
+	}
+	if appointment.Duration > MaxDuration {
+		return Appointment{}, internal.ErInvalidDuration
+	}
+
+	Normalize(&appointment)
+	appointment.Status = StatusScheduled
+
+	err := checkSchedule(repository, s.location, appointment)
+	if err != nil {
+		return Appointment{}, err
+	}
+
+	appointmentCreated, err := repository.Create(appointment)
+	if err != nil {
+		return Appointment{}, err
+	}
+
+	err = record(repository, principal, audit.ActionCreate, appointmentCreated.ID, nil, appointmentCreated)
+	if err != nil {
+		return Appointment{}, err
+	}
+	return appointmentCreated, nil
+}
+
+// Update replaces an appointment, dentists can't move their appointments to other dentists and
+// closed appointments can't be changed
+func (s *Service) Update(principal auth.Principal, appointment Appointment) (Appointment, error) {
+	appointmentSearched, err := s.GetByID(principal, appointment.ID)
+	if err != nil {
+		return Appointment{}, err
+	}
+
+	if !appointmentSearched.Status.Open() {
+		return Appointment{}, internal.ErAppointmentClosed
+	}
+
+	if !CanAccess(principal, appointment) {
+		return Appointment{}, internal.ErForbidden
+	}
+	if appointment.Duration > MaxDuration {
+		return Appointment{}, internal.ErInvalidDuration
+	}
+
+	Normalize(&appointment)
+
+	return s.save(principal, appointmentSearched, appointment)
+}
+
+// Patch updates the given fields of an appointment, dentists can't move their appointments to other dentists
+func (s *Service) Patch(principal auth.Principal, appointment Appointment) (Appointment, error) {
+	appointmentSearched, err := s.GetByID(principal, appointment.ID)
+	if err != nil {
+		return Appointment{}, err
+	}
+
+	if !appointmentSearched.Status.Open() {
+		return Appointment{}, internal.ErAppointmentClosed
+	}
+
+	CompareTo(&appointment, appointmentSearched)
+
+	if !CanAccess(principal, appointment) {
+		return Appointment{}, internal.ErForbidden
+	}
+	if appointment.Duration > MaxDuration {
+		return Appointment{}, internal.ErInvalidDuration
+	}
+	Normalize(&appointment)
+
+	return s.save(principal, appointmentSearched, appointment)
+}
+
+func (s *Service) Delete(principal auth.Principal, id uint) error {
+	appointmentSearched, err := s.GetByID(principal, id)
+	if err != nil {
+		return err
+	}
+
+	err = s.repository.Transaction(func(repository Repository) error {
+		err := repository.Delete(id)
+		if err != nil {
+			return err
+		}
+
+		return record(repository, principal, audit.ActionDelete, id, appointmentSearched, nil)
+	})
 	if err != nil {
 		switch {
 		case errors.Is(err, internal.ErNotFound):
-			return Appointment{}, internal.ErNotFound
+			return internal.ErNotFound
 
 		default:
-			return Appointment{}, internal.ErServiceUnavailable
+			return internal.ErServiceUnavailable
+		}
+	}
+
+	if appointmentSearched.Status != StatusCancelled {
+		s.freed(appointmentSearched)
+	}
+
+	return nil
+}
+
+// CreateSeries books every occurrence of a series in a single transaction, occurrences that overlap other
+// appointments or fall outside the working hours fail the whole series unless skipConflicts is set, then they
+// are left out and returned, a series where no occurrence can be booked always fails
+func (s *Service) CreateSeries(principal auth.Principal, series Series, skipConflicts bool) (Series, []internal.OccurrenceError, error) {
+	if !CanAccess(principal, Appointment{DentistID: series.DentistID}) {
+		return Series{}, nil, internal.ErForbidden
+	}
+
+	if series.Duration > MaxDuration {
+		return Series{}, nil, internal.ErInvalidDuration
+	}
+	if series.Duration == 0 {
+		series.Duration = DefaultDuration
+	}
+	series.Start = series.Start.UTC()
+	series.Appointments = nil
+
+	dates := series.Occurrences(s.location)
+	if len(dates) == 0 {
+		return Series{}, nil, internal.ErInvalidRecurrence
+	}
+
+	var seriesCreated Series
+	var skipped []internal.OccurrenceError
+	err := s.repository.Transaction(func(repository Repository) error {
+		var err error
+		seriesCreated, err = repository.CreateSeries(series)
+		if err != nil {
+			return err
+		}
+
+		skipped = nil
+		for _, date := range dates {
+			occurrence := Appointment{
+				PatientID:   series.PatientID,
+				DentistID:   series.DentistID,
+				Date:        date,
+				Duration:    series.Duration,
+				Description: series.Description,
+				Status:      StatusScheduled,
+				SeriesID:    &seriesCreated.ID,
+			}
+			Normalize(&occurrence)
+
+			err = checkSchedule(repository, s.location, occurrence)
+			if errors.Is(err, internal.ErAppointmentConflict) || errors.Is(err, internal.ErOutsideWorkingHours) {
+				skipped = append(skipped, internal.OccurrenceError{Date: date, Err: err})
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			occurrence, err = repository.Create(occurrence)
+			if err != nil {
+				return err
+			}
+
+			err = record(repository, principal, audit.ActionCreate, occurrence.ID, nil, occurrence)
+			if err != nil {
+				return err
+			}
+
+			seriesCreated.Appointments = append(seriesCreated.Appointments, occurrence)
+		}
+
+		if len(skipped) > 0 && (!skipConflicts || len(seriesCreated.Appointments) == 0) {
+			return &internal.SeriesConflictError{Occurrences: skipped}
+		}
+		return nil
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, internal.ErSeriesConflict):
+			return Series{}, nil, err
+
+		case errors.Is(err, internal.ErNotFound):
+			return Series{}, nil, internal.ErNotFound
+
+		default:
+			return Series{}, nil, internal.ErServiceUnavailable
 		}
 	}
 
-	appointmentUpdated, err := s.repository.Update(appointmentSearched)
+	return seriesCreated, skipped, nil
+}
+
+// GetSeries returns a series with its appointments, series of other dentists are not found for dentists
+func (s *Service) GetSeries(principal auth.Principal, id uint) (Series, error) {
+	data, err := s.repository.GetSeries(id)
 	if err != nil {
 		switch {
 		case errors.Is(err, internal.ErNotFound):
-			return Appointment{}, internal.ErNotFound
+			return Series{}, internal.ErNotFound
 
 		default:
-			return Appointment{}, internal.ErServiceUnavailable
+			return Series{}, internal.ErServiceUnavailable
 		}
 	}
 
-	return appointmentUpdated, nil
+	if !CanAccess(principal, Appointment{DentistID: data.DentistID}) {
+		return Series{}, internal.ErNotFound
+	}
+
+	return data, nil
+}
+
+// UpdateSeries applies the given fields to the occurrences of the series of an appointment in the scope,
+// a new date moves every occurrence by the same number of days to the same time, closed occurrences
+// are left as they are and any occurrence that can't be moved fails the whole change
+func (s *Service) UpdateSeries(principal auth.Principal, changes Appointment, scope Scope) ([]Appointment, error) {
+	if scope == ScopeThis {
+		appointmentUpdated, err := s.Patch(principal, changes)
+		if err != nil {
+			return nil, err
+		}
+		return []Appointment{appointmentUpdated}, nil
+	}
+
+	if changes.Duration > MaxDuration {
+		return nil, internal.ErInvalidDuration
+	}
+
+	target, occurrences, err := s.scope(principal, changes.ID, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	// Occurrences are moved starting from the side they move to, so they don't run into the old slots of the others
+	if changes.Date.After(target.Date) {
+		for i, j := 0, len(occurrences)-1; i < j; i, j = i+1, j-1 {
+			occurrences[i], occurrences[j] = occurrences[j], occurrences[i]
+		}
+	}
+
+	var updated []Appointment
+	err = s.repository.Transaction(func(repository Repository) error {
+		updated = nil
+		var failed []internal.OccurrenceError
+		for _, occurrence := range occurrences {
+			if !occurrence.Status.Open() {
+				continue
+			}
+
+			next := occurrence
+			if !changes.Date.IsZero() {
+				next.Date = Shift(occurrence.Date, target.Date, changes.Date, s.location)
+			}
+			if changes.PatientID != 0 {
+				next.PatientID = changes.PatientID
+			}
+			if changes.DentistID != 0 {
+				next.DentistID = changes.DentistID
+			}
+			if changes.Duration != 0 {
+				next.Duration = changes.Duration
+			}
+			if changes.Description != "" {
+				next.Description = changes.Description
+			}
+
+			if !CanAccess(principal, next) {
+				return internal.ErForbidden
+			}
+			Normalize(&next)
+
+			err := checkSchedule(repository, s.location, next)
+			if errors.Is(err, internal.ErAppointmentConflict) || errors.Is(err, internal.ErOutsideWorkingHours) {
+				failed = append(failed, internal.OccurrenceError{Date: next.Date, Err: err})
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			next, err = repository.Update(next)
+			if err != nil {
+				return err
+			}
+
+			err = record(repository, principal, audit.ActionUpdate, next.ID, occurrence, next)
+			if err != nil {
+				return err
+			}
+
+			updated = append(updated, next)
+		}
+
+		if len(failed) > 0 {
+			return &internal.SeriesConflictError{Occurrences: failed}
+		}
+		return nil
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, internal.ErSeriesConflict):
+			return nil, err
+
+		case errors.Is(err, internal.ErForbidden):
+			return nil, internal.ErForbidden
+
+		case errors.Is(err, internal.ErNotFound):
+			return nil, internal.ErNotFound
+
+		default:
+			return nil, internal.ErServiceUnavailable
+		}
+	}
+
+	return updated, nil
 }
 
-func (s *Service) Patch(appointment Appointment) (Appointment, error) {
+// CancelSeries cancels the occurrences of the series of an appointment in the scope with the reason,
+// only the occurrences that haven't started are cancelled
+func (s *Service) CancelSeries(principal auth.Principal, id uint, scope Scope, reason string) ([]Appointment, error) {
+	if scope == ScopeThis {
+		appointmentCancelled, err := s.Transition(principal, id, StatusCancelled, reason)
+		if err != nil {
+			return nil, err
+		}
+		return []Appointment{appointmentCancelled}, nil
+	}
+
+	target, occurrences, err := s.scope(principal, id, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	var cancelled []Appointment
+	err = s.repository.Transaction(func(repository Repository) error {
+		cancelled = nil
+		err := repository.LockSchedule(target.DentistID, target.PatientID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, occurrence := range occurrences {
+			if !CanAccess(principal, occurrence) {
+				return internal.ErForbidden
+			}
+
+			next := occurrence
+			if !next.Move(StatusCancelled, reason, now) {
+				continue
+			}
 
-	appointmentSearched, err := s.repository.GetByID(appointment.ID)
+			next, err = repository.Update(next)
+			if err != nil {
+				return err
+			}
+
+			err = record(repository, principal, audit.ActionUpdate, next.ID, occurrence, next)
+			if err != nil {
+				return err
+			}
+
+			cancelled = append(cancelled, next)
+		}
+		return nil
+	})
 	if err != nil {
 		switch {
+		case errors.Is(err, internal.ErForbidden):
+			return nil, internal.ErForbidden
+
 		case errors.Is(err, internal.ErNotFound):
-			return Appointment{}, internal.ErNotFound
+			return nil, internal.ErNotFound
 
 		default:
-			return Appointment{}, internal.ErServiceUnavailable
+			return nil, internal.ErServiceUnavailable
 		}
 	}
 
-	CompareTo(&appointment, appointmentSearched)
+	s.freed(cancelled...)
+
+	return cancelled, nil
+}
+
+// scope returns an appointment and the occurrences of its series in the scope, from it on or all of them
+func (s *Service) scope(principal auth.Principal, id uint, scope Scope) (Appointment, []Appointment, error) {
+	target, err := s.GetByID(principal, id)
+	if err != nil {
+		return Appointment{}, nil, err
+	}
+
+	if target.SeriesID == nil {
+		return Appointment{}, nil, internal.ErNotInSeries
+	}
+
+	var from time.Time
+	if scope == ScopeFollowing {
+		from = target.Date
+	}
+
+	occurrences, err := s.repository.GetSeriesAppointments(*target.SeriesID, from)
+	if err != nil {
+		return Appointment{}, nil, internal.ErServiceUnavailable
+	}
+
+	return target, occurrences, nil
+}
+
+// save checks the schedule and stores an existing appointment and its audit entry in a single transaction
+func (s *Service) save(principal auth.Principal, before Appointment, appointment Appointment) (Appointment, error) {
+	var appointmentUpdated Appointment
+	err := s.repository.Transaction(func(repository Repository) error {
+		err := checkSchedule(repository, s.location, appointment)
+		if err != nil {
+			return err
+		}
+
+		// The status is read again under the lock, it only changes through Transition
+		current, err := repository.GetByID(appointment.ID)
+		if err != nil {
+			return err
+		}
+		if !current.Status.Open() {
+			return internal.ErAppointmentClosed
+		}
+		keepLifecycle(&appointment, current)
+
+		appointmentUpdated, err = repository.Update(appointment)
+		if err != nil {
+			return err
+		}
 
-	appointmentUpdated, err := s.repository.Update(appointmentSearched)
+		return record(repository, principal, audit.ActionUpdate, appointment.ID, before, appointmentUpdated)
+	})
 	if err != nil {
 		switch {
+		case errors.Is(err, internal.ErAppointmentConflict):
+			return Appointment{}, err
+
+		case errors.Is(err, internal.ErOutsideWorkingHours):
+			return Appointment{}, internal.ErOutsideWorkingHours
+
+		case errors.Is(err, internal.ErAppointmentClosed):
+			return Appointment{}, internal.ErAppointmentClosed
+
 		case errors.Is(err, internal.ErNotFound):
 			return Appointment{}, internal.ErNotFound
 
@@ -123,34 +640,163 @@ func (s *Service) Patch(appointment Appointment) (Appointment, error) {
 	return appointmentUpdated, nil
 }
 
-func (s *Service) Delete(id uint) error {
-	_, err := s.repository.GetByID(id)
+// Transition moves an appointment to another status of its lifecycle, the reason is kept for cancellations,
+// cancelled appointments free their slot for other bookings
+func (s *Service) Transition(principal auth.Principal, id uint, status Status, reason string) (Appointment, error) {
+	appointmentSearched, err := s.GetByID(principal, id)
+	if err != nil {
+		return Appointment{}, err
+	}
+
+	var appointmentMoved Appointment
+	err = s.repository.Transaction(func(repository Repository) error {
+		err := repository.LockSchedule(appointmentSearched.DentistID, appointmentSearched.PatientID)
+		if err != nil {
+			return err
+		}
+
+		current, err := repository.GetByID(id)
+		if err != nil {
+			return err
+		}
+
+		appointmentMoved = current
+		if !appointmentMoved.Move(status, reason, time.Now()) {
+			return &internal.TransitionError{From: string(current.Status), To: string(status)}
+		}
+
+		appointmentMoved, err = repository.Update(appointmentMoved)
+		if err != nil {
+			return err
+		}
+
+		return record(repository, principal, audit.ActionUpdate, id, current, appointmentMoved)
+	})
 	if err != nil {
 		switch {
+		case errors.Is(err, internal.ErInvalidTransition):
+			return Appointment{}, err
+
 		case errors.Is(err, internal.ErNotFound):
-			return internal.ErNotFound
+			return Appointment{}, internal.ErNotFound
 
 		default:
-			return internal.ErServiceUnavailable
+			return Appointment{}, internal.ErServiceUnavailable
+		}
+	}
+
+	if status == StatusCancelled {
+		s.freed(appointmentMoved)
+	}
+
+	return appointmentMoved, nil
+}
+
+// freed tells the listeners added with OnSlotFreed that the slots of the appointments are free
+func (s *Service) freed(appointments ...Appointment) {
+	for _, listener := range s.slotFreed {
+		for _, current := range appointments {
+			listener(current)
 		}
 	}
+}
 
-	err = s.repository.Delete(id)
+// checkSchedule locks the dentist and patient schedules, checks the dentist working hours in the location and
+// looks for overlapping appointments, it must run inside a transaction so the lock is held until the appointment
+// is stored
+func checkSchedule(repository Repository, location *time.Location, appointment Appointment) error {
+	err := repository.LockSchedule(appointment.DentistID, appointment.PatientID)
 	if err != nil {
-		switch {
-		case errors.Is(err, internal.ErNotFound):
-			return internal.ErNotFound
+		return err
+	}
 
-		default:
-			return internal.ErServiceUnavailable
+	hours, err := repository.GetSchedule(appointment.DentistID)
+	if err != nil {
+		return err
+	}
+
+	if !schedule.Contains(hours, appointment.Date, appointment.EndDate, location) {
+		return internal.ErOutsideWorkingHours
+	}
+
+	overlapping, err := repository.GetOverlapping(appointment)
+	if err != nil {
+		return err
+	}
+
+	if len(overlapping) > 0 {
+		ids := make([]uint, 0, len(overlapping))
+		for _, current := range overlapping {
+			ids = append(ids, current.ID)
 		}
+		return &internal.AppointmentConflictError{IDs: ids}
 	}
 
 	return nil
 }
 
+// record adds the audit entry and the outbox event of a change to the transaction of the repository
+func record(repository Repository, principal auth.Principal, action audit.Action, id uint, before interface{}, after interface{}) error {
+	entry, err := audit.NewEntry(principal, audit.EntityAppointment, id, action, before, after)
+	if err != nil {
+		return err
+	}
+
+	err = repository.Record(entry)
+	if err != nil {
+		return err
+	}
+
+	change, err := event.New(principal, audit.EntityAppointment, id, action, before, after)
+	if err != nil {
+		return err
+	}
+
+	return repository.Publish(change)
+}
+
+// CanAccess reports whether the principal can see or change the appointment, dentists only their own
+func CanAccess(principal auth.Principal, appointment Appointment) bool {
+	if !principal.Role.Valid() {
+		return false
+	}
+
+	dentistID, ok := principal.Dentist()
+	return !ok || appointment.DentistID == dentistID
+}
+
+// byDate orders a history of appointments by date when no other order is requested
+func byDate(page pagination.Request) pagination.Request {
+	if len(page.Sort) == 0 {
+		page.Sort = []pagination.Sort{{Column: "date"}}
+	}
+	return page
+}
+
 // Custom functions for the service
-// Normalize and CompareTo are used to avoid empty fields in the database
+// Normalize and CompareTo are used to avoid empty fields in the database, Normalize also keeps dates in UTC
+
+func Normalize(appointment *Appointment) {
+	appointment.Date = appointment.Date.UTC()
+	if appointment.Duration == 0 {
+		appointment.Duration = DefaultDuration
+	}
+	appointment.EndDate = appointment.Date.Add(time.Duration(appointment.Duration) * time.Minute)
+}
+
+// keepLifecycle copies the status of b and the times of its transitions to a, along with its series
+// and sequence, which aren't changed by updates either
+func keepLifecycle(a *Appointment, b Appointment) {
+	a.SeriesID = b.SeriesID
+	a.Sequence = b.Sequence
+	a.Status = b.Status
+	a.ConfirmedAt = b.ConfirmedAt
+	a.CheckedInAt = b.CheckedInAt
+	a.CompletedAt = b.CompletedAt
+	a.CancelledAt = b.CancelledAt
+	a.NoShowAt = b.NoShowAt
+	a.CancelReason = b.CancelReason
+}
 
 func CompareTo(a *Appointment, b Appointment) {
 	if a.PatientID == 0 {
@@ -162,6 +808,9 @@ func CompareTo(a *Appointment, b Appointment) {
 	if a.Date.IsZero() {
 		a.Date = b.Date
 	}
+	if a.Duration == 0 {
+		a.Duration = b.Duration
+	}
 	if a.Description == "" {
 		a.Description = b.Description
 	}
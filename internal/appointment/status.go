@@ -0,0 +1,85 @@
+package appointment
+
+import (
+	"time"
+)
+
+// Status is the step of its lifecycle an appointment is in
+type Status string
+
+const (
+	StatusScheduled Status = "scheduled"
+	StatusConfirmed Status = "confirmed"
+	StatusCheckedIn Status = "checked_in"
+	StatusCompleted Status = "completed"
+	StatusCancelled Status = "cancelled"
+	StatusNoShow    Status = "no_show"
+)
+
+// Statuses lists every status in lifecycle order
+var Statuses = []Status{StatusScheduled, StatusConfirmed, StatusCheckedIn, StatusCompleted, StatusCancelled, StatusNoShow}
+
+// PendingStatuses are the statuses of the appointments that haven't started nor been called off yet
+var PendingStatuses = []Status{StatusScheduled, StatusConfirmed}
+
+// transitions maps every status to the statuses an appointment can move to from it,
+// completed, cancelled and no-show appointments are closed and can't move anymore
+var transitions = map[Status][]Status{
+	StatusScheduled: {StatusConfirmed, StatusCheckedIn, StatusCancelled, StatusNoShow},
+	StatusConfirmed: {StatusCheckedIn, StatusCancelled, StatusNoShow},
+	StatusCheckedIn: {StatusCompleted},
+}
+
+func (s Status) Valid() bool {
+	for _, current := range Statuses {
+		if current == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Open reports whether an appointment in this status can still change
+func (s Status) Open() bool {
+	_, ok := transitions[s]
+	return ok
+}
+
+// CanMoveTo reports whether the state machine allows moving from this status to the given one
+func (s Status) CanMoveTo(status Status) bool {
+	for _, current := range transitions[s] {
+		if current == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Move changes the status of the appointment and stamps the time of the transition, the reason is kept
+// for cancellations, it reports false and leaves the appointment untouched when the state machine doesn't allow it
+func (a *Appointment) Move(status Status, reason string, at time.Time) bool {
+	if !a.Status.CanMoveTo(status) {
+		return false
+	}
+
+	a.Status = status
+	switch status {
+	case StatusConfirmed:
+		a.ConfirmedAt = &at
+	case StatusCheckedIn:
+		a.CheckedInAt = &at
+	case StatusCompleted:
+		a.CompletedAt = &at
+	case StatusCancelled:
+		a.CancelledAt = &at
+		a.CancelReason = reason
+	case StatusNoShow:
+		a.NoShowAt = &at
+	}
+	return true
+}
+
+// IsCancelled reports whether the appointment was cancelled
+func (a Appointment) IsCancelled() bool {
+	return a.Status == StatusCancelled
+}
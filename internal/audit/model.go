@@ -0,0 +1,61 @@
+package audit
+
+import (
+	"time"
+)
+
+// Action is the kind of change made to a record
+type Action string
+
+const (
+	ActionCreate  Action = "create"
+	ActionUpdate  Action = "update"
+	ActionDelete  Action = "delete"
+	ActionRestore Action = "restore"
+)
+
+// Entities whose changes are audited
+const (
+	EntityPatient     = "patient"
+	EntityDentist     = "dentist"
+	EntityAppointment = "appointment"
+)
+
+// Entry records who changed a record, when, and the fields that changed, Changes holds them as JSON
+type Entry struct {
+	ID        uint      `gorm:"primaryKey"`
+	Entity    string    `gorm:"not null;type:varchar(40);index:idx_audit_entries_entity,priority:1"`
+	EntityID  uint      `gorm:"not null;index:idx_audit_entries_entity,priority:2"`
+	Action    Action    `gorm:"not null;type:varchar(40)"`
+	ActorID   uint      `gorm:"not null;index"`
+	Actor     string    `gorm:"not null;type:varchar(60)"`
+	CreatedAt time.Time `gorm:"not null;precision:3;index"`
+	Changes   string
+}
+
+func (Entry) TableName() string {
+	return "audit_entries"
+}
+
+// Change holds the value of a field before and after a change, nil when the record didn't exist
+type Change struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// Filter narrows a list of entries, empty fields match every entry
+type Filter struct {
+	Entity   string
+	EntityID uint
+	ActorID  uint
+	From     time.Time
+	To       time.Time
+}
+
+// SortColumns maps the fields a list of entries can be sorted by to their columns
+var SortColumns = map[string]string{
+	"id":         "id",
+	"created_at": "created_at",
+	"entity":     "entity",
+	"action":     "action",
+}
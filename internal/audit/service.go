@@ -0,0 +1,162 @@
+package audit
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/auth"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/pagination"
+)
+
+type Repository interface {
+	GetAll(filter Filter, page pagination.Request) (pagination.Page[Entry], error)
+}
+
+type Service struct {
+	repository Repository
+}
+
+func NewService(repository Repository) *Service {
+	return &Service{repository: repository}
+}
+
+func (s *Service) GetAll(filter Filter, page pagination.Request) (pagination.Page[Entry], error) {
+	if len(page.Sort) == 0 {
+		page.Sort = []pagination.Sort{{Column: "created_at", Desc: true}}
+	}
+
+	data, err := s.repository.GetAll(filter, page)
+	if err != nil {
+		return pagination.Page[Entry]{}, internal.ErServiceUnavailable
+	}
+
+	return data, nil
+}
+
+// NewEntry builds the entry of a change made by the principal, before is nil for creations and after for deletions
+func NewEntry(principal auth.Principal, entity string, entityID uint, action Action, before interface{}, after interface{}) (Entry, error) {
+	changes, err := json.Marshal(Diff(before, after))
+	if err != nil {
+		return Entry{}, err
+	}
+
+	return Entry{
+		Entity:    entity,
+		EntityID:  entityID,
+		Action:    action,
+		ActorID:   principal.UserID,
+		Actor:     principal.Username,
+		CreatedAt: time.Now(),
+		Changes:   string(changes),
+	}, nil
+}
+
+// Decode returns the changes stored in an entry
+func (e Entry) Decode() map[string]Change {
+	changes := map[string]Change{}
+	_ = json.Unmarshal([]byte(e.Changes), &changes)
+	return changes
+}
+
+// Diff compares two values of the same struct type field by field and returns the fields that differ,
+// keyed by their snake case name, relations tagged with a gorm foreignKey and fields tagged audit:"-" are left out
+func Diff(before interface{}, after interface{}) map[string]Change {
+	changes := map[string]Change{}
+
+	beforeValue := reflect.Indirect(reflect.ValueOf(before))
+	afterValue := reflect.Indirect(reflect.ValueOf(after))
+
+	var structType reflect.Type
+	switch {
+	case beforeValue.IsValid():
+		structType = beforeValue.Type()
+	case afterValue.IsValid():
+		structType = afterValue.Type()
+	default:
+		return changes
+	}
+	if structType.Kind() != reflect.Struct {
+		return changes
+	}
+
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if !audited(field) {
+			continue
+		}
+
+		var change Change
+		if beforeValue.IsValid() {
+			change.Before = beforeValue.Field(i).Interface()
+		}
+		if afterValue.IsValid() {
+			change.After = afterValue.Field(i).Interface()
+		}
+
+		if equal(change.Before, change.After) {
+			continue
+		}
+
+		changes[snakeCase(field.Name)] = change
+	}
+
+	return changes
+}
+
+// Snapshot returns the fields of a struct keyed by their snake case name, with the same fields Diff compares
+func Snapshot(value interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+
+	structValue := reflect.Indirect(reflect.ValueOf(value))
+	if !structValue.IsValid() || structValue.Kind() != reflect.Struct {
+		return fields
+	}
+
+	structType := structValue.Type()
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if !audited(field) {
+			continue
+		}
+		fields[snakeCase(field.Name)] = structValue.Field(i).Interface()
+	}
+
+	return fields
+}
+
+// audited reports whether a field is part of the audit, relations tagged with a gorm foreignKey and fields
+// tagged audit:"-" aren't
+func audited(field reflect.StructField) bool {
+	return field.IsExported() && !strings.Contains(field.Tag.Get("gorm"), "foreignKey") && field.Tag.Get("audit") != "-"
+}
+
+func equal(a interface{}, b interface{}) bool {
+	aTime, aIsTime := a.(time.Time)
+	bTime, bIsTime := b.(time.Time)
+	if aIsTime && bIsTime {
+		return aTime.Equal(bTime)
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+// snakeCase turns a field name like AdmissionDate or DNI into admission_date or dni
+func snakeCase(name string) string {
+	var builder strings.Builder
+	runes := []rune(name)
+	for i, current := range runes {
+		if unicode.IsUpper(current) && i > 0 {
+			previousLower := unicode.IsLower(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if previousLower || (nextLower && unicode.IsUpper(runes[i-1])) {
+				builder.WriteRune('_')
+			}
+		}
+		builder.WriteRune(unicode.ToLower(current))
+	}
+	return builder.String()
+}
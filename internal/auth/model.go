@@ -0,0 +1,52 @@
+package auth
+
+// Role groups the operations a user is allowed to do
+type Role string
+
+const (
+	RoleAdmin        Role = "admin"
+	RoleReceptionist Role = "receptionist"
+	RoleDentist      Role = "dentist"
+)
+
+// Roles lists every valid role
+var Roles = []Role{RoleAdmin, RoleReceptionist, RoleDentist}
+
+// Principal is the identity of the user making a request, DentistID is only set for the dentist role
+type Principal struct {
+	UserID    uint
+	Username  string
+	Role      Role
+	DentistID uint
+}
+
+// Valid reports whether the role is one of Roles
+func (r Role) Valid() bool {
+	for _, role := range Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Is reports whether the principal has any of the given roles
+func (p Principal) Is(roles ...Role) bool {
+	for _, role := range roles {
+		if p.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// System is the principal used by the server itself, outside of a request
+var System = Principal{Username: "system", Role: RoleAdmin}
+
+// Dentist returns the id of the dentist the principal is scoped to, only dentists are scoped
+func (p Principal) Dentist() (uint, bool) {
+	if p.Role != RoleDentist {
+		return 0, false
+	}
+	return p.DentistID, true
+}
@@ -0,0 +1,53 @@
+package bulk
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MaxErrors is how many row errors a report lists, the rest are only counted
+const MaxErrors = 1000
+
+// ErrRollback is returned inside the transaction of an import to undo it, for dry runs and files with errors
+var ErrRollback = errors.New("import rolled back")
+
+// RowError tells why a row of a file can't be imported, Line is its line in the file
+type RowError struct {
+	Line   int
+	Errors []string
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Errors)
+}
+
+// Report is the outcome of an import, rows are only created when every row is valid and it isn't a dry run
+type Report struct {
+	DryRun  bool
+	Rows    int
+	Invalid int
+	Created int
+	// Errors lists the first MaxErrors invalid rows
+	Errors []RowError
+}
+
+// Reject counts an invalid row, listing it while there is room
+func (r *Report) Reject(line int, errs ...string) {
+	r.Invalid++
+	if len(r.Errors) < MaxErrors {
+		r.Errors = append(r.Errors, RowError{Line: line, Errors: errs})
+	}
+}
+
+// Duplicates tracks the keys seen in a file, like dnis or licenses, to find the rows that repeat them
+type Duplicates map[string]int
+
+// Seen records the key at the line, it returns the line it was first seen at when it repeats
+func (d Duplicates) Seen(key string, line int) (int, bool) {
+	first, found := d[key]
+	if found {
+		return first, true
+	}
+	d[key] = line
+	return 0, false
+}
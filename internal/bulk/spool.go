@@ -0,0 +1,73 @@
+package bulk
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+)
+
+// Spool keeps the rows of an import in a temporary file while the rest of the file is read and checked, so they
+// are written afterwards in a transaction that doesn't last as long as the upload
+type Spool[T any] struct {
+	file   *os.File
+	writer *bufio.Writer
+}
+
+type spooledRow[T any] struct {
+	Line int
+	Row  T
+}
+
+func NewSpool[T any]() (*Spool[T], error) {
+	file, err := os.CreateTemp("", "import-*.jsonl")
+	if err != nil {
+		return nil, err
+	}
+	return &Spool[T]{file: file, writer: bufio.NewWriter(file)}, nil
+}
+
+// Add stores the row read at the line
+func (s *Spool[T]) Add(line int, row T) error {
+	encoded, err := json.Marshal(spooledRow[T]{Line: line, Row: row})
+	if err != nil {
+		return err
+	}
+	_, err = s.writer.Write(append(encoded, '\n'))
+	return err
+}
+
+// Each calls fn with the rows in the order they were added, it stops at the first error of fn
+func (s *Spool[T]) Each(fn func(line int, row T) error) error {
+	err := s.writer.Flush()
+	if err != nil {
+		return err
+	}
+	_, err = s.file.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bufio.NewReader(s.file))
+	for {
+		var current spooledRow[T]
+		err = decoder.Decode(&current)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		err = fn(current.Line, current.Row)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Close removes the file of the spool
+func (s *Spool[T]) Close() error {
+	return errors.Join(s.file.Close(), os.Remove(s.file.Name()))
+}
@@ -0,0 +1,95 @@
+package calendar
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// dateTimeLayout is the UTC form of DATE-TIME values
+const dateTimeLayout = "20060102T150405Z"
+
+// maxLineLength is the longest a content line can be, in octets, before it's folded
+const maxLineLength = 75
+
+// Encode writes the calendar in the iCalendar format of RFC 5545, events without a stamp are stamped
+// with now
+func Encode(w io.Writer, calendar Calendar, now time.Time) error {
+	writer := bufio.NewWriter(w)
+
+	writeLine(writer, "BEGIN", "VCALENDAR")
+	writeLine(writer, "VERSION", "2.0")
+	writeLine(writer, "PRODID", ProductID)
+	writeLine(writer, "CALSCALE", "GREGORIAN")
+	writeLine(writer, "METHOD", "PUBLISH")
+	if calendar.Name != "" {
+		writeLine(writer, "X-WR-CALNAME", escape(calendar.Name))
+	}
+
+	for _, event := range calendar.Events {
+		stamp := event.Stamp
+		if stamp.IsZero() {
+			stamp = now
+		}
+
+		writeLine(writer, "BEGIN", "VEVENT")
+		writeLine(writer, "UID", escape(event.UID))
+		writeLine(writer, "DTSTAMP", formatTime(stamp))
+		writeLine(writer, "LAST-MODIFIED", formatTime(stamp))
+		writeLine(writer, "DTSTART", formatTime(event.Start))
+		writeLine(writer, "DTEND", formatTime(event.End))
+		writeLine(writer, "SEQUENCE", strconv.FormatUint(uint64(event.Sequence), 10))
+		writeLine(writer, "STATUS", string(event.Status))
+		writeLine(writer, "SUMMARY", escape(event.Summary))
+		if event.Description != "" {
+			writeLine(writer, "DESCRIPTION", escape(event.Description))
+		}
+		writeLine(writer, "END", "VEVENT")
+	}
+
+	writeLine(writer, "END", "VCALENDAR")
+
+	return writer.Flush()
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(dateTimeLayout)
+}
+
+// escape escapes the characters that have a meaning in TEXT values
+func escape(text string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	).Replace(text)
+}
+
+// writeLine writes a content line ended by CRLF, lines longer than maxLineLength octets are folded
+// into several lines starting with a space, without splitting UTF-8 characters
+func writeLine(writer *bufio.Writer, name string, value string) {
+	line := name + ":" + value
+
+	limit := maxLineLength
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+
+		writer.WriteString(line[:cut])
+		writer.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space, which counts towards their length
+		limit = maxLineLength - 1
+	}
+
+	writer.WriteString(line)
+	writer.WriteString("\r\n")
+}
@@ -0,0 +1,74 @@
+package calendar
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/appointment"
+)
+
+// ProductID identifies the server as the producer of the calendars, as PRODID
+const ProductID = "-//Dental Clinic//Appointments//EN"
+
+// UIDDomain is the right hand side of the UID of every event, so UIDs stay the same across requests
+const UIDDomain = "dental-clinic"
+
+// History is how far back the appointments of a feed go, upcoming appointments are always included
+const History = 90 * 24 * time.Hour
+
+// Status is the STATUS of an event
+type Status string
+
+const (
+	StatusTentative Status = "TENTATIVE"
+	StatusConfirmed Status = "CONFIRMED"
+	StatusCancelled Status = "CANCELLED"
+)
+
+// Calendar is a VCALENDAR with the events of a dentist or a patient
+type Calendar struct {
+	Name   string
+	Events []Event
+}
+
+// Event is a VEVENT, Sequence grows every time the event changes so calendar apps replace their copy
+type Event struct {
+	UID         string
+	Stamp       time.Time
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Description string
+	Status      Status
+	Sequence    uint
+}
+
+// FromAppointment returns the event of an appointment, with the summary given by the owner of the calendar
+func FromAppointment(data appointment.Appointment, summary string) Event {
+	event := Event{
+		UID:         fmt.Sprintf("appointment-%d@%s", data.ID, UIDDomain),
+		Stamp:       data.UpdatedAt,
+		Start:       data.Date,
+		End:         data.EndDate,
+		Summary:     summary,
+		Description: data.Description,
+		Status:      StatusConfirmed,
+		Sequence:    data.Sequence,
+	}
+
+	switch data.Status {
+	case appointment.StatusScheduled:
+		event.Status = StatusTentative
+	case appointment.StatusCancelled:
+		event.Status = StatusCancelled
+		if data.CancelReason != "" {
+			event.Description = fmt.Sprintf("%s\nCancelled: %s", data.Description, data.CancelReason)
+		}
+	}
+
+	if event.End.IsZero() {
+		event.End = data.Date.Add(time.Duration(data.Duration) * time.Minute)
+	}
+
+	return event
+}
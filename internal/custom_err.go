@@ -1,18 +1,134 @@
 package internal
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
 	/* General errors */
 
 	ErNotFound           = errors.New("not found")
 	ErServiceUnavailable = errors.New("service unavailable, try again later")
+	ErInvalidSort        = errors.New("sort field is not allowed")
+	ErNotDeleted         = errors.New("record is not deleted")
+	ErInvalidStrategy    = errors.New("strategy must be reject or cascade, or reassign with a license for dentists")
+	ErHasAppointments    = errors.New("has upcoming appointments")
+
+	/* Auth errors */
+
+	ErInvalidCredentials    = errors.New("invalid username or password")
+	ErInvalidToken          = errors.New("invalid token")
+	ErTokenExpired          = errors.New("token expired")
+	ErForbidden             = errors.New("not allowed to perform this operation")
+	ErInvalidRole           = errors.New("role must be admin, receptionist or dentist, and only dentists are linked to a dentist")
+	ErWeakPassword          = errors.New("password must have at least 8 characters")
+	ErUsernameAlreadyExists = errors.New("username already exists")
 
 	/* Dentist errors */
 
-	ErLicenseAlreadyExists = errors.New("license already exists")
+	ErLicenseAlreadyExists    = errors.New("license already exists")
+	ErLicenseBelongsToDeleted = errors.New("license belongs to a deleted dentist, restore it instead")
+	ErReassignTarget          = errors.New("appointments must be reassigned to another existing dentist")
+	ErInvalidSchedule         = errors.New("working hours must be valid ranges that don't overlap on the same day")
 
 	/* Patient errors */
 
-	ErDniAlreadyExists = errors.New("dni already exists")
+	ErDniAlreadyExists    = errors.New("dni already exists")
+	ErDniBelongsToDeleted = errors.New("dni belongs to a deleted patient, restore it instead")
+
+	/* Appointment errors */
+
+	ErAppointmentConflict = errors.New("appointment overlaps with existing appointments")
+	ErOutsideWorkingHours = errors.New("appointment is outside the dentist working hours")
+	ErInvalidDuration     = errors.New("duration must be between 1 and 480 minutes")
+	ErInvalidTransition   = errors.New("appointment can't move to that status from its current one")
+	ErAppointmentClosed   = errors.New("appointment is completed, cancelled or no-show and can't be changed")
+	ErInvalidRecurrence   = errors.New("series must repeat daily, weekly or monthly with an interval of at least 1 and either a count or an until date, up to 100 appointments")
+	ErNotInSeries         = errors.New("appointment is not part of a series")
+	ErSeriesConflict      = errors.New("some appointments of the series can't be booked")
+
+	/* Waitlist errors */
+
+	ErInvalidWaitlistEntry = errors.New("waitlist entry must have a date range and a daily window in format HH:MM long enough for its duration")
+	ErEntryClosed          = errors.New("waitlist entry is booked, cancelled or expired")
+	ErOfferClosed          = errors.New("offer was already answered or expired")
+	ErOfferTaken           = errors.New("offered slot was booked by someone else")
+
+	/* Report errors */
+
+	ErInvalidReport = errors.New("report must have a from date before its to date, at most two years apart, and a period of day, week or month")
+
+	/* Webhook errors */
+
+	ErInvalidWebhook  = errors.New("webhook must have an http or https url, known event types and a secret of 16 to 64 characters")
+	ErDeliveryNotDead = errors.New("only dead deliveries can be retried")
+
+	/* Migration errors */
+
+	ErMigrationLocked   = errors.New("another migrator holds the migrations lock")
+	ErMigrationsPending = errors.New("database has pending migrations, run migrate up")
 )
+
+// AppointmentConflictError carries the ids of the appointments that overlap with a booking,
+// it matches ErAppointmentConflict with errors.Is
+type AppointmentConflictError struct {
+	IDs []uint
+}
+
+func (e *AppointmentConflictError) Error() string {
+	return ErAppointmentConflict.Error()
+}
+
+func (e *AppointmentConflictError) Unwrap() error {
+	return ErAppointmentConflict
+}
+
+// DependentAppointmentsError carries the number of upcoming appointments that block the deletion of a dentist
+// or patient, it matches ErHasAppointments with errors.Is
+type DependentAppointmentsError struct {
+	Count int
+}
+
+func (e *DependentAppointmentsError) Error() string {
+	return ErHasAppointments.Error()
+}
+
+func (e *DependentAppointmentsError) Unwrap() error {
+	return ErHasAppointments
+}
+
+// TransitionError carries the statuses of a change the appointment lifecycle doesn't allow,
+// it matches ErInvalidTransition with errors.Is
+type TransitionError struct {
+	From string
+	To   string
+}
+
+func (e *TransitionError) Error() string {
+	return ErInvalidTransition.Error()
+}
+
+func (e *TransitionError) Unwrap() error {
+	return ErInvalidTransition
+}
+
+// OccurrenceError tells why the appointment of a series on a date couldn't be booked or changed
+type OccurrenceError struct {
+	Date time.Time
+	Err  error
+}
+
+// SeriesConflictError carries the occurrences of a series that couldn't be booked or changed,
+// it matches ErSeriesConflict with errors.Is
+type SeriesConflictError struct {
+	Occurrences []OccurrenceError
+}
+
+func (e *SeriesConflictError) Error() string {
+	return ErSeriesConflict.Error()
+}
+
+func (e *SeriesConflictError) Unwrap() error {
+	return ErSeriesConflict
+}
@@ -2,12 +2,33 @@ package dentist
 
 import (
 	model "github.com/10Daniel10/web-server-go-ExamenFinal/internal/appointment"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/schedule"
+	"gorm.io/gorm"
 )
 
 type Dentist struct {
-	ID           uint                `gorm:"primaryKey"`
-	Lastname     string              `gorm:"not null;type:varchar(60)"`
-	Name         string              `gorm:"not null;type:varchar(60)"`
-	License      string              `gorm:"not null;unique;type:varchar(40)"`
-	Appointments []model.Appointment `gorm:"foreignKey:DentistID"`
+	ID           uint                    `gorm:"primaryKey"`
+	Lastname     string                  `gorm:"not null;type:varchar(60)"`
+	Name         string                  `gorm:"not null;type:varchar(60)"`
+	License      string                  `gorm:"not null;unique;type:varchar(40)"`
+	Appointments []model.Appointment     `gorm:"foreignKey:DentistID"`
+	WorkingHours []schedule.WorkingHours `gorm:"foreignKey:DentistID"`
+	DeletedAt    gorm.DeletedAt          `gorm:"index"`
+}
+
+// Filter narrows a list of dentists, empty fields match every dentist
+type Filter struct {
+	Name     string
+	Lastname string
+	License  string
+	// IncludeDeleted also lists the deleted dentists
+	IncludeDeleted bool
+}
+
+// SortColumns maps the fields a list of dentists can be sorted by to their columns
+var SortColumns = map[string]string{
+	"id":        "id",
+	"name":      "name",
+	"last_name": "lastname",
+	"license":   "license",
 }
@@ -2,31 +2,80 @@ package dentist
 
 import (
 	"errors"
-	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	"fmt"
+	"io"
 	"strings"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	model "github.com/10Daniel10/web-server-go-ExamenFinal/internal/appointment"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/audit"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/auth"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/bulk"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/event"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/pagination"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/schedule"
 )
 
 type Repository interface {
 	Create(dentist Dentist) (Dentist, error)
-	GetAll() ([]Dentist, error)
+	GetAll(filter Filter, page pagination.Request) (pagination.Page[Dentist], error)
 	GetByID(id uint) (Dentist, error)
 	GetByLicense(license string) (Dentist, error)
 	Update(dentist Dentist) (Dentist, error)
 	Delete(id uint) error
+	// Restore undoes the deletion of a dentist
+	Restore(id uint) (Dentist, error)
+	// Unscoped returns a repository that also finds deleted dentists
+	Unscoped() Repository
+	GetSchedule(dentistID uint) ([]schedule.WorkingHours, error)
+	UpdateSchedule(dentistID uint, hours []schedule.WorkingHours) ([]schedule.WorkingHours, error)
+	GetAppointments(dentistID uint, from time.Time, to time.Time) ([]model.Appointment, error)
+	// Lock blocks concurrent bookings for the dentists until the transaction ends
+	Lock(ids ...uint) error
+	// GetUpcoming returns the appointments of a dentist starting from the given time
+	GetUpcoming(dentistID uint, from time.Time) ([]model.Appointment, error)
+	CancelAppointments(ids []uint, reason string, at time.Time) error
+	ReassignAppointments(ids []uint, dentistID uint) error
+	// Record stores an audit entry, inside Transaction it is committed along with the change
+	Record(entry audit.Entry) error
+	// Publish stores an event in the outbox, inside Transaction it is committed along with the change
+	Publish(event event.Event) error
+	// Transaction runs fn with a repository bound to a single transaction
+	Transaction(fn func(repository Repository) error) error
 }
 
+// CancelReason is the reason given to the appointments cancelled by the deletion of their dentist
+const CancelReason = "dentist deleted"
+
+// Service handles the business rules, location is the time zone of the clinic, working hours and
+// recurring bookings follow its wall clock
 type Service struct {
 	repository Repository
+	location   *time.Location
+	// slotFreed are called with the appointments cancelled or reassigned by the deletion of their dentist
+	slotFreed []func(appointment model.Appointment)
 }
 
-func NewService(repository Repository) *Service {
-	return &Service{repository: repository}
+func NewService(repository Repository, location *time.Location) *Service {
+	return &Service{repository: repository, location: location}
 }
 
-func (s *Service) GetAll() ([]Dentist, error) {
-	data, err := s.repository.GetAll()
+// OnSlotFreed adds a listener called with each appointment a deletion cancelled or moved to another dentist, as it
+// was before, once the deletion is committed
+func (s *Service) OnSlotFreed(listener func(appointment model.Appointment)) {
+	s.slotFreed = append(s.slotFreed, listener)
+}
+
+// GetAll returns a page of dentists, only admins can list deleted dentists
+func (s *Service) GetAll(principal auth.Principal, filter Filter, page pagination.Request) (pagination.Page[Dentist], error) {
+	if !principal.Role.Valid() || (filter.IncludeDeleted && !principal.Is(auth.RoleAdmin)) {
+		return pagination.Page[Dentist]{}, internal.ErForbidden
+	}
+
+	data, err := s.repository.GetAll(filter, page)
 	if err != nil {
-		return nil, err
+		return pagination.Page[Dentist]{}, err
 	}
 
 	return data, nil
@@ -50,34 +99,159 @@ func (s *Service) GetByLicense(license string) (Dentist, error) {
 	return data, nil
 }
 
-func (s *Service) Create(dentist Dentist) (Dentist, error) {
+func (s *Service) Create(principal auth.Principal, dentist Dentist) (Dentist, error) {
+
+	Normalize(&dentist)
 
-	dentistExist, err := s.repository.GetByLicense(dentist.License)
+	err := checkLicense(s.repository, dentist.License)
 	if err != nil {
-		switch {
-		case errors.Is(err, internal.ErNotFound):
+		return Dentist{}, err
+	}
+
+	var dentistCreated Dentist
+	err = s.repository.Transaction(func(repository Repository) error {
+		dentistCreated, err = repository.Create(dentist)
+		if err != nil {
+			return err
+		}
+
+		return record(repository, principal, audit.ActionCreate, dentistCreated.ID, nil, dentistCreated)
+	})
+	if err != nil {
+		return Dentist{}, internal.ErServiceUnavailable
+	}
+
+	return dentistCreated, nil
+}
+
+// Import creates the dentists read from next until it returns io.EOF, next returns them one at a time with their
+// line in the file, or a *bulk.RowError for the rows it can't read. The whole file is checked before the dentists are
+// created in a single transaction, so nothing is created when a row is invalid, its license is repeated or already
+// exists, or on dry runs
+func (s *Service) Import(principal auth.Principal, next func() (Dentist, int, error), dryRun bool) (bulk.Report, error) {
+	report := bulk.Report{DryRun: dryRun}
+	seen := bulk.Duplicates{}
+
+	spool, err := bulk.NewSpool[Dentist]()
+	if err != nil {
+		return bulk.Report{}, internal.ErServiceUnavailable
+	}
+	defer spool.Close()
+
+	// The whole file is read and checked first, so the transaction only lasts the writes and not the upload
+	for {
+		dentist, line, err := next()
+		if errors.Is(err, io.EOF) {
 			break
 		}
+
+		report.Rows++
+		var rowErr *bulk.RowError
+		if errors.As(err, &rowErr) {
+			report.Reject(rowErr.Line, rowErr.Errors...)
+			continue
+		}
+		if err != nil {
+			return bulk.Report{}, internal.ErServiceUnavailable
+		}
+
+		Normalize(&dentist)
+		first, repeated := seen.Seen(dentist.License, line)
+		if repeated {
+			report.Reject(line, fmt.Sprintf("license %s is repeated from line %d", dentist.License, first))
+			continue
+		}
+
+		err = checkLicense(s.repository, dentist.License)
+		if errors.Is(err, internal.ErLicenseAlreadyExists) || errors.Is(err, internal.ErLicenseBelongsToDeleted) {
+			report.Reject(line, fmt.Sprintf("license %s: %s", dentist.License, err.Error()))
+			continue
+		}
+		if err != nil {
+			return bulk.Report{}, err
+		}
+
+		// The rest of the file is still checked, but nothing else is kept once a row is invalid
+		if dryRun || report.Invalid > 0 {
+			continue
+		}
+
+		err = spool.Add(line, dentist)
+		if err != nil {
+			return bulk.Report{}, internal.ErServiceUnavailable
+		}
 	}
+	if dryRun || report.Invalid > 0 {
+		return report, nil
+	}
+
+	err = s.repository.Transaction(func(repository Repository) error {
+		err := spool.Each(func(line int, dentist Dentist) error {
+			// The license may have been taken while the file was read
+			err := checkLicense(repository, dentist.License)
+			if errors.Is(err, internal.ErLicenseAlreadyExists) || errors.Is(err, internal.ErLicenseBelongsToDeleted) {
+				report.Reject(line, fmt.Sprintf("license %s: %s", dentist.License, err.Error()))
+				return nil
+			}
+			if err != nil || report.Invalid > 0 {
+				return err
+			}
 
-	if dentistExist.License != "" {
-		return Dentist{}, internal.ErLicenseAlreadyExists
+			dentistCreated, err := repository.Create(dentist)
+			if err != nil {
+				return internal.ErServiceUnavailable
+			}
+
+			err = record(repository, principal, audit.ActionCreate, dentistCreated.ID, nil, dentistCreated)
+			if err != nil {
+				return err
+			}
+			report.Created++
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if report.Invalid > 0 {
+			report.Created = 0
+			return bulk.ErrRollback
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, bulk.ErrRollback) {
+		return bulk.Report{}, internal.ErServiceUnavailable
 	}
 
-	Normalize(&dentist)
+	return report, nil
+}
+
+func (s *Service) Update(principal auth.Principal, dentist Dentist) (Dentist, error) {
 
-	dentistCreated, err := s.repository.Create(dentist)
+	dentistSearched, err := s.repository.GetByID(dentist.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, internal.ErNotFound):
-			break
+			return Dentist{}, internal.ErNotFound
+
+		default:
+			return Dentist{}, internal.ErServiceUnavailable
 		}
 	}
 
-	return dentistCreated, nil
+	Normalize(&dentist)
+
+	if dentistSearched.License != dentist.License {
+		err = checkLicense(s.repository, dentist.License)
+		if err != nil {
+			return Dentist{}, err
+		}
+	}
+
+	return s.save(principal, dentistSearched, dentist)
 }
 
-func (s *Service) Update(dentist Dentist) (Dentist, error) {
+func (s *Service) Patch(principal auth.Principal, dentist Dentist) (Dentist, error) {
 
 	dentistSearched, err := s.repository.GetByID(dentist.ID)
 	if err != nil {
@@ -90,39 +264,153 @@ func (s *Service) Update(dentist Dentist) (Dentist, error) {
 		}
 	}
 
+	CompareTo(&dentist, dentistSearched)
 	Normalize(&dentist)
 
 	if dentistSearched.License != dentist.License {
-		dentistExist, err := s.repository.GetByLicense(dentist.License)
+		err = checkLicense(s.repository, dentist.License)
+		if err != nil {
+			return Dentist{}, err
+		}
+	}
+
+	return s.save(principal, dentistSearched, dentist)
+}
+
+// Delete deletes a dentist, the policy says what happens to its upcoming appointments: they block the
+// deletion, are cancelled, or are reassigned to the dentist with the policy license, all in one transaction
+func (s *Service) Delete(principal auth.Principal, id uint, policy model.DeletePolicy) error {
+	dentistSearched, err := s.repository.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, internal.ErNotFound):
+			return internal.ErNotFound
+
+		default:
+			return internal.ErServiceUnavailable
+		}
+	}
+
+	if policy.Strategy == "" {
+		policy.Strategy = model.StrategyReject
+	}
+
+	var target Dentist
+	switch policy.Strategy {
+	case model.StrategyReject, model.StrategyCascade:
+		break
+
+	case model.StrategyReassign:
+		target, err = s.repository.GetByLicense(strings.ToLower(strings.TrimSpace(policy.License)))
 		if err != nil {
 			switch {
 			case errors.Is(err, internal.ErNotFound):
-				break
+				return internal.ErReassignTarget
+
+			default:
+				return internal.ErServiceUnavailable
 			}
 		}
-
-		if dentistExist.License != "" {
-			return Dentist{}, internal.ErLicenseAlreadyExists
+		if target.ID == id {
+			return internal.ErReassignTarget
 		}
+
+	default:
+		return internal.ErInvalidStrategy
 	}
 
-	dentistUpdated, err := s.repository.Update(dentist)
+	var freed []model.Appointment
+	err = s.repository.Transaction(func(repository Repository) error {
+		locked := []uint{id}
+		if target.ID != 0 {
+			locked = append(locked, target.ID)
+		}
+
+		// Bookings lock the dentist too, so no appointment can be added until the deletion ends
+		err := repository.Lock(locked...)
+		if err != nil {
+			return err
+		}
+
+		upcoming, err := repository.GetUpcoming(id, time.Now())
+		if err != nil {
+			return err
+		}
+
+		freed = nil
+		if len(upcoming) > 0 {
+			switch policy.Strategy {
+			case model.StrategyReject:
+				return &internal.DependentAppointmentsError{Count: len(upcoming)}
+
+			case model.StrategyCascade:
+				err = cascade(repository, principal, upcoming)
+
+			case model.StrategyReassign:
+				err = reassign(repository, principal, upcoming, target.ID, s.location)
+			}
+			if err != nil {
+				return err
+			}
+			freed = upcoming
+		}
+
+		err = repository.Delete(id)
+		if err != nil {
+			return err
+		}
+
+		return record(repository, principal, audit.ActionDelete, id, dentistSearched, nil)
+	})
 	if err != nil {
 		switch {
+		case errors.Is(err, internal.ErHasAppointments),
+			errors.Is(err, internal.ErAppointmentConflict):
+			return err
+
+		case errors.Is(err, internal.ErOutsideWorkingHours):
+			return internal.ErOutsideWorkingHours
+
 		case errors.Is(err, internal.ErNotFound):
-			return Dentist{}, internal.ErNotFound
+			return internal.ErNotFound
 
 		default:
-			return Dentist{}, internal.ErServiceUnavailable
+			return internal.ErServiceUnavailable
 		}
 	}
 
-	return dentistUpdated, nil
+	for _, listener := range s.slotFreed {
+		for _, current := range freed {
+			listener(current)
+		}
+	}
+	return nil
 }
 
-func (s *Service) Patch(dentist Dentist) (Dentist, error) {
+// Restore undoes the deletion of a dentist along with its working hours, dentists that aren't deleted can't be restored
+func (s *Service) Restore(principal auth.Principal, id uint) (Dentist, error) {
+	if !principal.Is(auth.RoleAdmin) {
+		return Dentist{}, internal.ErForbidden
+	}
 
-	dentistSearched, err := s.repository.GetByID(dentist.ID)
+	dentistSearched, err := s.repository.Unscoped().GetByID(id)
+	if err != nil {
+		return Dentist{}, err
+	}
+
+	if !dentistSearched.DeletedAt.Valid {
+		return Dentist{}, internal.ErNotDeleted
+	}
+
+	var dentistRestored Dentist
+	err = s.repository.Transaction(func(repository Repository) error {
+		dentistRestored, err = repository.Restore(id)
+		if err != nil {
+			return err
+		}
+
+		return record(repository, principal, audit.ActionRestore, id, dentistSearched, dentistRestored)
+	})
 	if err != nil {
 		switch {
 		case errors.Is(err, internal.ErNotFound):
@@ -133,23 +421,40 @@ func (s *Service) Patch(dentist Dentist) (Dentist, error) {
 		}
 	}
 
-	CompareTo(&dentist, dentistSearched)
+	return dentistRestored, nil
+}
 
-	if dentistSearched.License != dentist.License {
-		dentistExist, err := s.repository.GetByLicense(dentist.License)
-		if err != nil {
-			switch {
-			case errors.Is(err, internal.ErNotFound):
-				break
-			}
-		}
+// checkLicense fails when another dentist has the license, deleted dentists keep their license so they can be restored
+func checkLicense(repository Repository, license string) error {
+	dentistExist, err := repository.Unscoped().GetByLicense(license)
+	if err != nil {
+		switch {
+		case errors.Is(err, internal.ErNotFound):
+			return nil
 
-		if dentistExist.License != "" {
-			return Dentist{}, internal.ErLicenseAlreadyExists
+		default:
+			return internal.ErServiceUnavailable
 		}
 	}
 
-	dentistUpdated, err := s.repository.Update(dentist)
+	if dentistExist.DeletedAt.Valid {
+		return internal.ErLicenseBelongsToDeleted
+	}
+	return internal.ErLicenseAlreadyExists
+}
+
+// save stores the changes made to a dentist together with their audit entry
+func (s *Service) save(principal auth.Principal, before Dentist, dentist Dentist) (Dentist, error) {
+	var dentistUpdated Dentist
+	err := s.repository.Transaction(func(repository Repository) error {
+		var err error
+		dentistUpdated, err = repository.Update(dentist)
+		if err != nil {
+			return err
+		}
+
+		return record(repository, principal, audit.ActionUpdate, dentist.ID, before, dentistUpdated)
+	})
 	if err != nil {
 		switch {
 		case errors.Is(err, internal.ErNotFound):
@@ -163,31 +468,216 @@ func (s *Service) Patch(dentist Dentist) (Dentist, error) {
 	return dentistUpdated, nil
 }
 
-func (s *Service) Delete(id uint) error {
+func (s *Service) GetSchedule(id uint) ([]schedule.WorkingHours, error) {
 	_, err := s.repository.GetByID(id)
 	if err != nil {
 		switch {
 		case errors.Is(err, internal.ErNotFound):
-			return internal.ErNotFound
+			return nil, internal.ErNotFound
 
 		default:
-			return internal.ErServiceUnavailable
+			return nil, internal.ErServiceUnavailable
 		}
 	}
 
-	err = s.repository.Delete(id)
+	data, err := s.repository.GetSchedule(id)
 	if err != nil {
-		switch {
-		case errors.Is(err, internal.ErNotFound):
-			return internal.ErNotFound
+		return nil, internal.ErServiceUnavailable
+	}
 
-		default:
-			return internal.ErServiceUnavailable
+	return data, nil
+}
+
+// UpdateSchedule replaces the weekly working hours of a dentist
+func (s *Service) UpdateSchedule(principal auth.Principal, id uint, hours []schedule.WorkingHours) ([]schedule.WorkingHours, error) {
+	before, err := s.GetSchedule(id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = schedule.Validate(hours)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range hours {
+		hours[i].ID = 0
+		hours[i].DentistID = id
+	}
+
+	var data []schedule.WorkingHours
+	err = s.repository.Transaction(func(repository Repository) error {
+		data, err = repository.UpdateSchedule(id, hours)
+		if err != nil {
+			return err
+		}
+
+		return record(repository, principal, audit.ActionUpdate, id, snapshot(before), snapshot(data))
+	})
+	if err != nil {
+		return nil, internal.ErServiceUnavailable
+	}
+
+	return data, nil
+}
+
+// GetAvailability returns the free slots of a dentist between from and to,
+// computed from the working hours minus the booked appointments
+func (s *Service) GetAvailability(id uint, from time.Time, to time.Time, slot time.Duration) ([]schedule.Slot, error) {
+	hours, err := s.GetSchedule(id)
+	if err != nil {
+		return nil, err
+	}
+
+	appointments, err := s.repository.GetAppointments(id, from, to)
+	if err != nil {
+		return nil, internal.ErServiceUnavailable
+	}
+
+	busy := make([]schedule.Slot, 0, len(appointments))
+	for _, current := range appointments {
+		busy = append(busy, schedule.Slot{Start: current.Date, End: current.EndDate})
+	}
+
+	return schedule.FreeSlots(hours, busy, from, to, slot, s.location), nil
+}
+
+// record adds the audit entry and the outbox event of a change to the transaction of the repository
+func record(repository Repository, principal auth.Principal, action audit.Action, id uint, before interface{}, after interface{}) error {
+	entry, err := audit.NewEntry(principal, audit.EntityDentist, id, action, before, after)
+	if err != nil {
+		return err
+	}
+
+	err = repository.Record(entry)
+	if err != nil {
+		return err
+	}
+
+	change, err := event.New(principal, audit.EntityDentist, id, action, before, after)
+	if err != nil {
+		return err
+	}
+
+	return repository.Publish(change)
+}
+
+// cascade cancels the upcoming appointments of a dentist being deleted
+func cascade(repository Repository, principal auth.Principal, upcoming []model.Appointment) error {
+	now := time.Now()
+	ids := make([]uint, 0, len(upcoming))
+	for _, current := range upcoming {
+		ids = append(ids, current.ID)
+	}
+
+	err := repository.CancelAppointments(ids, CancelReason, now)
+	if err != nil {
+		return err
+	}
+
+	for _, current := range upcoming {
+		cancelled := current
+		cancelled.Move(model.StatusCancelled, CancelReason, now)
+		err = recordAppointment(repository, principal, audit.ActionUpdate, current.ID, current, cancelled)
+		if err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
+// reassign moves the upcoming appointments of a dentist being deleted to another dentist, they must fit
+// in its working hours in the location and not overlap with its appointments, the patients are the same so
+// they can't overlap
+func reassign(repository Repository, principal auth.Principal, upcoming []model.Appointment, dentistID uint, location *time.Location) error {
+	hours, err := repository.GetSchedule(dentistID)
+	if err != nil {
+		return err
+	}
+
+	from, to := upcoming[0].Date, upcoming[0].EndDate
+	for _, current := range upcoming {
+		if !schedule.Contains(hours, current.Date, current.EndDate, location) {
+			return internal.ErOutsideWorkingHours
+		}
+		if current.EndDate.After(to) {
+			to = current.EndDate
+		}
+	}
+
+	busy, err := repository.GetAppointments(dentistID, from, to)
+	if err != nil {
+		return err
+	}
+
+	var conflicts []uint
+	for _, current := range busy {
+		for _, moved := range upcoming {
+			if current.Date.Before(moved.EndDate) && current.EndDate.After(moved.Date) {
+				conflicts = append(conflicts, current.ID)
+				break
+			}
+		}
+	}
+	if len(conflicts) > 0 {
+		return &internal.AppointmentConflictError{IDs: conflicts}
+	}
+
+	ids := make([]uint, 0, len(upcoming))
+	for _, current := range upcoming {
+		ids = append(ids, current.ID)
+	}
+
+	err = repository.ReassignAppointments(ids, dentistID)
+	if err != nil {
+		return err
+	}
+
+	for _, current := range upcoming {
+		moved := current
+		moved.DentistID = dentistID
+		err = recordAppointment(repository, principal, audit.ActionUpdate, current.ID, current, moved)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordAppointment adds the audit entry and the outbox event of a change made to an appointment by a dentist
+// deletion
+func recordAppointment(repository Repository, principal auth.Principal, action audit.Action, id uint, before interface{}, after interface{}) error {
+	entry, err := audit.NewEntry(principal, audit.EntityAppointment, id, action, before, after)
+	if err != nil {
+		return err
+	}
+
+	err = repository.Record(entry)
+	if err != nil {
+		return err
+	}
+
+	change, err := event.New(principal, audit.EntityAppointment, id, action, before, after)
+	if err != nil {
+		return err
+	}
+
+	return repository.Publish(change)
+}
+
+// workingHours is how a schedule is recorded in the audit log, one "monday 09:00-13:00" item per range
+type workingHours struct {
+	WorkingHours []string
+}
+
+func snapshot(hours []schedule.WorkingHours) workingHours {
+	ranges := make([]string, 0, len(hours))
+	for _, current := range hours {
+		ranges = append(ranges, fmt.Sprintf("%s %s-%s", strings.ToLower(current.Weekday.String()), current.Start, current.End))
+	}
+	return workingHours{WorkingHours: ranges}
+}
+
 // Custom functions for the service
 // Normalize and CompareTo are used to avoid empty fields in the database
 
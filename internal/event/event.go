@@ -0,0 +1,66 @@
+package event
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/audit"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/auth"
+)
+
+// canceller is implemented by the records that can be cancelled, updates that cancel them are published as
+// cancellations instead of updates
+type canceller interface {
+	IsCancelled() bool
+}
+
+// New builds the event of a change made by the principal, before is nil for creations and after for deletions,
+// the arguments are the ones of audit.NewEntry so both are recorded together
+func New(principal auth.Principal, entity string, entityID uint, action audit.Action, before interface{}, after interface{}) (Event, error) {
+	payload := Payload{
+		ActorID: principal.UserID,
+		Actor:   principal.Username,
+		Data:    audit.Snapshot(after),
+	}
+	if action == audit.ActionDelete {
+		payload.Data = audit.Snapshot(before)
+	}
+	if action == audit.ActionUpdate {
+		payload.Changes = audit.Diff(before, after)
+	}
+
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return Event{}, err
+	}
+
+	return Event{
+		Type:      typeOf(entity, action, before, after),
+		Entity:    entity,
+		EntityID:  entityID,
+		Payload:   string(encoded),
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// typeOf names the event of an action, like appointment.created
+func typeOf(entity string, action audit.Action, before interface{}, after interface{}) string {
+	if action == audit.ActionUpdate {
+		previous, wasCanceller := before.(canceller)
+		next, isCanceller := after.(canceller)
+		if wasCanceller && isCanceller && !previous.IsCancelled() && next.IsCancelled() {
+			return entity + ".cancelled"
+		}
+	}
+
+	switch action {
+	case audit.ActionCreate:
+		return entity + ".created"
+	case audit.ActionDelete:
+		return entity + ".deleted"
+	case audit.ActionRestore:
+		return entity + ".restored"
+	default:
+		return entity + ".updated"
+	}
+}
@@ -0,0 +1,66 @@
+package event
+
+import (
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/audit"
+)
+
+// Types of the events published by the services
+const (
+	PatientCreated       = "patient.created"
+	PatientUpdated       = "patient.updated"
+	PatientDeleted       = "patient.deleted"
+	PatientRestored      = "patient.restored"
+	DentistCreated       = "dentist.created"
+	DentistUpdated       = "dentist.updated"
+	DentistDeleted       = "dentist.deleted"
+	DentistRestored      = "dentist.restored"
+	AppointmentCreated   = "appointment.created"
+	AppointmentUpdated   = "appointment.updated"
+	AppointmentCancelled = "appointment.cancelled"
+	AppointmentDeleted   = "appointment.deleted"
+)
+
+// Types lists every type of event
+var Types = []string{
+	PatientCreated, PatientUpdated, PatientDeleted, PatientRestored,
+	DentistCreated, DentistUpdated, DentistDeleted, DentistRestored,
+	AppointmentCreated, AppointmentUpdated, AppointmentCancelled, AppointmentDeleted,
+}
+
+// Event is a change of a patient, dentist or appointment stored in the outbox in the same transaction as the
+// change, so it is published if and only if the change is committed, DispatchedAt is set once it was handed to
+// the webhooks. Payload holds the Payload type as JSON
+type Event struct {
+	ID           uint   `gorm:"primaryKey"`
+	Type         string `gorm:"not null;type:varchar(40);index"`
+	Entity       string `gorm:"not null;type:varchar(40)"`
+	EntityID     uint   `gorm:"not null"`
+	Payload      string
+	CreatedAt    time.Time  `gorm:"not null;precision:3"`
+	DispatchedAt *time.Time `gorm:"precision:3;index"`
+}
+
+func (Event) TableName() string {
+	return "outbox_events"
+}
+
+// Payload is what happened, Data is the record after the change, or before it for deletions, and Changes
+// the fields that changed like in the audit log
+type Payload struct {
+	ActorID uint                    `json:"actor_id"`
+	Actor   string                  `json:"actor"`
+	Data    map[string]interface{}  `json:"data"`
+	Changes map[string]audit.Change `json:"changes,omitempty"`
+}
+
+// ValidType reports whether the type is one of Types
+func ValidType(eventType string) bool {
+	for _, current := range Types {
+		if current == eventType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type csvWriter struct {
+	csv    *csv.Writer
+	record []string
+}
+
+func newCSVWriter(w io.Writer, columns []string) (*csvWriter, error) {
+	writer := &csvWriter{csv: csv.NewWriter(w), record: make([]string, len(columns))}
+	err := writer.csv.Write(columns)
+	if err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (c *csvWriter) Write(values []interface{}) error {
+	for i, raw := range values {
+		switch typed := value(raw).(type) {
+		case nil:
+			c.record[i] = ""
+		case time.Time:
+			c.record[i] = typed.Format(time.RFC3339)
+		case string:
+			c.record[i] = escapeFormula(typed)
+		default:
+			c.record[i] = fmt.Sprint(typed)
+		}
+	}
+	return c.csv.Write(c.record)
+}
+
+func (c *csvWriter) Close() error {
+	c.csv.Flush()
+	return c.csv.Error()
+}
+
+// escapeFormula quotes the text spreadsheets would run as a formula, like a name starting with '=', so opening
+// an export can't run what a user typed in a form
+func escapeFormula(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
@@ -0,0 +1,45 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+)
+
+// jsonlWriter writes an object per row, the header isn't a line of the file but the keys of the objects, which
+// keep the order of the columns
+type jsonlWriter struct {
+	w    *bufio.Writer
+	keys [][]byte
+}
+
+func newJSONLWriter(w io.Writer, columns []string) *jsonlWriter {
+	writer := &jsonlWriter{w: bufio.NewWriter(w)}
+	for _, column := range columns {
+		key, _ := json.Marshal(column)
+		writer.keys = append(writer.keys, key)
+	}
+	return writer
+}
+
+func (j *jsonlWriter) Write(values []interface{}) error {
+	j.w.WriteByte('{')
+	for i, raw := range values {
+		if i > 0 {
+			j.w.WriteByte(',')
+		}
+		encoded, err := json.Marshal(value(raw))
+		if err != nil {
+			return err
+		}
+		j.w.Write(j.keys[i])
+		j.w.WriteByte(':')
+		j.w.Write(encoded)
+	}
+	_, err := j.w.WriteString("}\n")
+	return err
+}
+
+func (j *jsonlWriter) Close() error {
+	return j.w.Flush()
+}
@@ -0,0 +1,66 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Formats of the export files
+const (
+	CSV   = "csv"
+	JSONL = "jsonl"
+	XLSX  = "xlsx"
+)
+
+// Formats lists every format of an export, the first one is the default
+var Formats = []string{CSV, JSONL, XLSX}
+
+// ContentTypes maps the formats to the media types of their files
+var ContentTypes = map[string]string{
+	CSV:   "text/csv; charset=utf-8",
+	JSONL: "application/x-ndjson",
+	XLSX:  "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// Writer writes the rows of an export to a file as they come, so exports of any size are streamed. The values of
+// a row are in the order of the columns, they can be strings, numbers, booleans, dates or nil for empty cells
+type Writer interface {
+	Write(values []interface{}) error
+	// Close ends the file, an export that isn't closed is left incomplete
+	Close() error
+}
+
+// NewWriter starts a file of the format with a header naming the columns, name titles the sheet of XLSX files
+func NewWriter(format string, w io.Writer, name string, columns []string) (Writer, error) {
+	switch format {
+	case CSV:
+		return newCSVWriter(w, columns)
+	case JSONL:
+		return newJSONLWriter(w, columns), nil
+	case XLSX:
+		return newXLSXWriter(w, name, columns)
+	default:
+		return nil, fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+// value dereferences optional values, so a nil date is an empty cell
+func value(raw interface{}) interface{} {
+	switch typed := raw.(type) {
+	case *time.Time:
+		if typed == nil {
+			return nil
+		}
+		return *typed
+
+	case *string:
+		if typed == nil {
+			return nil
+		}
+		return *typed
+
+	default:
+		return raw
+	}
+}
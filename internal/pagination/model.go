@@ -0,0 +1,31 @@
+package pagination
+
+const (
+	DefaultSize = 20
+	MaxSize     = 100
+)
+
+// Sort orders a list by a column, in descending order when Desc is true
+type Sort struct {
+	Column string
+	Desc   bool
+}
+
+// Request asks for a page of a list, pages start at 1. With After, the last item of the previous page, the page
+// holds the items sorted after it instead of the ones at Offset, so rows added or removed meanwhile don't shift
+// the pages, and the total isn't counted
+type Request struct {
+	Page  int
+	Size  int
+	Sort  []Sort
+	After interface{}
+}
+
+// Page is a slice of a list along with the total count of items matching the filters, 0 for the pages requested
+// with After
+type Page[T any] struct {
+	Items []T
+	Total int64
+	Page  int
+	Size  int
+}
@@ -0,0 +1,93 @@
+package pagination
+
+import (
+	"strings"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+)
+
+// NewRequest builds a Request with the defaults applied to empty or out of range values
+func NewRequest(page int, size int, sort []Sort) Request {
+	if page < 1 {
+		page = 1
+	}
+
+	if size < 1 {
+		size = DefaultSize
+	}
+
+	if size > MaxSize {
+		size = MaxSize
+	}
+
+	return Request{Page: page, Size: size, Sort: sort}
+}
+
+// Offset is the number of items before the requested page
+func (r Request) Offset() int {
+	return (r.Page - 1) * r.Size
+}
+
+// ParseSort reads a list like "last_name,-id" where a leading dash means descending order,
+// fields are translated to columns with the allowed map
+func ParseSort(raw string, allowed map[string]string) ([]Sort, error) {
+	var sort []Sort
+	if strings.TrimSpace(raw) == "" {
+		return sort, nil
+	}
+
+	for _, field := range strings.Split(raw, ",") {
+		field = strings.TrimSpace(field)
+
+		desc := strings.HasPrefix(field, "-")
+		field = strings.TrimPrefix(field, "-")
+
+		column, ok := allowed[field]
+		if ok == false {
+			return nil, internal.ErInvalidSort
+		}
+
+		sort = append(sort, Sort{Column: column, Desc: desc})
+	}
+
+	return sort, nil
+}
+
+// NewPage wraps the items found for a Request
+func NewPage[T any](items []T, total int64, request Request) Page[T] {
+	if items == nil {
+		items = []T{}
+	}
+
+	return Page[T]{Items: items, Total: total, Page: request.Page, Size: request.Size}
+}
+
+// HasNext reports whether there are items after this page
+func (p Page[T]) HasNext() bool {
+	return int64(p.Page*p.Size) < p.Total
+}
+
+// Each calls fn with every item of a list in order, fetching it a page of MaxSize at a time so the list is never
+// held in memory at once. Each page is the one after the last item of the previous one, so the pages cost the
+// same however far in the list they are. It stops at the first error of fetch or fn
+func Each[T any](sort []Sort, fetch func(page Request) (Page[T], error), fn func(item T) error) error {
+	page := NewRequest(1, MaxSize, sort)
+	for {
+		current, err := fetch(page)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range current.Items {
+			err = fn(item)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(current.Items) < page.Size {
+			return nil
+		}
+		page.After = current.Items[len(current.Items)-1]
+	}
+}
@@ -2,6 +2,7 @@ package patient
 
 import (
 	model "github.com/10Daniel10/web-server-go-ExamenFinal/internal/appointment"
+	"gorm.io/gorm"
 	"time"
 )
 
@@ -12,6 +13,31 @@ type Patient struct {
 	Address       string              `gorm:"not null;type:varchar(120)"`
 	DNI           string              `gorm:"not null;unique;type:varchar(20)"`
 	Email         string              `gorm:"not null;type:varchar(80)"`
-	AdmissionDate time.Time           `gorm:"not null;type:datetime(3)"`
+	AdmissionDate time.Time           `gorm:"not null;precision:3"`
 	Appointments  []model.Appointment `gorm:"foreignKey:PatientID"`
+	DeletedAt     gorm.DeletedAt      `gorm:"index"`
+}
+
+// Filter narrows a list of patients, empty fields match every patient
+type Filter struct {
+	Name           string
+	Lastname       string
+	DNI            string
+	Email          string
+	AdmittedAfter  time.Time
+	AdmittedBefore time.Time
+	// DentistID keeps only the patients with appointments with that dentist
+	DentistID uint
+	// IncludeDeleted also lists the deleted patients
+	IncludeDeleted bool
+}
+
+// SortColumns maps the fields a list of patients can be sorted by to their columns
+var SortColumns = map[string]string{
+	"id":             "id",
+	"name":           "name",
+	"last_name":      "lastname",
+	"dni":            "dni",
+	"email":          "email",
+	"admission_date": "admission_date",
 }
@@ -2,71 +2,142 @@ package patient
 
 import (
 	"errors"
+	"fmt"
 	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	model "github.com/10Daniel10/web-server-go-ExamenFinal/internal/appointment"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/audit"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/auth"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/bulk"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/event"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/pagination"
+	"io"
 	"strings"
+	"time"
 )
 
 type Repository interface {
 	Create(patient Patient) (Patient, error)
-	GetAll() ([]Patient, error)
+	GetAll(filter Filter, page pagination.Request) (pagination.Page[Patient], error)
 	GetByID(id uint) (Patient, error)
 	GetByDNI(dni string) (Patient, error)
+	// HasDentist reports whether the patient has any appointment with the dentist
+	HasDentist(patientID uint, dentistID uint) (bool, error)
 	Update(patient Patient) (Patient, error)
 	Delete(id uint) error
+	// Lock blocks concurrent bookings for the patient until the transaction ends
+	Lock(id uint) error
+	// GetUpcoming returns the appointments of a patient starting from the given time
+	GetUpcoming(patientID uint, from time.Time) ([]model.Appointment, error)
+	CancelAppointments(ids []uint, reason string, at time.Time) error
+	// Restore undoes the deletion of a patient
+	Restore(id uint) (Patient, error)
+	// Unscoped returns a repository that also finds deleted patients
+	Unscoped() Repository
+	// Record stores an audit entry, inside Transaction it is committed along with the change
+	Record(entry audit.Entry) error
+	// Publish stores an event in the outbox, inside Transaction it is committed along with the change
+	Publish(event event.Event) error
+	// Transaction runs fn with a repository bound to a single transaction
+	Transaction(fn func(repository Repository) error) error
 }
 
+// CancelReason is the reason given to the appointments cancelled by the deletion of their patient
+const CancelReason = "patient deleted"
+
 type Service struct {
 	repository Repository
+	// slotFreed are called with the appointments cancelled by the deletion of their patient
+	slotFreed []func(appointment model.Appointment)
 }
 
 func NewService(repository Repository) *Service {
 	return &Service{repository: repository}
 }
 
-func (s *Service) GetAll() ([]Patient, error) {
-	data, err := s.repository.GetAll()
+// OnSlotFreed adds a listener called with each appointment cancelled by a deletion, once it is committed
+func (s *Service) OnSlotFreed(listener func(appointment model.Appointment)) {
+	s.slotFreed = append(s.slotFreed, listener)
+}
+
+// GetAll returns a page of patients, dentists only get the patients they have appointments with
+// and only admins can list deleted patients
+func (s *Service) GetAll(principal auth.Principal, filter Filter, page pagination.Request) (pagination.Page[Patient], error) {
+	if !principal.Role.Valid() || (filter.IncludeDeleted && !principal.Is(auth.RoleAdmin)) {
+		return pagination.Page[Patient]{}, internal.ErForbidden
+	}
+
+	if dentistID, ok := principal.Dentist(); ok {
+		filter.DentistID = dentistID
+	}
+
+	data, err := s.repository.GetAll(filter, page)
 	if err != nil {
-		return nil, err
+		return pagination.Page[Patient]{}, err
 	}
 
 	return data, nil
 }
 
-func (s *Service) GetByID(id uint) (Patient, error) {
+// GetByID returns a patient, patients without appointments with a dentist are not found for that dentist
+func (s *Service) GetByID(principal auth.Principal, id uint) (Patient, error) {
 	data, err := s.repository.GetByID(id)
 	if err != nil {
 		return Patient{}, err
 	}
 
-	return data, nil
+	return s.scope(principal, data)
 }
 
-func (s *Service) GetByDNI(dni string) (Patient, error) {
+// GetByDNI returns a patient, patients without appointments with a dentist are not found for that dentist
+func (s *Service) GetByDNI(principal auth.Principal, dni string) (Patient, error) {
 	data, err := s.repository.GetByDNI(dni)
 	if err != nil {
 		return Patient{}, err
 	}
 
-	return data, nil
+	return s.scope(principal, data)
 }
 
-func (s *Service) Create(patient Patient) (Patient, error) {
+// scope hides the patient from principals that can't see it
+func (s *Service) scope(principal auth.Principal, patient Patient) (Patient, error) {
+	if !principal.Role.Valid() {
+		return Patient{}, internal.ErForbidden
+	}
 
-	patientExist, err := s.repository.GetByDNI(patient.DNI)
-	if err != nil {
-		switch {
-		case errors.Is(err, internal.ErNotFound):
-			break
-		}
+	dentistID, ok := principal.Dentist()
+	if !ok {
+		return patient, nil
 	}
 
-	if patientExist.DNI != "" {
-		return Patient{}, internal.ErDniAlreadyExists
+	found, err := s.repository.HasDentist(patient.ID, dentistID)
+	if err != nil {
+		return Patient{}, internal.ErServiceUnavailable
+	}
+	if !found {
+		return Patient{}, internal.ErNotFound
 	}
 
+	return patient, nil
+}
+
+func (s *Service) Create(principal auth.Principal, patient Patient) (Patient, error) {
+
 	Normalize(&patient)
 
-	patientCreated, err := s.repository.Create(patient)
+	err := checkDNI(s.repository, patient.DNI)
+	if err != nil {
+		return Patient{}, err
+	}
+
+	var patientCreated Patient
+	err = s.repository.Transaction(func(repository Repository) error {
+		patientCreated, err = repository.Create(patient)
+		if err != nil {
+			return err
+		}
+
+		return record(repository, principal, audit.ActionCreate, patientCreated.ID, nil, patientCreated)
+	})
 	if err != nil {
 		return Patient{}, err
 	}
@@ -74,7 +145,109 @@ func (s *Service) Create(patient Patient) (Patient, error) {
 	return patientCreated, nil
 }
 
-func (s *Service) Update(patient Patient) (Patient, error) {
+// Import creates the patients read from next until it returns io.EOF, next returns them one at a time with their
+// line in the file, or a *bulk.RowError for the rows it can't read. The whole file is checked before the patients are
+// created in a single transaction, so nothing is created when a row is invalid, its dni is repeated or already
+// exists, or on dry runs
+func (s *Service) Import(principal auth.Principal, next func() (Patient, int, error), dryRun bool) (bulk.Report, error) {
+	report := bulk.Report{DryRun: dryRun}
+	seen := bulk.Duplicates{}
+
+	spool, err := bulk.NewSpool[Patient]()
+	if err != nil {
+		return bulk.Report{}, internal.ErServiceUnavailable
+	}
+	defer spool.Close()
+
+	// The whole file is read and checked first, so the transaction only lasts the writes and not the upload
+	for {
+		patient, line, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		report.Rows++
+		var rowErr *bulk.RowError
+		if errors.As(err, &rowErr) {
+			report.Reject(rowErr.Line, rowErr.Errors...)
+			continue
+		}
+		if err != nil {
+			return bulk.Report{}, internal.ErServiceUnavailable
+		}
+
+		Normalize(&patient)
+		first, repeated := seen.Seen(patient.DNI, line)
+		if repeated {
+			report.Reject(line, fmt.Sprintf("dni %s is repeated from line %d", patient.DNI, first))
+			continue
+		}
+
+		err = checkDNI(s.repository, patient.DNI)
+		if errors.Is(err, internal.ErDniAlreadyExists) || errors.Is(err, internal.ErDniBelongsToDeleted) {
+			report.Reject(line, fmt.Sprintf("dni %s: %s", patient.DNI, err.Error()))
+			continue
+		}
+		if err != nil {
+			return bulk.Report{}, err
+		}
+
+		// The rest of the file is still checked, but nothing else is kept once a row is invalid
+		if dryRun || report.Invalid > 0 {
+			continue
+		}
+
+		err = spool.Add(line, patient)
+		if err != nil {
+			return bulk.Report{}, internal.ErServiceUnavailable
+		}
+	}
+	if dryRun || report.Invalid > 0 {
+		return report, nil
+	}
+
+	err = s.repository.Transaction(func(repository Repository) error {
+		err := spool.Each(func(line int, patient Patient) error {
+			// The dni may have been taken while the file was read
+			err := checkDNI(repository, patient.DNI)
+			if errors.Is(err, internal.ErDniAlreadyExists) || errors.Is(err, internal.ErDniBelongsToDeleted) {
+				report.Reject(line, fmt.Sprintf("dni %s: %s", patient.DNI, err.Error()))
+				return nil
+			}
+			if err != nil || report.Invalid > 0 {
+				return err
+			}
+
+			patientCreated, err := repository.Create(patient)
+			if err != nil {
+				return internal.ErServiceUnavailable
+			}
+
+			err = record(repository, principal, audit.ActionCreate, patientCreated.ID, nil, patientCreated)
+			if err != nil {
+				return err
+			}
+			report.Created++
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if report.Invalid > 0 {
+			report.Created = 0
+			return bulk.ErrRollback
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, bulk.ErrRollback) {
+		return bulk.Report{}, internal.ErServiceUnavailable
+	}
+
+	return report, nil
+}
+
+func (s *Service) Update(principal auth.Principal, patient Patient) (Patient, error) {
 
 	patientSearched, err := s.repository.GetByID(patient.ID)
 	if err != nil {
@@ -90,20 +263,18 @@ func (s *Service) Update(patient Patient) (Patient, error) {
 	Normalize(&patient)
 
 	if patient.DNI != patientSearched.DNI {
-		patientExist, err := s.repository.GetByDNI(patient.DNI)
+		err = checkDNI(s.repository, patient.DNI)
 		if err != nil {
-			switch {
-			case errors.Is(err, internal.ErNotFound):
-				break
-			}
-		}
-
-		if patientExist.DNI != "" {
-			return Patient{}, internal.ErDniAlreadyExists
+			return Patient{}, err
 		}
 	}
 
-	patientUpdated, err := s.repository.Update(patient)
+	return s.save(principal, patientSearched, patient)
+}
+
+func (s *Service) Patch(principal auth.Principal, patient Patient) (Patient, error) {
+
+	patientSearched, err := s.repository.GetByID(patient.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, internal.ErNotFound):
@@ -114,25 +285,118 @@ func (s *Service) Update(patient Patient) (Patient, error) {
 		}
 	}
 
-	return patientUpdated, nil
+	CompareTo(&patient, patientSearched)
+	Normalize(&patient)
+
+	if patient.DNI != patientSearched.DNI {
+		err = checkDNI(s.repository, patient.DNI)
+		if err != nil {
+			return Patient{}, err
+		}
+	}
+
+	return s.save(principal, patientSearched, patient)
 }
 
-func (s *Service) Patch(patient Patient) (Patient, error) {
+// Delete deletes a patient, the policy says what happens to its upcoming appointments: they block
+// the deletion or are cancelled in the same transaction, they can't be reassigned to another patient
+func (s *Service) Delete(principal auth.Principal, id uint, policy model.DeletePolicy) error {
+	patientSearched, err := s.repository.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, internal.ErNotFound):
+			return internal.ErNotFound
 
-	patientSearched, err := s.repository.GetByID(patient.ID)
+		default:
+			return internal.ErServiceUnavailable
+		}
+	}
+
+	if policy.Strategy == "" {
+		policy.Strategy = model.StrategyReject
+	}
+	if policy.Strategy != model.StrategyReject && policy.Strategy != model.StrategyCascade {
+		return internal.ErInvalidStrategy
+	}
+
+	var cancelled []model.Appointment
+	err = s.repository.Transaction(func(repository Repository) error {
+		// Bookings lock the patient too, so no appointment can be added until the deletion ends
+		err := repository.Lock(id)
+		if err != nil {
+			return err
+		}
+
+		upcoming, err := repository.GetUpcoming(id, time.Now())
+		if err != nil {
+			return err
+		}
+
+		cancelled = nil
+		if len(upcoming) > 0 {
+			if policy.Strategy == model.StrategyReject {
+				return &internal.DependentAppointmentsError{Count: len(upcoming)}
+			}
+
+			err = cascade(repository, principal, upcoming)
+			if err != nil {
+				return err
+			}
+			cancelled = upcoming
+		}
+
+		err = repository.Delete(id)
+		if err != nil {
+			return err
+		}
+
+		return record(repository, principal, audit.ActionDelete, id, patientSearched, nil)
+	})
 	if err != nil {
 		switch {
+		case errors.Is(err, internal.ErHasAppointments):
+			return err
+
 		case errors.Is(err, internal.ErNotFound):
-			return Patient{}, internal.ErNotFound
+			return internal.ErNotFound
 
 		default:
-			return Patient{}, internal.ErServiceUnavailable
+			return internal.ErServiceUnavailable
 		}
 	}
 
-	CompareTo(&patient, patientSearched)
+	for _, listener := range s.slotFreed {
+		for _, current := range cancelled {
+			listener(current)
+		}
+	}
+	return nil
+}
+
+// Restore undoes the deletion of a patient, patients that aren't deleted can't be restored
+func (s *Service) Restore(principal auth.Principal, id uint) (Patient, error) {
+	if !principal.Is(auth.RoleAdmin) {
+		return Patient{}, internal.ErForbidden
+	}
+
+	patientSearched, err := s.repository.Unscoped().GetByID(id)
+	if err != nil {
+		return Patient{}, err
+	}
 
-	patientUpdated, err := s.repository.Update(patient)
+	if !patientSearched.DeletedAt.Valid {
+		return Patient{}, internal.ErNotDeleted
+	}
+
+	var patientRestored Patient
+	err = s.repository.Transaction(func(repository Repository) error {
+		patientRestored, err = repository.Restore(id)
+		if err != nil {
+			return err
+		}
+
+		return record(repository, principal, audit.ActionRestore, id, patientSearched, patientRestored)
+	})
 	if err != nil {
 		switch {
 		case errors.Is(err, internal.ErNotFound):
@@ -143,35 +407,119 @@ func (s *Service) Patch(patient Patient) (Patient, error) {
 		}
 	}
 
-	return patientUpdated, nil
+	return patientRestored, nil
 }
 
-func (s *Service) Delete(id uint) error {
-	_, err := s.repository.GetByID(id)
+// checkDNI fails when another patient has the dni, deleted patients keep their dni so they can be restored
+func checkDNI(repository Repository, dni string) error {
+	patientExist, err := repository.Unscoped().GetByDNI(dni)
 	if err != nil {
 		switch {
 		case errors.Is(err, internal.ErNotFound):
-			return internal.ErNotFound
+			return nil
 
 		default:
 			return internal.ErServiceUnavailable
 		}
 	}
 
-	err = s.repository.Delete(id)
+	if patientExist.DeletedAt.Valid {
+		return internal.ErDniBelongsToDeleted
+	}
+	return internal.ErDniAlreadyExists
+}
+
+// save stores the changes made to a patient together with their audit entry
+func (s *Service) save(principal auth.Principal, before Patient, patient Patient) (Patient, error) {
+	var patientUpdated Patient
+	err := s.repository.Transaction(func(repository Repository) error {
+		var err error
+		patientUpdated, err = repository.Update(patient)
+		if err != nil {
+			return err
+		}
+
+		return record(repository, principal, audit.ActionUpdate, patient.ID, before, patientUpdated)
+	})
 	if err != nil {
 		switch {
 		case errors.Is(err, internal.ErNotFound):
-			return internal.ErNotFound
+			return Patient{}, internal.ErNotFound
 
 		default:
-			return internal.ErServiceUnavailable
+			return Patient{}, internal.ErServiceUnavailable
 		}
 	}
 
+	return patientUpdated, nil
+}
+
+// cascade cancels the upcoming appointments of a patient being deleted
+func cascade(repository Repository, principal auth.Principal, upcoming []model.Appointment) error {
+	now := time.Now()
+	ids := make([]uint, 0, len(upcoming))
+	for _, current := range upcoming {
+		ids = append(ids, current.ID)
+	}
+
+	err := repository.CancelAppointments(ids, CancelReason, now)
+	if err != nil {
+		return err
+	}
+
+	for _, current := range upcoming {
+		cancelled := current
+		cancelled.Move(model.StatusCancelled, CancelReason, now)
+
+		err = recordAppointment(repository, principal, audit.ActionUpdate, current.ID, current, cancelled)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// record adds the audit entry and the outbox event of a change to the transaction of the repository
+func record(repository Repository, principal auth.Principal, action audit.Action, id uint, before interface{}, after interface{}) error {
+	entry, err := audit.NewEntry(principal, audit.EntityPatient, id, action, before, after)
+	if err != nil {
+		return err
+	}
+
+	err = repository.Record(entry)
+	if err != nil {
+		return err
+	}
+
+	change, err := event.New(principal, audit.EntityPatient, id, action, before, after)
+	if err != nil {
+		return err
+	}
+
+	return repository.Publish(change)
+}
+
+// recordAppointment adds the audit entry and the outbox event of a change made to an appointment by a patient
+// deletion
+func recordAppointment(repository Repository, principal auth.Principal, action audit.Action, id uint, before interface{}, after interface{}) error {
+	entry, err := audit.NewEntry(principal, audit.EntityAppointment, id, action, before, after)
+	if err != nil {
+		return err
+	}
+
+	err = repository.Record(entry)
+	if err != nil {
+		return err
+	}
+
+	change, err := event.New(principal, audit.EntityAppointment, id, action, before, after)
+	if err != nil {
+		return err
+	}
+
+	return repository.Publish(change)
+}
+
 // Custom functions for the service
 // Normalize and CompareTo are used to avoid empty fields in the database
 
@@ -0,0 +1,77 @@
+package reminder
+
+import (
+	"context"
+	"time"
+)
+
+// MaxAttempts is how many times a reminder is sent before giving up on it
+const MaxAttempts uint = 3
+
+// SendingLease is how long a reminder stays claimed, the ones still sending after it are taken as failed
+const SendingLease = 10 * time.Minute
+
+// Status is the outcome of a reminder
+type Status string
+
+const (
+	// StatusSending reminders were claimed and are being delivered, a crash or a failed update while sending
+	// leaves them in this status until SendingLease passes, then they are sent again like failed ones
+	StatusSending Status = "sending"
+	// StatusSent reminders were delivered
+	StatusSent Status = "sent"
+	// StatusFailed reminders are sent again until they reach MaxAttempts
+	StatusFailed Status = "failed"
+	// StatusSkipped reminders couldn't be sent, e.g. the patient has no email
+	StatusSkipped Status = "skipped"
+)
+
+// Reminder records a notification of an appointment, Date is the date of the appointment it was sent for so
+// moving the appointment schedules its reminders again, and the unique index sends every offset once per date
+type Reminder struct {
+	ID            uint       `gorm:"primaryKey"`
+	AppointmentID uint       `gorm:"not null;uniqueIndex:idx_reminders_appointment_date_offset,priority:1"`
+	Date          time.Time  `gorm:"not null;precision:3;uniqueIndex:idx_reminders_appointment_date_offset,priority:2"`
+	OffsetMinutes uint       `gorm:"not null;uniqueIndex:idx_reminders_appointment_date_offset,priority:3"`
+	Channel       string     `gorm:"not null;type:varchar(20)"`
+	Recipient     string     `gorm:"not null;type:varchar(80)"`
+	Status        Status     `gorm:"not null;type:varchar(20);index"`
+	Attempts      uint       `gorm:"not null;default:0"`
+	Error         string     `gorm:"type:varchar(255)"`
+	CreatedAt     time.Time  `gorm:"precision:3"`
+	ClaimedAt     *time.Time `gorm:"precision:3"`
+	SentAt        *time.Time `gorm:"precision:3"`
+}
+
+// Stale reports whether the reminder is still sending after SendingLease, the instance that claimed it crashed
+// or couldn't record the outcome
+func (r Reminder) Stale(now time.Time) bool {
+	return r.Status == StatusSending && (r.ClaimedAt == nil || !r.ClaimedAt.Add(SendingLease).After(now))
+}
+
+// Upcoming is an open appointment with the patient and dentist data its reminders need
+type Upcoming struct {
+	AppointmentID   uint
+	Date            time.Time
+	Duration        uint
+	Description     string
+	PatientName     string
+	PatientLastname string
+	PatientEmail    string
+	DentistName     string
+	DentistLastname string
+}
+
+// Message is a notification ready to be delivered
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Notifier delivers messages through a channel like email
+type Notifier interface {
+	// Channel names the channel, it is recorded with every reminder
+	Channel() string
+	Notify(ctx context.Context, message Message) error
+}
@@ -0,0 +1,227 @@
+package reminder
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+)
+
+type Repository interface {
+	// GetUpcoming returns the scheduled and confirmed appointments starting between from and to
+	GetUpcoming(from time.Time, to time.Time) ([]Upcoming, error)
+	// GetByAppointments returns the reminders of the appointments
+	GetByAppointments(ids []uint) ([]Reminder, error)
+	// Claim stores a reminder about to be sent, it returns false when it is already stored
+	Claim(reminder Reminder) (Reminder, bool, error)
+	// Retry claims again at now a failed reminder, or one still sending since before staleBefore, it returns false
+	// when it isn't either anymore
+	Retry(id uint, staleBefore time.Time, now time.Time) (bool, error)
+	Update(reminder Reminder) (Reminder, error)
+}
+
+// Service sends the reminders of the appointments offsets before they start, location is the time zone of the
+// clinic the dates of the messages are written in
+type Service struct {
+	repository Repository
+	notifier   Notifier
+	location   *time.Location
+	// offsets are sorted from the shortest to the longest
+	offsets []time.Duration
+}
+
+func NewService(repository Repository, notifier Notifier, location *time.Location, offsets []time.Duration) *Service {
+	sorted := append([]time.Duration(nil), offsets...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+	return &Service{repository: repository, notifier: notifier, location: location, offsets: sorted}
+}
+
+// GetByAppointment returns the reminders of an appointment
+func (s *Service) GetByAppointment(id uint) ([]Reminder, error) {
+	return s.repository.GetByAppointments([]uint{id})
+}
+
+// Send delivers the reminders due at now, every appointment gets the reminder of the shortest offset it is
+// already within, so appointments booked late skip the longer offsets instead of getting them all at once
+func (s *Service) Send(ctx context.Context, now time.Time) error {
+	if len(s.offsets) == 0 {
+		return nil
+	}
+
+	upcoming, err := s.repository.GetUpcoming(now, now.Add(s.offsets[len(s.offsets)-1]))
+	if err != nil {
+		return err
+	}
+	if len(upcoming) == 0 {
+		return nil
+	}
+
+	var ids []uint
+	for _, current := range upcoming {
+		ids = append(ids, current.AppointmentID)
+	}
+
+	stored, err := s.repository.GetByAppointments(ids)
+	if err != nil {
+		return err
+	}
+
+	recorded := make(map[key]Reminder)
+	for _, current := range stored {
+		recorded[keyOf(current.AppointmentID, current.Date, current.OffsetMinutes)] = current
+	}
+
+	for _, current := range upcoming {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		offset, ok := s.due(current.Date, now)
+		if !ok {
+			continue
+		}
+
+		reminder := Reminder{
+			AppointmentID: current.AppointmentID,
+			Date:          current.Date,
+			OffsetMinutes: uint(offset / time.Minute),
+			Channel:       s.notifier.Channel(),
+			Recipient:     current.PatientEmail,
+			Status:        StatusSending,
+			Attempts:      1,
+			ClaimedAt:     &now,
+		}
+
+		existing, found := recorded[keyOf(reminder.AppointmentID, reminder.Date, reminder.OffsetMinutes)]
+		switch {
+		case !found:
+			var claimed bool
+			reminder, claimed, err = s.repository.Claim(reminder)
+			if err != nil {
+				return err
+			}
+			if !claimed {
+				continue
+			}
+
+		case (existing.Status == StatusFailed || existing.Stale(now)) && existing.Attempts < MaxAttempts:
+			claimed, err := s.repository.Retry(existing.ID, now.Add(-SendingLease), now)
+			if err != nil {
+				return err
+			}
+			if !claimed {
+				continue
+			}
+			reminder = existing
+			reminder.Status = StatusSending
+			reminder.Attempts++
+			reminder.ClaimedAt = &now
+
+		case existing.Stale(now):
+			// Out of attempts, it is recorded as failed instead of being left sending
+			existing.Status = StatusFailed
+			existing.Error = "the delivery wasn't recorded before the lease ended"
+			_, err = s.repository.Update(existing)
+			if err != nil {
+				return err
+			}
+			continue
+
+		default:
+			continue
+		}
+
+		s.deliver(ctx, reminder, current, now)
+	}
+
+	return nil
+}
+
+// Run sends the due reminders every interval until the context is done
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case now := <-ticker.C:
+			err := s.Send(ctx, now)
+			if err != nil {
+				log.Printf("reminder: sending reminders: %v", err)
+			}
+		}
+	}
+}
+
+// due returns the shortest offset whose reminder time has passed at now
+func (s *Service) due(date time.Time, now time.Time) (time.Duration, bool) {
+	for _, offset := range s.offsets {
+		if !date.Add(-offset).After(now) {
+			return offset, true
+		}
+	}
+	return 0, false
+}
+
+// deliver sends a claimed reminder and records the outcome
+func (s *Service) deliver(ctx context.Context, reminder Reminder, upcoming Upcoming, now time.Time) {
+	if upcoming.PatientEmail == "" {
+		reminder.Status = StatusSkipped
+		reminder.Error = "the patient has no email"
+	} else {
+		err := s.notifier.Notify(ctx, s.message(upcoming))
+		if err != nil {
+			reminder.Status = StatusFailed
+			reminder.Error = internal.Truncate(err.Error(), 255)
+		} else {
+			reminder.Status = StatusSent
+			reminder.Error = ""
+			reminder.SentAt = &now
+		}
+	}
+
+	_, err := s.repository.Update(reminder)
+	if err != nil {
+		log.Printf("reminder: recording reminder of appointment %d: %v", reminder.AppointmentID, err)
+	}
+}
+
+// message writes the reminder of an appointment in the clinic time zone
+func (s *Service) message(upcoming Upcoming) Message {
+	date := upcoming.Date.In(s.location)
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "Hello %s %s,\n\n", upcoming.PatientName, upcoming.PatientLastname)
+	fmt.Fprintf(&body, "This is a reminder of your appointment with %s %s on %s at %s (%s), it lasts %d minutes.\n",
+		upcoming.DentistName, upcoming.DentistLastname, date.Format("Monday, January 2, 2006"), date.Format("15:04"),
+		s.location.String(), upcoming.Duration)
+	if upcoming.Description != "" {
+		fmt.Fprintf(&body, "\n%s\n", upcoming.Description)
+	}
+
+	return Message{
+		To:      upcoming.PatientEmail,
+		Subject: fmt.Sprintf("Reminder: appointment on %s", date.Format("Jan 2 at 15:04")),
+		Body:    body.String(),
+	}
+}
+
+// key identifies the reminder of an offset for a date of an appointment
+type key struct {
+	appointmentID uint
+	date          int64
+	offsetMinutes uint
+}
+
+func keyOf(appointmentID uint, date time.Time, offsetMinutes uint) key {
+	return key{appointmentID: appointmentID, date: date.UnixMilli(), offsetMinutes: offsetMinutes}
+}
@@ -0,0 +1,197 @@
+package report
+
+import (
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/appointment"
+)
+
+// MaxRange is the longest range a report covers, so a report by day has a bounded number of buckets
+const MaxRange = 2 * 366 * 24 * time.Hour
+
+// SlotLength is the length of the slots the dates are grouped by in the database. Every time zone is offset from
+// UTC by a multiple of 15 minutes, so each slot falls in a single day of any zone and the slots are grouped in
+// days, weeks or months of the zone of the report afterwards
+const SlotLength = 15 * time.Minute
+
+// Period is the length of the buckets of a report over time
+type Period string
+
+const (
+	PeriodDay   Period = "day"
+	PeriodWeek  Period = "week"
+	PeriodMonth Period = "month"
+)
+
+// Periods lists every period of a report
+var Periods = []Period{PeriodDay, PeriodWeek, PeriodMonth}
+
+func (p Period) Valid() bool {
+	for _, current := range Periods {
+		if current == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Start returns the start of the bucket holding the date in the location of the date, weeks start on Monday
+func (p Period) Start(date time.Time) time.Time {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	switch p {
+	case PeriodWeek:
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case PeriodMonth:
+		return day.AddDate(0, 0, 1-day.Day())
+	default:
+		return day
+	}
+}
+
+// Next returns the start of the bucket after the one starting at start
+func (p Period) Next(start time.Time) time.Time {
+	switch p {
+	case PeriodWeek:
+		return start.AddDate(0, 0, 7)
+	case PeriodMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// Filter selects the range [From, To) of a report, Location is the zone its days, weeks and months are in and
+// DentistID narrows it to a dentist when it isn't 0
+type Filter struct {
+	From      time.Time
+	To        time.Time
+	DentistID uint
+	Location  *time.Location
+}
+
+// Booked is the time a dentist had booked in the range of a report, the appointments are cut at its limits
+type Booked struct {
+	DentistID uint
+	Name      string
+	Lastname  string
+	Minutes   int64
+}
+
+// Utilization compares the time a dentist had booked with the time of its working hours
+type Utilization struct {
+	DentistID uint
+	Name      string
+	Lastname  string
+	Booked    time.Duration
+	Available time.Duration
+}
+
+// Rate is the share of the available time that was booked, it can go over 1 with appointments outside the
+// working hours
+func (u Utilization) Rate() float64 {
+	if u.Available <= 0 {
+		return 0
+	}
+	return float64(u.Booked) / float64(u.Available)
+}
+
+// Counts are the appointments in each status
+type Counts struct {
+	Total     int64
+	Scheduled int64
+	Confirmed int64
+	CheckedIn int64
+	Completed int64
+	Cancelled int64
+	NoShow    int64
+}
+
+// Add counts appointments of a status
+func (c *Counts) Add(status appointment.Status, count int64) {
+	c.Total += count
+	switch status {
+	case appointment.StatusScheduled:
+		c.Scheduled += count
+	case appointment.StatusConfirmed:
+		c.Confirmed += count
+	case appointment.StatusCheckedIn:
+		c.CheckedIn += count
+	case appointment.StatusCompleted:
+		c.Completed += count
+	case appointment.StatusCancelled:
+		c.Cancelled += count
+	case appointment.StatusNoShow:
+		c.NoShow += count
+	}
+}
+
+// Merge adds the appointments of other counts
+func (c *Counts) Merge(other Counts) {
+	c.Total += other.Total
+	c.Scheduled += other.Scheduled
+	c.Confirmed += other.Confirmed
+	c.CheckedIn += other.CheckedIn
+	c.Completed += other.Completed
+	c.Cancelled += other.Cancelled
+	c.NoShow += other.NoShow
+}
+
+// CancellationRate is the share of the appointments that were cancelled
+func (c Counts) CancellationRate() float64 {
+	if c.Total == 0 {
+		return 0
+	}
+	return float64(c.Cancelled) / float64(c.Total)
+}
+
+// NoShowRate is the share of the appointments that were due that the patient missed, cancelled and upcoming
+// appointments aren't due
+func (c Counts) NoShowRate() float64 {
+	due := c.CheckedIn + c.Completed + c.NoShow
+	if due == 0 {
+		return 0
+	}
+	return float64(c.NoShow) / float64(due)
+}
+
+// SlotCount is the number of appointments of a status, or of patients, starting in a slot. Slot is the number of
+// SlotLength since the Unix epoch
+type SlotCount struct {
+	Slot   int64
+	Status appointment.Status
+	Count  int64
+}
+
+// Time returns the start of the slot
+func (s SlotCount) Time() time.Time {
+	return time.Unix(s.Slot*int64(SlotLength/time.Second), 0).UTC()
+}
+
+// DentistCount is the number of appointments of a status of a dentist
+type DentistCount struct {
+	DentistID uint
+	Name      string
+	Lastname  string
+	Status    appointment.Status
+	Count     int64
+}
+
+// Bucket is the appointments starting in a period
+type Bucket struct {
+	Start time.Time
+	Counts
+}
+
+// DentistCounts is the appointments of a dentist in the range of a report
+type DentistCounts struct {
+	DentistID uint
+	Name      string
+	Lastname  string
+	Counts
+}
+
+// Admissions is the number of patients admitted in a period
+type Admissions struct {
+	Start    time.Time
+	Patients int64
+}
@@ -0,0 +1,193 @@
+package report
+
+import (
+	"sort"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/auth"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/schedule"
+)
+
+// Repository aggregates the appointments and patients in the database, only the totals are loaded
+type Repository interface {
+	// GetBooked returns the booked time of the dentists that aren't deleted or had appointments in the range,
+	// cancelled appointments aren't booked time
+	GetBooked(filter Filter) ([]Booked, error)
+	GetSchedules(dentistIDs []uint) ([]schedule.WorkingHours, error)
+	// GetAppointmentSlots counts the appointments starting in the range by slot and status
+	GetAppointmentSlots(filter Filter) ([]SlotCount, error)
+	// GetDentistCounts counts the appointments starting in the range by dentist and status
+	GetDentistCounts(filter Filter) ([]DentistCount, error)
+	// GetAdmissionSlots counts the patients admitted in the range by slot, deleted patients included
+	GetAdmissionSlots(filter Filter) ([]SlotCount, error)
+}
+
+type Service struct {
+	repository Repository
+}
+
+func NewService(repository Repository) *Service {
+	return &Service{repository: repository}
+}
+
+// Utilization returns the booked and available time of each dentist in the range, available time comes from
+// the current working hours of the dentists. Dentists only get their own
+func (s *Service) Utilization(principal auth.Principal, filter Filter) ([]Utilization, error) {
+	filter, err := s.scope(principal, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	booked, err := s.repository.GetBooked(filter)
+	if err != nil {
+		return nil, internal.ErServiceUnavailable
+	}
+
+	var ids []uint
+	for _, current := range booked {
+		ids = append(ids, current.DentistID)
+	}
+
+	hours := map[uint][]schedule.WorkingHours{}
+	if len(ids) > 0 {
+		schedules, err := s.repository.GetSchedules(ids)
+		if err != nil {
+			return nil, internal.ErServiceUnavailable
+		}
+		for _, current := range schedules {
+			hours[current.DentistID] = append(hours[current.DentistID], current)
+		}
+	}
+
+	data := make([]Utilization, 0, len(booked))
+	for _, current := range booked {
+		data = append(data, Utilization{
+			DentistID: current.DentistID,
+			Name:      current.Name,
+			Lastname:  current.Lastname,
+			Booked:    time.Duration(current.Minutes) * time.Minute,
+			Available: schedule.Available(hours[current.DentistID], filter.From, filter.To, filter.Location),
+		})
+	}
+	return data, nil
+}
+
+// Appointments counts the appointments by the period they start in, with a bucket for every period of the range
+// even when it is empty. Dentists only get their own
+func (s *Service) Appointments(principal auth.Principal, filter Filter, period Period) ([]Bucket, error) {
+	filter, err := s.scope(principal, filter)
+	if err != nil {
+		return nil, err
+	}
+	if !period.Valid() {
+		return nil, internal.ErInvalidReport
+	}
+
+	slots, err := s.repository.GetAppointmentSlots(filter)
+	if err != nil {
+		return nil, internal.ErServiceUnavailable
+	}
+
+	var data []Bucket
+	index := map[int64]int{}
+	for start := period.Start(filter.From.In(filter.Location)); start.Before(filter.To); start = period.Next(start) {
+		index[start.Unix()] = len(data)
+		data = append(data, Bucket{Start: start})
+	}
+
+	for _, current := range slots {
+		i, ok := index[period.Start(current.Time().In(filter.Location)).Unix()]
+		if ok {
+			data[i].Add(current.Status, current.Count)
+		}
+	}
+	return data, nil
+}
+
+// Attendance counts the appointments starting in the range by dentist, for their cancellation and no-show rates.
+// Dentists only get their own
+func (s *Service) Attendance(principal auth.Principal, filter Filter) ([]DentistCounts, error) {
+	filter, err := s.scope(principal, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := s.repository.GetDentistCounts(filter)
+	if err != nil {
+		return nil, internal.ErServiceUnavailable
+	}
+
+	var data []DentistCounts
+	index := map[uint]int{}
+	for _, current := range counts {
+		i, ok := index[current.DentistID]
+		if !ok {
+			i = len(data)
+			index[current.DentistID] = i
+			data = append(data, DentistCounts{DentistID: current.DentistID, Name: current.Name, Lastname: current.Lastname})
+		}
+		data[i].Add(current.Status, current.Count)
+	}
+
+	sort.Slice(data, func(i, j int) bool {
+		return data[i].DentistID < data[j].DentistID
+	})
+	return data, nil
+}
+
+// Admissions counts the patients by the period of their admission date, with a bucket for every period of the
+// range even when it is empty. Only admins and receptionists can see it
+func (s *Service) Admissions(principal auth.Principal, filter Filter, period Period) ([]Admissions, error) {
+	if !principal.Is(auth.RoleAdmin, auth.RoleReceptionist) {
+		return nil, internal.ErForbidden
+	}
+
+	filter, err := s.scope(principal, filter)
+	if err != nil {
+		return nil, err
+	}
+	if !period.Valid() {
+		return nil, internal.ErInvalidReport
+	}
+
+	slots, err := s.repository.GetAdmissionSlots(filter)
+	if err != nil {
+		return nil, internal.ErServiceUnavailable
+	}
+
+	var data []Admissions
+	index := map[int64]int{}
+	for start := period.Start(filter.From.In(filter.Location)); start.Before(filter.To); start = period.Next(start) {
+		index[start.Unix()] = len(data)
+		data = append(data, Admissions{Start: start})
+	}
+
+	for _, current := range slots {
+		i, ok := index[period.Start(current.Time().In(filter.Location)).Unix()]
+		if ok {
+			data[i].Patients += current.Count
+		}
+	}
+	return data, nil
+}
+
+// scope checks the range of a report and narrows it to the dentist of the principal
+func (s *Service) scope(principal auth.Principal, filter Filter) (Filter, error) {
+	if !principal.Role.Valid() {
+		return Filter{}, internal.ErForbidden
+	}
+
+	if filter.From.IsZero() || filter.To.IsZero() || !filter.From.Before(filter.To) || filter.To.Sub(filter.From) > MaxRange {
+		return Filter{}, internal.ErInvalidReport
+	}
+
+	if filter.Location == nil {
+		filter.Location = time.UTC
+	}
+
+	if dentistID, ok := principal.Dentist(); ok {
+		filter.DentistID = dentistID
+	}
+	return filter, nil
+}
@@ -0,0 +1,308 @@
+package repositorytest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/appointment"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/audit"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/dentist"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/event"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/pagination"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/patient"
+)
+
+func testAppointments(t *testing.T, open func(t *testing.T) Repositories) {
+	t.Run("Create and find", func(t *testing.T) {
+		repositories := open(t)
+		ana := createDentist(t, repositories, "ana", "perez", "mp-1")
+		juan := createPatient(t, repositories, "juan", "lopez", "100", base)
+
+		date := time.Date(2040, time.March, 5, 9, 30, 0, 0, time.FixedZone("ART", -3*60*60))
+		created, err := repositories.Appointments.Create(appointment.Appointment{
+			PatientID:   juan.ID,
+			DentistID:   ana.ID,
+			Date:        date,
+			EndDate:     date.Add(30 * time.Minute),
+			Description: "cleaning",
+		})
+		check(t, err)
+		if created.ID == 0 || created.Status != appointment.StatusScheduled || created.Duration != appointment.DefaultDuration {
+			t.Fatalf("got appointment %+v, want an id and the default status and duration", created)
+		}
+
+		found, err := repositories.Appointments.GetByID(created.ID)
+		check(t, err)
+		if found.PatientID != juan.ID || found.DentistID != ana.ID || found.Description != "cleaning" ||
+			found.Status != appointment.StatusScheduled || found.Duration != appointment.DefaultDuration || found.SeriesID != nil {
+			t.Fatalf("got appointment %+v, want %+v", found, created)
+		}
+		checkTime(t, "date", found.Date, date)
+		checkTime(t, "end date", found.EndDate, date.Add(30*time.Minute))
+
+		_, err = repositories.Appointments.GetByID(created.ID + 100)
+		checkIs(t, err, internal.ErNotFound)
+	})
+
+	t.Run("Update and delete", func(t *testing.T) {
+		repositories := open(t)
+		ana := createDentist(t, repositories, "ana", "perez", "mp-1")
+		juan := createPatient(t, repositories, "juan", "lopez", "100", base)
+		created := createAppointment(t, repositories, juan.ID, ana.ID, at(9, 0), 30, appointment.StatusScheduled)
+
+		confirmedAt := at(8, 0)
+		created.Status = appointment.StatusConfirmed
+		created.ConfirmedAt = &confirmedAt
+		updated, err := repositories.Appointments.Update(created)
+		check(t, err)
+		if updated.Sequence != created.Sequence+1 {
+			t.Fatalf("got sequence %d, want %d", updated.Sequence, created.Sequence+1)
+		}
+
+		found, err := repositories.Appointments.GetByID(created.ID)
+		check(t, err)
+		if found.Status != appointment.StatusConfirmed || found.ConfirmedAt == nil || found.Sequence != updated.Sequence {
+			t.Fatalf("got appointment %+v, want %+v", found, updated)
+		}
+		checkTime(t, "confirmed at", *found.ConfirmedAt, confirmedAt)
+
+		check(t, repositories.Appointments.Delete(created.ID))
+		_, err = repositories.Appointments.GetByID(created.ID)
+		checkIs(t, err, internal.ErNotFound)
+	})
+
+	t.Run("GetOverlapping", func(t *testing.T) {
+		repositories := open(t)
+		ana := createDentist(t, repositories, "ana", "perez", "mp-1")
+		eva := createDentist(t, repositories, "eva", "diaz", "mp-2")
+		juan := createPatient(t, repositories, "juan", "lopez", "100", base)
+		julia := createPatient(t, repositories, "julia", "diaz", "200", base)
+
+		sameDentist := createAppointment(t, repositories, julia.ID, ana.ID, at(9, 45), 30, appointment.StatusScheduled)
+		samePatient := createAppointment(t, repositories, juan.ID, eva.ID, at(9, 0), 60, appointment.StatusConfirmed)
+		createAppointment(t, repositories, julia.ID, ana.ID, at(9, 0), 30, appointment.StatusCancelled)
+		createAppointment(t, repositories, julia.ID, ana.ID, at(8, 30), 30, appointment.StatusScheduled)
+		createAppointment(t, repositories, julia.ID, eva.ID, at(9, 30), 30, appointment.StatusScheduled)
+		booking := createAppointment(t, repositories, juan.ID, ana.ID, at(9, 15), 45, appointment.StatusScheduled)
+
+		data, err := repositories.Appointments.GetOverlapping(booking)
+		check(t, err)
+		checkIDs(t, data, appointmentID, samePatient.ID, sameDentist.ID)
+	})
+
+	t.Run("GetAll filters, sorts and pages", func(t *testing.T) {
+		repositories := open(t)
+		ana := createDentist(t, repositories, "ana", "perez", "mp-1")
+		eva := createDentist(t, repositories, "eva", "diaz", "mp-2")
+		juan := createPatient(t, repositories, "juan", "lopez", "100", base)
+		julia := createPatient(t, repositories, "julia", "diaz", "200", base)
+
+		first := createAppointment(t, repositories, juan.ID, ana.ID, at(11, 0), 30, appointment.StatusScheduled)
+		second := createAppointment(t, repositories, julia.ID, ana.ID, at(9, 0), 60, appointment.StatusCancelled)
+		third := createAppointment(t, repositories, juan.ID, eva.ID, at(10, 0), 30, appointment.StatusCompleted)
+
+		tests := []struct {
+			name   string
+			filter appointment.Filter
+			want   []uint
+		}{
+			{"patient", appointment.Filter{PatientID: juan.ID}, []uint{first.ID, third.ID}},
+			{"dentist", appointment.Filter{DentistID: ana.ID}, []uint{first.ID, second.ID}},
+			{"dni", appointment.Filter{PatientDNI: "200"}, []uint{second.ID}},
+			{"license", appointment.Filter{DentistLicense: "mp-2"}, []uint{third.ID}},
+			{"unknown license", appointment.Filter{DentistLicense: "mp-3"}, nil},
+			{"overlapping range", appointment.Filter{From: at(9, 30), To: at(10, 30)}, []uint{second.ID, third.ID}},
+			{"touching range", appointment.Filter{From: at(10, 30), To: at(11, 0)}, nil},
+			{"statuses", appointment.Filter{Statuses: []appointment.Status{appointment.StatusScheduled, appointment.StatusCompleted}}, []uint{first.ID, third.ID}},
+		}
+		for _, test := range tests {
+			page, err := repositories.Appointments.GetAll(test.filter, pagination.NewRequest(1, 10, nil))
+			check(t, err)
+			if int(page.Total) != len(test.want) {
+				t.Fatalf("%s: got total %d, want %d", test.name, page.Total, len(test.want))
+			}
+			checkIDs(t, page.Items, appointmentID, test.want...)
+		}
+
+		sort := []pagination.Sort{{Column: "date"}}
+		page, err := repositories.Appointments.GetAll(appointment.Filter{}, pagination.NewRequest(1, 2, sort))
+		check(t, err)
+		checkIDs(t, page.Items, appointmentID, second.ID, third.ID)
+
+		sort = []pagination.Sort{{Column: "duration", Desc: true}, {Column: "dentist_id", Desc: true}}
+		page, err = repositories.Appointments.GetAll(appointment.Filter{}, pagination.NewRequest(1, 10, sort))
+		check(t, err)
+		checkIDs(t, page.Items, appointmentID, second.ID, third.ID, first.ID)
+	})
+
+	t.Run("LockSchedule", func(t *testing.T) {
+		repositories := open(t)
+		ana := createDentist(t, repositories, "ana", "perez", "mp-1")
+		eva := createDentist(t, repositories, "eva", "diaz", "mp-2")
+		juan := createPatient(t, repositories, "juan", "lopez", "100", base)
+		julia := createPatient(t, repositories, "julia", "diaz", "200", base)
+		check(t, repositories.Dentists.Delete(eva.ID))
+		check(t, repositories.Patients.Delete(julia.ID))
+
+		lock := func(dentistID uint, patientID uint) error {
+			return repositories.Appointments.Transaction(func(repository appointment.Repository) error {
+				return repository.LockSchedule(dentistID, patientID)
+			})
+		}
+		check(t, lock(ana.ID, juan.ID))
+		checkIs(t, lock(eva.ID, juan.ID), internal.ErNotFound)
+		checkIs(t, lock(ana.ID, julia.ID), internal.ErNotFound)
+		checkIs(t, lock(ana.ID, julia.ID+100), internal.ErNotFound)
+	})
+
+	t.Run("Series", func(t *testing.T) {
+		repositories := open(t)
+		ana := createDentist(t, repositories, "ana", "perez", "mp-1")
+		juan := createPatient(t, repositories, "juan", "lopez", "100", base)
+
+		until := at(24*14, 0)
+		series, err := repositories.Appointments.CreateSeries(appointment.Series{
+			PatientID: juan.ID,
+			DentistID: ana.ID,
+			Start:     at(9, 0),
+			Duration:  30,
+			Frequency: appointment.FrequencyWeekly,
+			Interval:  1,
+			Until:     &until,
+		})
+		check(t, err)
+		if series.ID == 0 {
+			t.Fatal("created series has no id")
+		}
+
+		var ids []uint
+		for _, day := range []int{14, 0, 7} {
+			date := at(24*day+9, 0)
+			created, err := repositories.Appointments.Create(appointment.Appointment{
+				PatientID: juan.ID,
+				DentistID: ana.ID,
+				Date:      date,
+				Duration:  30,
+				EndDate:   date.Add(30 * time.Minute),
+				SeriesID:  &series.ID,
+			})
+			check(t, err)
+			ids = append(ids, created.ID)
+		}
+		createAppointment(t, repositories, juan.ID, ana.ID, at(12, 0), 30, appointment.StatusScheduled)
+
+		found, err := repositories.Appointments.GetSeries(series.ID)
+		check(t, err)
+		if found.Frequency != appointment.FrequencyWeekly || found.Interval != 1 || found.Until == nil {
+			t.Fatalf("got series %+v, want %+v", found, series)
+		}
+		checkTime(t, "start", found.Start, series.Start)
+		checkTime(t, "until", *found.Until, until)
+		checkIDs(t, found.Appointments, appointmentID, ids[1], ids[2], ids[0])
+
+		data, err := repositories.Appointments.GetSeriesAppointments(series.ID, at(24*7+9, 0))
+		check(t, err)
+		checkIDs(t, data, appointmentID, ids[2], ids[0])
+
+		data, err = repositories.Appointments.GetSeriesAppointments(series.ID, time.Time{})
+		check(t, err)
+		checkIDs(t, data, appointmentID, ids[1], ids[2], ids[0])
+
+		_, err = repositories.Appointments.GetSeries(series.ID + 100)
+		checkIs(t, err, internal.ErNotFound)
+	})
+}
+
+func testTransactions(t *testing.T, open func(t *testing.T) Repositories) {
+	failed := errors.New("failed")
+
+	t.Run("Commit", func(t *testing.T) {
+		repositories := open(t)
+
+		var created dentist.Dentist
+		err := repositories.Dentists.Transaction(func(repository dentist.Repository) error {
+			var err error
+			created, err = repository.Create(dentist.Dentist{Name: "ana", Lastname: "perez", License: "mp-1"})
+			if err != nil {
+				return err
+			}
+
+			// Changes are seen inside the transaction before it commits
+			_, err = repository.GetByLicense("mp-1")
+			if err != nil {
+				return err
+			}
+
+			err = repository.Record(audit.Entry{Entity: audit.EntityDentist, EntityID: created.ID, Action: audit.ActionCreate, Actor: "admin"})
+			if err != nil {
+				return err
+			}
+			return repository.Publish(event.Event{Type: event.DentistCreated, Entity: audit.EntityDentist, EntityID: created.ID})
+		})
+		check(t, err)
+
+		_, err = repositories.Dentists.GetByID(created.ID)
+		check(t, err)
+	})
+
+	t.Run("Rollback", func(t *testing.T) {
+		repositories := open(t)
+		ana := createDentist(t, repositories, "ana", "perez", "mp-1")
+		juan := createPatient(t, repositories, "juan", "lopez", "100", base)
+		booked := createAppointment(t, repositories, juan.ID, ana.ID, at(9, 0), 30, appointment.StatusScheduled)
+
+		err := repositories.Dentists.Transaction(func(repository dentist.Repository) error {
+			_, err := repository.Create(dentist.Dentist{Name: "eva", Lastname: "diaz", License: "mp-2"})
+			if err != nil {
+				return err
+			}
+			err = repository.Delete(ana.ID)
+			if err != nil {
+				return err
+			}
+			err = repository.CancelAppointments([]uint{booked.ID}, "rolled back", at(8, 0))
+			if err != nil {
+				return err
+			}
+			return failed
+		})
+		checkIs(t, err, failed)
+
+		_, err = repositories.Dentists.GetByLicense("mp-2")
+		checkIs(t, err, internal.ErNotFound)
+		_, err = repositories.Dentists.GetByID(ana.ID)
+		check(t, err)
+
+		found, err := repositories.Appointments.GetByID(booked.ID)
+		check(t, err)
+		if found.Status != appointment.StatusScheduled {
+			t.Fatalf("got status %s after the rollback, want %s", found.Status, appointment.StatusScheduled)
+		}
+
+		err = repositories.Patients.Transaction(func(repository patient.Repository) error {
+			_, err := repository.Create(patient.Patient{Name: "julia", Lastname: "diaz", DNI: "200", AdmissionDate: base})
+			if err != nil {
+				return err
+			}
+			return failed
+		})
+		checkIs(t, err, failed)
+
+		_, err = repositories.Patients.GetByDNI("200")
+		checkIs(t, err, internal.ErNotFound)
+
+		err = repositories.Appointments.Transaction(func(repository appointment.Repository) error {
+			err := repository.Delete(booked.ID)
+			if err != nil {
+				return err
+			}
+			return failed
+		})
+		checkIs(t, err, failed)
+
+		_, err = repositories.Appointments.GetByID(booked.ID)
+		check(t, err)
+	})
+}
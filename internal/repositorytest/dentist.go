@@ -0,0 +1,264 @@
+package repositorytest
+
+import (
+	"testing"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/appointment"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/dentist"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/pagination"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/schedule"
+)
+
+func testDentists(t *testing.T, open func(t *testing.T) Repositories) {
+	t.Run("Create and find", func(t *testing.T) {
+		repositories := open(t)
+		created := createDentist(t, repositories, "ana", "perez", "mp-1")
+
+		found, err := repositories.Dentists.GetByID(created.ID)
+		check(t, err)
+		if found.Name != "ana" || found.Lastname != "perez" || found.License != "mp-1" {
+			t.Fatalf("got dentist %+v, want %+v", found, created)
+		}
+
+		found, err = repositories.Dentists.GetByLicense("mp-1")
+		check(t, err)
+		if found.ID != created.ID {
+			t.Fatalf("got dentist %d by license, want %d", found.ID, created.ID)
+		}
+
+		_, err = repositories.Dentists.GetByID(created.ID + 100)
+		checkIs(t, err, internal.ErNotFound)
+		_, err = repositories.Dentists.GetByLicense("mp-2")
+		checkIs(t, err, internal.ErNotFound)
+	})
+
+	t.Run("License is unique", func(t *testing.T) {
+		repositories := open(t)
+		createDentist(t, repositories, "ana", "perez", "mp-1")
+
+		_, err := repositories.Dentists.Create(dentist.Dentist{Name: "eva", Lastname: "diaz", License: "mp-1"})
+		if err == nil {
+			t.Fatal("created a dentist with a repeated license")
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		repositories := open(t)
+		created := createDentist(t, repositories, "ana", "perez", "mp-1")
+
+		created.Lastname = "gomez"
+		_, err := repositories.Dentists.Update(created)
+		check(t, err)
+
+		found, err := repositories.Dentists.GetByID(created.ID)
+		check(t, err)
+		if found.Lastname != "gomez" {
+			t.Fatalf("got lastname %q, want gomez", found.Lastname)
+		}
+	})
+
+	t.Run("GetAll filters, sorts and pages", func(t *testing.T) {
+		repositories := open(t)
+		ana := createDentist(t, repositories, "ana", "perez", "mp-1")
+		andres := createDentist(t, repositories, "andres", "diaz", "mp-2")
+		eva := createDentist(t, repositories, "eva", "perez", "mp-3")
+
+		page, err := repositories.Dentists.GetAll(dentist.Filter{Name: "AN"}, pagination.NewRequest(1, 10, nil))
+		check(t, err)
+		checkIDs(t, page.Items, dentistID, ana.ID, andres.ID)
+
+		page, err = repositories.Dentists.GetAll(dentist.Filter{Lastname: "per"}, pagination.NewRequest(1, 10, nil))
+		check(t, err)
+		checkIDs(t, page.Items, dentistID, ana.ID, eva.ID)
+
+		page, err = repositories.Dentists.GetAll(dentist.Filter{License: "mp-2"}, pagination.NewRequest(1, 10, nil))
+		check(t, err)
+		checkIDs(t, page.Items, dentistID, andres.ID)
+
+		sort := []pagination.Sort{{Column: "lastname", Desc: true}}
+		page, err = repositories.Dentists.GetAll(dentist.Filter{}, pagination.NewRequest(1, 2, sort))
+		check(t, err)
+		checkIDs(t, page.Items, dentistID, ana.ID, eva.ID)
+		if page.Total != 3 || page.Page != 1 || page.Size != 2 {
+			t.Fatalf("got page %d of size %d with total %d, want page 1 of size 2 with total 3", page.Page, page.Size, page.Total)
+		}
+
+		page, err = repositories.Dentists.GetAll(dentist.Filter{}, pagination.NewRequest(2, 2, sort))
+		check(t, err)
+		checkIDs(t, page.Items, dentistID, andres.ID)
+
+		page, err = repositories.Dentists.GetAll(dentist.Filter{}, pagination.NewRequest(3, 2, sort))
+		check(t, err)
+		checkIDs(t, page.Items, dentistID)
+		if page.Items == nil || page.Total != 3 {
+			t.Fatalf("got items %v with total %d past the last page, want no items with total 3", page.Items, page.Total)
+		}
+	})
+
+	t.Run("Delete and restore", func(t *testing.T) {
+		repositories := open(t)
+		ana := createDentist(t, repositories, "ana", "perez", "mp-1")
+		eva := createDentist(t, repositories, "eva", "diaz", "mp-2")
+
+		check(t, repositories.Dentists.Delete(ana.ID))
+
+		_, err := repositories.Dentists.GetByID(ana.ID)
+		checkIs(t, err, internal.ErNotFound)
+		_, err = repositories.Dentists.GetByLicense("mp-1")
+		checkIs(t, err, internal.ErNotFound)
+
+		found, err := repositories.Dentists.Unscoped().GetByLicense("mp-1")
+		check(t, err)
+		if found.ID != ana.ID || !found.DeletedAt.Valid {
+			t.Fatalf("got dentist %d deleted %v, want %d deleted", found.ID, found.DeletedAt.Valid, ana.ID)
+		}
+
+		page, err := repositories.Dentists.GetAll(dentist.Filter{}, pagination.NewRequest(1, 10, nil))
+		check(t, err)
+		checkIDs(t, page.Items, dentistID, eva.ID)
+
+		page, err = repositories.Dentists.GetAll(dentist.Filter{IncludeDeleted: true}, pagination.NewRequest(1, 10, nil))
+		check(t, err)
+		checkIDs(t, page.Items, dentistID, ana.ID, eva.ID)
+
+		restored, err := repositories.Dentists.Restore(ana.ID)
+		check(t, err)
+		if restored.ID != ana.ID || restored.DeletedAt.Valid {
+			t.Fatalf("got dentist %d deleted %v, want %d restored", restored.ID, restored.DeletedAt.Valid, ana.ID)
+		}
+
+		_, err = repositories.Dentists.GetByID(ana.ID)
+		check(t, err)
+		_, err = repositories.Dentists.Restore(eva.ID + 100)
+		checkIs(t, err, internal.ErNotFound)
+	})
+
+	t.Run("Schedule", func(t *testing.T) {
+		repositories := open(t)
+		ana := createDentist(t, repositories, "ana", "perez", "mp-1")
+		eva := createDentist(t, repositories, "eva", "diaz", "mp-2")
+
+		hours, err := repositories.Dentists.GetSchedule(ana.ID)
+		check(t, err)
+		if hours == nil || len(hours) != 0 {
+			t.Fatalf("got working hours %v, want none", hours)
+		}
+
+		_, err = repositories.Dentists.UpdateSchedule(eva.ID, []schedule.WorkingHours{
+			{DentistID: eva.ID, Weekday: 1, Start: "09:00", End: "12:00"},
+		})
+		check(t, err)
+
+		hours, err = repositories.Dentists.UpdateSchedule(ana.ID, []schedule.WorkingHours{
+			{DentistID: ana.ID, Weekday: 2, Start: "14:00", End: "18:00"},
+			{DentistID: ana.ID, Weekday: 1, Start: "14:00", End: "18:00"},
+			{DentistID: ana.ID, Weekday: 1, Start: "08:00", End: "12:00"},
+		})
+		check(t, err)
+		checkSchedule(t, hours, "1 08:00", "1 14:00", "2 14:00")
+
+		hours, err = repositories.Dentists.UpdateSchedule(ana.ID, []schedule.WorkingHours{
+			{DentistID: ana.ID, Weekday: 3, Start: "10:00", End: "11:00"},
+		})
+		check(t, err)
+		checkSchedule(t, hours, "3 10:00")
+
+		hours, err = repositories.Appointments.GetSchedule(ana.ID)
+		check(t, err)
+		checkSchedule(t, hours, "3 10:00")
+
+		hours, err = repositories.Dentists.GetSchedule(eva.ID)
+		check(t, err)
+		checkSchedule(t, hours, "1 09:00")
+	})
+
+	t.Run("Lock", func(t *testing.T) {
+		repositories := open(t)
+		ana := createDentist(t, repositories, "ana", "perez", "mp-1")
+		eva := createDentist(t, repositories, "eva", "diaz", "mp-2")
+
+		err := repositories.Dentists.Transaction(func(repository dentist.Repository) error {
+			return repository.Lock(ana.ID, eva.ID)
+		})
+		check(t, err)
+
+		err = repositories.Dentists.Transaction(func(repository dentist.Repository) error {
+			return repository.Lock(ana.ID, eva.ID+100)
+		})
+		checkIs(t, err, internal.ErNotFound)
+	})
+
+	t.Run("Appointments", func(t *testing.T) {
+		repositories := open(t)
+		ana := createDentist(t, repositories, "ana", "perez", "mp-1")
+		eva := createDentist(t, repositories, "eva", "diaz", "mp-2")
+		juan := createPatient(t, repositories, "juan", "lopez", "100", base)
+
+		late := createAppointment(t, repositories, juan.ID, ana.ID, at(11, 0), 30, appointment.StatusConfirmed)
+		early := createAppointment(t, repositories, juan.ID, ana.ID, at(9, 0), 30, appointment.StatusScheduled)
+		cancelled := createAppointment(t, repositories, juan.ID, ana.ID, at(10, 0), 30, appointment.StatusCancelled)
+		completed := createAppointment(t, repositories, juan.ID, ana.ID, at(12, 0), 30, appointment.StatusCompleted)
+		createAppointment(t, repositories, juan.ID, eva.ID, at(13, 0), 30, appointment.StatusScheduled)
+
+		data, err := repositories.Dentists.GetAppointments(ana.ID, at(9, 15), at(12, 0))
+		check(t, err)
+		checkIDs(t, data, appointmentID, early.ID, late.ID)
+
+		data, err = repositories.Dentists.GetUpcoming(ana.ID, at(9, 0))
+		check(t, err)
+		checkIDs(t, data, appointmentID, early.ID, late.ID)
+
+		data, err = repositories.Dentists.GetUpcoming(ana.ID, at(9, 1))
+		check(t, err)
+		checkIDs(t, data, appointmentID, late.ID)
+
+		check(t, repositories.Dentists.ReassignAppointments([]uint{late.ID}, eva.ID))
+		check(t, repositories.Dentists.CancelAppointments([]uint{early.ID}, "dentist on leave", at(8, 0)))
+		check(t, repositories.Dentists.CancelAppointments(nil, "nothing", at(8, 0)))
+
+		reassigned, err := repositories.Appointments.GetByID(late.ID)
+		check(t, err)
+		if reassigned.DentistID != eva.ID || reassigned.Sequence != late.Sequence+1 {
+			t.Fatalf("got dentist %d and sequence %d, want %d and %d", reassigned.DentistID, reassigned.Sequence, eva.ID, late.Sequence+1)
+		}
+
+		cancelledEarly, err := repositories.Appointments.GetByID(early.ID)
+		check(t, err)
+		if cancelledEarly.Status != appointment.StatusCancelled || cancelledEarly.CancelReason != "dentist on leave" ||
+			cancelledEarly.CancelledAt == nil || cancelledEarly.Sequence != early.Sequence+1 {
+			t.Fatalf("got appointment %+v, want it cancelled", cancelledEarly)
+		}
+		checkTime(t, "cancelled at", *cancelledEarly.CancelledAt, at(8, 0))
+
+		data, err = repositories.Dentists.GetUpcoming(ana.ID, at(0, 0))
+		check(t, err)
+		checkIDs(t, data, appointmentID)
+
+		for _, id := range []uint{cancelled.ID, completed.ID} {
+			unchanged, err := repositories.Appointments.GetByID(id)
+			check(t, err)
+			if unchanged.DentistID != ana.ID || unchanged.Sequence != 0 {
+				t.Fatalf("got appointment %+v, want it unchanged", unchanged)
+			}
+		}
+	})
+}
+
+// checkSchedule compares the working hours in order with their weekday and start like "1 08:00"
+func checkSchedule(t *testing.T, hours []schedule.WorkingHours, want ...string) {
+	t.Helper()
+	got := make([]string, 0, len(hours))
+	for _, current := range hours {
+		got = append(got, string(rune('0'+current.Weekday))+" "+current.Start)
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("got working hours %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("got working hours %v, want %v", got, want)
+		}
+	}
+}
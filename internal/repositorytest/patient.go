@@ -0,0 +1,195 @@
+package repositorytest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/appointment"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/pagination"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/patient"
+)
+
+func testPatients(t *testing.T, open func(t *testing.T) Repositories) {
+	t.Run("Create and find", func(t *testing.T) {
+		repositories := open(t)
+		admission := time.Date(2023, time.May, 10, 14, 30, 0, 0, time.FixedZone("ART", -3*60*60))
+		created := createPatient(t, repositories, "juan", "lopez", "100", admission)
+
+		found, err := repositories.Patients.GetByID(created.ID)
+		check(t, err)
+		if found.Name != "juan" || found.Lastname != "lopez" || found.DNI != "100" || found.Email != "100@mail.com" {
+			t.Fatalf("got patient %+v, want %+v", found, created)
+		}
+		checkTime(t, "admission date", found.AdmissionDate, admission)
+
+		found, err = repositories.Patients.GetByDNI("100")
+		check(t, err)
+		if found.ID != created.ID {
+			t.Fatalf("got patient %d by dni, want %d", found.ID, created.ID)
+		}
+
+		_, err = repositories.Patients.GetByID(created.ID + 100)
+		checkIs(t, err, internal.ErNotFound)
+		_, err = repositories.Patients.GetByDNI("200")
+		checkIs(t, err, internal.ErNotFound)
+	})
+
+	t.Run("DNI is unique", func(t *testing.T) {
+		repositories := open(t)
+		createPatient(t, repositories, "juan", "lopez", "100", base)
+
+		_, err := repositories.Patients.Create(patient.Patient{Name: "eva", Lastname: "diaz", DNI: "100", AdmissionDate: base})
+		if err == nil {
+			t.Fatal("created a patient with a repeated dni")
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		repositories := open(t)
+		created := createPatient(t, repositories, "juan", "lopez", "100", base)
+
+		created.Address = "Avenida 456"
+		_, err := repositories.Patients.Update(created)
+		check(t, err)
+
+		found, err := repositories.Patients.GetByID(created.ID)
+		check(t, err)
+		if found.Address != "Avenida 456" {
+			t.Fatalf("got address %q, want Avenida 456", found.Address)
+		}
+	})
+
+	t.Run("GetAll filters, sorts and pages", func(t *testing.T) {
+		repositories := open(t)
+		ana := createDentist(t, repositories, "ana", "perez", "mp-1")
+		juan := createPatient(t, repositories, "juan", "lopez", "100", at(-48, 0))
+		julia := createPatient(t, repositories, "julia", "diaz", "200", at(-24, 0))
+		pedro := createPatient(t, repositories, "pedro", "lopez", "300", at(0, 0))
+		createAppointment(t, repositories, julia.ID, ana.ID, at(9, 0), 30, appointment.StatusCancelled)
+
+		page, err := repositories.Patients.GetAll(patient.Filter{Name: "Ju"}, pagination.NewRequest(1, 10, nil))
+		check(t, err)
+		checkIDs(t, page.Items, patientID, juan.ID, julia.ID)
+
+		page, err = repositories.Patients.GetAll(patient.Filter{Lastname: "LOP"}, pagination.NewRequest(1, 10, nil))
+		check(t, err)
+		checkIDs(t, page.Items, patientID, juan.ID, pedro.ID)
+
+		page, err = repositories.Patients.GetAll(patient.Filter{DNI: "200"}, pagination.NewRequest(1, 10, nil))
+		check(t, err)
+		checkIDs(t, page.Items, patientID, julia.ID)
+
+		page, err = repositories.Patients.GetAll(patient.Filter{Email: "300@mail.com"}, pagination.NewRequest(1, 10, nil))
+		check(t, err)
+		checkIDs(t, page.Items, patientID, pedro.ID)
+
+		filter := patient.Filter{AdmittedAfter: at(-24, 0), AdmittedBefore: at(0, 0)}
+		page, err = repositories.Patients.GetAll(filter, pagination.NewRequest(1, 10, nil))
+		check(t, err)
+		checkIDs(t, page.Items, patientID, julia.ID)
+
+		page, err = repositories.Patients.GetAll(patient.Filter{DentistID: ana.ID}, pagination.NewRequest(1, 10, nil))
+		check(t, err)
+		checkIDs(t, page.Items, patientID, julia.ID)
+
+		sort := []pagination.Sort{{Column: "admission_date", Desc: true}}
+		page, err = repositories.Patients.GetAll(patient.Filter{}, pagination.NewRequest(1, 2, sort))
+		check(t, err)
+		checkIDs(t, page.Items, patientID, pedro.ID, julia.ID)
+		if page.Total != 3 {
+			t.Fatalf("got total %d, want 3", page.Total)
+		}
+
+		sort = []pagination.Sort{{Column: "lastname"}, {Column: "name", Desc: true}}
+		page, err = repositories.Patients.GetAll(patient.Filter{}, pagination.NewRequest(1, 10, sort))
+		check(t, err)
+		checkIDs(t, page.Items, patientID, julia.ID, pedro.ID, juan.ID)
+	})
+
+	t.Run("Delete and restore", func(t *testing.T) {
+		repositories := open(t)
+		juan := createPatient(t, repositories, "juan", "lopez", "100", base)
+		julia := createPatient(t, repositories, "julia", "diaz", "200", base)
+
+		check(t, repositories.Patients.Delete(juan.ID))
+
+		_, err := repositories.Patients.GetByID(juan.ID)
+		checkIs(t, err, internal.ErNotFound)
+		_, err = repositories.Patients.GetByDNI("100")
+		checkIs(t, err, internal.ErNotFound)
+
+		found, err := repositories.Patients.Unscoped().GetByDNI("100")
+		check(t, err)
+		if found.ID != juan.ID || !found.DeletedAt.Valid {
+			t.Fatalf("got patient %d deleted %v, want %d deleted", found.ID, found.DeletedAt.Valid, juan.ID)
+		}
+
+		page, err := repositories.Patients.GetAll(patient.Filter{}, pagination.NewRequest(1, 10, nil))
+		check(t, err)
+		checkIDs(t, page.Items, patientID, julia.ID)
+
+		page, err = repositories.Patients.GetAll(patient.Filter{IncludeDeleted: true}, pagination.NewRequest(1, 10, nil))
+		check(t, err)
+		checkIDs(t, page.Items, patientID, juan.ID, julia.ID)
+
+		restored, err := repositories.Patients.Restore(juan.ID)
+		check(t, err)
+		if restored.ID != juan.ID || restored.DeletedAt.Valid {
+			t.Fatalf("got patient %d deleted %v, want %d restored", restored.ID, restored.DeletedAt.Valid, juan.ID)
+		}
+
+		_, err = repositories.Patients.Restore(julia.ID + 100)
+		checkIs(t, err, internal.ErNotFound)
+	})
+
+	t.Run("Lock", func(t *testing.T) {
+		repositories := open(t)
+		juan := createPatient(t, repositories, "juan", "lopez", "100", base)
+
+		err := repositories.Patients.Transaction(func(repository patient.Repository) error {
+			return repository.Lock(juan.ID)
+		})
+		check(t, err)
+
+		err = repositories.Patients.Transaction(func(repository patient.Repository) error {
+			return repository.Lock(juan.ID + 100)
+		})
+		checkIs(t, err, internal.ErNotFound)
+	})
+
+	t.Run("Appointments", func(t *testing.T) {
+		repositories := open(t)
+		ana := createDentist(t, repositories, "ana", "perez", "mp-1")
+		eva := createDentist(t, repositories, "eva", "diaz", "mp-2")
+		juan := createPatient(t, repositories, "juan", "lopez", "100", base)
+		julia := createPatient(t, repositories, "julia", "diaz", "200", base)
+
+		late := createAppointment(t, repositories, juan.ID, ana.ID, at(11, 0), 30, appointment.StatusScheduled)
+		early := createAppointment(t, repositories, juan.ID, ana.ID, at(9, 0), 30, appointment.StatusConfirmed)
+		createAppointment(t, repositories, juan.ID, ana.ID, at(10, 0), 30, appointment.StatusNoShow)
+		createAppointment(t, repositories, julia.ID, ana.ID, at(12, 0), 30, appointment.StatusScheduled)
+
+		hasDentist, err := repositories.Patients.HasDentist(juan.ID, ana.ID)
+		check(t, err)
+		if !hasDentist {
+			t.Fatal("patient has appointments with the dentist but HasDentist is false")
+		}
+
+		hasDentist, err = repositories.Patients.HasDentist(juan.ID, eva.ID)
+		check(t, err)
+		if hasDentist {
+			t.Fatal("patient has no appointments with the dentist but HasDentist is true")
+		}
+
+		data, err := repositories.Patients.GetUpcoming(juan.ID, at(0, 0))
+		check(t, err)
+		checkIDs(t, data, appointmentID, early.ID, late.ID)
+
+		check(t, repositories.Patients.CancelAppointments([]uint{early.ID, late.ID}, "patient moved", at(8, 0)))
+
+		data, err = repositories.Patients.GetUpcoming(juan.ID, at(0, 0))
+		check(t, err)
+		checkIDs(t, data, appointmentID)
+	})
+}
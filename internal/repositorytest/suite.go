@@ -0,0 +1,137 @@
+// Package repositorytest checks that the storage backends behave like the database the services were written
+// against, every implementation of the dentist, patient and appointment repositories must pass it
+package repositorytest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/appointment"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/dentist"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/patient"
+)
+
+// Repositories are the repositories of a single store, they must see each other's changes
+type Repositories struct {
+	Dentists     dentist.Repository
+	Patients     patient.Repository
+	Appointments appointment.Repository
+}
+
+// Run runs the conformance tests, open returns the repositories of a new empty store and is called once per test
+func Run(t *testing.T, open func(t *testing.T) Repositories) {
+	t.Run("Dentists", func(t *testing.T) {
+		testDentists(t, open)
+	})
+	t.Run("Patients", func(t *testing.T) {
+		testPatients(t, open)
+	})
+	t.Run("Appointments", func(t *testing.T) {
+		testAppointments(t, open)
+	})
+	t.Run("Transactions", func(t *testing.T) {
+		testTransactions(t, open)
+	})
+}
+
+// base is the day the appointments of the tests are on, far enough in the future to be upcoming
+var base = time.Date(2040, time.March, 5, 0, 0, 0, 0, time.UTC)
+
+func at(hour int, minute int) time.Time {
+	return base.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+}
+
+func check(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func checkIs(t *testing.T, err error, target error) {
+	t.Helper()
+	if !errors.Is(err, target) {
+		t.Fatalf("got error %v, want %v", err, target)
+	}
+}
+
+func checkIDs[T any](t *testing.T, items []T, id func(item T) uint, want ...uint) {
+	t.Helper()
+	got := make([]uint, 0, len(items))
+	for _, item := range items {
+		got = append(got, id(item))
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("got ids %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("got ids %v, want %v", got, want)
+		}
+	}
+}
+
+func checkTime(t *testing.T, name string, got time.Time, want time.Time) {
+	t.Helper()
+	if !got.Equal(want) {
+		t.Fatalf("got %s %v, want %v", name, got, want)
+	}
+}
+
+func dentistID(dentist dentist.Dentist) uint {
+	return dentist.ID
+}
+
+func patientID(patient patient.Patient) uint {
+	return patient.ID
+}
+
+func appointmentID(appointment appointment.Appointment) uint {
+	return appointment.ID
+}
+
+func createDentist(t *testing.T, repositories Repositories, name string, lastname string, license string) dentist.Dentist {
+	t.Helper()
+	data, err := repositories.Dentists.Create(dentist.Dentist{Name: name, Lastname: lastname, License: license})
+	check(t, err)
+	if data.ID == 0 {
+		t.Fatalf("created dentist %s has no id", license)
+	}
+	return data
+}
+
+func createPatient(t *testing.T, repositories Repositories, name string, lastname string, dni string, admission time.Time) patient.Patient {
+	t.Helper()
+	data, err := repositories.Patients.Create(patient.Patient{
+		Name:          name,
+		Lastname:      lastname,
+		Address:       "Calle 123",
+		DNI:           dni,
+		Email:         dni + "@mail.com",
+		AdmissionDate: admission,
+	})
+	check(t, err)
+	if data.ID == 0 {
+		t.Fatalf("created patient %s has no id", dni)
+	}
+	return data
+}
+
+func createAppointment(t *testing.T, repositories Repositories, patientID uint, dentistID uint, date time.Time, minutes uint, status appointment.Status) appointment.Appointment {
+	t.Helper()
+	data, err := repositories.Appointments.Create(appointment.Appointment{
+		PatientID: patientID,
+		DentistID: dentistID,
+		Date:      date,
+		Duration:  minutes,
+		EndDate:   date.Add(time.Duration(minutes) * time.Minute),
+		Status:    status,
+	})
+	check(t, err)
+	if data.ID == 0 {
+		t.Fatalf("created appointment at %v has no id", date)
+	}
+	return data
+}
@@ -0,0 +1,24 @@
+package schedule
+
+import (
+	"time"
+)
+
+// TimeLayout is the layout used to store the start and end of working hours
+const TimeLayout = "15:04"
+
+// WorkingHours is a time range in which a dentist attends on a day of the week,
+// several ranges on the same day leave breaks between them and days without ranges are days off
+type WorkingHours struct {
+	ID        uint         `gorm:"primaryKey"`
+	DentistID uint         `gorm:"not null;index"`
+	Weekday   time.Weekday `gorm:"not null"`
+	Start     string       `gorm:"column:start_time;not null;type:varchar(5)"`
+	End       string       `gorm:"column:end_time;not null;type:varchar(5)"`
+}
+
+// Slot is a free time range in a dentist schedule
+type Slot struct {
+	Start time.Time
+	End   time.Time
+}
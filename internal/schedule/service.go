@@ -0,0 +1,187 @@
+package schedule
+
+import (
+	"sort"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+)
+
+// Validate checks that every range is well-formed and that ranges on the same day don't overlap
+func Validate(hours []WorkingHours) error {
+	byWeekday := map[time.Weekday][]WorkingHours{}
+	for _, current := range hours {
+		if current.Weekday < time.Sunday || current.Weekday > time.Saturday {
+			return internal.ErInvalidSchedule
+		}
+
+		start, end, err := minutes(current)
+		if err != nil || start >= end {
+			return internal.ErInvalidSchedule
+		}
+
+		byWeekday[current.Weekday] = append(byWeekday[current.Weekday], current)
+	}
+
+	for _, ranges := range byWeekday {
+		sortByStart(ranges)
+		for i := 1; i < len(ranges); i++ {
+			_, previousEnd, _ := minutes(ranges[i-1])
+			start, _, _ := minutes(ranges[i])
+			if start < previousEnd {
+				return internal.ErInvalidSchedule
+			}
+		}
+	}
+
+	return nil
+}
+
+// Contains reports whether the range from start to end fits in a single working range, like FreeSlots
+// a dentist without working hours has no time for appointments
+func Contains(hours []WorkingHours, start time.Time, end time.Time, loc *time.Location) bool {
+	start = start.In(loc)
+	end = end.In(loc)
+	for _, current := range hours {
+		if current.Weekday != start.Weekday() {
+			continue
+		}
+
+		from, to, err := bounds(current, start)
+		if err != nil {
+			continue
+		}
+
+		if !start.Before(from) && !end.After(to) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FreeSlots splits the working hours between from and to in slots of the given length,
+// leaving out the slots that overlap with a busy range
+func FreeSlots(hours []WorkingHours, busy []Slot, from time.Time, to time.Time, length time.Duration, loc *time.Location) []Slot {
+	slots := []Slot{}
+	if length <= 0 || !from.Before(to) {
+		return slots
+	}
+
+	sortByStart(hours)
+	from = from.In(loc)
+	to = to.In(loc)
+
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc)
+	for ; day.Before(to); day = day.AddDate(0, 0, 1) {
+		for _, current := range hours {
+			if current.Weekday != day.Weekday() {
+				continue
+			}
+
+			start, end, err := bounds(current, day)
+			if err != nil {
+				continue
+			}
+
+			for slotStart := start; !slotStart.Add(length).After(end); slotStart = slotStart.Add(length) {
+				slot := Slot{Start: slotStart, End: slotStart.Add(length)}
+				if slot.Start.Before(from) || slot.End.After(to) || overlaps(slot, busy) {
+					continue
+				}
+				slots = append(slots, slot)
+			}
+		}
+	}
+
+	return slots
+}
+
+// Available returns how long the working hours between from and to last, the ranges are cut at from and to
+func Available(hours []WorkingHours, from time.Time, to time.Time, loc *time.Location) time.Duration {
+	var total time.Duration
+	if !from.Before(to) {
+		return total
+	}
+
+	from = from.In(loc)
+	to = to.In(loc)
+
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc)
+	for ; day.Before(to); day = day.AddDate(0, 0, 1) {
+		for _, current := range hours {
+			if current.Weekday != day.Weekday() {
+				continue
+			}
+
+			start, end, err := bounds(current, day)
+			if err != nil {
+				continue
+			}
+
+			if start.Before(from) {
+				start = from
+			}
+			if end.After(to) {
+				end = to
+			}
+			if start.Before(end) {
+				total += end.Sub(start)
+			}
+		}
+	}
+
+	return total
+}
+
+func overlaps(slot Slot, busy []Slot) bool {
+	for _, current := range busy {
+		if current.Start.Before(slot.End) && current.End.After(slot.Start) {
+			return true
+		}
+	}
+	return false
+}
+
+// bounds returns the start and end of the working range on the day of the given date
+func bounds(hours WorkingHours, day time.Time) (time.Time, time.Time, error) {
+	start, end, err := minutes(hours)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	// Wall clock times are built with time.Date so they stay right on days with a DST change
+	from := time.Date(day.Year(), day.Month(), day.Day(), start/60, start%60, 0, 0, day.Location())
+	to := time.Date(day.Year(), day.Month(), day.Day(), end/60, end%60, 0, 0, day.Location())
+	return from, to, nil
+}
+
+// minutes returns the start and end of the working range as minutes since midnight
+func minutes(hours WorkingHours) (int, int, error) {
+	start, err := time.Parse(TimeLayout, hours.Start)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	end, err := time.Parse(TimeLayout, hours.End)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// 24:00 is not a valid clock time, 00:00 as end means the end of the day
+	endMinutes := end.Hour()*60 + end.Minute()
+	if endMinutes == 0 {
+		endMinutes = 24 * 60
+	}
+
+	return start.Hour()*60 + start.Minute(), endMinutes, nil
+}
+
+func sortByStart(hours []WorkingHours) {
+	sort.SliceStable(hours, func(i, j int) bool {
+		if hours[i].Weekday != hours[j].Weekday {
+			return hours[i].Weekday < hours[j].Weekday
+		}
+		return hours[i].Start < hours[j].Start
+	})
+}
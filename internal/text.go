@@ -0,0 +1,15 @@
+package internal
+
+import "unicode/utf8"
+
+// Truncate cuts text to at most size bytes without splitting a character, for the errors stored in columns of a
+// fixed size
+func Truncate(text string, size int) string {
+	if len(text) <= size {
+		return text
+	}
+	for size > 0 && !utf8.RuneStart(text[size]) {
+		size--
+	}
+	return text[:size]
+}
@@ -0,0 +1,46 @@
+package user
+
+import (
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/auth"
+)
+
+type User struct {
+	ID           uint      `gorm:"primaryKey"`
+	Username     string    `gorm:"not null;unique;type:varchar(60)"`
+	PasswordHash string    `gorm:"not null;type:varchar(100)"`
+	Role         auth.Role `gorm:"not null;type:varchar(20)"`
+	DentistID    *uint
+	CreatedAt    time.Time `gorm:"not null;precision:3"`
+	// FeedTokenHash is the SHA-256 of the token that gives access to the calendar feeds, only the hash is stored
+	FeedTokenHash *string `gorm:"unique;type:varchar(64)"`
+}
+
+// Tokens are the signed tokens given on login, the refresh token is used to get a new pair
+type Tokens struct {
+	AccessToken      string
+	RefreshToken     string
+	AccessExpiresAt  time.Time
+	RefreshExpiresAt time.Time
+}
+
+// TokenConfig holds the key used to sign tokens and how long they last
+type TokenConfig struct {
+	SigningKey []byte
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+}
+
+// Principal is the identity carried by the tokens of the user
+func (u User) Principal() auth.Principal {
+	principal := auth.Principal{
+		UserID:   u.ID,
+		Username: u.Username,
+		Role:     u.Role,
+	}
+	if u.DentistID != nil {
+		principal.DentistID = *u.DentistID
+	}
+	return principal
+}
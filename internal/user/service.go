@@ -0,0 +1,200 @@
+package user
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/auth"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MinPasswordLength is the shortest password accepted for a user
+const MinPasswordLength = 8
+
+type Repository interface {
+	Create(user User) (User, error)
+	GetAll() ([]User, error)
+	GetByID(id uint) (User, error)
+	GetByUsername(username string) (User, error)
+	// GetByFeedToken returns the user with the hash of a feed token
+	GetByFeedToken(hash string) (User, error)
+	SetFeedToken(id uint, hash string) error
+	Count() (int64, error)
+}
+
+type Service struct {
+	repository Repository
+	tokens     TokenConfig
+}
+
+func NewService(repository Repository, tokens TokenConfig) *Service {
+	return &Service{repository: repository, tokens: tokens}
+}
+
+func (s *Service) GetAll() ([]User, error) {
+	data, err := s.repository.GetAll()
+	if err != nil {
+		return nil, internal.ErServiceUnavailable
+	}
+
+	return data, nil
+}
+
+// Create stores a new user with a hash of the password, dentist users must be linked to a dentist
+func (s *Service) Create(user User, password string) (User, error) {
+	Normalize(&user)
+
+	if !user.Role.Valid() {
+		return User{}, internal.ErInvalidRole
+	}
+
+	if (user.Role == auth.RoleDentist) != (user.DentistID != nil) {
+		return User{}, internal.ErInvalidRole
+	}
+
+	if len(password) < MinPasswordLength {
+		return User{}, internal.ErWeakPassword
+	}
+
+	_, err := s.repository.GetByUsername(user.Username)
+	if err == nil {
+		return User{}, internal.ErUsernameAlreadyExists
+	}
+	if !errors.Is(err, internal.ErNotFound) {
+		return User{}, internal.ErServiceUnavailable
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return User{}, internal.ErWeakPassword
+	}
+	user.PasswordHash = string(hash)
+
+	userCreated, err := s.repository.Create(user)
+	if err != nil {
+		return User{}, internal.ErServiceUnavailable
+	}
+
+	return userCreated, nil
+}
+
+// Bootstrap creates an admin with the given credentials when there are no users yet
+func (s *Service) Bootstrap(username string, password string) error {
+	if username == "" || password == "" {
+		return nil
+	}
+
+	count, err := s.repository.Count()
+	if err != nil {
+		return internal.ErServiceUnavailable
+	}
+	if count > 0 {
+		return nil
+	}
+
+	_, err = s.Create(User{Username: username, Role: auth.RoleAdmin}, password)
+	return err
+}
+
+// Login checks the credentials of a user and issues a new pair of tokens
+func (s *Service) Login(username string, password string) (Tokens, error) {
+	userSearched, err := s.repository.GetByUsername(strings.ToLower(strings.TrimSpace(username)))
+	if err != nil {
+		switch {
+		case errors.Is(err, internal.ErNotFound):
+			// Hash anyway so unknown usernames take as long as wrong passwords
+			_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+			return Tokens{}, internal.ErInvalidCredentials
+
+		default:
+			return Tokens{}, internal.ErServiceUnavailable
+		}
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(userSearched.PasswordHash), []byte(password))
+	if err != nil {
+		return Tokens{}, internal.ErInvalidCredentials
+	}
+
+	return s.tokens.issue(userSearched, time.Now())
+}
+
+// Refresh issues a new pair of tokens from a refresh token, the user is read again so role changes apply
+func (s *Service) Refresh(refreshToken string) (Tokens, error) {
+	principal, err := s.tokens.parse(refreshToken, refreshTokenType)
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	userSearched, err := s.repository.GetByID(principal.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, internal.ErNotFound):
+			return Tokens{}, internal.ErInvalidToken
+
+		default:
+			return Tokens{}, internal.ErServiceUnavailable
+		}
+	}
+
+	return s.tokens.issue(userSearched, time.Now())
+}
+
+// Authenticate validates an access token and returns the identity it carries
+func (s *Service) Authenticate(accessToken string) (auth.Principal, error) {
+	return s.tokens.parse(accessToken, accessTokenType)
+}
+
+// RotateFeedToken gives the user a new feed token for the calendar feeds, the previous one stops working
+func (s *Service) RotateFeedToken(userID uint) (string, error) {
+	token, err := newFeedToken()
+	if err != nil {
+		return "", internal.ErServiceUnavailable
+	}
+
+	err = s.repository.SetFeedToken(userID, hashFeedToken(token))
+	if err != nil {
+		switch {
+		case errors.Is(err, internal.ErNotFound):
+			return "", internal.ErNotFound
+
+		default:
+			return "", internal.ErServiceUnavailable
+		}
+	}
+
+	return token, nil
+}
+
+// AuthenticateFeed returns the identity of the user a feed token belongs to, the user is read on every
+// request so role changes and rotations apply right away
+func (s *Service) AuthenticateFeed(feedToken string) (auth.Principal, error) {
+	if feedToken == "" {
+		return auth.Principal{}, internal.ErInvalidToken
+	}
+
+	userSearched, err := s.repository.GetByFeedToken(hashFeedToken(feedToken))
+	if err != nil {
+		switch {
+		case errors.Is(err, internal.ErNotFound):
+			return auth.Principal{}, internal.ErInvalidToken
+
+		default:
+			return auth.Principal{}, internal.ErServiceUnavailable
+		}
+	}
+
+	return userSearched.Principal(), nil
+}
+
+// dummyHash is compared against when the username doesn't exist
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
+// Custom functions for the service
+// Normalize is used to avoid duplicated usernames that only differ in case
+
+func Normalize(user *User) {
+	user.Username = strings.ToLower(strings.TrimSpace(user.Username))
+}
@@ -0,0 +1,115 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/auth"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	accessTokenType  = "access"
+	refreshTokenType = "refresh"
+)
+
+// feedTokenBytes is the number of random bytes of a feed token
+const feedTokenBytes = 32
+
+type claims struct {
+	Type      string    `json:"typ"`
+	Username  string    `json:"username"`
+	Role      auth.Role `json:"role"`
+	DentistID uint      `json:"dentist_id,omitempty"`
+	jwt.RegisteredClaims
+}
+
+func (c TokenConfig) issue(user User, now time.Time) (Tokens, error) {
+	tokens := Tokens{
+		AccessExpiresAt:  now.Add(c.AccessTTL),
+		RefreshExpiresAt: now.Add(c.RefreshTTL),
+	}
+
+	var err error
+	tokens.AccessToken, err = c.sign(user, accessTokenType, now, tokens.AccessExpiresAt)
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	tokens.RefreshToken, err = c.sign(user, refreshTokenType, now, tokens.RefreshExpiresAt)
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	return tokens, nil
+}
+
+func (c TokenConfig) sign(user User, tokenType string, now time.Time, expiresAt time.Time) (string, error) {
+	principal := user.Principal()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims{
+		Type:      tokenType,
+		Username:  principal.Username,
+		Role:      principal.Role,
+		DentistID: principal.DentistID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatUint(uint64(principal.UserID), 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	})
+
+	return token.SignedString(c.SigningKey)
+}
+
+// parse validates the signature, expiration and type of a token and returns its principal
+func (c TokenConfig) parse(raw string, tokenType string) (auth.Principal, error) {
+	parsed := claims{}
+	_, err := jwt.ParseWithClaims(raw, &parsed, func(token *jwt.Token) (interface{}, error) {
+		return c.SigningKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return auth.Principal{}, internal.ErTokenExpired
+		}
+		return auth.Principal{}, internal.ErInvalidToken
+	}
+
+	if parsed.Type != tokenType {
+		return auth.Principal{}, internal.ErInvalidToken
+	}
+
+	userID, err := strconv.ParseUint(parsed.Subject, 10, 64)
+	if err != nil {
+		return auth.Principal{}, internal.ErInvalidToken
+	}
+
+	return auth.Principal{
+		UserID:    uint(userID),
+		Username:  parsed.Username,
+		Role:      parsed.Role,
+		DentistID: parsed.DentistID,
+	}, nil
+}
+
+// newFeedToken returns a random token that can't be guessed, safe to use in URLs
+func newFeedToken() (string, error) {
+	raw := make([]byte, feedTokenBytes)
+	_, err := rand.Read(raw)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// hashFeedToken returns the hash stored for a feed token
+func hashFeedToken(feedToken string) string {
+	sum := sha256.Sum256([]byte(feedToken))
+	return hex.EncodeToString(sum[:])
+}
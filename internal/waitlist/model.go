@@ -0,0 +1,147 @@
+package waitlist
+
+import (
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/appointment"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/schedule"
+)
+
+// EntryStatus is the step an entry of the waitlist is in
+type EntryStatus string
+
+const (
+	// EntryWaiting entries are in the queue for the next matching slot
+	EntryWaiting EntryStatus = "waiting"
+	// EntryOffered entries have a pending offer
+	EntryOffered EntryStatus = "offered"
+	// EntryBooked entries accepted an offer and got an appointment
+	EntryBooked EntryStatus = "booked"
+	// EntryCancelled entries left the queue
+	EntryCancelled EntryStatus = "cancelled"
+	// EntryExpired entries reached the end of their date range without an appointment
+	EntryExpired EntryStatus = "expired"
+)
+
+// EntryStatuses lists every status of an entry
+var EntryStatuses = []EntryStatus{EntryWaiting, EntryOffered, EntryBooked, EntryCancelled, EntryExpired}
+
+func (s EntryStatus) Valid() bool {
+	for _, current := range EntryStatuses {
+		if current == s {
+			return true
+		}
+	}
+	return false
+}
+
+// OfferStatus is the answer to an offer
+type OfferStatus string
+
+const (
+	OfferPending  OfferStatus = "pending"
+	OfferAccepted OfferStatus = "accepted"
+	OfferDeclined OfferStatus = "declined"
+	OfferExpired  OfferStatus = "expired"
+	// OfferTaken offers were accepted after the slot had been booked by someone else
+	OfferTaken OfferStatus = "taken"
+)
+
+// Entry queues a patient for an appointment with a dentist, any time between From and To that starts
+// and ends inside the daily window, WindowStart and WindowEnd are wall clock times in the clinic time zone
+type Entry struct {
+	ID          uint      `gorm:"primaryKey"`
+	PatientID   uint      `gorm:"not null;index"`
+	DentistID   uint      `gorm:"not null;index:idx_waitlist_entries_dentist_status,priority:1"`
+	From        time.Time `gorm:"column:from_date;not null;precision:3"`
+	To          time.Time `gorm:"column:to_date;not null;precision:3"`
+	WindowStart string    `gorm:"not null;type:varchar(5)"`
+	WindowEnd   string    `gorm:"not null;type:varchar(5)"`
+	Duration    uint      `gorm:"not null;default:30"`
+	Description string
+	Status      EntryStatus `gorm:"not null;type:varchar(20);index:idx_waitlist_entries_dentist_status,priority:2"`
+	CreatedAt   time.Time   `gorm:"not null;precision:3"`
+	Offers      []Offer     `gorm:"foreignKey:EntryID"`
+}
+
+func (Entry) TableName() string {
+	return "waitlist_entries"
+}
+
+// Offer proposes a freed slot to the patient of an entry until ExpiresAt, AppointmentID is the
+// appointment booked when it's accepted
+type Offer struct {
+	ID            uint        `gorm:"primaryKey"`
+	EntryID       uint        `gorm:"not null;index"`
+	PatientID     uint        `gorm:"not null;index"`
+	DentistID     uint        `gorm:"not null;index:idx_waitlist_offers_dentist_start,priority:1"`
+	Start         time.Time   `gorm:"column:start_date;not null;precision:3;index:idx_waitlist_offers_dentist_start,priority:2"`
+	End           time.Time   `gorm:"column:end_date;not null;precision:3"`
+	Status        OfferStatus `gorm:"not null;type:varchar(20);index"`
+	ExpiresAt     time.Time   `gorm:"not null;precision:3;index"`
+	AppointmentID *uint
+	CreatedAt     time.Time  `gorm:"not null;precision:3"`
+	RespondedAt   *time.Time `gorm:"precision:3"`
+}
+
+func (Offer) TableName() string {
+	return "waitlist_offers"
+}
+
+// Filter narrows a list of entries, empty fields match every entry
+type Filter struct {
+	PatientID uint
+	DentistID uint
+	Statuses  []EntryStatus
+}
+
+// SortColumns maps the fields a list of entries can be sorted by to their columns
+var SortColumns = map[string]string{
+	"id":         "id",
+	"created_at": "created_at",
+	"from":       "from_date",
+	"to":         "to_date",
+	"status":     "status",
+}
+
+// Open reports whether the entry is still in the queue, waiting or with a pending offer
+func (e Entry) Open() bool {
+	return e.Status == EntryWaiting || e.Status == EntryOffered
+}
+
+// Valid reports whether the entry has a date range and a daily window that can hold its duration
+func (e Entry) Valid() bool {
+	if e.Duration == 0 || e.Duration > appointment.MaxDuration || !e.From.Before(e.To) {
+		return false
+	}
+
+	window := e.window(time.Sunday)
+	if schedule.Validate([]schedule.WorkingHours{window}) != nil {
+		return false
+	}
+
+	start, _ := time.Parse(schedule.TimeLayout, e.WindowStart)
+	end, _ := time.Parse(schedule.TimeLayout, e.WindowEnd)
+	// 00:00 as end means the end of the day, like in working hours
+	if e.WindowEnd == "00:00" {
+		end = end.Add(24 * time.Hour)
+	}
+	return end.Sub(start) >= time.Duration(e.Duration)*time.Minute
+}
+
+// Fits reports whether an appointment of the entry starting at start is in its date range and daily window,
+// the window is read in loc
+func (e Entry) Fits(start time.Time, loc *time.Location) bool {
+	end := start.Add(time.Duration(e.Duration) * time.Minute)
+	if start.Before(e.From) || end.After(e.To) {
+		return false
+	}
+
+	window := e.window(start.In(loc).Weekday())
+	return schedule.Contains([]schedule.WorkingHours{window}, start, end, loc)
+}
+
+// window returns the daily window of the entry as the working hours of a weekday
+func (e Entry) window(weekday time.Weekday) schedule.WorkingHours {
+	return schedule.WorkingHours{Weekday: weekday, Start: e.WindowStart, End: e.WindowEnd}
+}
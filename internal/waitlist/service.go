@@ -0,0 +1,481 @@
+package waitlist
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/appointment"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/auth"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/pagination"
+)
+
+type Repository interface {
+	GetAll(filter Filter, page pagination.Request) (pagination.Page[Entry], error)
+	// GetByID returns an entry with its offers, newest first
+	GetByID(id uint) (Entry, error)
+	Create(entry Entry) (Entry, error)
+	Update(entry Entry) (Entry, error)
+	// GetWaiting returns the waiting entries of a dentist in queue order, locked until the transaction ends
+	GetWaiting(dentistID uint) ([]Entry, error)
+	// GetOffer returns an offer locked until the transaction ends
+	GetOffer(id uint) (Offer, error)
+	CreateOffer(offer Offer) (Offer, error)
+	UpdateOffer(offer Offer) (Offer, error)
+	// GetPendingOffers returns the pending offers of a dentist that overlap with the range
+	GetPendingOffers(dentistID uint, start time.Time, end time.Time) ([]Offer, error)
+	// GetOfferedEntries returns the ids of the entries already offered the slot of a dentist starting at start
+	GetOfferedEntries(dentistID uint, start time.Time) ([]uint, error)
+	// GetExpiredOffers returns the pending offers that expired before now
+	GetExpiredOffers(now time.Time) ([]Offer, error)
+	// ExpireEntries moves to expired the waiting entries whose date range ended before now
+	ExpireEntries(now time.Time) error
+	// Appointments returns the repository of the appointments, inside Transaction it is bound to the same transaction
+	Appointments() appointment.Repository
+	// Transaction runs fn with a repository bound to a single transaction
+	Transaction(fn func(repository Repository) error) error
+}
+
+// Booker books the appointment of an accepted offer in the transaction of the offer, it checks the schedule like
+// any other booking
+type Booker interface {
+	CreateIn(repository appointment.Repository, principal auth.Principal, appointment appointment.Appointment) (appointment.Appointment, error)
+}
+
+// Service handles the waitlist, location is the time zone of the clinic the daily windows are read in,
+// offers last offerTTL unless the slot starts earlier
+type Service struct {
+	repository Repository
+	booker     Booker
+	location   *time.Location
+	offerTTL   time.Duration
+}
+
+func NewService(repository Repository, booker Booker, location *time.Location, offerTTL time.Duration) *Service {
+	return &Service{repository: repository, booker: booker, location: location, offerTTL: offerTTL}
+}
+
+// GetAll returns a page of entries, dentists only get the entries for themselves
+func (s *Service) GetAll(principal auth.Principal, filter Filter, page pagination.Request) (pagination.Page[Entry], error) {
+	if !principal.Role.Valid() {
+		return pagination.Page[Entry]{}, internal.ErForbidden
+	}
+
+	if dentistID, ok := principal.Dentist(); ok {
+		filter.DentistID = dentistID
+	}
+
+	data, err := s.repository.GetAll(filter, page)
+	if err != nil {
+		return pagination.Page[Entry]{}, internal.ErServiceUnavailable
+	}
+
+	return data, nil
+}
+
+// GetByID returns an entry with its offers, entries for other dentists are not found for dentists
+func (s *Service) GetByID(principal auth.Principal, id uint) (Entry, error) {
+	data, err := s.repository.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, internal.ErNotFound):
+			return Entry{}, internal.ErNotFound
+
+		default:
+			return Entry{}, internal.ErServiceUnavailable
+		}
+	}
+
+	if !canAccess(principal, data.DentistID) {
+		return Entry{}, internal.ErNotFound
+	}
+
+	return data, nil
+}
+
+// Create queues a patient for a dentist, dentists can only queue patients for themselves
+func (s *Service) Create(principal auth.Principal, entry Entry) (Entry, error) {
+	if !canAccess(principal, entry.DentistID) {
+		return Entry{}, internal.ErForbidden
+	}
+
+	if entry.Duration == 0 {
+		entry.Duration = appointment.DefaultDuration
+	}
+	entry.From = entry.From.UTC()
+	entry.To = entry.To.UTC()
+	entry.Status = EntryWaiting
+	entry.Offers = nil
+
+	if !entry.Valid() {
+		return Entry{}, internal.ErInvalidWaitlistEntry
+	}
+
+	entryCreated, err := s.repository.Create(entry)
+	if err != nil {
+		return Entry{}, internal.ErServiceUnavailable
+	}
+
+	return entryCreated, nil
+}
+
+// Cancel takes an entry out of the queue, its pending offer is declined and moves on to the next entry
+func (s *Service) Cancel(principal auth.Principal, id uint) (Entry, error) {
+	entrySearched, err := s.GetByID(principal, id)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	var entryCancelled Entry
+	err = s.repository.Transaction(func(repository Repository) error {
+		var pending *Offer
+		for _, current := range entrySearched.Offers {
+			if current.Status == OfferPending {
+				offer, err := repository.GetOffer(current.ID)
+				if err != nil {
+					return err
+				}
+				pending = &offer
+			}
+		}
+
+		// The entry is read again under the lock of its offer, an answer may have closed it meanwhile
+		current, err := repository.GetByID(id)
+		if err != nil {
+			return err
+		}
+		if !current.Open() {
+			return internal.ErEntryClosed
+		}
+
+		now := time.Now()
+		current.Status = EntryCancelled
+		entryCancelled, err = repository.Update(current)
+		if err != nil {
+			return err
+		}
+
+		if pending == nil || pending.Status != OfferPending {
+			return nil
+		}
+
+		pending.Status = OfferDeclined
+		pending.RespondedAt = &now
+		_, err = repository.UpdateOffer(*pending)
+		if err != nil {
+			return err
+		}
+
+		return s.offer(repository, pending.DentistID, pending.Start, pending.End, now)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, internal.ErEntryClosed):
+			return Entry{}, internal.ErEntryClosed
+
+		case errors.Is(err, internal.ErNotFound):
+			return Entry{}, internal.ErNotFound
+
+		default:
+			return Entry{}, internal.ErServiceUnavailable
+		}
+	}
+
+	return entryCancelled, nil
+}
+
+// SlotFreed offers the slot of a deleted or cancelled appointment to the first entry of the queue of its
+// dentist that fits in it, slots that already started are left alone
+func (s *Service) SlotFreed(freed appointment.Appointment) {
+	now := time.Now()
+	if !freed.Date.After(now) {
+		return
+	}
+
+	err := s.repository.Transaction(func(repository Repository) error {
+		return s.offer(repository, freed.DentistID, freed.Date, freed.EndDate, now)
+	})
+	if err != nil {
+		log.Printf("waitlist: offering the slot of appointment %d: %v", freed.ID, err)
+	}
+}
+
+// Accept books the appointment of a pending offer in the same transaction that closes the offer, when the slot
+// was booked by someone else meanwhile the offer is closed as taken and its entry goes back to the queue
+func (s *Service) Accept(principal auth.Principal, id uint) (Offer, error) {
+	var offerAccepted Offer
+	taken := false
+	err := s.repository.Transaction(func(repository Repository) error {
+		now := time.Now()
+		offer, entry, err := pendingOffer(repository, principal, id, now)
+		if err != nil {
+			return err
+		}
+
+		appointmentCreated, err := s.booker.CreateIn(repository.Appointments(), principal, appointment.Appointment{
+			PatientID:   offer.PatientID,
+			DentistID:   offer.DentistID,
+			Date:        offer.Start,
+			Duration:    entry.Duration,
+			Description: entry.Description,
+		})
+
+		status := EntryBooked
+		switch {
+		case err == nil:
+			offer.Status = OfferAccepted
+			offer.AppointmentID = &appointmentCreated.ID
+
+		case errors.Is(err, internal.ErAppointmentConflict), errors.Is(err, internal.ErOutsideWorkingHours):
+			// Nothing was booked, closing the offer is all this transaction commits
+			taken = true
+			offer.Status = OfferTaken
+			status = EntryWaiting
+
+		default:
+			return err
+		}
+
+		offer.RespondedAt = &now
+		offerAccepted, err = repository.UpdateOffer(offer)
+		if err != nil {
+			return err
+		}
+
+		if entry.Status == EntryCancelled {
+			return nil
+		}
+		entry.Status = status
+		_, err = repository.Update(entry)
+		return err
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, internal.ErOfferClosed), errors.Is(err, internal.ErNotFound), errors.Is(err, internal.ErForbidden):
+			return Offer{}, err
+
+		default:
+			return Offer{}, internal.ErServiceUnavailable
+		}
+	}
+
+	if taken {
+		return Offer{}, internal.ErOfferTaken
+	}
+	return offerAccepted, nil
+}
+
+// Decline closes a pending offer, its entry goes back to the queue and the slot moves on to the next entry
+func (s *Service) Decline(principal auth.Principal, id uint) (Offer, error) {
+	var offerDeclined Offer
+	err := s.repository.Transaction(func(repository Repository) error {
+		now := time.Now()
+		offer, _, err := pendingOffer(repository, principal, id, now)
+		if err != nil {
+			return err
+		}
+
+		offer.Status = OfferDeclined
+		offer.RespondedAt = &now
+		offerDeclined, err = repository.UpdateOffer(offer)
+		if err != nil {
+			return err
+		}
+
+		err = requeue(repository, offer.EntryID)
+		if err != nil {
+			return err
+		}
+		return s.offer(repository, offer.DentistID, offer.Start, offer.End, now)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, internal.ErOfferClosed), errors.Is(err, internal.ErNotFound):
+			return Offer{}, err
+
+		default:
+			return Offer{}, internal.ErServiceUnavailable
+		}
+	}
+
+	return offerDeclined, nil
+}
+
+// Expire closes the offers that weren't answered in time, moving their slots on to the next entries, and
+// takes out of the queue the entries whose date range ended
+func (s *Service) Expire(now time.Time) error {
+	expired, err := s.repository.GetExpiredOffers(now)
+	if err != nil {
+		return internal.ErServiceUnavailable
+	}
+
+	for _, current := range expired {
+		err = s.repository.Transaction(func(repository Repository) error {
+			offer, err := repository.GetOffer(current.ID)
+			if err != nil {
+				return err
+			}
+			if offer.Status != OfferPending {
+				return nil
+			}
+
+			offer.Status = OfferExpired
+			_, err = repository.UpdateOffer(offer)
+			if err != nil {
+				return err
+			}
+
+			err = requeue(repository, offer.EntryID)
+			if err != nil {
+				return err
+			}
+
+			return s.offer(repository, offer.DentistID, offer.Start, offer.End, now)
+		})
+		if err != nil {
+			return internal.ErServiceUnavailable
+		}
+	}
+
+	err = s.repository.ExpireEntries(now)
+	if err != nil {
+		return internal.ErServiceUnavailable
+	}
+	return nil
+}
+
+// Run expires the offers every interval until the context is done
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case now := <-ticker.C:
+			err := s.Expire(now)
+			if err != nil {
+				log.Printf("waitlist: expiring offers: %v", err)
+			}
+		}
+	}
+}
+
+// pendingOffer returns an offer the principal can still answer with its entry, the offer stays locked until the
+// transaction ends
+func pendingOffer(repository Repository, principal auth.Principal, id uint, now time.Time) (Offer, Entry, error) {
+	offer, err := repository.GetOffer(id)
+	if err != nil {
+		return Offer{}, Entry{}, err
+	}
+
+	if !canAccess(principal, offer.DentistID) {
+		return Offer{}, Entry{}, internal.ErNotFound
+	}
+
+	if offer.Status != OfferPending || !offer.ExpiresAt.After(now) {
+		return Offer{}, Entry{}, internal.ErOfferClosed
+	}
+
+	entry, err := repository.GetByID(offer.EntryID)
+	if err != nil {
+		return Offer{}, Entry{}, err
+	}
+	return offer, entry, nil
+}
+
+// offer creates an offer of the slot from start to end for the first waiting entry of the dentist that fits
+// in it and wasn't offered that slot before, nothing is offered while another offer of the slot is pending
+func (s *Service) offer(repository Repository, dentistID uint, start time.Time, end time.Time, now time.Time) error {
+	if !start.After(now) {
+		return nil
+	}
+
+	pending, err := repository.GetPendingOffers(dentistID, start, end)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return nil
+	}
+
+	waiting, err := repository.GetWaiting(dentistID)
+	if err != nil {
+		return err
+	}
+
+	offered, err := repository.GetOfferedEntries(dentistID, start)
+	if err != nil {
+		return err
+	}
+
+	for _, current := range waiting {
+		if contains(offered, current.ID) || !current.Fits(start, s.location) {
+			continue
+		}
+
+		offerEnd := start.Add(time.Duration(current.Duration) * time.Minute)
+		if offerEnd.After(end) {
+			continue
+		}
+
+		// Patients can't answer after the slot started
+		expiresAt := now.Add(s.offerTTL)
+		if expiresAt.After(start) {
+			expiresAt = start
+		}
+
+		_, err = repository.CreateOffer(Offer{
+			EntryID:   current.ID,
+			PatientID: current.PatientID,
+			DentistID: dentistID,
+			Start:     start,
+			End:       offerEnd,
+			Status:    OfferPending,
+			ExpiresAt: expiresAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		current.Status = EntryOffered
+		_, err = repository.Update(current)
+		return err
+	}
+
+	return nil
+}
+
+// requeue puts an entry with an offer back in the queue, unless it was cancelled meanwhile
+func requeue(repository Repository, id uint) error {
+	entry, err := repository.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if entry.Status != EntryOffered {
+		return nil
+	}
+
+	entry.Status = EntryWaiting
+	_, err = repository.Update(entry)
+	return err
+}
+
+// canAccess reports whether the principal can see the entries and offers for the dentist,
+// dentists only see their own
+func canAccess(principal auth.Principal, dentistID uint) bool {
+	return appointment.CanAccess(principal, appointment.Appointment{DentistID: dentistID})
+}
+
+func contains(ids []uint, id uint) bool {
+	for _, current := range ids {
+		if current == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package webhook
+
+import (
+	"strings"
+	"time"
+)
+
+// MaxAttempts is how many times a delivery is tried before it is dead
+const MaxAttempts uint = 10
+
+// Subscription is a URL the events are POSTed to, signed with its secret. Events is a comma separated list of
+// event types, empty for every type
+type Subscription struct {
+	ID        uint      `gorm:"primaryKey"`
+	URL       string    `gorm:"not null;type:varchar(2048)"`
+	Secret    string    `gorm:"not null;type:varchar(64)"`
+	Events    string    `gorm:"type:varchar(512)"`
+	Active    bool      `gorm:"not null;default:true"`
+	CreatedAt time.Time `gorm:"precision:3"`
+	UpdatedAt time.Time `gorm:"precision:3"`
+}
+
+func (Subscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+// EventTypes returns the event types of the subscription, empty for every type
+func (s Subscription) EventTypes() []string {
+	if s.Events == "" {
+		return nil
+	}
+	return strings.Split(s.Events, ",")
+}
+
+// JoinEvents turns a list of event types into the Events of a subscription
+func JoinEvents(types []string) string {
+	return strings.Join(types, ",")
+}
+
+// Matches reports whether the subscription gets the events of the type
+func (s Subscription) Matches(eventType string) bool {
+	if !s.Active {
+		return false
+	}
+	if s.Events == "" {
+		return true
+	}
+	for _, current := range s.EventTypes() {
+		if current == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// DeliveryStatus is the state of the delivery of an event to a subscription
+type DeliveryStatus string
+
+const (
+	// DeliveryPending deliveries are sent at NextAttemptAt
+	DeliveryPending DeliveryStatus = "pending"
+	// DeliveryDelivered deliveries got a 2xx response
+	DeliveryDelivered DeliveryStatus = "delivered"
+	// DeliveryDead deliveries failed MaxAttempts times, or their subscription was deleted, they are kept until
+	// retried by hand
+	DeliveryDead DeliveryStatus = "dead"
+)
+
+// DeliveryStatuses lists every status of a delivery
+var DeliveryStatuses = []DeliveryStatus{DeliveryPending, DeliveryDelivered, DeliveryDead}
+
+func (s DeliveryStatus) Valid() bool {
+	for _, current := range DeliveryStatuses {
+		if current == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivery is an event to send to a subscription, with the outcome of its last attempt
+type Delivery struct {
+	ID             uint           `gorm:"primaryKey"`
+	EventID        uint           `gorm:"not null;uniqueIndex:idx_webhook_deliveries_event_subscription,priority:1"`
+	SubscriptionID uint           `gorm:"not null;uniqueIndex:idx_webhook_deliveries_event_subscription,priority:2;index"`
+	EventType      string         `gorm:"not null;type:varchar(40)"`
+	Status         DeliveryStatus `gorm:"not null;type:varchar(20);index:idx_webhook_deliveries_due,priority:1"`
+	Attempts       uint           `gorm:"not null;default:0"`
+	NextAttemptAt  time.Time      `gorm:"not null;precision:3;index:idx_webhook_deliveries_due,priority:2"`
+	LastStatusCode int
+	LastError      string     `gorm:"type:varchar(255)"`
+	DeliveredAt    *time.Time `gorm:"precision:3"`
+	CreatedAt      time.Time  `gorm:"precision:3"`
+}
+
+func (Delivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// DeliveryFilter narrows a list of deliveries, empty fields match every delivery
+type DeliveryFilter struct {
+	SubscriptionID uint
+	EventID        uint
+	Status         DeliveryStatus
+}
+
+// DeliverySortColumns maps the fields a list of deliveries can be sorted by to their columns
+var DeliverySortColumns = map[string]string{
+	"id":              "id",
+	"created_at":      "created_at",
+	"next_attempt_at": "next_attempt_at",
+	"attempts":        "attempts",
+}
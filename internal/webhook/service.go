@@ -0,0 +1,425 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/event"
+	"github.com/10Daniel10/web-server-go-ExamenFinal/internal/pagination"
+)
+
+const (
+	// batchSize is how many events are dispatched, and deliveries sent, on every run
+	batchSize = 100
+	// lease is how long other instances skip the deliveries being sent, it is renewed for the ones left once
+	// half of it passed, so a batch of slow webhooks isn't sent twice
+	lease = 2 * time.Minute
+	// secretBytes is the number of random bytes of a generated secret
+	secretBytes = 32
+	// minSecretLength and maxSecretLength bound the length of a secret given by the user
+	minSecretLength = 16
+	maxSecretLength = 64
+)
+
+// Headers of the requests sent to the webhooks
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+type Repository interface {
+	GetAll() ([]Subscription, error)
+	GetByID(id uint) (Subscription, error)
+	Create(subscription Subscription) (Subscription, error)
+	Update(subscription Subscription) (Subscription, error)
+	// Delete removes a subscription, its pending deliveries are moved to dead
+	Delete(id uint) error
+	GetDeliveries(filter DeliveryFilter, page pagination.Request) (pagination.Page[Delivery], error)
+	// GetDelivery returns a delivery locked until the transaction ends
+	GetDelivery(id uint) (Delivery, error)
+	UpdateDelivery(delivery Delivery) (Delivery, error)
+	// GetUndispatched returns the oldest events not dispatched yet, up to limit, skipping the ones other
+	// transactions locked
+	GetUndispatched(limit int) ([]event.Event, error)
+	// Dispatch stores the deliveries of the events and marks the events as dispatched at now
+	Dispatch(deliveries []Delivery, eventIDs []uint, now time.Time) error
+	// GetDue returns the pending deliveries due at now, up to limit, skipping the ones other transactions locked
+	GetDue(now time.Time, limit int) ([]Delivery, error)
+	// Postpone moves the next attempt of the deliveries to until
+	Postpone(ids []uint, until time.Time) error
+	GetEvents(ids []uint) ([]event.Event, error)
+	// Transaction runs fn with a repository bound to a single transaction
+	Transaction(fn func(repository Repository) error) error
+}
+
+// Service manages the webhook subscriptions and delivers the events of the outbox to them
+type Service struct {
+	repository Repository
+	client     *http.Client
+}
+
+func NewService(repository Repository, client *http.Client) *Service {
+	return &Service{repository: repository, client: client}
+}
+
+func (s *Service) GetAll() ([]Subscription, error) {
+	return s.repository.GetAll()
+}
+
+func (s *Service) GetByID(id uint) (Subscription, error) {
+	return s.repository.GetByID(id)
+}
+
+// Create stores a subscription, a secret is generated when it has none
+func (s *Service) Create(subscription Subscription) (Subscription, error) {
+	if subscription.Secret == "" {
+		secret, err := newSecret()
+		if err != nil {
+			return Subscription{}, internal.ErServiceUnavailable
+		}
+		subscription.Secret = secret
+	}
+
+	err := validate(subscription)
+	if err != nil {
+		return Subscription{}, err
+	}
+
+	return s.repository.Create(subscription)
+}
+
+func (s *Service) Update(subscription Subscription) (Subscription, error) {
+	err := validate(subscription)
+	if err != nil {
+		return Subscription{}, err
+	}
+
+	_, err = s.repository.GetByID(subscription.ID)
+	if err != nil {
+		return Subscription{}, err
+	}
+
+	return s.repository.Update(subscription)
+}
+
+func (s *Service) Delete(id uint) error {
+	_, err := s.repository.GetByID(id)
+	if err != nil {
+		return err
+	}
+
+	return s.repository.Delete(id)
+}
+
+// GetDeliveries returns a page of deliveries, the newest first unless the page is sorted
+func (s *Service) GetDeliveries(filter DeliveryFilter, page pagination.Request) (pagination.Page[Delivery], error) {
+	if len(page.Sort) == 0 {
+		page.Sort = []pagination.Sort{{Column: "id", Desc: true}}
+	}
+
+	return s.repository.GetDeliveries(filter, page)
+}
+
+// Retry sends a dead delivery again, as if it was new
+func (s *Service) Retry(id uint) (Delivery, error) {
+	var deliveryRetried Delivery
+	err := s.repository.Transaction(func(repository Repository) error {
+		delivery, err := repository.GetDelivery(id)
+		if err != nil {
+			return err
+		}
+		if delivery.Status != DeliveryDead {
+			return internal.ErDeliveryNotDead
+		}
+
+		delivery.Status = DeliveryPending
+		delivery.Attempts = 0
+		delivery.NextAttemptAt = time.Now()
+		deliveryRetried, err = repository.UpdateDelivery(delivery)
+		return err
+	})
+	if err != nil {
+		return Delivery{}, err
+	}
+
+	return deliveryRetried, nil
+}
+
+// Deliver hands the new events of the outbox to the subscriptions that match them and sends the deliveries
+// due at now
+func (s *Service) Deliver(ctx context.Context, now time.Time) error {
+	err := s.dispatch(now)
+	if err != nil {
+		return err
+	}
+
+	var due []Delivery
+	var ids []uint
+	leased := now.Add(lease)
+	err = s.repository.Transaction(func(repository Repository) error {
+		var err error
+		due, err = repository.GetDue(now, batchSize)
+		if err != nil || len(due) == 0 {
+			return err
+		}
+
+		ids = make([]uint, 0, len(due))
+		for _, current := range due {
+			ids = append(ids, current.ID)
+		}
+
+		// Other instances skip them while they are sent, they are due again if this one stops halfway
+		return repository.Postpone(ids, leased)
+	})
+	if err != nil || len(due) == 0 {
+		return err
+	}
+
+	subscriptions, err := s.repository.GetAll()
+	if err != nil {
+		return err
+	}
+	subscriptionsByID := make(map[uint]Subscription, len(subscriptions))
+	for _, current := range subscriptions {
+		subscriptionsByID[current.ID] = current
+	}
+
+	eventIDs := make([]uint, 0, len(due))
+	for _, current := range due {
+		eventIDs = append(eventIDs, current.EventID)
+	}
+	events, err := s.repository.GetEvents(eventIDs)
+	if err != nil {
+		return err
+	}
+	eventsByID := make(map[uint]event.Event, len(events))
+	for _, current := range events {
+		eventsByID[current.ID] = current
+	}
+
+	for i, current := range due {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if time.Until(leased) < lease/2 {
+			leased = time.Now().Add(lease)
+			err = s.repository.Postpone(ids[i:], leased)
+			if err != nil {
+				return err
+			}
+		}
+
+		subscription, found := subscriptionsByID[current.SubscriptionID]
+		switch {
+		case !found:
+			current.Status = DeliveryDead
+			current.LastError = "subscription deleted"
+		case !subscription.Active:
+			current.Status = DeliveryDead
+			current.LastError = "subscription inactive"
+		default:
+			s.send(ctx, &current, subscription, eventsByID[current.EventID], time.Now())
+		}
+
+		_, err = s.repository.UpdateDelivery(current)
+		if err != nil {
+			log.Printf("webhook: recording delivery %d: %v", current.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// Run delivers the events every interval until the context is done
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case now := <-ticker.C:
+			err := s.Deliver(ctx, now)
+			if err != nil {
+				log.Printf("webhook: delivering events: %v", err)
+			}
+		}
+	}
+}
+
+// Sign returns the signature of a request, the hex HMAC-SHA256 of the timestamp, a dot and the body keyed with
+// the secret of the subscription, prefixed with sha256=
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff is how long a delivery waits after failing attempts times, it doubles from 30 seconds up to 6 hours
+func Backoff(attempts uint) time.Duration {
+	delay := 30 * time.Second
+	for i := uint(1); i < attempts && delay < 6*time.Hour; i++ {
+		delay *= 2
+	}
+	if delay > 6*time.Hour {
+		delay = 6 * time.Hour
+	}
+	return delay
+}
+
+// dispatch creates the deliveries of the events not dispatched yet, one for every subscription matching them
+func (s *Service) dispatch(now time.Time) error {
+	return s.repository.Transaction(func(repository Repository) error {
+		events, err := repository.GetUndispatched(batchSize)
+		if err != nil || len(events) == 0 {
+			return err
+		}
+
+		subscriptions, err := repository.GetAll()
+		if err != nil {
+			return err
+		}
+
+		var deliveries []Delivery
+		eventIDs := make([]uint, 0, len(events))
+		for _, currentEvent := range events {
+			eventIDs = append(eventIDs, currentEvent.ID)
+			for _, subscription := range subscriptions {
+				if !subscription.Matches(currentEvent.Type) {
+					continue
+				}
+				deliveries = append(deliveries, Delivery{
+					EventID:        currentEvent.ID,
+					SubscriptionID: subscription.ID,
+					EventType:      currentEvent.Type,
+					Status:         DeliveryPending,
+					NextAttemptAt:  now,
+				})
+			}
+		}
+
+		return repository.Dispatch(deliveries, eventIDs, now)
+	})
+}
+
+// envelope is the body POSTed to the webhooks
+type envelope struct {
+	ID         uint            `json:"id"`
+	Type       string          `json:"type"`
+	Entity     string          `json:"entity"`
+	EntityID   uint            `json:"entity_id"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+// send POSTs the event of a delivery to its subscription and records the outcome in the delivery
+func (s *Service) send(ctx context.Context, delivery *Delivery, subscription Subscription, data event.Event, now time.Time) {
+	delivery.Attempts++
+
+	err := s.post(ctx, delivery, subscription, data, now)
+	if err == nil {
+		delivery.Status = DeliveryDelivered
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+		return
+	}
+
+	delivery.LastError = internal.Truncate(err.Error(), 255)
+	if delivery.Attempts >= MaxAttempts {
+		delivery.Status = DeliveryDead
+		return
+	}
+	delivery.NextAttemptAt = now.Add(Backoff(delivery.Attempts))
+}
+
+func (s *Service) post(ctx context.Context, delivery *Delivery, subscription Subscription, data event.Event, now time.Time) error {
+	if data.ID == 0 {
+		return fmt.Errorf("event %d not found", delivery.EventID)
+	}
+
+	body, err := json.Marshal(envelope{
+		ID:         data.ID,
+		Type:       data.Type,
+		Entity:     data.Entity,
+		EntityID:   data.EntityID,
+		OccurredAt: data.CreatedAt.UTC(),
+		Payload:    json.RawMessage(data.Payload),
+	})
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(HeaderEvent, data.Type)
+	request.Header.Set(HeaderDelivery, strconv.FormatUint(uint64(delivery.ID), 10))
+	request.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	request.Header.Set(HeaderSignature, Sign(subscription.Secret, now.Unix(), body))
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		delivery.LastStatusCode = 0
+		return err
+	}
+	defer response.Body.Close()
+	// The body is drained so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, 64*1024))
+
+	delivery.LastStatusCode = response.StatusCode
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", response.StatusCode)
+	}
+	return nil
+}
+
+// validate checks the url, the event types and the secret of a subscription
+func validate(subscription Subscription) error {
+	target, err := url.Parse(subscription.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return internal.ErInvalidWebhook
+	}
+
+	for _, current := range subscription.EventTypes() {
+		if !event.ValidType(current) {
+			return internal.ErInvalidWebhook
+		}
+	}
+
+	if len(subscription.Secret) < minSecretLength || len(subscription.Secret) > maxSecretLength {
+		return internal.ErInvalidWebhook
+	}
+
+	return nil
+}
+
+func newSecret() (string, error) {
+	secret := make([]byte, secretBytes)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}